@@ -0,0 +1,353 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"library-api/backup"
+	"library-api/bookio"
+	"library-api/mergepatch"
+	"library-api/models"
+	"library-api/openapi"
+	"library-api/problem"
+)
+
+// apiVersion - Versión publicada en / y en /openapi.json
+const apiVersion = "1.1.0"
+
+// apiInfo - Encabezado del documento OpenAPI
+var apiInfo = openapi.Info{
+	Title:       "Library Digital API",
+	Version:     apiVersion,
+	Description: "API para gestión de biblioteca digital con integración de APIs externas (Open Library, Google Books)",
+}
+
+// apiErrorBody - Todos los errores se responden como problem+json (RFC 7807)
+var apiErrorBody = openapi.Content{problem.ContentType: problem.Problem{}}
+
+// ==================== DOCUMENTACIÓN DE RUTAS ====================
+
+// apiRoutes - Tabla de rutas documentadas en /openapi.json y en /. Cada ruta
+// registrada en setupRoutes debe figurar aquí (lo comprueba main_test.go)
+func apiRoutes() []openapi.Route {
+	var (
+		errorBody    = apiErrorBody
+		message      = openapi.Object{"message": ""}
+		bookNotFound = openapi.Response{Status: http.StatusNotFound, Description: "Book not found", Body: errorBody}
+		badRequest   = openapi.Response{Status: http.StatusBadRequest, Description: "Invalid request", Body: errorBody}
+		readiness    = openapi.Object{"status": "", "message": "", "checks": openapi.Object{"database": "", "migrations": "", "providers": map[string]string{}}}
+		rateLimited  = openapi.Response{Status: http.StatusTooManyRequests, Description: "Too Many Requests", Body: errorBody, Headers: map[string]string{"Retry-After": "Segundos hasta poder reintentar"}}
+		formatParam  = openapi.Param{Name: "format", Description: "Formato de salida (también se negocia con Accept)", Enum: append([]string{"json"}, bookio.FormatNames()...)}
+		filters      = []openapi.Param{
+			{Name: "title", Description: "Parte del título"},
+			{Name: "author", Description: "Parte del autor"},
+			{Name: "genre", Description: "Parte del género o materia"},
+			{Name: "available", Description: "Solo disponibles (true) o prestados (false)", Type: "boolean"},
+		}
+	)
+
+	return []openapi.Route{
+		// Información general
+		{Method: "GET", Path: "/", Tag: "General", Summary: "Descripción de la API y lista de endpoints",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: openapi.Object{
+				"message": "", "version": "", "features": "", "docs": "", "openapi": "", "endpoints": map[string]string{},
+			}}}},
+		{Method: "GET", Path: "/healthz", Tag: "General", Summary: "Liveness: el proceso responde",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: openapi.Object{"status": ""}}}},
+		{Method: "GET", Path: "/readyz", Tag: "General", Summary: "Readiness: base de datos, esquema y proveedores externos",
+			Description: "503 si la base de datos no responde o su esquema está incompleto; un proveedor con el circuito abierto deja el estado en degraded",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: readiness},
+				{Status: http.StatusServiceUnavailable, Description: "Not ready", Body: readiness},
+			}},
+		{Method: "GET", Path: "/health", Tag: "General", Summary: "Estado del servicio (alias de /readyz)",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: readiness},
+				{Status: http.StatusServiceUnavailable, Description: "Not ready", Body: readiness},
+			}},
+		{Method: "GET", Path: "/metrics", Tag: "General", Summary: "Métricas en formato Prometheus (según metrics.access: pública, solo admin o desactivada)",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.Content{"text/plain": nil}},
+				{Status: http.StatusForbidden, Description: "Admin access required (metrics.access: admin)", Body: errorBody},
+			}},
+		{Method: "GET", Path: "/openapi.json", Tag: "General", Summary: "Este documento OpenAPI",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: openapi.Object{}}}},
+		{Method: "GET", Path: "/docs", Tag: "General", Summary: "Documentación interactiva (Swagger UI)",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: openapi.Content{"text/html": nil}}}},
+
+		// Autenticación
+		{Method: "POST", Path: "/register", Tag: "Auth", Summary: "Registrar un usuario y obtener su token",
+			Body: models.RegisterRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: models.LoginResponse{}},
+				badRequest,
+				{Status: http.StatusConflict, Description: "Username already exists", Body: errorBody},
+				rateLimited,
+			}},
+		{Method: "POST", Path: "/api/register", Tag: "Auth", Summary: "Registrar un usuario (alias de /register)",
+			Body: models.RegisterRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: models.LoginResponse{}},
+				badRequest,
+				{Status: http.StatusConflict, Description: "Username already exists", Body: errorBody},
+				rateLimited,
+			}},
+		{Method: "POST", Path: "/login", Tag: "Auth", Summary: "Iniciar sesión",
+			Body: models.LoginRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: models.LoginResponse{}},
+				badRequest,
+				{Status: http.StatusUnauthorized, Description: "Invalid credentials", Body: errorBody},
+				rateLimited,
+			}},
+		{Method: "GET", Path: "/me", Tag: "Auth", Summary: "Usuario autenticado", Access: openapi.User,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: openapi.Object{"user_id": "", "role": ""}}}},
+
+		// Libros del catálogo
+		{Method: "GET", Path: "/books", Tag: "Books", Summary: "Listar libros",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: []models.Book{}}}},
+		{Method: "POST", Path: "/books", Tag: "Books", Summary: "Crear un libro", Access: openapi.User,
+			Body: models.CreateBookRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: models.Book{}},
+				badRequest,
+				{Status: http.StatusConflict, Description: "A book with this ISBN already exists", Body: errorBody},
+			}},
+		{Method: "GET", Path: "/books/:id", Tag: "Books", Summary: "Obtener un libro",
+			Description: "Con ?format= o Accept devuelve el registro en otro formato (marcxml, bibtex, ris, csl-json...)",
+			Params:      []openapi.Param{formatParam},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: bookContent(models.Book{})}, bookNotFound}},
+		{Method: "PUT", Path: "/books/:id", Tag: "Books", Summary: "Actualizar un libro", Access: openapi.User,
+			Description: "Solo se modifican los campos enviados",
+			Body:        models.UpdateBookRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: models.Book{}},
+				badRequest,
+				bookNotFound,
+				{Status: http.StatusConflict, Description: "A book with this ISBN already exists", Body: errorBody},
+			}},
+		{Method: "PATCH", Path: "/books/:id", Tag: "Books", Summary: "Modificar campos de un libro", Access: openapi.User,
+			Description: "JSON Merge Patch (RFC 7396): los campos ausentes no cambian y null borra el campo. " +
+				"Solo se informan los errores de validación de los campos enviados",
+			Body: openapi.Content{mergepatch.ContentType: openapi.Object{"description": nil, "published": 1967}},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: models.Book{}},
+				badRequest,
+				bookNotFound,
+				{Status: http.StatusConflict, Description: "A book with this ISBN already exists", Body: errorBody},
+				{Status: http.StatusUnsupportedMediaType, Description: "Content-Type is not merge-patch+json or JSON", Body: errorBody},
+			}},
+		{Method: "DELETE", Path: "/books/:id", Tag: "Books", Summary: "Eliminar un libro", Access: openapi.User,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: message}, bookNotFound}},
+		{Method: "GET", Path: "/books/search", Tag: "Books", Summary: "Buscar libros",
+			Params:    append(filters, formatParam),
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: bookContent([]models.Book{})}}},
+		{Method: "GET", Path: "/books/export", Tag: "Catalog", Summary: "Exportar el catálogo filtrado",
+			Params: append(filters, openapi.Param{Name: "format", Description: "Formato del archivo (csv por defecto)", Enum: bookio.FormatNames()}),
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: bookContent(nil), Headers: map[string]string{"Content-Disposition": "Nombre del archivo descargado"}},
+				badRequest,
+			}},
+		{Method: "POST", Path: "/books/export", Tag: "Catalog", Summary: "Bibliografía de una selección de libros",
+			Description: "BibTeX por defecto; el formato también se negocia con Accept",
+			Body:        models.ExportBooksRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: bookContent(nil)},
+				badRequest,
+				{Status: http.StatusNotFound, Description: "Some books were not found (IDs in missing)", Body: errorBody},
+			}},
+		{Method: "POST", Path: "/books/import", Tag: "Catalog", Summary: "Importar un catálogo (CSV, XLSX, MARC21, MARCXML)", Access: openapi.User,
+			Description: "El archivo va como multipart (campo file) o como cuerpo completo. Las filas válidas se guardan en lotes de 100, cada uno en su transacción: si un lote no se puede guardar sus filas quedan como failed (failed_batches) y el resto se importa",
+			Params: []openapi.Param{
+				{Name: "format", Description: "Formato del archivo (si no, se deduce del nombre o Content-Type)", Enum: bookio.FormatNames()},
+				{Name: "mapping", Description: "JSON campo → columna para archivos tabulares"},
+				{Name: "dry_run", Description: "Validar sin guardar", Type: "boolean"},
+			},
+			Body: importContent(),
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: models.CatalogImportReport{}},
+				badRequest,
+				{Status: http.StatusRequestEntityTooLarge, Description: "File too large", Body: errorBody},
+			}},
+
+		// Préstamos
+		{Method: "POST", Path: "/books/:id/borrow", Tag: "Loans", Summary: "Prestar un libro", Access: openapi.User,
+			Body: models.LoanRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: models.Loan{}},
+				{Status: http.StatusBadRequest, Description: "Invalid request or book not available", Body: errorBody},
+				bookNotFound,
+				{Status: http.StatusConflict, Description: "The user reached the active loan limit", Body: errorBody},
+			}},
+		{Method: "POST", Path: "/loans/:id/return", Tag: "Loans", Summary: "Devolver un libro", Access: openapi.User,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: message},
+				{Status: http.StatusNotFound, Description: "Loan not found or already returned", Body: errorBody},
+			}},
+		{Method: "GET", Path: "/loans", Tag: "Loans", Summary: "Listar préstamos con su libro", Access: openapi.User,
+			Params:    []openapi.Param{{Name: "status", Description: "active para ver solo los préstamos abiertos", Enum: []string{"active"}}},
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: []models.LoanWithBook{}}}},
+
+		// Autores y materias
+		{Method: "GET", Path: "/authors", Tag: "Catalog", Summary: "Listar autores",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: []models.Author{}}}},
+		{Method: "GET", Path: "/authors/:id/books", Tag: "Catalog", Summary: "Libros de un autor",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.Object{"author": models.Author{}, "books": []models.Book{}}},
+				{Status: http.StatusNotFound, Description: "Author not found", Body: errorBody},
+			}},
+		{Method: "GET", Path: "/subjects", Tag: "Catalog", Summary: "Listar materias",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: []models.Subject{}}}},
+		{Method: "GET", Path: "/subjects/:id/books", Tag: "Catalog", Summary: "Libros de una materia",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.Object{"subject": models.Subject{}, "books": []models.Book{}}},
+				{Status: http.StatusNotFound, Description: "Subject not found", Body: errorBody},
+			}},
+
+		// OAI-PMH
+		{Method: "GET", Path: "/oai", Tag: "OAI-PMH", Summary: "Punto de acceso OAI-PMH (Dublin Core)",
+			Params: []openapi.Param{
+				{Name: "verb", Required: true, Enum: []string{"Identify", "ListMetadataFormats", "ListSets", "ListIdentifiers", "ListRecords", "GetRecord"}},
+				{Name: "metadataPrefix"}, {Name: "identifier"}, {Name: "from"}, {Name: "until"}, {Name: "set"}, {Name: "resumptionToken"},
+			},
+			Responses: []openapi.Response{{Status: http.StatusOK, Description: "Respuesta OAI-PMH (los errores del protocolo también van con 200)", Body: openapi.Content{"text/xml": nil}}}},
+		{Method: "POST", Path: "/oai", Tag: "OAI-PMH", Summary: "Punto de acceso OAI-PMH con formulario",
+			Body:      openapi.Content{"application/x-www-form-urlencoded": openapi.Object{"verb": "", "metadataPrefix": "", "identifier": "", "from": "", "until": "", "set": "", "resumptionToken": ""}},
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: openapi.Content{"text/xml": nil}}}},
+
+		// APIs externas
+		{Method: "GET", Path: "/api/external/search", Tag: "External", Summary: "Buscar en Open Library o Google Books",
+			Params: []openapi.Param{
+				{Name: "q", Required: true, Description: "Texto a buscar"},
+				{Name: "source", Enum: []string{"openlibrary", "google"}},
+				{Name: "limit", Type: "integer", Description: "Entre 1 y 50 (10 por defecto)"},
+			},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.Object{"source": "", "query": "", "results": []models.Book{}}},
+				badRequest,
+				{Status: http.StatusBadGateway, Description: "External API failed", Body: errorBody},
+				rateLimited,
+			}},
+		{Method: "GET", Path: "/api/external/import", Tag: "External", Summary: "Importar un libro de una API externa",
+			Description: "Si ya existe un libro con el mismo ISBN se completan sus campos vacíos",
+			Params: []openapi.Param{
+				{Name: "source", Required: true, Enum: []string{"openlibrary", "google"}},
+				{Name: "id", Required: true, Description: "Identificador en la fuente (OL1234567M, id de Google)"},
+			},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Description: "Book imported", Body: externalImport()},
+				{Status: http.StatusOK, Description: "Book already existed (updated or skipped)", Body: externalImport()},
+				badRequest,
+				{Status: http.StatusUnprocessableEntity, Description: "Book could not be imported (import status and isbn included)", Body: errorBody},
+				{Status: http.StatusBadGateway, Description: "External API failed", Body: errorBody},
+				rateLimited,
+			}},
+		{Method: "POST", Path: "/api/external/import/bulk", Tag: "Jobs", Summary: "Importación masiva en segundo plano", Access: openapi.User,
+			Body: models.BulkImportRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusAccepted, Body: openapi.Object{"message": "", "job_id": "", "job": models.Job{}}, Headers: map[string]string{"Location": "URL del trabajo"}},
+				badRequest,
+				{Status: http.StatusServiceUnavailable, Description: "Job queue is full", Body: errorBody},
+				rateLimited,
+			}},
+		{Method: "GET", Path: "/api/books/:id/details", Tag: "External", Summary: "Detalles de un libro combinando fuentes",
+			Params: []openapi.Param{
+				{Name: "enrich", Description: "Completar un libro local con otra fuente", Enum: []string{"openlibrary", "google"}},
+				{Name: "source", Description: "Buscar en otra fuente si no está en el catálogo", Enum: []string{"openlibrary", "google"}},
+			},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.Object{"source": "", "in_local": true, "can_import": true, "book": models.Book{}}},
+				bookNotFound,
+				rateLimited,
+			}},
+
+		// Trabajos en segundo plano
+		{Method: "GET", Path: "/jobs", Tag: "Jobs", Summary: "Listar trabajos (los propios; el administrador, todos)", Access: openapi.User,
+			Params:    []openapi.Param{{Name: "status", Enum: []string{models.JobPending, models.JobRunning, models.JobCompleted, models.JobFailed, models.JobCancelled}}},
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: []models.Job{}}}},
+		{Method: "GET", Path: "/jobs/:id", Tag: "Jobs", Summary: "Progreso de un trabajo", Access: openapi.User,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: models.Job{}},
+				{Status: http.StatusNotFound, Description: "Job not found (or created by another user)", Body: errorBody},
+			}},
+		{Method: "POST", Path: "/jobs/:id/cancel", Tag: "Jobs", Summary: "Cancelar un trabajo", Access: openapi.User,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.Object{"message": "", "job": models.Job{}}},
+				{Status: http.StatusNotFound, Description: "Job not found (or created by another user)", Body: errorBody},
+				{Status: http.StatusConflict, Description: "Job already finished (current state in job)", Body: errorBody},
+			}},
+
+		// Administración
+		{Method: "POST", Path: "/admin/backup", Tag: "Admin", Summary: "Crear un respaldo", Access: openapi.Admin,
+			Params: []openapi.Param{{Name: "format", Description: "Nativo del almacenamiento por defecto", Enum: []string{backup.FormatSQLite, backup.FormatJSON}}},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: openapi.Object{"message": "", "backup": backup.Manifest{}}, Headers: map[string]string{"Location": "URL de descarga"}},
+				{Status: http.StatusBadRequest, Description: "Unsupported format", Body: errorBody},
+			}},
+		{Method: "GET", Path: "/admin/backups", Tag: "Admin", Summary: "Listar respaldos", Access: openapi.Admin,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: openapi.Object{"backups": []backup.Manifest{}, "count": 0}}}},
+		{Method: "GET", Path: "/admin/backups/:name", Tag: "Admin", Summary: "Descargar un respaldo", Access: openapi.Admin,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.Content{"application/vnd.sqlite3": openapi.Binary, "application/json": nil},
+					Headers: map[string]string{"X-Checksum-SHA256": "SHA-256 del archivo"}},
+				{Status: http.StatusNotFound, Description: "Backup not found", Body: errorBody},
+			}},
+	}
+}
+
+// bookContent - Libros en JSON (si body no es nil) y en los formatos de
+// intercambio registrados en bookio
+func bookContent(body any) openapi.Content {
+	content := openapi.Content{}
+	if body != nil {
+		content["application/json"] = body
+	}
+	for _, name := range bookio.FormatNames() {
+		format, _ := bookio.LookupFormat(name)
+		mediaType, _, _ := strings.Cut(format.ContentType, ";")
+		content[mediaType] = formatBody(format)
+	}
+	return content
+}
+
+// importContent - Formas de subir un catálogo a /books/import
+func importContent() openapi.Content {
+	content := openapi.Content{"multipart/form-data": openapi.Object{
+		"file": openapi.Binary, "format": "", "mapping": "", "dry_run": true,
+	}}
+	for _, name := range bookio.FormatNames() {
+		if format, _ := bookio.LookupFormat(name); format.Read != nil {
+			mediaType, _, _ := strings.Cut(format.ContentType, ";")
+			content[mediaType] = formatBody(format)
+		}
+	}
+	return content
+}
+
+// formatBody - Texto o binario según el formato
+func formatBody(format bookio.Format) any {
+	if format.Name == "xlsx" || format.Name == "marc" {
+		return openapi.Binary
+	}
+	return nil
+}
+
+func externalImport() openapi.Object {
+	return openapi.Object{"message": "", "status": "", "source": "", "book": models.Book{}}
+}
+
+// endpointIndex - "MÉTODO /ruta" → resumen, para la ruta raíz
+func endpointIndex(routes []openapi.Route) map[string]string {
+	index := make(map[string]string, len(routes))
+	for _, route := range routes {
+		summary := route.Summary
+		switch route.Access {
+		case openapi.User:
+			summary += " (requiere auth)"
+		case openapi.Admin:
+			summary += " (requiere admin)"
+		}
+		index[route.Method+" "+route.Path] = summary
+	}
+	return index
+}
@@ -6,7 +6,24 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtSecret = []byte("library-api-secret-key-change-in-production")
+// DefaultSecret - Secreto de desarrollo; en producción se configura otro
+const DefaultSecret = "library-api-secret-key-change-in-production"
+
+// DefaultTokenTTL - Vigencia de los tokens si no se configura otra
+const DefaultTokenTTL = 24 * time.Hour
+
+var jwtSecret = []byte(DefaultSecret)
+
+var tokenTTL = DefaultTokenTTL
+
+// Configure - Fija el secreto de firma y la vigencia de los tokens (antes de
+// atender peticiones)
+func Configure(secret string, ttl time.Duration) {
+	jwtSecret = []byte(secret)
+	if ttl > 0 {
+		tokenTTL = ttl
+	}
+}
 
 type Claims struct {
 	UserID string `json:"user_id"`
@@ -15,7 +32,7 @@ type Claims struct {
 }
 
 func GenerateToken(userID, role string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := time.Now().Add(tokenTTL)
 	
 	claims := &Claims{
 		UserID: userID,
@@ -0,0 +1,447 @@
+// Package backup - Respaldos del almacenamiento: copias nativas de la base de
+// datos SQLite (VACUUM INTO) o volcados JSON de cualquier backend. Cada
+// respaldo tiene un manifiesto con su checksum SHA-256; se crean a pedido o
+// periódicamente y solo se conservan los más recientes.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"library-api/storage"
+)
+
+// Formatos de respaldo
+const (
+	// FormatSQLite - Copia del archivo de la base de datos (solo SQLite)
+	FormatSQLite = "sqlite"
+	// FormatJSON - Volcado storage.Dump, restaurable en cualquier backend
+	FormatJSON = "json"
+)
+
+// Errores de los respaldos
+var (
+	ErrUnsupportedFormat = errors.New("unsupported backup format")
+	ErrBackupNotFound    = errors.New("backup not found")
+	ErrChecksumMismatch  = errors.New("backup checksum mismatch")
+)
+
+// manifestSuffix - El manifiesto de "x.db" es "x.db.manifest.json"
+const manifestSuffix = ".manifest.json"
+
+// Manifest - Descripción de un respaldo, guardada junto al archivo
+type Manifest struct {
+	Name      string         `json:"name"`
+	Format    string         `json:"format"`
+	Storage   string         `json:"storage"`
+	Size      int64          `json:"size"`
+	SHA256    string         `json:"sha256"`
+	CreatedAt time.Time      `json:"created_at"`
+	Counts    map[string]int `json:"counts,omitempty"` // solo en volcados JSON
+}
+
+// Config - Directorio, retención y frecuencia de los respaldos
+type Config struct {
+	// Dir - Directorio donde se guardan los respaldos
+	Dir string
+	// Retention - Cantidad de respaldos a conservar (0: todos)
+	Retention int
+	// Interval - Frecuencia de los respaldos programados (0: ninguno)
+	Interval time.Duration
+	// Storage - Backend de origen, se anota en los manifiestos
+	Storage string
+}
+
+// Manager - Crea, lista y depura los respaldos de un store
+type Manager struct {
+	store  storage.Store
+	config Config
+
+	mu   sync.Mutex // un respaldo a la vez
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewManager - Constructor
+func NewManager(store storage.Store, config Config) *Manager {
+	return &Manager{
+		store:  store,
+		config: config,
+	}
+}
+
+// DefaultFormat - Formato nativo si el backend lo permite, si no JSON
+func (m *Manager) DefaultFormat() string {
+	if _, ok := m.store.(storage.FileBackuper); ok {
+		return FormatSQLite
+	}
+	return FormatJSON
+}
+
+// Create - Crea un respaldo consistente del store ("" usa DefaultFormat),
+// escribe su manifiesto y elimina los respaldos que exceden la retención
+func (m *Manager) Create(ctx context.Context, format string) (*Manifest, error) {
+	if format == "" {
+		format = m.DefaultFormat()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating backup directory: %w", err)
+	}
+
+	manifest := &Manifest{
+		Format:    format,
+		Storage:   m.config.Storage,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var extension string
+	switch format {
+	case FormatSQLite:
+		extension = ".db"
+	case FormatJSON:
+		extension = ".json"
+	default:
+		return nil, storage.InvalidValue(ErrUnsupportedFormat, "format", format, fmt.Sprintf("%q", format))
+	}
+	manifest.Name = "library-" + manifest.CreatedAt.Format("20060102-150405.000") + extension
+
+	// Se escribe con otro nombre y se renombra al terminar: un respaldo a
+	// medias nunca tiene el nombre de uno válido
+	path := filepath.Join(m.config.Dir, manifest.Name)
+	tmpPath := filepath.Join(m.config.Dir, "."+manifest.Name+".tmp")
+	os.Remove(tmpPath)
+	defer os.Remove(tmpPath)
+
+	switch format {
+	case FormatSQLite:
+		backuper, ok := m.store.(storage.FileBackuper)
+		if !ok {
+			return nil, storage.InvalidValue(ErrUnsupportedFormat, "format", format, fmt.Sprintf("%q is only available for sqlite storage", format))
+		}
+		if err := backuper.BackupTo(ctx, tmpPath); err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		counts, err := m.writeDump(ctx, tmpPath)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Counts = counts
+	}
+
+	size, checksum, err := fileChecksum(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Size, manifest.SHA256 = size, checksum
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, fmt.Errorf("error saving backup: %w", err)
+	}
+	if err := writeManifest(path, manifest); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	slog.Info("Respaldo creado", "name", manifest.Name, "bytes", manifest.Size)
+
+	if err := m.prune(); err != nil {
+		slog.Warn("Error eliminando respaldos antiguos", "error", err)
+	}
+
+	return manifest, nil
+}
+
+// writeDump - Escribe el volcado JSON del store en path
+func (m *Manager) writeDump(ctx context.Context, path string) (map[string]int, error) {
+	dumper, ok := m.store.(storage.Dumper)
+	if !ok {
+		return nil, storage.InvalidValue(ErrUnsupportedFormat, "format", FormatJSON, "storage does not support json dumps")
+	}
+
+	dump, err := dumper.Dump(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating backup file: %w", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(dump); err != nil {
+		return nil, fmt.Errorf("error writing dump: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("error writing dump: %w", err)
+	}
+
+	return dump.Counts(), nil
+}
+
+// List - Respaldos del directorio, del más reciente al más antiguo
+func (m *Manager) List() ([]Manifest, error) {
+	paths, err := filepath.Glob(filepath.Join(m.config.Dir, "*"+manifestSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := []Manifest{}
+	for _, manifestPath := range paths {
+		manifest, err := readManifest(strings.TrimSuffix(manifestPath, manifestSuffix))
+		if err != nil {
+			slog.Warn("Manifiesto ilegible", "path", manifestPath, "error", err)
+			continue
+		}
+		manifests = append(manifests, *manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		if !manifests[i].CreatedAt.Equal(manifests[j].CreatedAt) {
+			return manifests[i].CreatedAt.After(manifests[j].CreatedAt)
+		}
+		return manifests[i].Name > manifests[j].Name
+	})
+	return manifests, nil
+}
+
+// Get - Ruta y manifiesto de un respaldo por nombre
+func (m *Manager) Get(name string) (string, *Manifest, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") || strings.HasSuffix(name, manifestSuffix) {
+		return "", nil, fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+
+	path := filepath.Join(m.config.Dir, name)
+	manifest, err := readManifest(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil, fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", nil, fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+
+	return path, manifest, nil
+}
+
+// prune - Elimina los respaldos más antiguos que exceden la retención
+func (m *Manager) prune() error {
+	if m.config.Retention <= 0 {
+		return nil
+	}
+
+	manifests, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	for i := m.config.Retention; i < len(manifests); i++ {
+		path := filepath.Join(m.config.Dir, filepath.Base(manifests[i].Name))
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := os.Remove(path + manifestSuffix); err != nil {
+			return err
+		}
+		slog.Info("Respaldo eliminado por retención", "name", manifests[i].Name)
+	}
+
+	return nil
+}
+
+// ==============================================
+// RESPALDOS PROGRAMADOS
+// ==============================================
+
+// Start - Crea un respaldo cada config.Interval (no hace nada si es 0)
+func (m *Manager) Start() {
+	if m.config.Interval <= 0 || m.stop != nil {
+		return
+	}
+
+	m.stop = make(chan struct{})
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				if _, err := m.Create(context.Background(), ""); err != nil {
+					slog.Error("Error en el respaldo programado", "error", err)
+				}
+			}
+		}
+	}()
+
+	slog.Info("Respaldos programados", "interval", m.config.Interval, "dir", m.config.Dir, "retention", m.config.Retention)
+}
+
+// Stop - Detiene los respaldos programados (espera al que esté en curso)
+func (m *Manager) Stop() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	m.wg.Wait()
+	m.stop = nil
+}
+
+// ==============================================
+// VERIFICACIÓN Y RESTAURACIÓN
+// ==============================================
+
+// Verify - Comprueba el archivo de un respaldo contra su manifiesto (path
+// puede ser el del respaldo o el de su manifiesto)
+func Verify(path string) (*Manifest, error) {
+	path = strings.TrimSuffix(path, manifestSuffix)
+	manifest, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	size, checksum, err := fileChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	if size != manifest.Size || checksum != manifest.SHA256 {
+		return nil, fmt.Errorf("%w: %s (sha256 %s, expected %s)", ErrChecksumMismatch, filepath.Base(path), checksum, manifest.SHA256)
+	}
+
+	return manifest, nil
+}
+
+// ReadDump - Lee un respaldo en formato JSON
+func ReadDump(path string) (*storage.Dump, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening dump: %w", err)
+	}
+	defer file.Close()
+
+	var dump storage.Dump
+	if err := json.NewDecoder(file).Decode(&dump); err != nil {
+		return nil, fmt.Errorf("error reading dump: %w", err)
+	}
+	return &dump, nil
+}
+
+// RestoreSQLiteFile - Reemplaza la base de datos SQLite en dbPath por la
+// copia src. La API no debe estar usando dbPath: se descartan también los
+// archivos -wal y -shm de la base de datos anterior.
+func RestoreSQLiteFile(src, dbPath string) error {
+	tmpPath := dbPath + ".restore.tmp"
+	if err := copyFile(src, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmpPath)
+			return fmt.Errorf("error removing %s: %w", dbPath+suffix, err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error replacing database: %w", err)
+	}
+	return nil
+}
+
+// ==============================================
+// FUNCIONES AUXILIARES
+// ==============================================
+
+func readManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path + manifestSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func writeManifest(path string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + manifestSuffix + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, path+manifestSuffix); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	return nil
+}
+
+// fileChecksum - Tamaño y SHA-256 (hexadecimal) de un archivo
+func fileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("error opening backup: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("error reading backup: %w", err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("error opening backup: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("error copying backup: %w", err)
+	}
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("error copying backup: %w", err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,133 @@
+package backup_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"library-api/backup"
+	"library-api/models"
+	"library-api/storage"
+)
+
+func TestJSONBackupVerifiesChecksum(t *testing.T) {
+	store := storage.NewMemoryStore()
+	if _, err := store.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"}); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+
+	dir := t.TempDir()
+	manager := backup.NewManager(store, backup.Config{Dir: dir, Storage: "memory"})
+
+	if _, err := manager.Create(t.Context(), backup.FormatSQLite); !errors.Is(err, backup.ErrUnsupportedFormat) {
+		t.Errorf("Create sqlite from memory: got %v, want ErrUnsupportedFormat", err)
+	}
+
+	manifest, err := manager.Create(t.Context(), "")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if manifest.Format != backup.FormatJSON || manifest.Counts["books"] != 1 || manifest.SHA256 == "" {
+		t.Errorf("manifest: got %+v", manifest)
+	}
+
+	path, got, err := manager.Get(manifest.Name)
+	if err != nil || got.SHA256 != manifest.SHA256 {
+		t.Fatalf("Get: got %+v, %v", got, err)
+	}
+	if _, err := backup.Verify(path); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	dump, err := backup.ReadDump(path)
+	if err != nil {
+		t.Fatalf("ReadDump: %v", err)
+	}
+	restored := storage.NewMemoryStore()
+	if err := restored.Load(t.Context(), dump); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := restored.GetBookByISBN(t.Context(), "9788437604572"); err != nil {
+		t.Errorf("GetBookByISBN after restore: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backup.Verify(path); !errors.Is(err, backup.ErrChecksumMismatch) {
+		t.Errorf("Verify tampered backup: got %v, want ErrChecksumMismatch", err)
+	}
+
+	for _, name := range []string{"../" + manifest.Name, manifest.Name + ".manifest.json", "missing.db"} {
+		if _, _, err := manager.Get(name); !errors.Is(err, backup.ErrBackupNotFound) {
+			t.Errorf("Get(%q): got %v, want ErrBackupNotFound", name, err)
+		}
+	}
+}
+
+func TestSQLiteBackupRestoresFile(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	book, err := store.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"})
+	if err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+
+	manager := backup.NewManager(store, backup.Config{Dir: t.TempDir(), Storage: "sqlite"})
+	manifest, err := manager.Create(t.Context(), "")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if manifest.Format != backup.FormatSQLite {
+		t.Errorf("default format for sqlite: got %q", manifest.Format)
+	}
+
+	path, _, err := manager.Get(manifest.Name)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := backup.Verify(path); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	target := filepath.Join(t.TempDir(), "restored.db")
+	if err := backup.RestoreSQLiteFile(path, target); err != nil {
+		t.Fatalf("RestoreSQLiteFile: %v", err)
+	}
+	restored, err := storage.NewSQLiteStore(target)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore(restored): %v", err)
+	}
+	if got, err := restored.GetBookByID(t.Context(), book.ID); err != nil || got.Title != "Rayuela" {
+		t.Errorf("GetBookByID after restore: got %+v, %v", got, err)
+	}
+}
+
+func TestRetentionKeepsNewest(t *testing.T) {
+	manager := backup.NewManager(storage.NewMemoryStore(), backup.Config{Dir: t.TempDir(), Retention: 2})
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		manifest, err := manager.Create(t.Context(), backup.FormatJSON)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		names = append(names, manifest.Name)
+		time.Sleep(2 * time.Millisecond) // nombres distintos
+	}
+
+	manifests, err := manager.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(manifests) != 2 || manifests[0].Name != names[3] || manifests[1].Name != names[2] {
+		t.Fatalf("List after retention: got %+v, want %v", manifests, names[2:])
+	}
+	if _, _, err := manager.Get(names[0]); !errors.Is(err, backup.ErrBackupNotFound) {
+		t.Errorf("Get pruned backup: got %v, want ErrBackupNotFound", err)
+	}
+}
@@ -0,0 +1,309 @@
+package bookio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"library-api/models"
+)
+
+// ==============================================
+// NOMBRES DE AUTORES
+// ==============================================
+
+// nameParticles - Partículas que forman parte del apellido ("de Cervantes")
+var nameParticles = map[string]bool{
+	"de": true, "del": true, "la": true, "las": true, "los": true, "y": true,
+	"da": true, "das": true, "do": true, "dos": true, "di": true, "du": true,
+	"van": true, "von": true, "der": true, "den": true, "le": true,
+}
+
+// splitName - Separa nombre y apellido. Acepta "Apellido, Nombre"; si no hay
+// coma toma la última palabra (con sus partículas) como apellido.
+func splitName(name string) (given, family string) {
+	name = strings.TrimSpace(name)
+	if surname, forename, found := strings.Cut(name, ","); found {
+		return strings.TrimSpace(forename), strings.TrimSpace(surname)
+	}
+
+	words := strings.Fields(name)
+	if len(words) < 2 {
+		return "", name
+	}
+
+	start := len(words) - 1
+	for start > 1 && nameParticles[strings.ToLower(words[start-1])] {
+		start--
+	}
+	return strings.Join(words[:start], " "), strings.Join(words[start:], " ")
+}
+
+// ==============================================
+// BIBTEX
+// ==============================================
+
+// BibTeXWriter - Escritura de entradas @book
+type BibTeXWriter struct {
+	w io.Writer
+	// used - Claves ya escritas; suffixes - Último sufijo probado por clave base
+	used     map[string]bool
+	suffixes map[string]int
+}
+
+func NewBibTeXWriter(w io.Writer) (*BibTeXWriter, error) {
+	return &BibTeXWriter{w: w, used: make(map[string]bool), suffixes: make(map[string]int)}, nil
+}
+
+// Write - Escribe la entrada de un libro
+func (bw *BibTeXWriter) Write(book models.Book) error {
+	book.NormalizeContributors()
+
+	fields := []struct{ name, value string }{
+		{"title", book.Title},
+		{"author", strings.Join(book.Authors, " and ")},
+		{"year", intOrEmpty(book.Published)},
+		{"publisher", book.Publisher},
+		{"edition", book.Edition},
+		{"isbn", book.ISBN},
+		{"language", book.Language},
+		{"pagetotal", intOrEmpty(book.PageCount)},
+		{"keywords", strings.Join(book.Subjects, ", ")},
+		{"abstract", book.Description},
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "@book{%s,\n", bw.citationKey(book))
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s = {%s},\n", field.name, escapeBibTeX(field.value))
+	}
+	b.WriteString("}\n\n")
+
+	_, err := io.WriteString(bw.w, b.String())
+	return err
+}
+
+func (bw *BibTeXWriter) Flush() error { return nil }
+func (bw *BibTeXWriter) Close() error { return nil }
+
+// citationKey - "garciamarquez1967cien", con sufijo b, c... si se repite
+func (bw *BibTeXWriter) citationKey(book models.Book) string {
+	family := "anon"
+	if len(book.Authors) > 0 {
+		_, family = splitName(book.Authors[0])
+		// "de Cervantes" → "cervantes"
+		words := strings.Fields(family)
+		for len(words) > 1 && nameParticles[strings.ToLower(words[0])] {
+			words = words[1:]
+		}
+		family = strings.Join(words, "")
+	}
+
+	title := ""
+	for _, word := range strings.Fields(book.Title) {
+		if len([]rune(word)) > 3 {
+			title = word
+			break
+		}
+	}
+
+	key := asciiKey(family) + intOrEmpty(book.Published) + asciiKey(title)
+	if key == "" {
+		key = "book"
+	}
+
+	// Las repetidas llevan sufijo (b, c, … z, aa, ab, …); cada candidata se
+	// comprueba porque otra clave base puede haberla generado ya
+	base := key
+	for bw.used[key] {
+		// La primera aparición cuenta como "a": la segunda es "b"
+		n := max(bw.suffixes[base], 1) + 1
+		bw.suffixes[base] = n
+		key = base + keySuffix(n)
+	}
+	bw.used[key] = true
+	return key
+}
+
+// keySuffix - Sufijo en base 26 biyectiva: 1 → a, 26 → z, 27 → aa
+func keySuffix(n int) string {
+	var suffix []byte
+	for ; n > 0; n = (n - 1) / 26 {
+		suffix = append([]byte{byte('a' + (n-1)%26)}, suffix...)
+	}
+	return string(suffix)
+}
+
+// asciiKey - Minúsculas sin acentos ni símbolos
+func asciiKey(value string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(value) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+var bibTeXEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`, "}", `\}`,
+	"&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+)
+
+func escapeBibTeX(value string) string {
+	return bibTeXEscaper.Replace(value)
+}
+
+// ==============================================
+// RIS
+// ==============================================
+
+// RISWriter - Escritura de registros RIS (TY - BOOK ... ER -)
+type RISWriter struct {
+	w io.Writer
+}
+
+func NewRISWriter(w io.Writer) (*RISWriter, error) {
+	return &RISWriter{w: w}, nil
+}
+
+// Write - Escribe el registro de un libro (líneas terminadas en CRLF)
+func (rw *RISWriter) Write(book models.Book) error {
+	book.NormalizeContributors()
+
+	var b strings.Builder
+	line := func(tag, value string) {
+		if value = strings.TrimSpace(strings.ReplaceAll(value, "\n", " ")); value != "" {
+			fmt.Fprintf(&b, "%s  - %s\r\n", tag, value)
+		}
+	}
+
+	line("TY", "BOOK")
+	line("ID", book.ID)
+	line("TI", book.Title)
+	for _, author := range book.Authors {
+		given, family := splitName(author)
+		if given != "" {
+			family += ", " + given
+		}
+		line("AU", family)
+	}
+	line("PY", intOrEmpty(book.Published))
+	line("PB", book.Publisher)
+	line("ET", book.Edition)
+	line("SN", book.ISBN)
+	line("LA", book.Language)
+	line("SP", intOrEmpty(book.PageCount))
+	for _, subject := range book.Subjects {
+		line("KW", subject)
+	}
+	line("AB", book.Description)
+	b.WriteString("ER  - \r\n\r\n")
+
+	_, err := io.WriteString(rw.w, b.String())
+	return err
+}
+
+func (rw *RISWriter) Flush() error { return nil }
+func (rw *RISWriter) Close() error { return nil }
+
+// ==============================================
+// CSL-JSON
+// ==============================================
+
+// CSLItem - Elemento CSL-JSON (formato de citeproc, Zotero, Pandoc)
+type CSLItem struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Title         string    `json:"title"`
+	Author        []CSLName `json:"author,omitempty"`
+	Issued        *CSLDate  `json:"issued,omitempty"`
+	Publisher     string    `json:"publisher,omitempty"`
+	Edition       string    `json:"edition,omitempty"`
+	ISBN          string    `json:"ISBN,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	NumberOfPages string    `json:"number-of-pages,omitempty"`
+	Keyword       string    `json:"keyword,omitempty"`
+	Abstract      string    `json:"abstract,omitempty"`
+}
+
+type CSLName struct {
+	Family string `json:"family,omitempty"`
+	Given  string `json:"given,omitempty"`
+}
+
+type CSLDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// BookToCSL - Elemento CSL-JSON de un libro
+func BookToCSL(book models.Book) CSLItem {
+	book.NormalizeContributors()
+
+	item := CSLItem{
+		ID:            book.ID,
+		Type:          "book",
+		Title:         book.Title,
+		Publisher:     book.Publisher,
+		Edition:       book.Edition,
+		ISBN:          book.ISBN,
+		Language:      book.Language,
+		NumberOfPages: intOrEmpty(book.PageCount),
+		Keyword:       strings.Join(book.Subjects, ", "),
+		Abstract:      book.Description,
+	}
+	for _, author := range book.Authors {
+		given, family := splitName(author)
+		item.Author = append(item.Author, CSLName{Family: family, Given: given})
+	}
+	if book.Published != 0 {
+		item.Issued = &CSLDate{DateParts: [][]int{{book.Published}}}
+	}
+	return item
+}
+
+// CSLWriter - Escritura incremental de un arreglo CSL-JSON
+type CSLWriter struct {
+	w     io.Writer
+	count int
+}
+
+func NewCSLWriter(w io.Writer) (*CSLWriter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &CSLWriter{w: w}, nil
+}
+
+// Write - Escribe el elemento de un libro
+func (cw *CSLWriter) Write(book models.Book) error {
+	data, err := json.MarshalIndent(BookToCSL(book), "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	separator := "\n  "
+	if cw.count > 0 {
+		separator = ",\n  "
+	}
+	cw.count++
+
+	_, err = io.WriteString(cw.w, separator+string(data))
+	return err
+}
+
+func (cw *CSLWriter) Flush() error { return nil }
+
+// Close - Cierra el arreglo
+func (cw *CSLWriter) Close() error {
+	_, err := io.WriteString(cw.w, "\n]\n")
+	return err
+}
@@ -0,0 +1,45 @@
+package bookio
+
+import (
+	"strings"
+	"testing"
+
+	"library-api/models"
+)
+
+func TestKeySuffix(t *testing.T) {
+	cases := map[int]string{1: "a", 2: "b", 26: "z", 27: "aa", 28: "ab", 52: "az", 53: "ba", 702: "zz", 703: "aaa"}
+	for n, want := range cases {
+		if got := keySuffix(n); got != want {
+			t.Errorf("keySuffix(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestBibTeXKeysAreUnique(t *testing.T) {
+	bw, err := NewBibTeXWriter(&strings.Builder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book := models.Book{Title: "Rayuela", Authors: []string{"Julio Cortázar"}, Published: 1963}
+	seen := make(map[string]bool)
+	var keys []string
+	for range 30 {
+		key := bw.citationKey(book)
+		if seen[key] {
+			t.Fatalf("duplicate key %q", key)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	if keys[0] != "cortazar1963rayuela" || keys[1] != "cortazar1963rayuelab" || keys[26] != "cortazar1963rayuelaaa" {
+		t.Errorf("keys: got %v", keys)
+	}
+
+	// Una clave base que coincide con una ya generada con sufijo
+	collision := models.Book{Title: "Rayuelab", Authors: []string{"Julio Cortázar"}, Published: 1963}
+	if key := bw.citationKey(collision); seen[key] {
+		t.Errorf("collision: got already used key %q", key)
+	}
+}
@@ -0,0 +1,81 @@
+package bookio
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"library-api/models"
+)
+
+// ReadCSV - Lee un CSV (separador "," o ";" detectado del encabezado)
+func ReadCSV(r io.Reader, mapping Mapping) ([]Row, error) {
+	reader := bufio.NewReader(r)
+
+	delimiter := ','
+	if firstLine, err := reader.Peek(4096); err == nil || err == io.EOF || err == bufio.ErrBufferFull {
+		line := string(firstLine)
+		if end := strings.IndexByte(line, '\n'); end >= 0 {
+			line = line[:end]
+		}
+		if strings.Count(line, ";") > strings.Count(line, ",") {
+			delimiter = ';'
+		}
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = delimiter
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	// El lector salta las líneas vacías y un campo entre comillas puede
+	// ocupar varias: cada registro lleva la línea en la que empieza
+	var records [][]string
+	var lines []int
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading csv: %w", err)
+		}
+		line, _ := csvReader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+
+	return recordsToRows(records, lines, mapping)
+}
+
+// CSVWriter - Escritura incremental de libros en CSV
+type CSVWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter - Crea el escritor y escribe el encabezado
+func NewCSVWriter(w io.Writer) (*CSVWriter, error) {
+	writer := &CSVWriter{w: csv.NewWriter(w)}
+	if err := writer.w.Write(ExportColumns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write - Escribe un libro
+func (cw *CSVWriter) Write(book models.Book) error {
+	return cw.w.Write(BookRecord(book))
+}
+
+// Flush - Envía al destino lo escrito hasta ahora
+func (cw *CSVWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// Close - Termina la escritura
+func (cw *CSVWriter) Close() error {
+	return cw.Flush()
+}
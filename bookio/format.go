@@ -0,0 +1,154 @@
+package bookio
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format - Formato de intercambio registrado: cómo leerlo y cómo escribirlo
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	// Read - nil si el formato solo se exporta. El mapeo de columnas solo
+	// se aplica a los formatos tabulares.
+	Read func(data []byte, mapping Mapping) ([]Row, error)
+	// NewWriter - Escritor incremental de libros
+	NewWriter func(w io.Writer) (Writer, error)
+}
+
+var formats = map[string]Format{
+	"csv": {
+		Name:        "csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		Read: func(data []byte, mapping Mapping) ([]Row, error) {
+			return ReadCSV(bytes.NewReader(data), mapping)
+		},
+		NewWriter: func(w io.Writer) (Writer, error) { return NewCSVWriter(w) },
+	},
+	"xlsx": {
+		Name:        "xlsx",
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extension:   "xlsx",
+		Read: func(data []byte, mapping Mapping) ([]Row, error) {
+			return ReadXLSX(bytes.NewReader(data), int64(len(data)), mapping)
+		},
+		NewWriter: func(w io.Writer) (Writer, error) { return NewXLSXWriter(w) },
+	},
+	"marc": {
+		Name:        "marc",
+		ContentType: "application/marc",
+		Extension:   "mrc",
+		Read: func(data []byte, _ Mapping) ([]Row, error) {
+			return ReadMARC(bytes.NewReader(data))
+		},
+		NewWriter: func(w io.Writer) (Writer, error) { return NewMARCWriter(w) },
+	},
+	"marcxml": {
+		Name:        "marcxml",
+		ContentType: "application/marcxml+xml",
+		Extension:   "xml",
+		Read: func(data []byte, _ Mapping) ([]Row, error) {
+			return ReadMARCXML(bytes.NewReader(data))
+		},
+		NewWriter: func(w io.Writer) (Writer, error) { return NewMARCXMLWriter(w) },
+	},
+	"bibtex": {
+		Name:        "bibtex",
+		ContentType: "application/x-bibtex; charset=utf-8",
+		Extension:   "bib",
+		NewWriter:   func(w io.Writer) (Writer, error) { return NewBibTeXWriter(w) },
+	},
+	"ris": {
+		Name:        "ris",
+		ContentType: "application/x-research-info-systems; charset=utf-8",
+		Extension:   "ris",
+		NewWriter:   func(w io.Writer) (Writer, error) { return NewRISWriter(w) },
+	},
+	"csl-json": {
+		Name:        "csl-json",
+		ContentType: "application/vnd.citationstyles.csl+json",
+		Extension:   "json",
+		NewWriter:   func(w io.Writer) (Writer, error) { return NewCSLWriter(w) },
+	},
+}
+
+// formatAliases - Otros nombres aceptados en ?format=
+var formatAliases = map[string]string{
+	"mrc":     "marc",
+	"marc21":  "marc",
+	"iso2709": "marc",
+	"bib":     "bibtex",
+	"csl":     "csl-json",
+	"csljson": "csl-json",
+}
+
+// LookupFormat - Busca un formato por nombre (sin distinguir mayúsculas)
+func LookupFormat(name string) (Format, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := formatAliases[name]; ok {
+		name = alias
+	}
+	format, ok := formats[name]
+	return format, ok
+}
+
+// FormatNames - Nombres de los formatos registrados, ordenados
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatForMediaType - Formato registrado para un tipo MIME (negociación
+// por Accept); también acepta los alias habituales de BibTeX y RIS
+func FormatForMediaType(mediaType string) (Format, bool) {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	switch mediaType {
+	case "text/x-bibtex", "application/x-bibtex-text-file":
+		return formats["bibtex"], true
+	case "text/x-ris":
+		return formats["ris"], true
+	}
+
+	for _, format := range formats {
+		base, _, _ := strings.Cut(format.ContentType, ";")
+		if strings.TrimSpace(base) == mediaType {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// DetectFormat - Formato de un archivo subido a partir de su extensión o
+// Content-Type; CSV si no se reconoce
+func DetectFormat(filename, contentType string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		return "xlsx"
+	case ".mrc", ".marc":
+		return "marc"
+	case ".xml":
+		return "marcxml"
+	case ".csv", ".txt":
+		return "csv"
+	}
+	// BibTeX, RIS y CSL-JSON solo se exportan
+
+	switch {
+	case strings.Contains(contentType, "spreadsheetml"):
+		return "xlsx"
+	case strings.Contains(contentType, "marcxml"), strings.HasSuffix(contentType, "/xml"):
+		return "marcxml"
+	case strings.Contains(contentType, "marc"):
+		return "marc"
+	}
+	return "csv"
+}
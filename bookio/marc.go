@@ -0,0 +1,389 @@
+package bookio
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"library-api/models"
+)
+
+// ErrInvalidMARC - El registro no es MARC21 válido
+var ErrInvalidMARC = errors.New("invalid marc record")
+
+// MARCRecord - Registro bibliográfico MARC21
+type MARCRecord struct {
+	Leader        string
+	ControlFields []MARCControlField
+	DataFields    []MARCDataField
+}
+
+// MARCControlField - Campo de control (001-009), sin indicadores ni subcampos
+type MARCControlField struct {
+	Tag   string
+	Value string
+}
+
+// MARCDataField - Campo de datos con indicadores y subcampos
+type MARCDataField struct {
+	Tag       string
+	Ind1      byte
+	Ind2      byte
+	Subfields []MARCSubfield
+}
+
+// MARCSubfield - Subcampo ($a, $b, ...)
+type MARCSubfield struct {
+	Code  byte
+	Value string
+}
+
+// defaultLeader - Registro nuevo, libro, monografía, UTF-8, ISBD
+const defaultLeader = "00000nam a2200000 i 4500"
+
+// ControlField - Valor del primer campo de control con la etiqueta dada
+func (r MARCRecord) ControlField(tag string) string {
+	for _, field := range r.ControlFields {
+		if field.Tag == tag {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Fields - Campos de datos con la etiqueta dada
+func (r MARCRecord) Fields(tag string) []MARCDataField {
+	var fields []MARCDataField
+	for _, field := range r.DataFields {
+		if field.Tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Subfield - Valor del primer subcampo con el código dado
+func (f MARCDataField) Subfield(code byte) string {
+	for _, sub := range f.Subfields {
+		if sub.Code == code {
+			return sub.Value
+		}
+	}
+	return ""
+}
+
+// firstSubfield - Primer valor no vacío del subcampo en los campos dados
+func (r MARCRecord) firstSubfield(tag string, code byte) string {
+	for _, field := range r.Fields(tag) {
+		if value := field.Subfield(code); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (r *MARCRecord) addControl(tag, value string) {
+	if value != "" {
+		r.ControlFields = append(r.ControlFields, MARCControlField{Tag: tag, Value: value})
+	}
+}
+
+func (r *MARCRecord) addData(tag string, ind1, ind2 byte, subfields ...MARCSubfield) {
+	var kept []MARCSubfield
+	for _, sub := range subfields {
+		if sub.Value != "" {
+			kept = append(kept, sub)
+		}
+	}
+	if len(kept) > 0 {
+		r.DataFields = append(r.DataFields, MARCDataField{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: kept})
+	}
+}
+
+func sub(code byte, value string) MARCSubfield {
+	return MARCSubfield{Code: code, Value: value}
+}
+
+// ==============================================
+// CORRESPONDENCIA LIBRO ↔ MARC21
+// ==============================================
+
+// Prefijos de los números de sistema (035) para los identificadores externos
+const (
+	oclcPrefix        = "(OCoLC)"
+	openLibraryPrefix = "(OpenLibrary)"
+	googleBooksPrefix = "(GoogleBooks)"
+)
+
+// marcLanguages - Códigos ISO 639-1 ↔ códigos de idioma MARC
+var marcLanguages = map[string]string{
+	"es": "spa", "en": "eng", "fr": "fre", "de": "ger", "it": "ita",
+	"pt": "por", "ca": "cat", "gl": "glg", "eu": "baq", "la": "lat",
+	"ru": "rus", "zh": "chi", "ja": "jpn", "ar": "ara", "nl": "dut",
+}
+
+// BookToMARC - Construye el registro MARC21 de un libro. Los nombres se
+// escriben en orden directo (primer indicador 0) para no invertir mal los
+// apellidos compuestos.
+func BookToMARC(book models.Book) MARCRecord {
+	book.NormalizeContributors()
+	record := MARCRecord{Leader: defaultLeader}
+
+	record.addControl("001", book.ID)
+	if !book.UpdatedAt.IsZero() {
+		record.addControl("005", book.UpdatedAt.UTC().Format("20060102150405")+".0")
+	}
+	record.addControl("008", marcFixedData(book))
+
+	record.addData("010", ' ', ' ', sub('a', book.LCCN))
+	record.addData("020", ' ', ' ', sub('a', book.ISBN))
+	if book.OCLC != "" {
+		record.addData("035", ' ', ' ', sub('a', oclcPrefix+book.OCLC))
+	}
+	if book.OLID != "" {
+		record.addData("035", ' ', ' ', sub('a', openLibraryPrefix+book.OLID))
+	}
+	if book.GoogleID != "" {
+		record.addData("035", ' ', ' ', sub('a', googleBooksPrefix+book.GoogleID))
+	}
+
+	for i, author := range book.Authors {
+		tag := "700"
+		if i == 0 {
+			tag = "100"
+		}
+		record.addData(tag, '0', ' ', sub('a', author))
+	}
+
+	titleInd1 := byte('0')
+	if len(book.Authors) > 0 {
+		titleInd1 = '1'
+	}
+	record.addData("245", titleInd1, '0', sub('a', book.Title))
+	record.addData("250", ' ', ' ', sub('a', book.Edition))
+
+	year := ""
+	if book.Published != 0 {
+		year = strconv.Itoa(book.Published)
+	}
+	record.addData("264", ' ', '1', sub('b', book.Publisher), sub('c', year))
+
+	if book.PageCount > 0 {
+		record.addData("300", ' ', ' ', sub('a', fmt.Sprintf("%d pages", book.PageCount)))
+	}
+	record.addData("520", ' ', ' ', sub('a', book.Description))
+
+	for _, subject := range book.Subjects {
+		record.addData("650", ' ', '4', sub('a', subject))
+	}
+
+	if book.CoverURL != "" {
+		record.addData("856", '4', '2', sub('3', "Cover image"), sub('u', book.CoverURL))
+	}
+
+	return record
+}
+
+// MARCToBook - Convierte un registro MARC21 en libro. Lee tanto 264 (RDA)
+// como 260 (AACR2) y limpia la puntuación ISBD final.
+func MARCToBook(record MARCRecord) models.Book {
+	var book models.Book
+
+	book.Title = trimISBD(record.firstSubfield("245", 'a'))
+	if subtitle := trimISBD(record.firstSubfield("245", 'b')); subtitle != "" {
+		book.Title += ": " + subtitle
+	}
+
+	for _, tag := range []string{"100", "110", "700", "710"} {
+		for _, field := range record.Fields(tag) {
+			if field.Subfield('t') != "" {
+				continue // entrada de nombre/título, no es un autor del libro
+			}
+			name := trimISBD(field.Subfield('a'))
+			if tag[1] == '0' && field.Ind1 == '1' {
+				name = invertName(name)
+			}
+			if name != "" {
+				book.Authors = append(book.Authors, name)
+			}
+		}
+	}
+
+	for _, field := range record.Fields("020") {
+		if code := firstToken(field.Subfield('a')); code != "" {
+			book.ISBN = code
+			break
+		}
+	}
+
+	book.LCCN = strings.TrimSpace(record.firstSubfield("010", 'a'))
+	for _, field := range record.Fields("035") {
+		value := strings.TrimSpace(field.Subfield('a'))
+		switch {
+		case strings.HasPrefix(value, oclcPrefix):
+			book.OCLC = strings.TrimPrefix(value, oclcPrefix)
+		case strings.HasPrefix(value, openLibraryPrefix):
+			book.OLID = strings.TrimPrefix(value, openLibraryPrefix)
+		case strings.HasPrefix(value, googleBooksPrefix):
+			book.GoogleID = strings.TrimPrefix(value, googleBooksPrefix)
+		}
+	}
+
+	book.Edition = strings.TrimSpace(record.firstSubfield("250", 'a')) // "2a ed." conserva el punto
+
+	publication := record.Fields("264")
+	var imprint []MARCDataField
+	for _, field := range publication {
+		if field.Ind2 == '1' {
+			imprint = append(imprint, field)
+		}
+	}
+	imprint = append(imprint, record.Fields("260")...)
+	for _, field := range imprint {
+		if book.Publisher == "" {
+			book.Publisher = trimISBD(field.Subfield('b'))
+		}
+		if book.Published == 0 {
+			book.Published = firstYear(field.Subfield('c'))
+		}
+	}
+
+	fixed := record.ControlField("008")
+	if book.Published == 0 && len(fixed) >= 11 {
+		book.Published = firstYear(fixed[7:11])
+	}
+	if len(fixed) >= 38 {
+		book.Language = languageFromMARC(fixed[35:38])
+	}
+	if book.Language == "" {
+		book.Language = languageFromMARC(record.firstSubfield("041", 'a'))
+	}
+
+	book.PageCount = firstNumber(record.firstSubfield("300", 'a'))
+	book.Description = strings.TrimSpace(record.firstSubfield("520", 'a'))
+
+	for _, field := range record.Fields("650") {
+		if subject := trimISBD(field.Subfield('a')); subject != "" {
+			book.Subjects = append(book.Subjects, subject)
+		}
+	}
+
+	for _, field := range record.Fields("856") {
+		label := strings.ToLower(field.Subfield('3'))
+		if strings.Contains(label, "cover") || strings.Contains(label, "cubierta") || strings.Contains(label, "portada") {
+			book.CoverURL = field.Subfield('u')
+			break
+		}
+	}
+
+	book.NormalizeContributors()
+	return book
+}
+
+// marcToRows - Valida los libros de una lista de registros
+func marcToRows(records []MARCRecord) []Row {
+	rows := make([]Row, 0, len(records))
+	for i, record := range records {
+		book := MARCToBook(record)
+		errs := validateBook(&book)
+		rows = append(rows, Row{Line: i + 1, Book: book, Errors: errs})
+	}
+	return rows
+}
+
+// marcFixedData - Campo 008 (40 posiciones) con la fecha de alta, el año de
+// publicación y el idioma
+func marcFixedData(book models.Book) string {
+	fixed := []byte(strings.Repeat(" ", 40))
+
+	created := book.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+	copy(fixed[0:6], created.UTC().Format("060102"))
+
+	if book.Published > 0 && book.Published <= 9999 {
+		fixed[6] = 's'
+		copy(fixed[7:11], fmt.Sprintf("%04d", book.Published))
+	} else {
+		fixed[6] = 'n'
+		copy(fixed[7:11], "uuuu")
+	}
+
+	copy(fixed[15:18], "xx ")
+	copy(fixed[35:38], languageToMARC(book.Language))
+	fixed[39] = 'd'
+
+	return string(fixed)
+}
+
+func languageToMARC(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := marcLanguages[language]; ok {
+		return code
+	}
+	if len(language) == 3 {
+		return language
+	}
+	return "und"
+}
+
+func languageFromMARC(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" || code == "und" || code == "|||" {
+		return ""
+	}
+	for iso, marc := range marcLanguages {
+		if marc == code {
+			return iso
+		}
+	}
+	return code
+}
+
+// trimISBD - Quita la puntuación ISBD final (" /", " :", ",", ".")
+func trimISBD(value string) string {
+	value = strings.TrimSpace(value)
+	for {
+		trimmed := strings.TrimSpace(strings.TrimRight(value, "/:;,."))
+		if trimmed == value {
+			return value
+		}
+		value = trimmed
+	}
+}
+
+// invertName - "Cortázar, Julio" → "Julio Cortázar"
+func invertName(name string) string {
+	surname, forename, found := strings.Cut(name, ",")
+	if !found {
+		return name
+	}
+	return strings.TrimSpace(forename) + " " + strings.TrimSpace(surname)
+}
+
+func firstToken(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+var (
+	yearPattern   = regexp.MustCompile(`\d{4}`)
+	numberPattern = regexp.MustCompile(`\d+`)
+)
+
+func firstYear(value string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(value))
+	return year
+}
+
+func firstNumber(value string) int {
+	n, _ := strconv.Atoi(numberPattern.FindString(value))
+	return n
+}
@@ -0,0 +1,224 @@
+package bookio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"library-api/models"
+)
+
+// Delimitadores de ISO 2709
+const (
+	marcSubfieldDelimiter = 0x1F
+	marcFieldTerminator   = 0x1E
+	marcRecordTerminator  = 0x1D
+)
+
+// ReadMARC - Lee registros MARC21 en formato binario (ISO 2709)
+func ReadMARC(r io.Reader) ([]Row, error) {
+	records, err := ReadMARCRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	return marcToRows(records), nil
+}
+
+// ReadMARCRecords - Decodifica todos los registros de un archivo .mrc. Solo
+// se admite UTF-8 (posición 9 del leader = "a").
+func ReadMARCRecords(r io.Reader) ([]MARCRecord, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading marc: %w", err)
+	}
+
+	var records []MARCRecord
+	for len(bytes.TrimSpace(data)) > 0 {
+		data = bytes.TrimLeft(data, " \r\n\t")
+
+		if len(data) < 24 {
+			return nil, fmt.Errorf("%w: record %d: truncated leader", ErrInvalidMARC, len(records)+1)
+		}
+		length, ok := marcNumber(data[0:5])
+		if !ok || length < 25 || length > len(data) {
+			return nil, fmt.Errorf("%w: record %d: bad record length", ErrInvalidMARC, len(records)+1)
+		}
+
+		record, err := decodeMARC(data[:length])
+		if err != nil {
+			return nil, fmt.Errorf("%w: record %d: %v", ErrInvalidMARC, len(records)+1, err)
+		}
+		records = append(records, record)
+		data = data[length:]
+	}
+
+	return records, nil
+}
+
+func decodeMARC(raw []byte) (MARCRecord, error) {
+	if len(raw) < 25 {
+		return MARCRecord{}, fmt.Errorf("truncated record")
+	}
+	leader := string(raw[:24])
+	if raw[len(raw)-1] != marcRecordTerminator {
+		return MARCRecord{}, fmt.Errorf("missing record terminator")
+	}
+	if leader[9] != 'a' && !utf8.Valid(raw) {
+		return MARCRecord{}, fmt.Errorf("MARC-8 encoded records are not supported")
+	}
+
+	// La dirección base va después del leader y del terminador del directorio
+	base, ok := marcNumber(raw[12:17])
+	if !ok || base < 25 || base > len(raw) || raw[base-1] != marcFieldTerminator {
+		return MARCRecord{}, fmt.Errorf("bad base address")
+	}
+
+	record := MARCRecord{Leader: leader}
+	directory := raw[24 : base-1]
+	if len(directory)%12 != 0 {
+		return MARCRecord{}, fmt.Errorf("bad directory length")
+	}
+
+	for i := 0; i < len(directory); i += 12 {
+		entry := directory[i : i+12]
+		tag := string(entry[0:3])
+		length, ok1 := marcNumber(entry[3:7])
+		start, ok2 := marcNumber(entry[7:12])
+		if !ok1 || !ok2 || length < 1 || base+start >= len(raw) || base+start+length > len(raw) {
+			return MARCRecord{}, fmt.Errorf("bad directory entry for tag %q", tag)
+		}
+
+		// El campo incluye su terminador
+		value := raw[base+start : base+start+length-1]
+
+		if tag < "010" {
+			record.ControlFields = append(record.ControlFields, MARCControlField{Tag: tag, Value: string(value)})
+			continue
+		}
+
+		if len(value) < 2 {
+			return MARCRecord{}, fmt.Errorf("field %s has no indicators", tag)
+		}
+		field := MARCDataField{Tag: tag, Ind1: value[0], Ind2: value[1]}
+		for _, part := range bytes.Split(value[2:], []byte{marcSubfieldDelimiter}) {
+			if len(part) == 0 {
+				continue
+			}
+			field.Subfields = append(field.Subfields, MARCSubfield{Code: part[0], Value: string(part[1:])})
+		}
+		record.DataFields = append(record.DataFields, field)
+	}
+
+	return record, nil
+}
+
+// marcNumber - Número de longitud fija del leader o del directorio: solo
+// dígitos ASCII (strconv.Atoi aceptaría signos, y con ellos posiciones
+// negativas)
+func marcNumber(digits []byte) (int, bool) {
+	if len(digits) == 0 {
+		return 0, false
+	}
+	n := 0
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			return 0, false
+		}
+		n = n*10 + int(d-'0')
+	}
+	return n, true
+}
+
+// EncodeMARC - Codifica un registro en ISO 2709, recalculando la longitud,
+// la dirección base y el directorio
+func EncodeMARC(record MARCRecord) ([]byte, error) {
+	var directory, fields bytes.Buffer
+
+	addField := func(tag string, value []byte) error {
+		if len(tag) != 3 {
+			return fmt.Errorf("%w: bad tag %q", ErrInvalidMARC, tag)
+		}
+		value = append(value, marcFieldTerminator)
+		if len(value) > 9999 {
+			return fmt.Errorf("%w: field %s too long", ErrInvalidMARC, tag)
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", tag, len(value), fields.Len())
+		fields.Write(value)
+		return nil
+	}
+
+	for _, field := range record.ControlFields {
+		if err := addField(field.Tag, []byte(field.Value)); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range record.DataFields {
+		value := []byte{indicator(field.Ind1), indicator(field.Ind2)}
+		for _, sub := range field.Subfields {
+			value = append(value, marcSubfieldDelimiter, sub.Code)
+			value = append(value, sub.Value...)
+		}
+		if err := addField(field.Tag, value); err != nil {
+			return nil, err
+		}
+	}
+	directory.WriteByte(marcFieldTerminator)
+
+	base := 24 + directory.Len()
+	length := base + fields.Len() + 1
+	if length > 99999 {
+		return nil, fmt.Errorf("%w: record too long", ErrInvalidMARC)
+	}
+
+	leader := []byte(record.Leader)
+	if len(leader) != 24 {
+		leader = []byte(defaultLeader)
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", length))
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+
+	out := make([]byte, 0, length)
+	out = append(out, leader...)
+	out = append(out, directory.Bytes()...)
+	out = append(out, fields.Bytes()...)
+	out = append(out, marcRecordTerminator)
+	return out, nil
+}
+
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
+
+// MARCWriter - Escritura incremental de libros en MARC21 binario
+type MARCWriter struct {
+	w io.Writer
+}
+
+// NewMARCWriter - Crea el escritor
+func NewMARCWriter(w io.Writer) (*MARCWriter, error) {
+	return &MARCWriter{w: w}, nil
+}
+
+// Write - Escribe un libro como registro MARC21
+func (mw *MARCWriter) Write(book models.Book) error {
+	data, err := EncodeMARC(BookToMARC(book))
+	if err != nil {
+		return err
+	}
+	_, err = mw.w.Write(data)
+	return err
+}
+
+// Flush - Los registros se escriben directamente
+func (mw *MARCWriter) Flush() error {
+	return nil
+}
+
+// Close - Termina la escritura
+func (mw *MARCWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,295 @@
+package bookio
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"library-api/models"
+)
+
+// expectedFixtureBooks - Libros que describen testdata/records.{xml,mrc}
+var expectedFixtureBooks = []models.Book{
+	{
+		Title:     "Rayuela",
+		Author:    "Julio Cortázar",
+		Authors:   []string{"Julio Cortázar"},
+		ISBN:      "9788437604572",
+		Published: 1963,
+		Genre:     "Novela, Literatura argentina",
+		Subjects:  []string{"Novela", "Literatura argentina"},
+		Publisher: "Cátedra",
+		Language:  "es",
+		PageCount: 736,
+		Edition:   "Edición de Andrés Amorós.",
+		OCLC:      "1053867",
+	},
+	{
+		Title:       "El ingenioso hidalgo don Quijote de la Mancha: edición del IV centenario",
+		Author:      "Miguel de Cervantes Saavedra, Francisco Rico",
+		Authors:     []string{"Miguel de Cervantes Saavedra", "Francisco Rico"},
+		ISBN:        "9788420412146",
+		Published:   2004,
+		Genre:       "Knights and knighthood",
+		Subjects:    []string{"Knights and knighthood"},
+		Description: "Las aventuras de un hidalgo que enloquece leyendo libros de caballerías.",
+		Publisher:   "Alfaguara",
+		Language:    "es",
+		PageCount:   1250,
+		CoverURL:    "https://covers.openlibrary.org/b/isbn/8420412147-L.jpg",
+		LCCN:        "2004465107",
+	},
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	return data
+}
+
+func rowBooks(t *testing.T, rows []Row) []models.Book {
+	t.Helper()
+	books := make([]models.Book, 0, len(rows))
+	for _, row := range rows {
+		if !row.Valid() {
+			t.Errorf("record %d: unexpected errors %v", row.Line, row.Errors)
+		}
+		books = append(books, row.Book)
+	}
+	return books
+}
+
+func TestReadMARCXMLFixture(t *testing.T) {
+	rows, err := ReadMARCXML(bytes.NewReader(readFixture(t, "records.xml")))
+	if err != nil {
+		t.Fatalf("ReadMARCXML: %v", err)
+	}
+
+	books := rowBooks(t, rows)
+	if !reflect.DeepEqual(books, expectedFixtureBooks) {
+		t.Errorf("books mismatch\n got: %+v\nwant: %+v", books, expectedFixtureBooks)
+	}
+}
+
+func TestReadMARCFixture(t *testing.T) {
+	rows, err := ReadMARC(bytes.NewReader(readFixture(t, "records.mrc")))
+	if err != nil {
+		t.Fatalf("ReadMARC: %v", err)
+	}
+
+	books := rowBooks(t, rows)
+	if !reflect.DeepEqual(books, expectedFixtureBooks) {
+		t.Errorf("books mismatch\n got: %+v\nwant: %+v", books, expectedFixtureBooks)
+	}
+}
+
+// El binario y el XML del fixture describen los mismos registros
+func TestMARCBinaryAndXMLFixturesAgree(t *testing.T) {
+	binary, err := ReadMARCRecords(bytes.NewReader(readFixture(t, "records.mrc")))
+	if err != nil {
+		t.Fatalf("ReadMARCRecords: %v", err)
+	}
+	fromXML, err := ReadMARCXMLRecords(bytes.NewReader(readFixture(t, "records.xml")))
+	if err != nil {
+		t.Fatalf("ReadMARCXMLRecords: %v", err)
+	}
+
+	for i := range binary {
+		// La longitud y la dirección base del leader solo existen en binario
+		binary[i].Leader = binary[i].Leader[5:12] + binary[i].Leader[17:]
+		fromXML[i].Leader = fromXML[i].Leader[5:12] + fromXML[i].Leader[17:]
+	}
+	if !reflect.DeepEqual(binary, fromXML) {
+		t.Errorf("records mismatch\nbinary: %+v\n   xml: %+v", binary, fromXML)
+	}
+}
+
+func TestMARCBinaryRecordRoundTrip(t *testing.T) {
+	fixture := readFixture(t, "records.mrc")
+	records, err := ReadMARCRecords(bytes.NewReader(fixture))
+	if err != nil {
+		t.Fatalf("ReadMARCRecords: %v", err)
+	}
+
+	var out bytes.Buffer
+	for _, record := range records {
+		data, err := EncodeMARC(record)
+		if err != nil {
+			t.Fatalf("EncodeMARC: %v", err)
+		}
+		out.Write(data)
+	}
+
+	if !bytes.Equal(out.Bytes(), fixture) {
+		t.Errorf("re-encoded records differ from fixture")
+	}
+}
+
+func TestMARCXMLRecordRoundTrip(t *testing.T) {
+	records, err := ReadMARCXMLRecords(bytes.NewReader(readFixture(t, "records.xml")))
+	if err != nil {
+		t.Fatalf("ReadMARCXMLRecords: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writer, err := NewMARCXMLWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewMARCXMLWriter: %v", err)
+	}
+	for _, record := range records {
+		if err := EncodeMARCXMLRecord(writer.encoder, record); err != nil {
+			t.Fatalf("EncodeMARCXMLRecord: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	again, err := ReadMARCXMLRecords(&buf)
+	if err != nil {
+		t.Fatalf("re-reading MARCXML: %v", err)
+	}
+	if !reflect.DeepEqual(again, records) {
+		t.Errorf("records changed after round trip\n got: %+v\nwant: %+v", again, records)
+	}
+}
+
+func TestBookMARCRoundTrip(t *testing.T) {
+	book := models.Book{
+		ID:          "b1",
+		Title:       "Cien años de soledad",
+		Authors:     []string{"Gabriel García Márquez"},
+		ISBN:        "9780307474728",
+		Published:   1967,
+		Subjects:    []string{"Novela", "Realismo mágico"},
+		Description: "La historia de la familia Buendía.",
+		Publisher:   "Vintage Español",
+		Language:    "es",
+		PageCount:   417,
+		Edition:     "1a ed.",
+		CoverURL:    "https://covers.openlibrary.org/b/id/1-L.jpg",
+		GoogleID:    "g123",
+		OLID:        "OL1M",
+		LCCN:        "2009018950",
+		OCLC:        "310225823",
+		CreatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	want := book
+	want.ID, want.CreatedAt, want.UpdatedAt = "", time.Time{}, time.Time{}
+	want.NormalizeContributors()
+
+	for _, name := range []string{"marc", "marcxml"} {
+		t.Run(name, func(t *testing.T) {
+			format, _ := LookupFormat(name)
+
+			var buf bytes.Buffer
+			writer, err := format.NewWriter(&buf)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			if err := writer.Write(book); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			rows, err := format.Read(buf.Bytes(), nil)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("got %d records, want 1", len(rows))
+			}
+			if got := rowBooks(t, rows)[0]; !reflect.DeepEqual(got, want) {
+				t.Errorf("book changed after round trip\n got: %+v\nwant: %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadMARCRejectsTruncatedRecord(t *testing.T) {
+	fixture := readFixture(t, "records.mrc")
+	_, err := ReadMARC(bytes.NewReader(fixture[:len(fixture)-10]))
+	if err == nil || !strings.Contains(err.Error(), ErrInvalidMARC.Error()) {
+		t.Errorf("got %v, want ErrInvalidMARC", err)
+	}
+}
+
+// firstFixtureRecord - Primer registro de testdata/records.mrc
+func firstFixtureRecord(t testing.TB) []byte {
+	fixture, err := os.ReadFile("testdata/records.mrc")
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	length, ok := marcNumber(fixture[:5])
+	if !ok {
+		t.Fatalf("fixture has a bad record length %q", fixture[:5])
+	}
+	return fixture[:length]
+}
+
+// withBytes - Copia de record con value a partir de offset
+func withBytes(record []byte, offset int, value string) []byte {
+	mutated := append([]byte(nil), record...)
+	copy(mutated[offset:], value)
+	return mutated
+}
+
+func TestReadMARCRejectsMalformedRecords(t *testing.T) {
+	record := firstFixtureRecord(t)
+	if _, err := ReadMARCRecords(bytes.NewReader(record)); err != nil {
+		t.Fatalf("unmodified record: %v", err)
+	}
+
+	// Leader: longitud 0-4, dirección base 12-16. Primera entrada del
+	// directorio en 24: etiqueta 24-26, longitud 27-30, posición 31-35
+	cases := map[string][]byte{
+		"signed record length":    withBytes(record, 0, "+0484"),
+		"negative record length":  withBytes(record, 0, "-0484"),
+		"signed base address":     withBytes(record, 12, "+0169"),
+		"base inside the leader":  withBytes(record, 12, "00020"),
+		"base off the directory":  withBytes(record, 12, "00168"),
+		"negative field start":    withBytes(record, 31, "-9960"),
+		"negative field length":   withBytes(record, 27, "-012"),
+		"field start past end":    withBytes(record, 31, "99999"),
+		"field length past end":   withBytes(record, 27, "9999"),
+		"zero field length":       withBytes(record, 27, "0000"),
+		"spaces in field length":  withBytes(record, 27, " 13 "),
+		"missing terminator":      withBytes(record, len(record)-1, "x"),
+		"record shorter than 25":  []byte("00024cam a2200025 i 4500"),
+		"MARC-8 with bad UTF-8":   withBytes(withBytes(record, 9, " "), 300, "\xff"),
+		"directory not in twelve": withBytes(withBytes(record, 12, "00168"), 166, "\x1e"),
+	}
+	for name, data := range cases {
+		if _, err := ReadMARCRecords(bytes.NewReader(data)); !errors.Is(err, ErrInvalidMARC) {
+			t.Errorf("%s: got %v, want ErrInvalidMARC", name, err)
+		}
+	}
+}
+
+// FuzzDecodeMARC - Ningún registro, por malformado que esté, hace panic
+func FuzzDecodeMARC(f *testing.F) {
+	record := firstFixtureRecord(f)
+	f.Add(record)
+	f.Add(withBytes(record, 31, "-9960"))
+	f.Add(withBytes(record, 12, "+0169"))
+	f.Add([]byte("00025cam a2200025 i 4500\x1d"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if decoded, err := decodeMARC(data); err == nil {
+			if _, err := EncodeMARC(decoded); err != nil {
+				t.Logf("decoded record does not encode: %v", err)
+			}
+		}
+		ReadMARCRecords(bytes.NewReader(data))
+	})
+}
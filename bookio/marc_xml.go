@@ -0,0 +1,183 @@
+package bookio
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"library-api/models"
+)
+
+// marcXMLNamespace - Espacio de nombres de MARCXML (MARC21 slim)
+const marcXMLNamespace = "http://www.loc.gov/MARC21/slim"
+
+type marcXMLRecord struct {
+	XMLName       xml.Name `xml:"record"`
+	Leader        string   `xml:"leader"`
+	ControlFields []struct {
+		Tag   string `xml:"tag,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"controlfield"`
+	DataFields []struct {
+		Tag       string `xml:"tag,attr"`
+		Ind1      string `xml:"ind1,attr"`
+		Ind2      string `xml:"ind2,attr"`
+		Subfields []struct {
+			Code  string `xml:"code,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"subfield"`
+	} `xml:"datafield"`
+}
+
+// ReadMARCXML - Lee registros MARCXML (<collection> o un único <record>)
+func ReadMARCXML(r io.Reader) ([]Row, error) {
+	records, err := ReadMARCXMLRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	return marcToRows(records), nil
+}
+
+// ReadMARCXMLRecords - Decodifica los <record> del documento uno a uno
+func ReadMARCXMLRecords(r io.Reader) ([]MARCRecord, error) {
+	decoder := xml.NewDecoder(r)
+
+	var records []MARCRecord
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMARC, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var raw marcXMLRecord
+		if err := decoder.DecodeElement(&raw, &start); err != nil {
+			return nil, fmt.Errorf("%w: record %d: %v", ErrInvalidMARC, len(records)+1, err)
+		}
+		records = append(records, raw.toRecord())
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no records found", ErrInvalidMARC)
+	}
+	return records, nil
+}
+
+func (raw marcXMLRecord) toRecord() MARCRecord {
+	record := MARCRecord{Leader: raw.Leader}
+	for _, field := range raw.ControlFields {
+		record.ControlFields = append(record.ControlFields, MARCControlField{Tag: field.Tag, Value: field.Value})
+	}
+	for _, field := range raw.DataFields {
+		data := MARCDataField{Tag: field.Tag, Ind1: firstByte(field.Ind1), Ind2: firstByte(field.Ind2)}
+		for _, sub := range field.Subfields {
+			data.Subfields = append(data.Subfields, MARCSubfield{Code: firstByte(sub.Code), Value: sub.Value})
+		}
+		record.DataFields = append(record.DataFields, data)
+	}
+	return record
+}
+
+func firstByte(value string) byte {
+	if value == "" {
+		return ' '
+	}
+	return value[0]
+}
+
+// EncodeMARCXMLRecord - Escribe un <record> MARCXML
+func EncodeMARCXMLRecord(encoder *xml.Encoder, record MARCRecord) error {
+	start := xml.StartElement{Name: xml.Name{Local: "record"}}
+	tokens := []xml.Token{start}
+
+	text := func(name string, attrs []xml.Attr, value string) {
+		element := xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+		tokens = append(tokens, element, xml.CharData(value), element.End())
+	}
+	attr := func(name, value string) xml.Attr {
+		return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+	}
+
+	text("leader", nil, record.Leader)
+	for _, field := range record.ControlFields {
+		text("controlfield", []xml.Attr{attr("tag", field.Tag)}, field.Value)
+	}
+	for _, field := range record.DataFields {
+		element := xml.StartElement{
+			Name: xml.Name{Local: "datafield"},
+			Attr: []xml.Attr{
+				attr("tag", field.Tag),
+				attr("ind1", string(indicator(field.Ind1))),
+				attr("ind2", string(indicator(field.Ind2))),
+			},
+		}
+		tokens = append(tokens, element)
+		for _, sub := range field.Subfields {
+			text("subfield", []xml.Attr{attr("code", string(sub.Code))}, sub.Value)
+		}
+		tokens = append(tokens, element.End())
+	}
+	tokens = append(tokens, start.End())
+
+	for _, token := range tokens {
+		if err := encoder.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MARCXMLWriter - Escritura incremental de libros en una <collection> MARCXML
+type MARCXMLWriter struct {
+	w       io.Writer
+	encoder *xml.Encoder
+}
+
+// NewMARCXMLWriter - Crea el escritor y abre la colección
+func NewMARCXMLWriter(w io.Writer) (*MARCXMLWriter, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	collection := xml.StartElement{
+		Name: xml.Name{Local: "collection"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: marcXMLNamespace}},
+	}
+	if err := encoder.EncodeToken(collection); err != nil {
+		return nil, err
+	}
+
+	return &MARCXMLWriter{w: w, encoder: encoder}, nil
+}
+
+// Write - Escribe un libro como <record>
+func (mw *MARCXMLWriter) Write(book models.Book) error {
+	return EncodeMARCXMLRecord(mw.encoder, BookToMARC(book))
+}
+
+// Flush - Envía al destino lo escrito hasta ahora
+func (mw *MARCXMLWriter) Flush() error {
+	return mw.encoder.Flush()
+}
+
+// Close - Cierra la colección
+func (mw *MARCXMLWriter) Close() error {
+	end := xml.EndElement{Name: xml.Name{Local: "collection"}}
+	if err := mw.encoder.EncodeToken(end); err != nil {
+		return err
+	}
+	if err := mw.encoder.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(mw.w, "\n")
+	return err
+}
@@ -0,0 +1,296 @@
+// Package bookio - Lectura y escritura de libros en formatos de intercambio
+// (CSV, hojas de cálculo XLSX, MARC21, MARCXML) y de cita (BibTeX, RIS, CSL-JSON)
+package bookio
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"library-api/isbn"
+	"library-api/models"
+)
+
+// ErrInvalidMapping - El mapeo de columnas no es aplicable al archivo
+var ErrInvalidMapping = errors.New("invalid column mapping")
+
+// Mapping - Campo del libro → encabezado de columna en el archivo. El valor
+// también puede ser el número de columna (empezando en 1).
+type Mapping map[string]string
+
+// Row - Fila leída de un archivo tabular con sus errores de validación
+type Row struct {
+	Line   int         `json:"row"`
+	Book   models.Book `json:"-"`
+	Errors []string    `json:"errors,omitempty"`
+}
+
+// Valid - Indica si la fila no tiene errores
+func (r Row) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// fieldAliases - Campos importables, con los encabezados que se reconocen
+// automáticamente cuando no se indica un mapeo
+var fieldAliases = map[string][]string{
+	"title":       {"title", "titulo", "título"},
+	"author":      {"author", "autor"},
+	"authors":     {"authors", "autores"},
+	"isbn":        {"isbn", "isbn13", "isbn-13", "isbn10", "isbn-10"},
+	"published":   {"published", "year", "año", "anio", "publicado"},
+	"genre":       {"genre", "genero", "género"},
+	"subjects":    {"subjects", "materias", "temas"},
+	"description": {"description", "descripcion", "descripción"},
+	"publisher":   {"publisher", "editorial"},
+	"language":    {"language", "idioma"},
+	"page_count":  {"page_count", "pages", "paginas", "páginas"},
+	"edition":     {"edition", "edicion", "edición"},
+	"cover_url":   {"cover_url", "cover", "portada"},
+	"google_id":   {"google_id"},
+	"olid":        {"olid"},
+	"lccn":        {"lccn"},
+	"oclc":        {"oclc"},
+}
+
+// ExportColumns - Columnas de la exportación tabular, en orden
+var ExportColumns = []string{
+	"id", "isbn", "title", "author", "published", "genre", "publisher", "language",
+	"page_count", "edition", "description", "cover_url", "google_id", "olid", "lccn",
+	"oclc", "available", "created_at", "updated_at",
+}
+
+// ValidateMapping - Comprueba que el mapeo solo use campos conocidos
+func ValidateMapping(m Mapping) error {
+	for field := range m {
+		if _, ok := fieldAliases[field]; !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidMapping, field)
+		}
+	}
+	return nil
+}
+
+// RecordsToRows - Convierte registros (la primera fila es el encabezado) en
+// libros validados
+func RecordsToRows(records [][]string, mapping Mapping) ([]Row, error) {
+	return recordsToRows(records, nil, mapping)
+}
+
+// recordsToRows - Como RecordsToRows, con el número de línea de cada
+// registro en el archivo (nil: consecutivos desde 1)
+func recordsToRows(records [][]string, lines []int, mapping Mapping) ([]Row, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidMapping)
+	}
+
+	columns, err := resolveColumns(records[0], mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if isBlank(record) {
+			continue
+		}
+
+		row := Row{Line: i + 2}
+		if lines != nil {
+			row.Line = lines[i+1]
+		}
+		row.Book, row.Errors = recordToBook(record, columns)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// resolveColumns - Índice de columna para cada campo mapeado
+func resolveColumns(header []string, mapping Mapping) (map[string]int, error) {
+	if err := ValidateMapping(mapping); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]int, len(header))
+	for i, name := range header {
+		byName[normalizeHeader(name)] = i
+	}
+
+	columns := make(map[string]int)
+	for field, aliases := range fieldAliases {
+		if column, ok := mapping[field]; ok {
+			if n, err := strconv.Atoi(column); err == nil && n >= 1 && n <= len(header) {
+				columns[field] = n - 1
+				continue
+			}
+			index, found := byName[normalizeHeader(column)]
+			if !found {
+				return nil, fmt.Errorf("%w: column %q for field %q not found", ErrInvalidMapping, column, field)
+			}
+			columns[field] = index
+			continue
+		}
+
+		for _, alias := range aliases {
+			if index, found := byName[alias]; found {
+				columns[field] = index
+				break
+			}
+		}
+	}
+
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("%w: no column for required field \"title\"", ErrInvalidMapping)
+	}
+	if _, ok := columns["isbn"]; !ok {
+		return nil, fmt.Errorf("%w: no column for required field \"isbn\"", ErrInvalidMapping)
+	}
+	_, hasAuthor := columns["author"]
+	_, hasAuthors := columns["authors"]
+	if !hasAuthor && !hasAuthors {
+		return nil, fmt.Errorf("%w: no column for required field \"author\"", ErrInvalidMapping)
+	}
+
+	return columns, nil
+}
+
+// recordToBook - Construye y valida un libro a partir de una fila
+func recordToBook(record []string, columns map[string]int) (models.Book, []string) {
+	get := func(field string) string {
+		index, ok := columns[field]
+		if !ok || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	book := models.Book{
+		Title:       get("title"),
+		Author:      get("author"),
+		Authors:     splitMulti(get("authors")),
+		Genre:       get("genre"),
+		Subjects:    splitMulti(get("subjects")),
+		Description: get("description"),
+		Publisher:   get("publisher"),
+		Language:    get("language"),
+		Edition:     get("edition"),
+		CoverURL:    get("cover_url"),
+		GoogleID:    get("google_id"),
+		OLID:        get("olid"),
+		LCCN:        get("lccn"),
+		OCLC:        get("oclc"),
+		ISBN:        get("isbn"),
+	}
+	errs := validateBook(&book)
+
+	if value := get("published"); value != "" {
+		year, err := strconv.Atoi(value)
+		if err != nil || year < 0 || year > time.Now().Year()+1 {
+			errs = append(errs, fmt.Sprintf("published %q is not a valid year", value))
+		} else {
+			book.Published = year
+		}
+	}
+
+	if value := get("page_count"); value != "" {
+		pages, err := strconv.Atoi(value)
+		if err != nil || pages < 0 {
+			errs = append(errs, fmt.Sprintf("page_count %q is not a valid number", value))
+		} else {
+			book.PageCount = pages
+		}
+	}
+
+	book.NormalizeContributors()
+	return book, errs
+}
+
+// validateBook - Campos obligatorios para importar un libro; deja el ISBN
+// normalizado cuando es válido
+func validateBook(book *models.Book) []string {
+	var errs []string
+
+	if book.Title == "" {
+		errs = append(errs, "title is required")
+	}
+	if book.Author == "" && len(book.Authors) == 0 {
+		errs = append(errs, "author is required")
+	}
+
+	if book.ISBN == "" {
+		errs = append(errs, "isbn is required")
+	} else if normalized, err := isbn.Normalize(book.ISBN); err != nil {
+		errs = append(errs, fmt.Sprintf("isbn %q: %v", book.ISBN, err))
+	} else {
+		book.ISBN = normalized
+	}
+
+	return errs
+}
+
+// BookRecord - Valores de un libro en el orden de ExportColumns
+func BookRecord(book models.Book) []string {
+	return []string{
+		book.ID,
+		book.ISBN,
+		book.Title,
+		book.Author,
+		intOrEmpty(book.Published),
+		book.Genre,
+		book.Publisher,
+		book.Language,
+		intOrEmpty(book.PageCount),
+		book.Edition,
+		book.Description,
+		book.CoverURL,
+		book.GoogleID,
+		book.OLID,
+		book.LCCN,
+		book.OCLC,
+		strconv.FormatBool(book.Available),
+		book.CreatedAt.Format(time.RFC3339),
+		book.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// ==============================================
+// FUNCIONES AUXILIARES
+// ==============================================
+
+func normalizeHeader(name string) string {
+	name = strings.TrimPrefix(name, "\ufeff") // BOM de Excel
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// splitMulti - Listas dentro de una celda separadas por ";" o "|"
+func splitMulti(value string) []string {
+	if value == "" {
+		return nil
+	}
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '|' })
+	return models.SplitList(strings.Join(parts, ","))
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func intOrEmpty(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// Writer - Escritor incremental de libros (CSV, XLSX)
+type Writer interface {
+	Write(book models.Book) error
+	Flush() error
+	Close() error
+}
@@ -0,0 +1,253 @@
+package bookio
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// ==============================================
+// CSV
+// ==============================================
+
+func TestReadCSV(t *testing.T) {
+	data := "\ufeffTítulo;Autor;ISBN;Año;Materias\n" +
+		"Rayuela;Julio Cortázar;978-84-376-0457-2;1963;Novela|Literatura argentina\n" +
+		";;;;\n" +
+		"Sin ISBN;Anónimo;;1900;\n" +
+		"Malo;X;9788437604571;dos mil;\n"
+
+	rows, err := ReadCSV(strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3 (blank rows are skipped)", len(rows))
+	}
+
+	rayuela := rows[0]
+	if !rayuela.Valid() || rayuela.Line != 2 || rayuela.Book.ISBN != "9788437604572" || rayuela.Book.Published != 1963 ||
+		strings.Join(rayuela.Book.Subjects, ",") != "Novela,Literatura argentina" {
+		t.Errorf("first row: got %+v", rayuela)
+	}
+	if rows[1].Line != 4 || strings.Join(rows[1].Errors, "; ") != "isbn is required" {
+		t.Errorf("row without isbn: got line %d, errors %v", rows[1].Line, rows[1].Errors)
+	}
+	if len(rows[2].Errors) != 2 {
+		t.Errorf("row with a bad checksum and year: got errors %v", rows[2].Errors)
+	}
+}
+
+// TestReadCSVLines - Las líneas vacías y los campos de varias líneas no
+// desplazan el número de línea de las filas siguientes
+func TestReadCSVLines(t *testing.T) {
+	data := "title,author,isbn,description\n" +
+		"\n" +
+		"Rayuela,Julio Cortázar,9788437604572,\"primera\nedición\"\n" +
+		"Ficciones,Jorge Luis Borges,9788420633115,\n"
+
+	rows, err := ReadCSV(strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 3 || rows[1].Line != 5 {
+		t.Fatalf("got rows %+v, want lines 3 and 5", rows)
+	}
+	if rows[1].Valid() {
+		t.Errorf("row with a bad checksum at line %d has no errors", rows[1].Line)
+	}
+}
+
+func TestReadCSVMapping(t *testing.T) {
+	data := "Nombre,Escritor,Código,Notas\n" +
+		"Rayuela,Julio Cortázar,9788437604572,primera edición\n"
+
+	// Por encabezado y por número de columna
+	rows, err := ReadCSV(strings.NewReader(data), Mapping{"title": "nombre", "author": "2", "isbn": "Código", "description": "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book := rows[0].Book
+	if !rows[0].Valid() || book.Title != "Rayuela" || book.Author != "Julio Cortázar" || book.Description != "primera edición" {
+		t.Errorf("mapped row: got %+v", rows[0])
+	}
+
+	for name, mapping := range map[string]Mapping{
+		"unknown field":   {"title": "Nombre", "author": "Escritor", "isbn": "Código", "price": "Notas"},
+		"missing column":  {"title": "Nombre", "author": "Escritor", "isbn": "EAN"},
+		"no title column": {"author": "Escritor", "isbn": "Código"},
+	} {
+		if _, err := ReadCSV(strings.NewReader(data), mapping); !errors.Is(err, ErrInvalidMapping) {
+			t.Errorf("%s: got %v, want ErrInvalidMapping", name, err)
+		}
+	}
+}
+
+// ==============================================
+// XLSX
+// ==============================================
+
+// buildXLSX - Libro de Excel mínimo con la hoja y las cadenas compartidas
+// indicadas (sin cadenas compartidas si shared es "")
+func buildXLSX(t *testing.T, sheet, shared string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"[Content_Types].xml":        xlsxContentTypes,
+		"_rels/.rels":                xlsxRootRels,
+		"xl/workbook.xml":            xlsxWorkbookXML,
+		"xl/_rels/workbook.xml.rels": xlsxWorkbookRels,
+		"xl/worksheets/sheet1.xml":   `<worksheet><sheetData>` + sheet + `</sheetData></worksheet>`,
+	}
+	if shared != "" {
+		parts["xl/sharedStrings.xml"] = `<sst>` + shared + `</sst>`
+	}
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readXLSXBytes(data []byte, mapping Mapping) ([]Row, error) {
+	return ReadXLSX(bytes.NewReader(data), int64(len(data)), mapping)
+}
+
+func TestReadXLSX(t *testing.T) {
+	// Cadenas compartidas, texto en línea, un ISBN guardado como número y
+	// una celda vacía que se salta (B2 falta: la columna se toma de r)
+	sheet := `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>ISBN</t></is></c><c r="D1" t="s"><v>2</v></c></row>` +
+		`<row r="2"><c r="A2" t="s"><v>3</v></c><c r="C2"><v>9788437604572</v></c><c r="D2"><v>1963</v></c></row>` +
+		`<row r="3"><c r="A3" t="inlineStr"><is><r><t>Don </t></r><r><t>Quijote</t></r></is></c><c r="B3" t="s"><v>4</v></c><c r="C3"><v>9.788420412146E12</v></c></row>`
+	shared := `<si><t>Title</t></si><si><t>Author</t></si><si><t>Year</t></si><si><t>Rayuela</t></si><si><t>Miguel de Cervantes</t></si>`
+
+	rows, err := readXLSXBytes(buildXLSX(t, sheet, shared), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].Book.Title != "Rayuela" || rows[0].Book.ISBN != "9788437604572" || rows[0].Book.Published != 1963 {
+		t.Errorf("first row: got %+v", rows[0])
+	}
+	if strings.Join(rows[0].Errors, "; ") != "author is required" {
+		t.Errorf("first row errors: got %v", rows[0].Errors)
+	}
+	if !rows[1].Valid() || rows[1].Book.Title != "Don Quijote" || rows[1].Book.ISBN != "9788420412146" {
+		t.Errorf("second row: got %+v", rows[1])
+	}
+
+	// Mapeo por número de columna
+	rows, err = readXLSXBytes(buildXLSX(t, sheet, shared), Mapping{"title": "1", "author": "2", "isbn": "3", "published": "Year"})
+	if err != nil || rows[1].Book.Author != "Miguel de Cervantes" {
+		t.Errorf("mapped: got %+v, %v", rows, err)
+	}
+}
+
+// TestReadXLSXSparseSheet - Las filas vacías no están en el XML y a las
+// celdas les pueden faltar las anteriores: cada valor va a la columna de su
+// referencia y cada fila conserva su número en la hoja
+func TestReadXLSXSparseSheet(t *testing.T) {
+	sheet := `<row r="2"><c r="B2" t="inlineStr"><is><t>title</t></is></c><c r="C2" t="inlineStr"><is><t>author</t></is></c><c r="E2" t="inlineStr"><is><t>isbn</t></is></c></row>` +
+		`<row r="5"><c r="B5" t="inlineStr"><is><t>Rayuela</t></is></c><c r="E5"><v>9788437604572</v></c></row>` +
+		`<row><c r="B6" t="inlineStr"><is><t>Ficciones</t></is></c><c t="inlineStr"><is><t>Jorge Luis Borges</t></is></c><c r="E6"><v>9788420633115</v></c></row>` +
+		`<row r="9"><c r="C9" t="inlineStr"><is><t>Julio Cortázar</t></is></c><c r="E9"><v>9788437604572</v></c></row>`
+
+	rows, err := readXLSXBytes(buildXLSX(t, sheet, ""), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+
+	rayuela, ficciones, untitled := rows[0], rows[1], rows[2]
+	if rayuela.Line != 5 || rayuela.Book.Title != "Rayuela" || rayuela.Book.Author != "" || rayuela.Book.ISBN != "9788437604572" {
+		t.Errorf("row with a skipped cell: got %+v", rayuela)
+	}
+	if ficciones.Line != 6 || ficciones.Book.Author != "Jorge Luis Borges" || len(ficciones.Errors) != 1 {
+		t.Errorf("row without r and a bad checksum: got %+v", ficciones)
+	}
+	if untitled.Line != 9 || untitled.Book.Author != "Julio Cortázar" || untitled.Book.Title != "" {
+		t.Errorf("row after a gap: got %+v", untitled)
+	}
+}
+
+func TestXLSXRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewXLSXWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, book := range expectedFixtureBooks {
+		if err := writer.Write(book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := readXLSXBytes(buf.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(expectedFixtureBooks) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(expectedFixtureBooks))
+	}
+	for i, row := range rows {
+		want := expectedFixtureBooks[i]
+		if !row.Valid() || row.Book.Title != want.Title || row.Book.ISBN != want.ISBN || row.Book.Published != want.Published {
+			t.Errorf("row %d: got %+v, want %s (%s)", i+2, row, want.Title, want.ISBN)
+		}
+	}
+}
+
+func TestReadXLSXRejectsBadFiles(t *testing.T) {
+	header := `<row r="1"><c r="A1" t="inlineStr"><is><t>title</t></is></c><c r="B1" t="inlineStr"><is><t>author</t></is></c><c r="C1" t="inlineStr"><is><t>isbn</t></is></c></row>`
+	cases := map[string][]byte{
+		"not a zip":              []byte("title,author,isbn\n"),
+		"column overflows int":   buildXLSX(t, header+`<row r="2"><c r="ZZZZZZZZZZZZZZ2" t="inlineStr"><is><t>x</t></is></c></row>`, ""),
+		"column past XFD":        buildXLSX(t, header+`<row r="2"><c r="XFE2" t="inlineStr"><is><t>x</t></is></c></row>`, ""),
+		"reference without col":  buildXLSX(t, header+`<row r="2"><c r="12" t="inlineStr"><is><t>x</t></is></c></row>`, ""),
+		"missing shared strings": buildXLSX(t, header+`<row r="2"><c r="A2" t="s"><v>7</v></c></row>`, ""),
+		"row numbers go back":    buildXLSX(t, header+`<row r="3"></row><row r="2"></row>`, ""),
+		"row past the last one":  buildXLSX(t, header+`<row r="1048577"></row>`, ""),
+		"too many cells":         buildXLSX(t, header+strings.Repeat(`<row><c r="XFD2"><v>1</v></c></row>`, maxXLSXCells/maxXLSXColumns+1), ""),
+	}
+	for name, data := range cases {
+		if _, err := readXLSXBytes(data, nil); !errors.Is(err, ErrInvalidXLSX) {
+			t.Errorf("%s: got %v, want ErrInvalidXLSX", name, err)
+		}
+	}
+
+	// XFD, la última columna de Excel, sí se acepta
+	if _, err := readXLSXBytes(buildXLSX(t, header+`<row r="2"><c r="XFD2" t="inlineStr"><is><t>x</t></is></c></row>`, ""), nil); err != nil {
+		t.Errorf("column XFD: %v", err)
+	}
+}
+
+func TestReadXLSXRejectsOversizedEntries(t *testing.T) {
+	// Pocos KB comprimidos que se expanden más allá de maxXLSXPartSize
+	padding := strings.Repeat(" ", maxXLSXPartSize)
+	data := buildXLSX(t, `<row r="1"><c r="A1" t="inlineStr"><is><t>title</t></is></c></row>`+padding, "")
+	if len(data) > 1<<20 {
+		t.Fatalf("test file is %d bytes compressed", len(data))
+	}
+
+	_, err := readXLSXBytes(data, nil)
+	if !errors.Is(err, ErrInvalidXLSX) || !strings.Contains(err.Error(), "uncompressed") {
+		t.Errorf("zip bomb: got %v", err)
+	}
+}
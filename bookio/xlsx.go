@@ -0,0 +1,374 @@
+package bookio
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"library-api/models"
+)
+
+// ErrInvalidXLSX - El archivo no es un libro de Excel legible
+var ErrInvalidXLSX = errors.New("invalid xlsx file")
+
+// maxXLSXColumns - Columnas de una hoja de Excel (A…XFD); una referencia
+// más allá es un archivo malformado
+const maxXLSXColumns = 16384
+
+// maxXLSXPartSize - Tamaño descomprimido máximo de cada parte del zip: el
+// límite de la subida no frena un zip que se expande al leerlo
+const maxXLSXPartSize = 64 << 20
+
+// maxXLSXRows - Filas de una hoja de Excel
+const maxXLSXRows = 1 << 20
+
+// maxXLSXCells - Celdas de la hoja como máximo, contando las vacías hasta la
+// última de cada fila (una celda suelta en XFD ocupa la fila entera)
+const maxXLSXCells = 4 << 20
+
+// ==============================================
+// LECTURA
+// ==============================================
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxText - Texto simple (<t>) o enriquecido (<r><t>)
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Ref   int `xml:"r,attr"` // número de fila (0: la siguiente a la anterior)
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX - Lee la primera hoja de un archivo XLSX
+func ReadXLSX(r io.ReaderAt, size int64, mapping Mapping) ([]Row, error) {
+	records, lines, err := readXLSXRecords(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return recordsToRows(records, lines, mapping)
+}
+
+// readXLSXRecords - Filas de la primera hoja con su número de fila: las
+// filas vacías no aparecen en el XML y las celdas van en la columna de su
+// referencia, aunque falten las anteriores
+func readXLSXRecords(r io.ReaderAt, size int64) (records [][]string, lines []int, err error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeZipXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, nil, fmt.Errorf("%w: workbook has no sheets", ErrInvalidXLSX)
+	}
+
+	var rels xlsxRelationships
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, nil, err
+	}
+
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RID {
+			if strings.HasPrefix(rel.Target, "/") {
+				sheetPath = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				sheetPath = path.Join("xl", rel.Target)
+			}
+			break
+		}
+	}
+	if sheetPath == "" {
+		return nil, nil, fmt.Errorf("%w: first sheet not found", ErrInvalidXLSX)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var sheet xlsxSheet
+	if err := decodeZipXML(files, sheetPath, &sheet); err != nil {
+		return nil, nil, err
+	}
+
+	records = make([][]string, 0, len(sheet.Rows))
+	lines = make([]int, 0, len(sheet.Rows))
+	cells, line := 0, 0
+	for _, row := range sheet.Rows {
+		switch {
+		case row.Ref == 0:
+			line++
+		case row.Ref <= line || row.Ref > maxXLSXRows:
+			return nil, nil, fmt.Errorf("%w: bad row number %d after row %d", ErrInvalidXLSX, row.Ref, line)
+		default:
+			line = row.Ref
+		}
+
+		var record []string
+		column := -1
+		for _, cell := range row.Cells {
+			column++
+			if cell.Ref != "" {
+				column = columnIndex(cell.Ref)
+			}
+			if column < 0 || column >= maxXLSXColumns {
+				return nil, nil, fmt.Errorf("%w: bad cell reference %q", ErrInvalidXLSX, cell.Ref)
+			}
+			if column >= len(record) {
+				cells += column + 1 - len(record)
+				if cells > maxXLSXCells {
+					return nil, nil, fmt.Errorf("%w: sheet has more than %d cells", ErrInvalidXLSX, maxXLSXCells)
+				}
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, nil, fmt.Errorf("%w: bad shared string in cell %s", ErrInvalidXLSX, cell.Ref)
+				}
+				record[column] = shared.Items[index].String()
+			case "inlineStr":
+				record[column] = cell.Inline.String()
+			case "b":
+				record[column] = strconv.FormatBool(cell.Value == "1")
+			case "", "n":
+				record[column] = formatNumber(cell.Value)
+			default:
+				record[column] = cell.Value
+			}
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+
+	return records, lines, nil
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrInvalidXLSX, name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+	defer rc.Close()
+
+	limited := &io.LimitedReader{R: rc, N: maxXLSXPartSize + 1}
+	err = xml.NewDecoder(limited).Decode(v)
+	if limited.N <= 0 {
+		return fmt.Errorf("%w: %s is larger than %d bytes uncompressed", ErrInvalidXLSX, name, maxXLSXPartSize)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidXLSX, name, err)
+	}
+	return nil
+}
+
+// columnIndex - "C12" → 2; -1 sin letras y maxXLSXColumns si pasa de XFD
+func columnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		if index > maxXLSXColumns {
+			return maxXLSXColumns
+		}
+	}
+	return index - 1
+}
+
+// columnName - 2 → "C"
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// formatNumber - Excel guarda los ISBN y los años como números; se devuelven
+// sin notación científica ni decimales
+func formatNumber(value string) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) >= 1e15 {
+		return value
+	}
+	return strconv.FormatInt(int64(f), 10)
+}
+
+// ==============================================
+// ESCRITURA
+// ==============================================
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbookXML = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Books" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
+
+// XLSXWriter - Escritura incremental de libros en una hoja XLSX. La hoja es
+// la última entrada del zip, así que las filas se envían según se escriben.
+type XLSXWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+// NewXLSXWriter - Crea el escritor y escribe el encabezado
+func NewXLSXWriter(w io.Writer) (*XLSXWriter, error) {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+	create := func(name string) (io.Writer, error) {
+		return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbookXML},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+
+	writer := &XLSXWriter{zw: zw, sheet: sheet}
+	if err := writer.writeRow(ExportColumns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write - Escribe un libro
+func (xw *XLSXWriter) Write(book models.Book) error {
+	return xw.writeRow(BookRecord(book))
+}
+
+func (xw *XLSXWriter) writeRow(values []string) error {
+	xw.row++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, xw.row)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, columnName(i), xw.row)
+		if err := xml.EscapeText(&b, []byte(value)); err != nil {
+			return err
+		}
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+
+	_, err := io.WriteString(xw.sheet, b.String())
+	return err
+}
+
+// Flush - Envía al destino lo escrito hasta ahora
+func (xw *XLSXWriter) Flush() error {
+	return xw.zw.Flush()
+}
+
+// Close - Cierra la hoja y el archivo zip
+func (xw *XLSXWriter) Close() error {
+	if _, err := io.WriteString(xw.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return xw.zw.Close()
+}
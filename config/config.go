@@ -0,0 +1,438 @@
+// Package config - Configuración tipada de la API. Cada ajuste tiene un valor
+// por defecto y se puede cambiar desde un archivo (YAML o TOML), una variable
+// de entorno o un flag, en ese orden de precedencia creciente
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"library-api/auth"
+	"library-api/backup"
+	"library-api/logging"
+	"library-api/middleware"
+	"library-api/oai"
+	"library-api/ratelimit"
+	"library-api/server"
+	"library-api/services"
+	"library-api/storage"
+)
+
+// Modos de ejecución (los de Gin)
+const (
+	ModeDebug   = "debug"
+	ModeRelease = "release"
+	ModeTest    = "test"
+)
+
+// Acceso a /metrics
+const (
+	// MetricsAuto - off con server.mode release, public en otro caso
+	MetricsAuto   = "auto"
+	MetricsPublic = "public"
+	// MetricsAdmin - Con el token de un administrador
+	MetricsAdmin = "admin"
+	MetricsOff   = "off"
+)
+
+// Config - Configuración efectiva de la API
+type Config struct {
+	Server    ServerConfig
+	Log       LogConfig
+	Metrics   MetricsConfig
+	Auth      AuthConfig
+	Storage   StorageConfig
+	Backup    BackupConfig
+	CORS      CORSConfig
+	Loans     LoanConfig
+	RateLimit RateLimitConfig
+	Providers ProvidersConfig
+	Jobs      JobsConfig
+	OAI       OAIConfig
+
+	// File - Archivo de configuración leído ("" si no hubo)
+	File string
+	// sources - Origen de cada ajuste que no tiene su valor por defecto
+	sources map[string]string
+}
+
+// ServerConfig - Servidor HTTP
+type ServerConfig struct {
+	Port string
+	// Mode - Modo de Gin: debug, release o test
+	Mode string
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout - Plazo para escribir la respuesta (0: sin límite; las
+	// exportaciones grandes pueden necesitar más)
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	MaxHeaderBytes  int
+	// MaxBodyBytes - Cuerpo máximo de una petición (la importación de
+	// catálogos tiene su propio límite)
+	MaxBodyBytes int
+
+	// TLSCertFile y TLSKeyFile - TLS opcional (vacíos: HTTP)
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TrustedProxies - Proxies cuyo X-Forwarded-For se cree (vacío: ninguno,
+	// la IP del cliente es la de la conexión)
+	TrustedProxies []string
+}
+
+// AuthConfig - Tokens JWT y usuario admin inicial
+type AuthConfig struct {
+	JWTSecret     string
+	TokenTTL      time.Duration
+	AdminUsername string
+	AdminPassword string
+}
+
+// LogConfig - Logs de la API (log/slog)
+type LogConfig struct {
+	// Level - debug, info, warn o error
+	Level string
+	// Format - text, json o auto (json con server.mode release)
+	Format string
+}
+
+// MetricsConfig - Métricas de Prometheus
+type MetricsConfig struct {
+	// Access - public, admin, off o auto (ver MetricsAccess)
+	Access string
+}
+
+// StorageConfig - Backend de almacenamiento y sus parámetros
+type StorageConfig struct {
+	Type string
+	// Fallback - "memory" permite seguir en memoria si el backend no abre
+	Fallback    string
+	SQLitePath  string
+	PostgresDSN string
+
+	PGMaxOpenConns    int
+	PGMaxIdleConns    int
+	PGConnMaxLifetime time.Duration
+	PGConnMaxIdleTime time.Duration
+
+	// QueryTimeout - Plazo de cada operación en los backends SQL (0: sin límite)
+	QueryTimeout time.Duration
+
+	// MemoryDir - Persistencia del backend en memoria ("": sin persistencia)
+	MemoryDir              string
+	MemoryFsync            string
+	MemorySnapshotInterval time.Duration
+}
+
+// BackupConfig - Respaldos a pedido y programados
+type BackupConfig struct {
+	Dir       string
+	Retention int
+	// Interval - Frecuencia de los respaldos programados (0: ninguno)
+	Interval time.Duration
+}
+
+// CORSConfig - Orígenes que pueden llamar a la API desde el navegador
+type CORSConfig struct {
+	// AllowedOrigins - Exactos o con * en el host o el puerto; "*": todos
+	AllowedOrigins   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge - Caché de los preflight en el navegador
+	MaxAge time.Duration
+}
+
+// LoanConfig - Reglas de los préstamos
+type LoanConfig struct {
+	// MaxActivePerUser - Préstamos sin devolver por usuario (0: sin límite)
+	MaxActivePerUser int
+	// Period - Plazo de devolución (un préstamo activo más antiguo está
+	// vencido)
+	Period time.Duration
+}
+
+// RateLimitConfig - Cuotas de peticiones por cliente
+type RateLimitConfig struct {
+	Enabled bool
+	// Default - Cuota de las rutas sin regla propia ("300/1m")
+	Default string
+	// Routes - Reglas por ruta ("GET /api/external/search=30/1m", "@ip" al
+	// final para contar por IP)
+	Routes []string
+	// APIKeys - Clientes con API key propia ("nombre=key")
+	APIKeys []string
+}
+
+// ProvidersConfig - APIs externas de libros
+type ProvidersConfig struct {
+	GoogleBooksAPIKey    string
+	GoogleBooksURL       string
+	OpenLibraryURL       string
+	OpenLibraryCoversURL string
+	Timeout              time.Duration
+	// CacheTTL - Vigencia de las respuestas guardadas (0: sin caché)
+	CacheTTL time.Duration
+	// CircuitThreshold y CircuitCooldown - Fallos seguidos que dejan de
+	// consultar a un proveedor (0: nunca) y espera antes de volver a probar
+	CircuitThreshold int
+	CircuitCooldown  time.Duration
+}
+
+// JobsConfig - Trabajos en segundo plano
+type JobsConfig struct {
+	Workers int
+}
+
+// OAIConfig - Proveedor OAI-PMH
+type OAIConfig struct {
+	RepositoryName       string
+	RepositoryIdentifier string
+	BaseURL              string
+	AdminEmail           string
+	PageSize             int
+}
+
+// Default - Configuración sin archivo, variables ni flags
+func Default() *Config {
+	pool := storage.DefaultPostgresConfig
+	providers := services.DefaultProviderConfig
+
+	return &Config{
+		Server: ServerConfig{
+			Port:              "8080",
+			Mode:              ModeDebug,
+			ReadTimeout:       server.DefaultConfig.ReadTimeout,
+			ReadHeaderTimeout: server.DefaultConfig.ReadHeaderTimeout,
+			WriteTimeout:      server.DefaultConfig.WriteTimeout,
+			IdleTimeout:       server.DefaultConfig.IdleTimeout,
+			ShutdownTimeout:   server.DefaultConfig.ShutdownTimeout,
+			MaxHeaderBytes:    server.DefaultConfig.MaxHeaderBytes,
+			MaxBodyBytes:      10 << 20,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logging.FormatAuto,
+		},
+		Metrics: MetricsConfig{
+			Access: MetricsAuto,
+		},
+		Auth: AuthConfig{
+			JWTSecret:     auth.DefaultSecret,
+			TokenTTL:      auth.DefaultTokenTTL,
+			AdminUsername: storage.DefaultAdmin.Username,
+			AdminPassword: storage.DefaultAdmin.Password,
+		},
+		Storage: StorageConfig{
+			Type:                   "sqlite",
+			SQLitePath:             "./data/library.db",
+			PGMaxOpenConns:         pool.MaxOpenConns,
+			PGMaxIdleConns:         pool.MaxIdleConns,
+			PGConnMaxLifetime:      pool.ConnMaxLifetime,
+			PGConnMaxIdleTime:      pool.ConnMaxIdleTime,
+			QueryTimeout:           storage.DefaultQueryTimeout,
+			MemoryFsync:            storage.MemorySyncInterval,
+			MemorySnapshotInterval: storage.DefaultMemorySnapshotInterval,
+		},
+		Backup: BackupConfig{
+			Dir:       "./data/backups",
+			Retention: 7,
+		},
+		CORS: CORSConfig{
+			// El frontend de docker-compose (nginx en el puerto 3000)
+			AllowedOrigins: []string{"http://localhost:3000"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "Accept", "Accept-Language", "X-Requested-With", "X-Request-ID"},
+			ExposedHeaders: []string{"Content-Length", "Content-Range", "Content-Disposition", "Content-Language", "X-Request-ID"},
+			MaxAge:         10 * time.Minute,
+		},
+		Loans: LoanConfig{
+			Period: 14 * 24 * time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: "300/1m",
+			// Las rutas que llaman a Google Books con nuestra clave, y login y
+			// registro por IP contra la fuerza bruta
+			Routes: []string{
+				"GET /api/external/search=30/1m",
+				"GET /api/external/import=30/1m",
+				"POST /api/external/import/bulk=5/1m",
+				"GET /api/books/:id/details=60/1m",
+				"POST /login=10/1m@ip",
+				"POST /register=5/1m@ip",
+				"POST /api/register=5/1m@ip",
+			},
+		},
+		Providers: ProvidersConfig{
+			GoogleBooksURL:       providers.GoogleBooksURL,
+			OpenLibraryURL:       providers.OpenLibraryURL,
+			OpenLibraryCoversURL: providers.OpenLibraryCoversURL,
+			Timeout:              providers.Timeout,
+			CacheTTL:             providers.CacheTTL,
+			CircuitThreshold:     providers.CircuitThreshold,
+			CircuitCooldown:      providers.CircuitCooldown,
+		},
+		Jobs: JobsConfig{
+			Workers: 2,
+		},
+		OAI: OAIConfig{
+			RepositoryName:       "Library Digital API",
+			RepositoryIdentifier: "biblioteca-digital.local",
+			AdminEmail:           "admin@biblioteca-digital.local",
+			PageSize:             100,
+		},
+		sources: make(map[string]string),
+	}
+}
+
+// ==============================================
+// CONFIGURACIÓN DE CADA PAQUETE
+// ==============================================
+
+// HTTPServer - Plazos, límites y TLS del servidor HTTP
+func (c *Config) HTTPServer() server.Config {
+	return server.Config{
+		Addr:              ":" + c.Server.Port,
+		ReadTimeout:       c.Server.ReadTimeout,
+		ReadHeaderTimeout: c.Server.ReadHeaderTimeout,
+		WriteTimeout:      c.Server.WriteTimeout,
+		IdleTimeout:       c.Server.IdleTimeout,
+		MaxHeaderBytes:    c.Server.MaxHeaderBytes,
+		ShutdownTimeout:   c.Server.ShutdownTimeout,
+		TLSCertFile:       c.Server.TLSCertFile,
+		TLSKeyFile:        c.Server.TLSKeyFile,
+	}
+}
+
+// MetricsAccess - Acceso a /metrics: el de metrics.access o, con auto,
+// ninguno en modo release (las métricas revelan rutas, volumen de uso y
+// estado de los proveedores) y público en desarrollo
+func (c *Config) MetricsAccess() string {
+	if c.Metrics.Access != MetricsAuto {
+		return c.Metrics.Access
+	}
+	if c.Server.Mode == ModeRelease {
+		return MetricsOff
+	}
+	return MetricsPublic
+}
+
+// CORSPolicy - Política CORS del middleware
+func (c *Config) CORSPolicy() middleware.CORSConfig {
+	return middleware.CORSConfig{
+		AllowedOrigins:   c.CORS.AllowedOrigins,
+		AllowedHeaders:   c.CORS.AllowedHeaders,
+		ExposedHeaders:   c.CORS.ExposedHeaders,
+		AllowCredentials: c.CORS.AllowCredentials,
+		MaxAge:           c.CORS.MaxAge,
+	}
+}
+
+// RateLimitPolicy - Cuotas del middleware, ya interpretadas
+func (c *Config) RateLimitPolicy() (middleware.RateLimitConfig, error) {
+	var policy middleware.RateLimitConfig
+	var errs []error
+
+	if c.RateLimit.Default != "" && c.RateLimit.Default != "0" {
+		limit, err := ratelimit.ParseLimit(c.RateLimit.Default)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ratelimit.default: %w", err))
+		}
+		policy.Default = limit
+	}
+
+	for _, route := range c.RateLimit.Routes {
+		rule, err := ratelimit.ParseRule(route)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ratelimit.routes: %w", err))
+			continue
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+
+	policy.APIKeys = make(map[string]string, len(c.RateLimit.APIKeys))
+	for _, entry := range c.RateLimit.APIKeys {
+		name, key, ok := strings.Cut(entry, "=")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			// Sin la entrada: es un secreto y no debe acabar en los logs
+			errs = append(errs, fmt.Errorf("ratelimit.api_keys: entries must be name=key"))
+			continue
+		}
+		policy.APIKeys[key] = name
+	}
+
+	return policy, errors.Join(errs...)
+}
+
+// StorageFor - Parámetros para abrir el backend indicado (el configurado o
+// el de respaldo en memoria)
+func (c *Config) StorageFor(storageType string) storage.Config {
+	// QueryTimeout 0 desactiva el plazo; en storage.Config eso es negativo
+	queryTimeout := c.Storage.QueryTimeout
+	if queryTimeout == 0 {
+		queryTimeout = -1
+	}
+
+	return storage.Config{
+		Type:        storageType,
+		SQLitePath:  c.Storage.SQLitePath,
+		PostgresDSN: c.Storage.PostgresDSN,
+		Postgres: storage.PostgresConfig{
+			MaxOpenConns:    c.Storage.PGMaxOpenConns,
+			MaxIdleConns:    c.Storage.PGMaxIdleConns,
+			ConnMaxLifetime: c.Storage.PGConnMaxLifetime,
+			ConnMaxIdleTime: c.Storage.PGConnMaxIdleTime,
+		},
+		QueryTimeout: queryTimeout,
+		Memory: storage.MemoryPersistence{
+			Dir:              c.Storage.MemoryDir,
+			SnapshotInterval: c.Storage.MemorySnapshotInterval,
+			Sync:             c.Storage.MemoryFsync,
+		},
+		Admin: storage.AdminCredentials{
+			Username: c.Auth.AdminUsername,
+			Password: c.Auth.AdminPassword,
+		},
+	}
+}
+
+// BackupFor - Configuración de los respaldos del backend indicado
+func (c *Config) BackupFor(storageType string) backup.Config {
+	return backup.Config{
+		Dir:       c.Backup.Dir,
+		Retention: c.Backup.Retention,
+		Interval:  c.Backup.Interval,
+		Storage:   storageType,
+	}
+}
+
+// ProviderConfig - URLs y plazo de las APIs externas
+func (c *Config) ProviderConfig() services.ProviderConfig {
+	return services.ProviderConfig{
+		GoogleAPIKey:         c.Providers.GoogleBooksAPIKey,
+		GoogleBooksURL:       c.Providers.GoogleBooksURL,
+		OpenLibraryURL:       c.Providers.OpenLibraryURL,
+		OpenLibraryCoversURL: c.Providers.OpenLibraryCoversURL,
+		Timeout:              c.Providers.Timeout,
+		CacheTTL:             c.Providers.CacheTTL,
+		CircuitThreshold:     c.Providers.CircuitThreshold,
+		CircuitCooldown:      c.Providers.CircuitCooldown,
+	}
+}
+
+// OAIProviderConfig - Identificación y paginación del proveedor OAI-PMH
+func (c *Config) OAIProviderConfig() oai.Config {
+	return oai.Config{
+		RepositoryName:       c.OAI.RepositoryName,
+		RepositoryIdentifier: c.OAI.RepositoryIdentifier,
+		BaseURL:              c.OAI.BaseURL,
+		AdminEmail:           c.OAI.AdminEmail,
+		PageSize:             c.OAI.PageSize,
+	}
+}
@@ -0,0 +1,165 @@
+package config_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"library-api/config"
+)
+
+// load - Lee la configuración con los argumentos indicados
+func load(t *testing.T, args ...string) (*config.Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := config.NewLoader(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("Parse(%v): %v", args, err)
+	}
+	return loader.Load()
+}
+
+// writeFile - Archivo de configuración temporal
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	if err := config.Default().Validate(); err != nil {
+		t.Errorf("Validate(Default()): %v", err)
+	}
+}
+
+func TestPrecedenceFileEnvFlags(t *testing.T) {
+	path := writeFile(t, "library.yaml", `
+server:
+  port: 9000
+storage:
+  sqlite_path: /srv/library.db
+  query_timeout: 0
+cors:
+  allowed_origins: [http://localhost:3000, https://kiosk.example.org]
+loans:
+  max_active_per_user: 3
+`)
+	t.Setenv("PORT", "9100")
+	t.Setenv("JOB_WORKERS", "4")
+
+	cfg, err := load(t, "-config", path, "-server.port", "9200")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	checks := []struct{ key, got, want, source string }{
+		{"server.port", cfg.Server.Port, "9200", config.SourceFlag},
+		{"jobs.workers", strconv.Itoa(cfg.Jobs.Workers), "4", config.SourceEnv},
+		{"storage.sqlite_path", cfg.Storage.SQLitePath, "/srv/library.db", config.SourceFile},
+		{"loans.max_active_per_user", strconv.Itoa(cfg.Loans.MaxActivePerUser), "3", config.SourceFile},
+		{"storage.type", cfg.Storage.Type, "sqlite", config.SourceDefault},
+	}
+	for _, c := range checks {
+		if c.got != c.want || cfg.Source(c.key) != c.source {
+			t.Errorf("%s: got %q from %s, want %q from %s", c.key, c.got, cfg.Source(c.key), c.want, c.source)
+		}
+	}
+	if cfg.Storage.QueryTimeout != 0 || cfg.StorageFor("sqlite").QueryTimeout >= 0 {
+		t.Errorf("query_timeout 0 must disable the timeout: got %v", cfg.StorageFor("sqlite").QueryTimeout)
+	}
+	if got := strings.Join(cfg.CORS.AllowedOrigins, " "); got != "http://localhost:3000 https://kiosk.example.org" {
+		t.Errorf("cors.allowed_origins: got %q", got)
+	}
+}
+
+func TestTOMLFile(t *testing.T) {
+	path := writeFile(t, "library.toml", `
+[auth]
+token_ttl = "2h"
+
+[providers]
+open_library_url = "http://openlibrary.internal"
+`)
+	cfg, err := load(t, "-config", path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.TokenTTL != 2*time.Hour || cfg.Providers.OpenLibraryURL != "http://openlibrary.internal" {
+		t.Errorf("got token_ttl %v, open_library_url %q", cfg.Auth.TokenTTL, cfg.Providers.OpenLibraryURL)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	cases := map[string]string{
+		"unknown.yaml": "server:\n  prot: 9000\n",
+		"badint.yaml":  "jobs:\n  workers: many\n",
+		"library.json": "{}",
+	}
+	for name, content := range cases {
+		if _, err := load(t, "-config", writeFile(t, name, content)); err == nil {
+			t.Errorf("Load(%s): expected an error", name)
+		}
+	}
+}
+
+func TestReleaseModeRejectsDefaultSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.Mode = config.ModeRelease
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "auth.jwt_secret") || !strings.Contains(err.Error(), "auth.admin_password") {
+		t.Fatalf("Validate in release mode with defaults: got %v", err)
+	}
+
+	cfg.Auth.JWTSecret = "a-long-random-production-secret"
+	cfg.Auth.AdminPassword = "another-password"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate in release mode with own secrets: %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.Port = "http"
+	cfg.Storage.Type = "postgres"
+	cfg.Jobs.Workers = 0
+	cfg.Providers.GoogleBooksURL = "googleapis"
+	cfg.RateLimit.Routes = []string{"GET /books=lots"}
+	cfg.Server.TrustedProxies = []string{"proxy.local"}
+	cfg.Log.Level = "verbose"
+	cfg.Log.Format = "xml"
+	cfg.Metrics.Access = "private"
+
+	err := cfg.Validate()
+	for _, key := range []string{"server.port", "storage.postgres_dsn", "jobs.workers", "providers.google_books_url", "ratelimit.routes",
+		"server.trusted_proxies", "log.level", "log.format", "metrics.access"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Validate: expected a problem with %s, got %v", key, err)
+		}
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	t.Setenv("JWT_SECRET", "super-secret-value")
+	t.Setenv("DATABASE_URL", "postgres://library:hunter2@db/library")
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	dump := cfg.Dump()
+	for _, secret := range []string{"super-secret-value", "hunter2", "admin123"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("Dump shows the secret %q:\n%s", secret, dump)
+		}
+	}
+	if !strings.Contains(dump, "env JWT_SECRET") || !strings.Contains(dump, "[REDACTED]") {
+		t.Errorf("Dump must show the source of redacted settings:\n%s", dump)
+	}
+}
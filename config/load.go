@@ -0,0 +1,156 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv - Variable de entorno con la ruta del archivo de configuración
+// (el flag -config tiene precedencia)
+const FileEnv = "CONFIG_FILE"
+
+// Loader - Lee la configuración en orden de precedencia: valores por
+// defecto, archivo, variables de entorno y flags
+type Loader struct {
+	flags *flag.FlagSet
+	file  *string
+}
+
+// NewLoader - Registra en flags el flag -config y uno por ajuste, con la
+// clave como nombre (-server.port, -storage.sqlite_path...). Load se llama
+// después de flags.Parse
+func NewLoader(flags *flag.FlagSet) *Loader {
+	loader := &Loader{
+		flags: flags,
+		file:  flags.String("config", "", "Archivo de configuración YAML o TOML (también "+FileEnv+")"),
+	}
+
+	for _, s := range Default().settings() {
+		flags.String(s.key, "", fmt.Sprintf("%s (env %s)", s.description, s.env))
+	}
+
+	return loader
+}
+
+// Load - Configuración efectiva. No la valida: ver Config.Validate
+func (l *Loader) Load() (*Config, error) {
+	config := Default()
+
+	// Archivo: el del flag o el de la variable de entorno
+	path := *l.file
+	if path == "" {
+		path = os.Getenv(FileEnv)
+	}
+	if path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Variables de entorno (vacías equivalen a no definidas)
+	for _, s := range config.settings() {
+		if value := os.Getenv(s.env); value != "" {
+			if err := config.set(s, value, SourceEnv); err != nil {
+				return nil, fmt.Errorf("error in %s: %w", s.env, err)
+			}
+		}
+	}
+
+	// Flags indicados en la línea de comandos
+	var err error
+	l.flags.Visit(func(f *flag.Flag) {
+		s, ok := config.lookup(f.Name)
+		if !ok || err != nil {
+			return
+		}
+		if setErr := config.set(s, f.Value.String(), SourceFlag); setErr != nil {
+			err = fmt.Errorf("error in -%s: %w", f.Name, setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// ==============================================
+// ARCHIVO DE CONFIGURACIÓN
+// ==============================================
+
+// loadFile - Aplica los ajustes de un archivo YAML (.yaml, .yml) o TOML
+// (.toml). Una clave desconocida es un error: suele ser un error de tipeo
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	document := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return fmt.Errorf("unsupported config file %s: use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", document, values)
+
+	// Orden fijo para que el primer error sea siempre el mismo
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, ok := c.lookup(key)
+		if !ok {
+			return fmt.Errorf("unknown setting %q in config file %s", key, path)
+		}
+		if err := c.set(s, values[key], SourceFile); err != nil {
+			return fmt.Errorf("error in config file %s: %w", path, err)
+		}
+	}
+
+	c.File = path
+	return nil
+}
+
+// flatten - Convierte las secciones anidadas en claves sección.nombre. Las
+// listas quedan separadas por comas, como en las variables de entorno
+func flatten(prefix string, document map[string]any, values map[string]string) {
+	for key, value := range document {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(key, v, values)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Orígenes de un ajuste, de menor a mayor precedencia
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// setting - Un ajuste: su clave en el archivo y en los flags (sección.nombre),
+// la variable de entorno equivalente y el campo de Config que modifica
+type setting struct {
+	key         string
+	env         string
+	description string
+	// secret - El volcado de la configuración no muestra su valor
+	secret bool
+	// value - Puntero al campo: *string, *int, *bool, *time.Duration o *[]string
+	value any
+}
+
+// settings - Tabla de todos los ajustes. Las variables de entorno son las que
+// la API leía antes de tener archivo de configuración
+func (c *Config) settings() []setting {
+	return []setting{
+		{"server.port", "PORT", "Puerto HTTP", false, &c.Server.Port},
+		{"server.mode", "GIN_MODE", "Modo de Gin: debug, release o test", false, &c.Server.Mode},
+		{"server.read_timeout", "SERVER_READ_TIMEOUT", "Plazo para leer una petición completa", false, &c.Server.ReadTimeout},
+		{"server.read_header_timeout", "SERVER_READ_HEADER_TIMEOUT", "Plazo para leer las cabeceras", false, &c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", "SERVER_WRITE_TIMEOUT", "Plazo para escribir la respuesta (0: sin límite)", false, &c.Server.WriteTimeout},
+		{"server.idle_timeout", "SERVER_IDLE_TIMEOUT", "Inactividad máxima de una conexión keep-alive", false, &c.Server.IdleTimeout},
+		{"server.shutdown_timeout", "SERVER_SHUTDOWN_TIMEOUT", "Plazo para terminar las peticiones en curso al apagar", false, &c.Server.ShutdownTimeout},
+		{"server.max_header_bytes", "SERVER_MAX_HEADER_BYTES", "Tamaño máximo de las cabeceras", false, &c.Server.MaxHeaderBytes},
+		{"server.max_body_bytes", "SERVER_MAX_BODY_BYTES", "Tamaño máximo del cuerpo de una petición", false, &c.Server.MaxBodyBytes},
+		{"server.tls_cert_file", "TLS_CERT_FILE", "Certificado TLS en PEM (vacío: HTTP)", false, &c.Server.TLSCertFile},
+		{"server.tls_key_file", "TLS_KEY_FILE", "Clave privada TLS en PEM", false, &c.Server.TLSKeyFile},
+		{"server.trusted_proxies", "TRUSTED_PROXIES", "IPs o redes de los proxies cuyo X-Forwarded-For se cree (vacío: ninguno)", false, &c.Server.TrustedProxies},
+
+		{"log.level", "LOG_LEVEL", "Nivel de los logs: debug, info, warn o error", false, &c.Log.Level},
+		{"log.format", "LOG_FORMAT", "Formato de los logs: text, json o auto (json con server.mode release)", false, &c.Log.Format},
+
+		{"metrics.access", "METRICS_ACCESS", "Acceso a /metrics: public, admin (token de administrador), off o auto (off con server.mode release)", false, &c.Metrics.Access},
+
+		{"auth.jwt_secret", "JWT_SECRET", "Secreto de firma de los tokens JWT", true, &c.Auth.JWTSecret},
+		{"auth.token_ttl", "TOKEN_TTL", "Vigencia de los tokens", false, &c.Auth.TokenTTL},
+		{"auth.admin_username", "ADMIN_USERNAME", "Usuario admin que se crea en un almacenamiento vacío", false, &c.Auth.AdminUsername},
+		{"auth.admin_password", "ADMIN_PASSWORD", "Contraseña inicial del usuario admin", true, &c.Auth.AdminPassword},
+
+		{"storage.type", "STORAGE_TYPE", "Backend: sqlite, postgres o memory", false, &c.Storage.Type},
+		{"storage.fallback", "STORAGE_FALLBACK", "memory: seguir en memoria si el backend no abre", false, &c.Storage.Fallback},
+		{"storage.sqlite_path", "DB_PATH", "Archivo de la base de datos SQLite", false, &c.Storage.SQLitePath},
+		{"storage.postgres_dsn", "DATABASE_URL", "Cadena de conexión de PostgreSQL", true, &c.Storage.PostgresDSN},
+		{"storage.pg_max_open_conns", "PG_MAX_OPEN_CONNS", "Conexiones abiertas como máximo", false, &c.Storage.PGMaxOpenConns},
+		{"storage.pg_max_idle_conns", "PG_MAX_IDLE_CONNS", "Conexiones inactivas como máximo", false, &c.Storage.PGMaxIdleConns},
+		{"storage.pg_conn_max_lifetime", "PG_CONN_MAX_LIFETIME", "Vida máxima de una conexión", false, &c.Storage.PGConnMaxLifetime},
+		{"storage.pg_conn_max_idle_time", "PG_CONN_MAX_IDLE_TIME", "Inactividad máxima de una conexión", false, &c.Storage.PGConnMaxIdleTime},
+		{"storage.query_timeout", "QUERY_TIMEOUT", "Plazo de cada consulta SQL (0: sin límite)", false, &c.Storage.QueryTimeout},
+		{"storage.memory_dir", "MEMORY_DATA_DIR", "Persistencia del backend en memoria (vacío: ninguna)", false, &c.Storage.MemoryDir},
+		{"storage.memory_fsync", "MEMORY_FSYNC", "fsync del journal: always, interval o never", false, &c.Storage.MemoryFsync},
+		{"storage.memory_snapshot_interval", "MEMORY_SNAPSHOT_INTERVAL", "Frecuencia de las fotos del backend en memoria", false, &c.Storage.MemorySnapshotInterval},
+
+		{"backup.dir", "BACKUP_DIR", "Directorio de los respaldos", false, &c.Backup.Dir},
+		{"backup.retention", "BACKUP_RETENTION", "Respaldos a conservar (0: todos)", false, &c.Backup.Retention},
+		{"backup.interval", "BACKUP_INTERVAL", "Frecuencia de los respaldos programados (0: ninguno)", false, &c.Backup.Interval},
+
+		{"cors.allowed_origins", "CORS_ALLOWED_ORIGINS", "Orígenes permitidos, separados por comas; admiten * en el host o el puerto (*: todos)", false, &c.CORS.AllowedOrigins},
+		{"cors.allowed_headers", "CORS_ALLOWED_HEADERS", "Cabeceras que el navegador puede enviar", false, &c.CORS.AllowedHeaders},
+		{"cors.exposed_headers", "CORS_EXPOSED_HEADERS", "Cabeceras de la respuesta visibles desde JavaScript", false, &c.CORS.ExposedHeaders},
+		{"cors.allow_credentials", "CORS_ALLOW_CREDENTIALS", "Permitir cookies y autenticación HTTP (no con *)", false, &c.CORS.AllowCredentials},
+		{"cors.max_age", "CORS_MAX_AGE", "Caché de los preflight en el navegador", false, &c.CORS.MaxAge},
+
+		{"loans.max_active_per_user", "LOANS_MAX_ACTIVE_PER_USER", "Préstamos sin devolver por usuario (0: sin límite)", false, &c.Loans.MaxActivePerUser},
+		{"loans.period", "LOANS_PERIOD", "Plazo de devolución; después el préstamo cuenta como vencido", false, &c.Loans.Period},
+
+		{"ratelimit.enabled", "RATE_LIMIT_ENABLED", "Limitar las peticiones de cada cliente", false, &c.RateLimit.Enabled},
+		{"ratelimit.default", "RATE_LIMIT_DEFAULT", "Cuota de las rutas sin regla propia, peticiones/período (0: sin límite)", false, &c.RateLimit.Default},
+		{"ratelimit.routes", "RATE_LIMIT_ROUTES", "Reglas \"MÉTODO /ruta=peticiones/período[@ip]\", separadas por comas", false, &c.RateLimit.Routes},
+		{"ratelimit.api_keys", "RATE_LIMIT_API_KEYS", "Clientes con API key propia (cabecera X-API-Key), \"nombre=key\" separados por comas", true, &c.RateLimit.APIKeys},
+
+		{"providers.google_books_api_key", "GOOGLE_BOOKS_API_KEY", "Clave de la API de Google Books", true, &c.Providers.GoogleBooksAPIKey},
+		{"providers.google_books_url", "GOOGLE_BOOKS_URL", "Endpoint de volúmenes de Google Books", false, &c.Providers.GoogleBooksURL},
+		{"providers.open_library_url", "OPEN_LIBRARY_URL", "Raíz de Open Library", false, &c.Providers.OpenLibraryURL},
+		{"providers.open_library_covers_url", "OPEN_LIBRARY_COVERS_URL", "Raíz del servicio de portadas de Open Library", false, &c.Providers.OpenLibraryCoversURL},
+		{"providers.timeout", "PROVIDER_TIMEOUT", "Plazo de cada petición a un proveedor", false, &c.Providers.Timeout},
+		{"providers.cache_ttl", "PROVIDER_CACHE_TTL", "Vigencia de las respuestas guardadas de los proveedores (0: sin caché)", false, &c.Providers.CacheTTL},
+		{"providers.circuit_threshold", "PROVIDER_CIRCUIT_THRESHOLD", "Fallos seguidos que dejan de consultar a un proveedor (0: nunca)", false, &c.Providers.CircuitThreshold},
+		{"providers.circuit_cooldown", "PROVIDER_CIRCUIT_COOLDOWN", "Espera antes de volver a probar un proveedor que falla", false, &c.Providers.CircuitCooldown},
+
+		{"jobs.workers", "JOB_WORKERS", "Trabajos en segundo plano simultáneos", false, &c.Jobs.Workers},
+
+		{"oai.repository_name", "OAI_REPOSITORY_NAME", "Nombre del repositorio OAI-PMH", false, &c.OAI.RepositoryName},
+		{"oai.repository_id", "OAI_REPOSITORY_ID", "Dominio de los identificadores oai:<dominio>:<id>", false, &c.OAI.RepositoryIdentifier},
+		{"oai.base_url", "OAI_BASE_URL", "URL base del proveedor (vacío: la de la petición)", false, &c.OAI.BaseURL},
+		{"oai.admin_email", "OAI_ADMIN_EMAIL", "Correo del administrador del repositorio", false, &c.OAI.AdminEmail},
+		{"oai.page_size", "OAI_PAGE_SIZE", "Registros por página de OAI-PMH", false, &c.OAI.PageSize},
+	}
+}
+
+// lookup - Ajuste con la clave indicada
+func (c *Config) lookup(key string) (setting, bool) {
+	for _, s := range c.settings() {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// set - Asigna el valor (texto) al campo del ajuste y anota su origen
+func (c *Config) set(s setting, raw, source string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch field := s.value.(type) {
+	case *string:
+		*field = raw
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", s.key, raw)
+		}
+		*field = n
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a boolean", s.key, raw)
+		}
+		*field = b
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a duration (30s, 5m, 24h)", s.key, raw)
+		}
+		*field = d
+	case *[]string:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field = list
+	default:
+		return fmt.Errorf("%s: unsupported setting type %T", s.key, s.value)
+	}
+
+	c.sources[s.key] = source
+	return nil
+}
+
+// format - Valor del ajuste como texto (el formato que acepta set)
+func format(s setting) string {
+	switch field := s.value.(type) {
+	case *string:
+		return *field
+	case *int:
+		return strconv.Itoa(*field)
+	case *bool:
+		return strconv.FormatBool(*field)
+	case *time.Duration:
+		return field.String()
+	case *[]string:
+		return strings.Join(*field, ",")
+	}
+	return fmt.Sprint(s.value)
+}
+
+// Source - Origen del valor efectivo de un ajuste (SourceDefault si nadie lo
+// cambió)
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"library-api/auth"
+	"library-api/logging"
+	"library-api/storage"
+)
+
+// ==============================================
+// VALIDACIÓN
+// ==============================================
+
+// Validate - Comprueba la configuración antes de arrancar. Devuelve todos los
+// problemas juntos; en modo release rechaza el secreto JWT y la contraseña
+// de admin por defecto
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port <= 65535, "server.port: %q is not a valid port", c.Server.Port)
+	check(oneOf(c.Server.Mode, ModeDebug, ModeRelease, ModeTest), "server.mode: %q must be debug, release or test", c.Server.Mode)
+	check(c.Server.ReadTimeout >= 0 && c.Server.ReadHeaderTimeout >= 0 && c.Server.WriteTimeout >= 0 && c.Server.IdleTimeout >= 0,
+		"server.*_timeout: must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+	check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes: must be positive")
+	check(c.Server.MaxBodyBytes > 0, "server.max_body_bytes: must be positive")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "server.tls_cert_file and server.tls_key_file: set both or neither")
+
+	_, err = logging.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level: %q must be debug, info, warn or error", c.Log.Level)
+	check(oneOf(c.Log.Format, logging.FormatAuto, logging.FormatText, logging.FormatJSON), "log.format: %q must be text, json or auto", c.Log.Format)
+	check(oneOf(c.Metrics.Access, MetricsAuto, MetricsPublic, MetricsAdmin, MetricsOff), "metrics.access: %q must be public, admin, off or auto", c.Metrics.Access)
+
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret: must not be empty")
+	check(c.Auth.TokenTTL > 0, "auth.token_ttl: must be positive")
+	check(c.Auth.AdminUsername != "", "auth.admin_username: must not be empty")
+	check(c.Auth.AdminPassword != "", "auth.admin_password: must not be empty")
+	if c.Server.Mode == ModeRelease {
+		check(c.Auth.JWTSecret != auth.DefaultSecret, "auth.jwt_secret: the default secret is not allowed in release mode (set JWT_SECRET)")
+		check(c.Auth.AdminPassword != storage.DefaultAdmin.Password, "auth.admin_password: the default admin password is not allowed in release mode (set ADMIN_PASSWORD)")
+	}
+
+	check(oneOf(c.Storage.Type, storage.Types()...), "storage.type: %q must be one of %v", c.Storage.Type, storage.Types())
+	check(c.Storage.Type != "postgres" || c.Storage.PostgresDSN != "", "storage.postgres_dsn: required with storage.type postgres (set DATABASE_URL)")
+	check(oneOf(c.Storage.Fallback, "", "memory"), "storage.fallback: %q must be empty or memory", c.Storage.Fallback)
+	check(c.Storage.Type != "sqlite" || c.Storage.SQLitePath != "", "storage.sqlite_path: required with storage.type sqlite")
+	check(c.Storage.PGMaxOpenConns >= 0 && c.Storage.PGMaxIdleConns >= 0, "storage.pg_max_*_conns: must not be negative")
+	check(c.Storage.QueryTimeout >= 0, "storage.query_timeout: must not be negative")
+	check(oneOf(c.Storage.MemoryFsync, storage.MemorySyncAlways, storage.MemorySyncInterval, storage.MemorySyncNever),
+		"storage.memory_fsync: %q must be always, interval or never", c.Storage.MemoryFsync)
+	check(c.Storage.MemorySnapshotInterval > 0, "storage.memory_snapshot_interval: must be positive")
+
+	check(c.Backup.Dir != "", "backup.dir: must not be empty")
+	check(c.Backup.Retention >= 0, "backup.retention: must not be negative")
+	check(c.Backup.Interval >= 0, "backup.interval: must not be negative")
+
+	check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins: at least one origin is required (* allows all)")
+	if err := c.CORSPolicy().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("cors: %w", err))
+	}
+	check(c.CORS.MaxAge >= 0, "cors.max_age: must not be negative")
+	check(c.Loans.MaxActivePerUser >= 0, "loans.max_active_per_user: must not be negative")
+	check(c.Loans.Period > 0, "loans.period: must be positive")
+	if _, err := c.RateLimitPolicy(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		check(isIPOrCIDR(proxy), "server.trusted_proxies: %q is not an IP address or CIDR", proxy)
+	}
+
+	check(isHTTPURL(c.Providers.GoogleBooksURL), "providers.google_books_url: %q is not an http(s) URL", c.Providers.GoogleBooksURL)
+	check(isHTTPURL(c.Providers.OpenLibraryURL), "providers.open_library_url: %q is not an http(s) URL", c.Providers.OpenLibraryURL)
+	check(isHTTPURL(c.Providers.OpenLibraryCoversURL), "providers.open_library_covers_url: %q is not an http(s) URL", c.Providers.OpenLibraryCoversURL)
+	check(c.Providers.Timeout > 0, "providers.timeout: must be positive")
+	check(c.Providers.CacheTTL >= 0, "providers.cache_ttl: must not be negative")
+	check(c.Providers.CircuitThreshold >= 0, "providers.circuit_threshold: must not be negative")
+	check(c.Providers.CircuitThreshold == 0 || c.Providers.CircuitCooldown > 0,
+		"providers.circuit_cooldown: must be positive when providers.circuit_threshold is set")
+
+	check(c.Jobs.Workers >= 1, "jobs.workers: at least one worker is required")
+	check(c.OAI.PageSize > 0, "oai.page_size: must be positive")
+	check(c.OAI.BaseURL == "" || isHTTPURL(c.OAI.BaseURL), "oai.base_url: %q is not an http(s) URL", c.OAI.BaseURL)
+
+	return errors.Join(errs...)
+}
+
+// oneOf - value es alguno de los valores permitidos
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// isIPOrCIDR - Una IP ("10.0.0.1") o una red ("10.0.0.0/8")
+func isIPOrCIDR(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(value)
+	return err == nil
+}
+
+// isHTTPURL - URL absoluta http o https
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// ==============================================
+// VOLCADO DE LA CONFIGURACIÓN EFECTIVA
+// ==============================================
+
+// redacted - Lo que muestra el volcado en lugar de un secreto
+const redacted = "[REDACTED]"
+
+// Dump - Configuración efectiva, un ajuste por línea con su origen. Los
+// secretos no se muestran (solo si están vacíos)
+func (c *Config) Dump() string {
+	var b strings.Builder
+	if c.File != "" {
+		fmt.Fprintf(&b, "# config file: %s\n", c.File)
+	}
+
+	for _, s := range c.settings() {
+		value := format(s)
+		if s.secret && value != "" {
+			value = redacted
+		}
+		fmt.Fprintf(&b, "%-34s = %-40q # %s\n", s.key, value, c.describeSource(s))
+	}
+
+	return b.String()
+}
+
+// describeSource - Origen de un ajuste para el volcado
+func (c *Config) describeSource(s setting) string {
+	switch source := c.Source(s.key); source {
+	case SourceEnv:
+		return "env " + s.env
+	case SourceFlag:
+		return "flag -" + s.key
+	default:
+		return source
+	}
+}
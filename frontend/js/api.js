@@ -6,7 +6,9 @@ async function apiRequest(endpoint, options = {}) {
     const token = localStorage.getItem('library_token');
     const defaultHeaders = {
         'Content-Type': 'application/json',
-        'Accept': 'application/json'
+        'Accept': 'application/json',
+        // La interfaz es en español: los errores de validación también
+        'Accept-Language': 'es'
     };
     
     if (token) {
@@ -21,15 +23,29 @@ async function apiRequest(endpoint, options = {}) {
             headers
         });
         
-        // Si es 401 (no autorizado), redirigir a login
-        if (response.status === 401) {
-            logout();
-            return null;
-        }
-        
         // Intentar parsear JSON
-        const contentType = response.headers.get('content-type');
-        if (contentType && contentType.includes('application/json')) {
+        const contentType = response.headers.get('content-type') || '';
+
+        // Errores de la API (problem+json): se decide por el código estable,
+        // nunca por el texto en inglés del servidor
+        if (contentType.includes('application/problem+json')) {
+            const problem = await response.json();
+
+            // Token ausente o vencido: redirigir a login
+            if (problem.code === 'unauthorized') {
+                logout();
+                return null;
+            }
+
+            return {
+                error: problemMessage(problem),
+                code: problem.code,
+                status: problem.status,
+                errors: problem.errors || []
+            };
+        }
+
+        if (contentType.includes('application/json')) {
             return await response.json();
         } else {
             return await response.text();
@@ -42,6 +58,45 @@ async function apiRequest(endpoint, options = {}) {
     }
 }
 
+// Mensajes en español para los códigos de error de la API
+const PROBLEM_MESSAGES = {
+    bad_request: 'La petición no es válida',
+    validation_failed: 'Hay campos con errores',
+    unsupported_format: 'Formato no soportado',
+    invalid_isbn: 'El ISBN no es válido',
+    unauthorized: 'Debes iniciar sesión',
+    invalid_credentials: 'Credenciales incorrectas',
+    forbidden: 'No tienes permisos para esta acción',
+    not_found: 'Recurso no encontrado',
+    book_not_found: 'Libro no encontrado',
+    loan_not_found: 'Préstamo no encontrado o ya devuelto',
+    author_not_found: 'Autor no encontrado',
+    subject_not_found: 'Materia no encontrada',
+    job_not_found: 'Trabajo no encontrado',
+    user_not_found: 'Usuario no encontrado',
+    backup_not_found: 'Respaldo no encontrado',
+    book_unavailable: 'El libro no está disponible',
+    isbn_conflict: 'Ya existe un libro con ese ISBN',
+    username_taken: 'El nombre de usuario ya existe',
+    job_finished: 'El trabajo ya terminó',
+    payload_too_large: 'La petición es demasiado grande',
+    import_failed: 'No se pudo importar el libro',
+    external_service_error: 'Error consultando el servicio externo',
+    service_unavailable: 'Servicio no disponible, inténtalo más tarde',
+    query_timeout: 'La consulta tardó demasiado, inténtalo de nuevo',
+    internal_error: 'Error interno del servidor'
+};
+
+// Mensaje para mostrar de un problema (con el detalle de cada campo inválido,
+// que el servidor ya traduce según Accept-Language)
+function problemMessage(problem) {
+    const message = PROBLEM_MESSAGES[problem.code] || problem.detail || 'Error desconocido';
+    if (problem.errors && problem.errors.length > 0) {
+        return `${message}: ${problem.errors.map(e => e.message).join('; ')}`;
+    }
+    return message;
+}
+
 // Verificar autenticación
 function isAuthenticated() {
     const token = localStorage.getItem('library_token');
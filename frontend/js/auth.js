@@ -7,7 +7,7 @@ async function login(username, password) {
         });
         
         if (!response || !response.token) {
-            throw new Error('Credenciales incorrectas');
+            throw new Error(response?.error || 'Credenciales incorrectas');
         }
         
         // Guardar token y usuario
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"library-api/auth"
 	"library-api/models"
+	"library-api/problem"
 	"library-api/storage"
 	"net/http"
 	"time"
@@ -25,21 +28,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest // ← Ahora solo tiene Username y Password
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Verificar si usuario ya existe
-	existingUser, err := h.store.GetUserByUsername(req.Username)
-	if err == nil && existingUser != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		fail(c, err)
 		return
 	}
 
 	// Hash de la contraseña
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing password"})
+		fail(c, fmt.Errorf("error hashing password: %w", err))
 		return
 	}
 
@@ -52,17 +48,26 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		UpdatedAt: time.Now(),
 	}
 
-	// Guardar usuario en el store
-	createdUser, err := h.store.CreateUser(user)
+	// Verificar que el usuario no existe y guardarlo en la misma transacción
+	var createdUser *models.User
+	err = h.store.WithTx(c.Request.Context(), func(tx storage.Store) error {
+		existingUser, err := tx.GetUserByUsername(c.Request.Context(), req.Username)
+		if err == nil && existingUser != nil {
+			return storage.ErrUserAlreadyExists
+		}
+
+		createdUser, err = tx.CreateUser(c.Request.Context(), user)
+		return err
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user: " + err.Error()})
+		storeError(c, "Error creating user", err)
 		return
 	}
 
 	// Generar token
 	token, err := auth.GenerateToken(createdUser.Username, createdUser.Role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		fail(c, fmt.Errorf("error generating token: %w", err))
 		return
 	}
 
@@ -80,27 +85,31 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
 	// Buscar usuario
-	user, err := h.store.GetUserByUsername(req.Username)
-	if err != nil || user == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	user, err := h.store.GetUserByUsername(c.Request.Context(), req.Username)
+	if errors.Is(err, storage.ErrUserNotFound) || (err == nil && user == nil) {
+		fail(c, storage.ErrInvalidCredentials)
+		return
+	}
+	if err != nil {
+		storeError(c, "Error getting user", err)
 		return
 	}
 
-	// TEMPORAL: Comparar contraseñas SIN bcrypt
-	if user.Password != req.Password {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	// Las contraseñas se guardan como hash bcrypt en todos los backends
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		fail(c, storage.ErrInvalidCredentials)
 		return
 	}
 
 	// Generar token
 	token, err := auth.GenerateToken(user.Username, user.Role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		fail(c, fmt.Errorf("error generating token: %w", err))
 		return
 	}
 
@@ -117,7 +126,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) Me(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		fail(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
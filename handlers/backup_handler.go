@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"library-api/backup"
+	"library-api/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BackupHandler struct {
+	backups *backup.Manager
+}
+
+func NewBackupHandler(backups *backup.Manager) *BackupHandler {
+	return &BackupHandler{backups: backups}
+}
+
+// CreateBackup - Crear un respaldo consistente (?format=sqlite|json; por
+// defecto el nativo del almacenamiento)
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	manifest, err := h.backups.Create(c.Request.Context(), c.Query("format"))
+	if err != nil {
+		storeError(c, "Error creating backup", err)
+		return
+	}
+
+	c.Header("Location", "/admin/backups/"+manifest.Name)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": i18n.T(i18n.FromContext(c), "Backup created"),
+		"backup":  *manifest,
+	})
+}
+
+// GetBackups - Listar los respaldos disponibles, del más reciente al más antiguo
+func (h *BackupHandler) GetBackups(c *gin.Context) {
+	manifests, err := h.backups.List()
+	if err != nil {
+		fail(c, fmt.Errorf("error listing backups: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backups": manifests,
+		"count":   len(manifests),
+	})
+}
+
+// DownloadBackup - Descargar el archivo de un respaldo; el checksum va en
+// la cabecera X-Checksum-SHA256
+func (h *BackupHandler) DownloadBackup(c *gin.Context) {
+	path, manifest, err := h.backups.Get(c.Param("name"))
+	if err != nil {
+		fail(c, fmt.Errorf("error reading backup: %w", err))
+		return
+	}
+
+	contentType := "application/vnd.sqlite3"
+	if manifest.Format == backup.FormatJSON {
+		contentType = "application/json"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("X-Checksum-SHA256", manifest.SHA256)
+	c.FileAttachment(path, manifest.Name)
+}
@@ -1,30 +1,52 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"library-api/i18n"
+	"library-api/mergepatch"
 	"library-api/models"
+	"library-api/problem"
 	"library-api/storage"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
 // Interface para servicio externo
 type ExternalBookService interface {
-	SearchGoogleBooks(query string, maxResults int) ([]models.Book, error)
-	SearchOpenLibrary(query string, limit int) ([]models.Book, error)
-	GetGoogleBook(bookID string) (models.Book, error)
-	GetOpenLibraryBook(bookID string) (models.Book, error)
-	SearchGoogleBooksByISBN(isbn string, maxResults int) ([]models.Book, error)
-	SearchOpenLibraryByISBN(isbn string, limit int) ([]models.Book, error)
+	SearchGoogleBooks(ctx context.Context, query string, maxResults int) ([]models.Book, error)
+	SearchOpenLibrary(ctx context.Context, query string, limit int) ([]models.Book, error)
+	GetGoogleBook(ctx context.Context, bookID string) (models.Book, error)
+	GetOpenLibraryBook(ctx context.Context, bookID string) (models.Book, error)
+	SearchGoogleBooksByISBN(ctx context.Context, isbn string, maxResults int) ([]models.Book, error)
+	SearchOpenLibraryByISBN(ctx context.Context, isbn string, limit int) ([]models.Book, error)
 }
 
 type BookHandler struct {
 	store           storage.Store
 	externalService ExternalBookService
+	loanPolicy      LoanPolicy
+}
+
+// LoanPolicy - Reglas de los préstamos
+type LoanPolicy struct {
+	// MaxActiveLoans - Préstamos sin devolver por usuario (0: sin límite)
+	MaxActiveLoans int
+}
+
+// SetLoanPolicy - Reemplaza las reglas de préstamo (por defecto, sin límite)
+func (h *BookHandler) SetLoanPolicy(policy LoanPolicy) {
+	h.loanPolicy = policy
 }
 
 func NewBookHandler(store storage.Store, externalService ExternalBookService) *BookHandler {
@@ -43,22 +65,35 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 	var req models.CreateBookRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
 	book := models.Book{
 		Title:       req.Title,
 		Author:      req.Author,
+		Authors:     req.Authors,
 		ISBN:        req.ISBN,
 		Published:   req.Published,
 		Genre:       req.Genre,
+		Subjects:    req.Subjects,
 		Description: req.Description,
+		Publisher:   req.Publisher,
+		Language:    req.Language,
+		PageCount:   req.PageCount,
+		Edition:     req.Edition,
+		CoverURL:    req.CoverURL,
+		GoogleID:    req.GoogleID,
+		OLID:        req.OLID,
+		LCCN:        req.LCCN,
+		OCLC:        req.OCLC,
+
+		Translations: req.Translations,
 	}
 
-	createdBook, err := h.store.CreateBook(book)
+	createdBook, err := h.store.CreateBook(c.Request.Context(), book)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating book: " + err.Error()})
+		storeError(c, "Error creating book", err)
 		return
 	}
 
@@ -67,29 +102,32 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 
 // GetBooks - Obtener todos los libros
 func (h *BookHandler) GetBooks(c *gin.Context) {
-	books, err := h.store.GetBooks()
+	books, err := h.store.GetBooks(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting books: " + err.Error()})
+		storeError(c, "Error getting books", err)
 		return
 	}
 
+	localizeBooks(c, books)
 	c.JSON(http.StatusOK, books)
 }
 
-// GetBook - Obtener un libro por ID
+// GetBook - Obtener un libro por ID (?format= o Accept para marcxml, bibtex, ris, csl-json...)
 func (h *BookHandler) GetBook(c *gin.Context) {
 	id := c.Param("id")
 
-	book, err := h.store.GetBookByID(id)
+	book, err := h.store.GetBookByID(c.Request.Context(), id)
 	if err != nil {
-		if err == storage.ErrBookNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting book: " + err.Error()})
-		}
+		storeError(c, "Error getting book", err)
 		return
 	}
 
+	if format := negotiateFormat(c); format != "" && format != "json" {
+		renderBooks(c, []models.Book{*book}, format, "")
+		return
+	}
+
+	localizeBook(c, book)
 	c.JSON(http.StatusOK, *book) // ← DESREFERENCIADO
 }
 
@@ -99,98 +137,287 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 
 	var req models.UpdateBookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	existingBookPtr, err := h.store.GetBookByID(id)
+	// Leer y actualizar en la misma transacción: una modificación simultánea
+	// del libro no se pierde
+	var updatedBook *models.Book
+	err := h.store.WithTx(c.Request.Context(), func(tx storage.Store) error {
+		existingBook, err := tx.GetBookByID(c.Request.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		applyBookUpdate(existingBook, req)
+
+		updatedBook, err = tx.UpdateBook(c.Request.Context(), id, *existingBook)
+		return err
+	})
 	if err != nil {
-		if err == storage.ErrBookNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting book: " + err.Error()})
+		storeError(c, "Error updating book", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, *updatedBook) // ← DESREFERENCIADO
+}
+
+// PatchBook - Actualizar un libro con JSON Merge Patch (RFC 7396): los
+// campos ausentes no cambian y null borra el campo
+func (h *BookHandler) PatchBook(c *gin.Context) {
+	id := c.Param("id")
+
+	if mediaType := c.ContentType(); mediaType != mergepatch.ContentType && mediaType != binding.MIMEJSON {
+		fail(c, problem.Newf(http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType,
+			"Use Content-Type %s or %s", mergepatch.ContentType, binding.MIMEJSON))
+		return
+	}
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	keys, err := mergepatch.Keys(patch)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	var updatedBook *models.Book
+	err = h.store.WithTx(c.Request.Context(), func(tx storage.Store) error {
+		existingBook, err := tx.GetBookByID(c.Request.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		doc, err := patchBookDocument(existingBook.Document(), patch, keys)
+		if err != nil {
+			return err
 		}
+		existingBook.ApplyDocument(doc)
+
+		updatedBook, err = tx.UpdateBook(c.Request.Context(), id, *existingBook)
+		return err
+	})
+	if err != nil {
+		storeError(c, "Error patching book", err)
 		return
 	}
 
-	// Crear copia para modificar
-	existingBook := *existingBookPtr
+	c.JSON(http.StatusOK, *updatedBook)
+}
+
+// patchBookDocument - Aplica el parche al documento del libro y lo valida. Solo
+// se informan los errores de los campos del parche (y los obligatorios): los
+// datos anteriores a una regla nueva no impiden editar otros campos
+func patchBookDocument(doc models.BookDocument, patch []byte, keys map[string]bool) (models.BookDocument, error) {
+	// Un autor o género plano sin lista reemplaza a la lista, como en PUT
+	if keys["author"] && !keys["authors"] {
+		doc.Authors = nil
+	}
+	if keys["genre"] && !keys["subjects"] {
+		doc.Subjects = nil
+	}
+
+	current, err := json.Marshal(doc)
+	if err != nil {
+		return doc, fmt.Errorf("error encoding book: %w", err)
+	}
+	merged, err := mergepatch.Apply(current, patch)
+	if err != nil {
+		return doc, err
+	}
+
+	var patched models.BookDocument
+	if err := json.Unmarshal(merged, &patched); err != nil {
+		return doc, err
+	}
+
+	var validationErrors validator.ValidationErrors
+	if err := binding.Validator.ValidateStruct(&patched); !errors.As(err, &validationErrors) {
+		return patched, err
+	}
+	var relevant validator.ValidationErrors
+	for _, fe := range validationErrors {
+		field, _, _ := strings.Cut(fe.Field(), "[")
+		if keys[field] || strings.HasPrefix(fe.Tag(), "required") {
+			relevant = append(relevant, fe)
+		}
+	}
+	if len(relevant) > 0 {
+		return patched, relevant
+	}
+	return patched, nil
+}
 
+// applyBookUpdate - Copia al libro los campos indicados en la petición
+func applyBookUpdate(book *models.Book, req models.UpdateBookRequest) {
 	if req.Title != "" {
-		existingBook.Title = req.Title
+		book.Title = req.Title
 	}
-	if req.Author != "" {
-		existingBook.Author = req.Author
+	if len(req.Authors) > 0 {
+		book.Authors = req.Authors
+	} else if req.Author != "" {
+		book.Author = req.Author
+		book.Authors = nil
 	}
 	if req.ISBN != "" {
-		existingBook.ISBN = req.ISBN
+		book.ISBN = req.ISBN
 	}
 	if req.Published != 0 {
-		existingBook.Published = req.Published
+		book.Published = req.Published
 	}
-	if req.Genre != "" {
-		existingBook.Genre = req.Genre
+	if len(req.Subjects) > 0 {
+		book.Subjects = req.Subjects
+	} else if req.Genre != "" {
+		book.Genre = req.Genre
+		book.Subjects = nil
 	}
 	if req.Description != "" {
-		existingBook.Description = req.Description
+		book.Description = req.Description
+	}
+	if req.Publisher != "" {
+		book.Publisher = req.Publisher
+	}
+	if req.Language != "" {
+		book.Language = req.Language
+	}
+	if req.PageCount != 0 {
+		book.PageCount = req.PageCount
+	}
+	if req.Edition != "" {
+		book.Edition = req.Edition
+	}
+	if req.CoverURL != "" {
+		book.CoverURL = req.CoverURL
+	}
+	if req.GoogleID != "" {
+		book.GoogleID = req.GoogleID
+	}
+	if req.OLID != "" {
+		book.OLID = req.OLID
+	}
+	if req.LCCN != "" {
+		book.LCCN = req.LCCN
+	}
+	if req.OCLC != "" {
+		book.OCLC = req.OCLC
 	}
 	if req.Available != nil {
-		existingBook.Available = *req.Available
+		book.Available = *req.Available
 	}
-
-	updatedBook, err := h.store.UpdateBook(id, existingBook)
-	if err != nil {
-		if err == storage.ErrBookNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating book: " + err.Error()})
-		}
-		return
+	if req.Translations != nil {
+		book.Translations = req.Translations
 	}
-
-	c.JSON(http.StatusOK, *updatedBook) // ← DESREFERENCIADO
 }
 
 // DeleteBook - Eliminar un libro
 func (h *BookHandler) DeleteBook(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.store.DeleteBook(id); err != nil {
-		if err == storage.ErrBookNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting book: " + err.Error()})
-		}
+	if err := h.store.DeleteBook(c.Request.Context(), id); err != nil {
+		storeError(c, "Error deleting book", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(i18n.FromContext(c), "Book deleted successfully")})
 }
 
-// SearchBooks - Buscar libros en nuestra base
+// SearchBooks - Buscar libros en nuestra base (?format= o Accept para bibtex, ris, csl-json...)
 func (h *BookHandler) SearchBooks(c *gin.Context) {
 	title := c.Query("title")
 	author := c.Query("author")
 	genre := c.Query("genre")
-	availableStr := c.Query("available")
+	available := availableFilter(c)
 
-	var available *bool
-	if availableStr != "" {
-		avail, err := strconv.ParseBool(availableStr)
-		if err == nil {
-			available = &avail
-		}
+	books, err := h.store.SearchBooks(c.Request.Context(), title, author, genre, available)
+	if err != nil {
+		storeError(c, "Error searching books", err)
+		return
 	}
 
-	books, err := h.store.SearchBooks(title, author, genre, available)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching books: " + err.Error()})
+	if format := negotiateFormat(c); format != "" && format != "json" {
+		renderBooks(c, books, format, "")
 		return
 	}
 
+	localizeBooks(c, books)
 	c.JSON(http.StatusOK, books)
 }
 
+// ==============================================
+// AUTORES Y MATERIAS
+// ==============================================
+
+// GetAuthors - Listar autores del catálogo
+func (h *BookHandler) GetAuthors(c *gin.Context) {
+	authors, err := h.store.GetAuthors(c.Request.Context())
+	if err != nil {
+		storeError(c, "Error getting authors", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, authors)
+}
+
+// GetAuthorBooks - Obtener todos los libros de un autor
+func (h *BookHandler) GetAuthorBooks(c *gin.Context) {
+	id := c.Param("id")
+
+	author, err := h.store.GetAuthorByID(c.Request.Context(), id)
+	if err != nil {
+		storeError(c, "Error getting author", err)
+		return
+	}
+
+	books, err := h.store.GetBooksByAuthor(c.Request.Context(), id)
+	if err != nil {
+		storeError(c, "Error getting books", err)
+		return
+	}
+
+	localizeBooks(c, books)
+	c.JSON(http.StatusOK, gin.H{
+		"author": *author,
+		"books":  books,
+	})
+}
+
+// GetSubjects - Listar materias del catálogo
+func (h *BookHandler) GetSubjects(c *gin.Context) {
+	subjects, err := h.store.GetSubjects(c.Request.Context())
+	if err != nil {
+		storeError(c, "Error getting subjects", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subjects)
+}
+
+// GetSubjectBooks - Obtener todos los libros de una materia
+func (h *BookHandler) GetSubjectBooks(c *gin.Context) {
+	id := c.Param("id")
+
+	subject, err := h.store.GetSubjectByID(c.Request.Context(), id)
+	if err != nil {
+		storeError(c, "Error getting subject", err)
+		return
+	}
+
+	books, err := h.store.GetBooksBySubject(c.Request.Context(), id)
+	if err != nil {
+		storeError(c, "Error getting books", err)
+		return
+	}
+
+	localizeBooks(c, books)
+	c.JSON(http.StatusOK, gin.H{
+		"subject": *subject,
+		"books":   books,
+	})
+}
+
 // BorrowBook - Prestar un libro
 func (h *BookHandler) BorrowBook(c *gin.Context) {
 	// SOLUCIÓN: Usar estructura local para evitar problemas de import
@@ -201,7 +428,7 @@ func (h *BookHandler) BorrowBook(c *gin.Context) {
 
 	var req LocalLoanRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
@@ -211,35 +438,58 @@ func (h *BookHandler) BorrowBook(c *gin.Context) {
 		User:   req.User,
 	}
 
-	createdLoan, err := h.store.CreateLoan(loan)
-	if err != nil {
-		if err == storage.ErrBookNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		} else if err == storage.ErrBookNotAvailable {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Book is not available"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error: " + err.Error()})
+	// El límite se comprueba en la misma transacción que crea el préstamo
+	var createdLoan *models.Loan
+	err := h.store.WithTx(c.Request.Context(), func(tx storage.Store) error {
+		if err := h.checkLoanLimit(c.Request.Context(), tx, req.User); err != nil {
+			return err
 		}
+		var err error
+		createdLoan, err = tx.CreateLoan(c.Request.Context(), loan)
+		return err
+	})
+	if err != nil {
+		storeError(c, "Error creating loan", err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, *createdLoan) // ← DESREFERENCIADO
 }
 
+// checkLoanLimit - Error si el usuario ya tiene el máximo de préstamos activos
+func (h *BookHandler) checkLoanLimit(ctx context.Context, store storage.Store, user string) error {
+	if h.loanPolicy.MaxActiveLoans <= 0 {
+		return nil
+	}
+
+	loans, err := store.GetActiveLoans(ctx)
+	if err != nil {
+		return err
+	}
+	active := 0
+	for _, loan := range loans {
+		if loan.User == user {
+			active++
+		}
+	}
+	if active >= h.loanPolicy.MaxActiveLoans {
+		return problem.Newf(http.StatusConflict, problem.CodeLoanLimitReached,
+			"User %s already has %d active loans (limit %d)", user, active, h.loanPolicy.MaxActiveLoans).
+			With("max_active_loans", h.loanPolicy.MaxActiveLoans)
+	}
+	return nil
+}
+
 // ReturnBook - Devolver un libro
 func (h *BookHandler) ReturnBook(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.store.ReturnBook(id); err != nil {
-		if err == storage.ErrLoanNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Loan not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error: " + err.Error()})
-		}
+	if err := h.store.ReturnBook(c.Request.Context(), id); err != nil {
+		storeError(c, "Error returning book", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Book returned successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(i18n.FromContext(c), "Book returned successfully")})
 }
 
 // GetLoans - Obtener todos los préstamos CON información de libros
@@ -248,34 +498,31 @@ func (h *BookHandler) GetLoans(c *gin.Context) {
 	var loans []models.LoanWithBook
 	var err error
 
-	// DEBUG
-	fmt.Printf("GetLoans called with status: %s\n", status)
-
 	// Verificar si el store tiene el nuevo método
 	if storeWithBooks, ok := h.store.(interface {
-		GetLoansWithBooks() ([]models.LoanWithBook, error)
-		GetActiveLoansWithBooks() ([]models.LoanWithBook, error)
+		GetLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error)
+		GetActiveLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error)
 	}); ok {
 		// Usar métodos nuevos que incluyen libros
 		if strings.ToLower(status) == "active" {
-			loans, err = storeWithBooks.GetActiveLoansWithBooks()
+			loans, err = storeWithBooks.GetActiveLoansWithBooks(c.Request.Context())
 		} else {
-			loans, err = storeWithBooks.GetLoansWithBooks()
+			loans, err = storeWithBooks.GetLoansWithBooks(c.Request.Context())
 		}
 
 		if err != nil {
 			// Si hay error, usar fallback
-			fmt.Printf("Error con GetLoansWithBooks: %v, usando fallback\n", err)
-			loans, err = h.getLoansFallback(status)
+			slog.WarnContext(c.Request.Context(), "Error listando préstamos con sus libros, se usa la consulta por separado",
+				"status", status, "error", err)
+			loans, err = h.getLoansFallback(c.Request.Context(), status)
 		}
 	} else {
 		// Fallback a métodos viejos
-		loans, err = h.getLoansFallback(status)
+		loans, err = h.getLoansFallback(c.Request.Context(), status)
 	}
 
 	if err != nil {
-		fmt.Printf("Error final en GetLoans: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting loans: " + err.Error()})
+		storeError(c, "Error getting loans", err)
 		return
 	}
 
@@ -284,19 +531,19 @@ func (h *BookHandler) GetLoans(c *gin.Context) {
 		loans = []models.LoanWithBook{}
 	}
 
-	fmt.Printf("Returning %d loans\n", len(loans))
+	localizeLoans(c, loans)
 	c.JSON(http.StatusOK, loans)
 }
 
 // Método de fallback
-func (h *BookHandler) getLoansFallback(status string) ([]models.LoanWithBook, error) {
+func (h *BookHandler) getLoansFallback(ctx context.Context, status string) ([]models.LoanWithBook, error) {
 	var oldLoans []models.Loan
 	var err error
 
 	if strings.ToLower(status) == "active" {
-		oldLoans, err = h.store.GetActiveLoans()
+		oldLoans, err = h.store.GetActiveLoans(ctx)
 	} else {
-		oldLoans, err = h.store.GetLoans()
+		oldLoans, err = h.store.GetLoans(ctx)
 	}
 
 	if err != nil {
@@ -306,15 +553,12 @@ func (h *BookHandler) getLoansFallback(status string) ([]models.LoanWithBook, er
 	// Convertir a LoanWithBook
 	var loans []models.LoanWithBook
 	for _, loan := range oldLoans {
-		bookPtr, _ := h.store.GetBookByID(loan.BookID)
+		bookPtr, _ := h.store.GetBookByID(ctx, loan.BookID)
 		var book models.Book
 		if bookPtr != nil {
 			book = *bookPtr
 		} else {
-			book = models.Book{
-				ID:    loan.BookID,
-				Title: "Libro no encontrado",
-			}
+			book = storage.DeletedLoanBook(loan)
 		}
 
 		loans = append(loans, models.LoanWithBook{
@@ -326,14 +570,6 @@ func (h *BookHandler) getLoansFallback(status string) ([]models.LoanWithBook, er
 	return loans, nil
 }
 
-// HealthCheck - Verificar estado del API
-func (h *BookHandler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"message": "Library API is running",
-	})
-}
-
 // ==============================================
 // NUEVOS MÉTODOS PARA APIS EXTERNAS
 // ==============================================
@@ -342,7 +578,7 @@ func (h *BookHandler) HealthCheck(c *gin.Context) {
 func (h *BookHandler) SearchExternalBooks(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		fail(c, problem.BadRequest("Query parameter 'q' is required"))
 		return
 	}
 
@@ -358,16 +594,16 @@ func (h *BookHandler) SearchExternalBooks(c *gin.Context) {
 
 	switch source {
 	case "google":
-		books, err = h.externalService.SearchGoogleBooks(query, limit)
+		books, err = h.externalService.SearchGoogleBooks(c.Request.Context(), query, limit)
 	case "openlibrary":
-		books, err = h.externalService.SearchOpenLibrary(query, limit)
+		books, err = h.externalService.SearchOpenLibrary(c.Request.Context(), query, limit)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source. Use 'google' or 'openlibrary'"})
+		fail(c, invalidSource())
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, externalError(source, err))
 		return
 	}
 
@@ -384,10 +620,8 @@ func (h *BookHandler) ImportBookFromExternal(c *gin.Context) {
 	externalID := c.Query("id")
 
 	if source == "" || externalID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Parameters 'source' and 'id' are required",
-			"example": "/api/external/import?source=openlibrary&id=OL1234567M",
-		})
+		fail(c, problem.BadRequest("Parameters 'source' and 'id' are required").
+			With("example", "/api/external/import?source=openlibrary&id=OL1234567M"))
 		return
 	}
 
@@ -396,134 +630,66 @@ func (h *BookHandler) ImportBookFromExternal(c *gin.Context) {
 
 	switch source {
 	case "google":
-		book, err = h.externalService.GetGoogleBook(externalID)
+		book, err = h.externalService.GetGoogleBook(c.Request.Context(), externalID)
 	case "openlibrary":
 		// Open Library usa búsqueda para obtener detalles
-		books, searchErr := h.externalService.SearchOpenLibrary(externalID, 1)
-		if searchErr != nil || len(books) == 0 {
-			err = fmt.Errorf("book not found in Open Library")
+		books, searchErr := h.externalService.SearchOpenLibrary(c.Request.Context(), externalID, 1)
+		if searchErr != nil {
+			err = searchErr
+		} else if len(books) == 0 {
+			err = problem.New(http.StatusNotFound, problem.CodeBookNotFound, "Book not found in Open Library")
 		} else {
 			book = books[0]
 		}
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source. Use 'google' or 'openlibrary'"})
+		fail(c, invalidSource())
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, externalError(source, err))
 		return
 	}
 
-	// Verificar si ya existe en nuestra base por ISBN
-	if book.ISBN != "" {
-		existingBooks, _ := h.store.SearchBooks("", "", "", nil)
-		for _, existing := range existingBooks {
-			if existing.ISBN == book.ISBN {
-				c.JSON(http.StatusConflict, gin.H{
-					"error":         "Book already exists in database",
-					"existing_book": existing,
-				})
-				return
-			}
-		}
-	}
-
-	// Guardar en nuestra base de datos
-	createdBook, err := h.store.CreateBook(book)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving book: " + err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Book imported successfully",
-		"book":    *createdBook, // ← DESREFERENCIADO
-		"source":  source,
+	// Crear o completar el libro existente con el mismo ISBN, buscándolo y
+	// escribiendo en la misma transacción
+	var result models.ImportItemResult
+	err = h.store.WithTx(c.Request.Context(), func(tx storage.Store) error {
+		result = storage.UpsertBook(c.Request.Context(), tx, book, nil)
+		return nil
 	})
-}
-
-// BulkImportBooks - Importar múltiples libros desde búsqueda
-func (h *BookHandler) BulkImportBooks(c *gin.Context) {
-	var req struct {
-		Query  string `json:"query" binding:"required"`
-		Source string `json:"source" binding:"required"`
-		Limit  int    `json:"limit"`
-		Filter string `json:"filter"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if req.Limit <= 0 || req.Limit > 20 {
-		req.Limit = 5
-	}
-
-	var externalBooks []models.Book
-	var err error
-
-	switch req.Source {
-	case "google":
-		externalBooks, err = h.externalService.SearchGoogleBooks(req.Query, req.Limit)
-	case "openlibrary":
-		externalBooks, err = h.externalService.SearchOpenLibrary(req.Query, req.Limit)
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source"})
-		return
-	}
-
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		storeError(c, "Error importing book", err)
 		return
 	}
 
-	// Aplicar filtro si se especificó
-	if req.Filter != "" {
-		filteredBooks := make([]models.Book, 0)
-		for _, book := range externalBooks {
-			if matchesFilter(book, req.Filter) {
-				filteredBooks = append(filteredBooks, book)
-			}
-		}
-		externalBooks = filteredBooks
-	}
-
-	// Importar solo los que no existen
-	imported := make([]models.Book, 0)
-	failed := make([]string, 0)
-
-	for _, book := range externalBooks {
-		// Verificar si ya existe por ISBN
-		exists := false
-		if book.ISBN != "" {
-			existingBooks, _ := h.store.SearchBooks("", "", "", nil)
-			for _, existing := range existingBooks {
-				if existing.ISBN == book.ISBN {
-					exists = true
-					break
-				}
-			}
-		}
-
-		if !exists {
-			createdBook, err := h.store.CreateBook(book)
-			if err != nil {
-				failed = append(failed, fmt.Sprintf("%s: %v", book.Title, err))
-			} else {
-				imported = append(imported, *createdBook) // ← DESREFERENCIADO
-			}
-		}
+	switch result.Status {
+	case models.ImportCreated:
+		c.JSON(http.StatusCreated, gin.H{
+			"message": i18n.T(i18n.FromContext(c), "Book imported successfully"),
+			"status":  result.Status,
+			"book":    result.Book,
+			"source":  source,
+		})
+	case models.ImportUpdated:
+		c.JSON(http.StatusOK, gin.H{
+			"message": i18n.T(i18n.FromContext(c), "Book already existed; missing fields were completed"),
+			"status":  result.Status,
+			"book":    result.Book,
+			"source":  source,
+		})
+	case models.ImportSkipped:
+		c.JSON(http.StatusOK, gin.H{
+			"message": i18n.T(i18n.FromContext(c), "Book already exists in database"),
+			"status":  result.Status,
+			"book":    result.Book,
+			"source":  source,
+		})
+	default:
+		fail(c, problem.Newf(http.StatusUnprocessableEntity, problem.CodeImportFailed, "Error importing book: %s", result.Error).
+			With("status", result.Status).
+			With("isbn", result.ISBN))
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"imported":       len(imported),
-		"already_exists": len(externalBooks) - len(imported) - len(failed),
-		"failed":         len(failed),
-		"imported_books": imported,
-		"failed_books":   failed,
-	})
 }
 
 // GetBookDetails - Obtener detalles extendidos de un libro (combinando fuentes)
@@ -531,16 +697,20 @@ func (h *BookHandler) GetBookDetails(c *gin.Context) {
 	id := c.Param("id")
 
 	// Primero buscar en nuestra base de datos
-	bookPtr, err := h.store.GetBookByID(id)
+	bookPtr, err := h.store.GetBookByID(c.Request.Context(), id)
+	if err != nil && !errors.Is(err, storage.ErrBookNotFound) {
+		storeError(c, "Error getting book", err)
+		return
+	}
 	if err != nil {
 		// Si no está en nuestra base, buscar en APIs externas
 		source := c.Query("source")
 		if source != "" {
 			switch source {
 			case "google":
-				book, err := h.externalService.GetGoogleBook(id)
+				book, err := h.externalService.GetGoogleBook(c.Request.Context(), id)
 				if err != nil {
-					c.JSON(http.StatusNotFound, gin.H{"error": "Book not found in any source"})
+					fail(c, notFoundAnywhere())
 					return
 				}
 				c.JSON(http.StatusOK, gin.H{
@@ -551,7 +721,7 @@ func (h *BookHandler) GetBookDetails(c *gin.Context) {
 				})
 				return
 			case "openlibrary":
-				books, searchErr := h.externalService.SearchOpenLibrary(id, 1)
+				books, searchErr := h.externalService.SearchOpenLibrary(c.Request.Context(), id, 1)
 				if searchErr == nil && len(books) > 0 {
 					c.JSON(http.StatusOK, gin.H{
 						"source":     source,
@@ -563,11 +733,11 @@ func (h *BookHandler) GetBookDetails(c *gin.Context) {
 				}
 			}
 
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found in any source"})
+			fail(c, notFoundAnywhere())
 			return
 		}
 
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		fail(c, err)
 		return
 	}
 
@@ -580,30 +750,14 @@ func (h *BookHandler) GetBookDetails(c *gin.Context) {
 		var enrichedBooks []models.Book
 		switch enrichSource {
 		case "google":
-			enrichedBooks, _ = h.externalService.SearchGoogleBooksByISBN(book.ISBN, 1)
+			enrichedBooks, _ = h.externalService.SearchGoogleBooksByISBN(c.Request.Context(), book.ISBN, 1)
 		case "openlibrary":
-			enrichedBooks, _ = h.externalService.SearchOpenLibraryByISBN(book.ISBN, 1)
+			enrichedBooks, _ = h.externalService.SearchOpenLibraryByISBN(c.Request.Context(), book.ISBN, 1)
 		}
 
 		// Combinar información si encontramos
 		if len(enrichedBooks) > 0 {
-			enrichedBook := enrichedBooks[0]
-
-			if enrichedBook.Title != "" && book.Title == "" {
-				book.Title = enrichedBook.Title
-			}
-			if enrichedBook.Author != "" && book.Author == "" {
-				book.Author = enrichedBook.Author
-			}
-			if enrichedBook.Description != "" && book.Description == "" {
-				book.Description = enrichedBook.Description
-			}
-			if enrichedBook.Published > 0 && book.Published == 0 {
-				book.Published = enrichedBook.Published
-			}
-			if enrichedBook.Genre != "" && book.Genre == "" {
-				book.Genre = enrichedBook.Genre
-			}
+			book.FillMissing(enrichedBooks[0])
 		}
 	}
 
@@ -613,24 +767,3 @@ func (h *BookHandler) GetBookDetails(c *gin.Context) {
 		"book":     book,
 	})
 }
-
-// ==============================================
-// MÉTODOS AUXILIARES
-// ==============================================
-
-func matchesFilter(book models.Book, filter string) bool {
-	switch filter {
-	case "title":
-		return book.Title != ""
-	case "author":
-		return book.Author != ""
-	case "year":
-		return book.Published > 0
-	case "isbn":
-		return book.ISBN != ""
-	case "complete":
-		return book.Title != "" && book.Author != "" && book.ISBN != ""
-	default:
-		return true
-	}
-}
@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"library-api/bookio"
+	"library-api/models"
+	"library-api/problem"
+	"library-api/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize - Tamaño máximo de un archivo de catálogo (20 MB)
+const maxImportSize = 20 << 20
+
+// exportFlushEvery - Filas entre cada envío parcial de la exportación
+const exportFlushEvery = 100
+
+// importBatchSize - Filas válidas por transacción al importar: el bloqueo de
+// escritura se libera entre lotes y un lote que falla no deshace los demás
+const importBatchSize = 100
+
+// ==============================================
+// IMPORTACIÓN Y EXPORTACIÓN DEL CATÁLOGO
+// ==============================================
+
+// ImportBooks - Importar un catálogo desde CSV, XLSX, MARC21 o MARCXML
+// Parámetros (query o formulario): format, mapping (JSON campo → columna), dry_run
+func (h *BookHandler) ImportBooks(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	data, filename, err := readImportFile(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fail(c, problem.New(http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "File too large").Wrap(err))
+			return
+		}
+		fail(c, problem.BadRequest(err.Error()))
+		return
+	}
+
+	var mapping bookio.Mapping
+	if raw := formOrQuery(c, "mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			fail(c, problem.Newf(http.StatusBadRequest, problem.CodeBadRequest, "Invalid mapping: %v", err))
+			return
+		}
+	}
+
+	dryRun, _ := strconv.ParseBool(formOrQuery(c, "dry_run"))
+
+	name := formOrQuery(c, "format")
+	if name == "" {
+		name = bookio.DetectFormat(filename, c.ContentType())
+	}
+
+	format, ok := bookio.LookupFormat(name)
+	if !ok || format.Read == nil {
+		fail(c, unsupportedFormat(name))
+		return
+	}
+
+	rows, err := format.Read(data, mapping)
+	if err != nil {
+		fail(c, problem.Newf(http.StatusBadRequest, problem.CodeImportFailed, "Error reading %s file: %v", format.Name, err))
+		return
+	}
+
+	report, err := h.importRows(c.Request.Context(), rows, dryRun)
+	if err != nil {
+		storeError(c, "Error importing books", err)
+		return
+	}
+	report.Format = format.Name
+
+	if !dryRun {
+		slog.InfoContext(c.Request.Context(), "Catálogo importado", "format", format.Name, "created", report.Created,
+			"updated", report.Updated, "skipped", report.Skipped, "errors", report.Invalid+report.Failed)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// importRows - Valida las filas e importa (o simula importar) las válidas
+func (h *BookHandler) importRows(ctx context.Context, rows []bookio.Row, dryRun bool) (*models.CatalogImportReport, error) {
+	report := &models.CatalogImportReport{
+		DryRun:  dryRun,
+		Rows:    len(rows),
+		Results: make([]models.CatalogImportRow, len(rows)),
+	}
+
+	var books []models.Book
+	var positions []int
+	for i, row := range rows {
+		report.Results[i] = models.CatalogImportRow{
+			Row:   row.Line,
+			ISBN:  row.Book.ISBN,
+			Title: row.Book.Title,
+		}
+
+		if !row.Valid() {
+			report.Results[i].Status = models.ImportInvalid
+			report.Results[i].Errors = row.Errors
+			report.Invalid++
+			continue
+		}
+
+		books = append(books, row.Book)
+		positions = append(positions, i)
+	}
+
+	var results []models.ImportItemResult
+	var err error
+	if dryRun {
+		results, err = storage.PreviewUpsertBooks(ctx, h.store, books)
+	} else {
+		results, err = h.importBatches(ctx, books, report)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for j, result := range results {
+		item := &report.Results[positions[j]]
+		item.Status = result.Status
+		item.ISBN = result.ISBN
+		if result.Book != nil {
+			item.BookID = result.Book.ID
+		}
+		if result.Error != "" {
+			item.Errors = []string{result.Error}
+		}
+
+		switch result.Status {
+		case models.ImportCreated:
+			report.Created++
+		case models.ImportUpdated:
+			report.Updated++
+		case models.ImportSkipped:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+
+	return report, nil
+}
+
+// importBatches - Importa los libros en lotes de importBatchSize, cada uno
+// en su transacción: las filas que fallan se informan una a una y un error
+// del almacenamiento deja sin importar solo su lote, cuyas filas quedan como
+// fallidas. Si no se importa ningún lote devuelve el error.
+func (h *BookHandler) importBatches(ctx context.Context, books []models.Book, report *models.CatalogImportReport) ([]models.ImportItemResult, error) {
+	results := make([]models.ImportItemResult, 0, len(books))
+	var lastErr error
+	for start := 0; start < len(books); start += importBatchSize {
+		batch := books[start:min(start+importBatchSize, len(books))]
+
+		var batchResults []models.ImportItemResult
+		err := h.store.WithTx(ctx, func(tx storage.Store) error {
+			var err error
+			batchResults, err = storage.UpsertBooks(ctx, tx, batch)
+			return err
+		})
+		if err == nil {
+			results = append(results, batchResults...)
+			continue
+		}
+
+		slog.WarnContext(ctx, "Lote de la importación sin guardar", "batch", start/importBatchSize+1, "rows", len(batch), "error", err)
+		report.FailedBatches++
+		lastErr = err
+		for _, book := range batch {
+			results = append(results, models.ImportItemResult{
+				Status: models.ImportFailed,
+				ISBN:   book.ISBN,
+				Title:  book.Title,
+				Error:  "not imported: the batch could not be saved",
+			})
+		}
+	}
+
+	if lastErr != nil && report.FailedBatches == (len(books)+importBatchSize-1)/importBatchSize {
+		return nil, lastErr
+	}
+	return results, nil
+}
+
+// ExportBooks - Exportar el catálogo filtrado en streaming (csv, xlsx, marc, marcxml)
+func (h *BookHandler) ExportBooks(c *gin.Context) {
+	name := c.DefaultQuery("format", "csv")
+	format, ok := bookio.LookupFormat(name)
+	if !ok {
+		fail(c, unsupportedFormat(name))
+		return
+	}
+
+	// El escritor se crea con el primer libro: si la consulta falla antes,
+	// todavía se puede responder con un error JSON
+	var writer bookio.Writer
+	start := func() error {
+		c.Header("Content-Type", format.ContentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="books-%s.%s"`,
+			time.Now().Format("20060102"), format.Extension))
+		c.Status(http.StatusOK)
+
+		var err error
+		writer, err = format.NewWriter(c.Writer)
+		return err
+	}
+
+	count := 0
+	err := h.store.ForEachBook(c.Request.Context(), c.Query("title"), c.Query("author"), c.Query("genre"), availableFilter(c),
+		func(book models.Book) error {
+			if writer == nil {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+
+			if err := writer.Write(book); err != nil {
+				return err
+			}
+
+			count++
+			if count%exportFlushEvery == 0 {
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+				c.Writer.Flush()
+			}
+			return nil
+		})
+
+	if err != nil {
+		if writer == nil {
+			storeError(c, "Error exporting books", err)
+			return
+		}
+		// La respuesta ya empezó: solo se puede cortar la descarga
+		slog.ErrorContext(c.Request.Context(), "Exportación interrumpida", "books", count, "error", err)
+		c.Abort()
+		return
+	}
+
+	if writer == nil {
+		if err := start(); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error iniciando la exportación", "error", err)
+			return
+		}
+	}
+	if err := writer.Close(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error cerrando la exportación", "error", err)
+	}
+}
+
+// ExportSelectedBooks - Bibliografía combinada de una lista de libros
+// (BibTeX por defecto; también ris, csl-json, marc, marcxml, csv...)
+func (h *BookHandler) ExportSelectedBooks(c *gin.Context) {
+	var req models.ExportBooksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, err)
+		return
+	}
+
+	format := req.Format
+	if format == "" {
+		format = negotiateFormat(c)
+	}
+	if format == "" || format == "json" {
+		format = "bibtex"
+	}
+
+	books := make([]models.Book, 0, len(req.IDs))
+	var missing []string
+	for _, id := range req.IDs {
+		book, err := h.store.GetBookByID(c.Request.Context(), id)
+		if err != nil {
+			if err == storage.ErrBookNotFound {
+				missing = append(missing, id)
+				continue
+			}
+			storeError(c, "Error getting book", err)
+			return
+		}
+		books = append(books, *book)
+	}
+
+	if len(missing) > 0 {
+		fail(c, problem.New(http.StatusNotFound, problem.CodeBookNotFound, "Books not found").With("missing", missing))
+		return
+	}
+
+	renderBooks(c, books, format, "bibliography")
+}
+
+// renderBooks - Responder con libros en un formato de intercambio. Con
+// filename se envía como archivo adjunto.
+func renderBooks(c *gin.Context, books []models.Book, name, filename string) {
+	format, ok := bookio.LookupFormat(name)
+	if !ok {
+		fail(c, unsupportedFormat(name))
+		return
+	}
+
+	var buf bytes.Buffer
+	writer, err := format.NewWriter(&buf)
+	for i := 0; err == nil && i < len(books); i++ {
+		err = writer.Write(books[i])
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		fail(c, fmt.Errorf("error rendering books: %w", err))
+		return
+	}
+
+	if filename != "" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format.Extension))
+	}
+
+	// El middleware UTF-8 de main.go ya fijó Content-Type JSON; c.Data no lo reemplaza
+	c.Header("Content-Type", format.ContentType)
+	c.Data(http.StatusOK, format.ContentType, buf.Bytes())
+}
+
+// unsupportedFormat - Formato de intercambio desconocido en ?format=
+func unsupportedFormat(name string) *problem.Problem {
+	return problem.Newf(http.StatusBadRequest, problem.CodeUnsupportedFormat, "Unsupported format: %s", name)
+}
+
+// negotiateFormat - Formato pedido con ?format= o, si no, con la cabecera
+// Accept. Devuelve "" cuando corresponde responder JSON.
+func negotiateFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+
+	type candidate struct {
+		mediaType string
+		q         float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if _, value, found := strings.Cut(params, "q="); found {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		candidates = append(candidates, candidate{strings.TrimSpace(mediaType), q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, candidate := range candidates {
+		if candidate.q <= 0 {
+			continue
+		}
+		switch candidate.mediaType {
+		case "application/json", "*/*", "application/*":
+			return ""
+		}
+		if format, ok := bookio.FormatForMediaType(candidate.mediaType); ok {
+			return format.Name
+		}
+	}
+	return ""
+}
+
+// ==============================================
+// FUNCIONES AUXILIARES
+// ==============================================
+
+// readImportFile - Lee el archivo subido (multipart "file") o el cuerpo completo
+func readImportFile(c *gin.Context) ([]byte, string, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("missing file: %w", err)
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		return data, fileHeader.Filename, err
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) == 0 {
+		return nil, "", errors.New("empty request body")
+	}
+	return data, "", nil
+}
+
+// formOrQuery - Valor de un campo de formulario o, si no existe, de la query
+func formOrQuery(c *gin.Context, key string) string {
+	if value := c.PostForm(key); value != "" {
+		return value
+	}
+	return c.Query(key)
+}
+
+// availableFilter - Filtro opcional ?available=true|false
+func availableFilter(c *gin.Context) *bool {
+	if value := c.Query("available"); value != "" {
+		if avail, err := strconv.ParseBool(value); err == nil {
+			return &avail
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"library-api/problem"
+	"library-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fail - Registra el error y corta la cadena: middleware.ErrorHandler lo
+// responde como problem+json (los Err* conocidos tienen su código estable)
+func fail(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// storeError - Error del almacenamiento con el contexto de la operación para
+// los logs: los Err* conocidos responden con su código (book_not_found...),
+// un plazo vencido con 504 y el resto con 500 y un detalle genérico
+func storeError(c *gin.Context, message string, err error) {
+	fail(c, fmt.Errorf("%s: %w", message, err))
+}
+
+// ==============================================
+// ERRORES DE LAS APIS EXTERNAS
+// ==============================================
+
+// invalidSource - Fuente externa desconocida en ?source=
+func invalidSource() *problem.Problem {
+	return problem.BadRequest("Invalid source. Use 'google' or 'openlibrary'")
+}
+
+// notFoundAnywhere - El libro no está en el catálogo ni en la fuente externa
+func notFoundAnywhere() *problem.Problem {
+	return problem.New(http.StatusNotFound, problem.CodeBookNotFound, "Book not found in any source")
+}
+
+// externalError - Fallo al consultar Open Library o Google Books (502, o 503
+// si su circuito está abierto); los problemas ya armados, como un libro no
+// encontrado, se respetan
+func externalError(source string, err error) error {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	if errors.Is(err, services.ErrProviderUnavailable) {
+		return problem.Newf(http.StatusServiceUnavailable, problem.CodeUnavailable, "%s is temporarily unavailable", source).Wrap(err)
+	}
+	return problem.Newf(http.StatusBadGateway, problem.CodeExternalService, "Error contacting %s", source).Wrap(err)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"library-api/i18n"
+	"library-api/services"
+	"library-api/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProviderCircuits - Servicio externo que informa el estado del circuito de
+// cada proveedor
+type ProviderCircuits interface {
+	CircuitStates() map[string]string
+}
+
+// HealthHandler - Sondas de liveness y readiness
+type HealthHandler struct {
+	store     storage.Store
+	providers ProviderCircuits
+}
+
+func NewHealthHandler(store storage.Store, providers ProviderCircuits) *HealthHandler {
+	return &HealthHandler{store: store, providers: providers}
+}
+
+// readinessTimeout - Plazo de las comprobaciones de /readyz: una sonda que
+// tarda más ya es una respuesta
+const readinessTimeout = 3 * time.Second
+
+// Estado de cada comprobación
+const (
+	checkUp      = "up"
+	checkDown    = "down"
+	checkSkipped = "skipped"
+)
+
+// Liveness - GET /healthz: el proceso responde. No consulta nada más, para
+// que una base de datos caída no haga reiniciar el contenedor
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness - GET /readyz: la base de datos responde y tiene el esquema
+// completo (si no, 503). Un proveedor externo con el circuito abierto deja
+// el estado en "degraded" pero no saca la API de servicio: el catálogo
+// funciona sin él
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	database, migrations := checkUp, checkSkipped
+	if err := h.store.Ping(ctx); err != nil {
+		slog.ErrorContext(ctx, "Readiness: la base de datos no responde", "error", err)
+		database = checkDown
+	} else if err := h.store.CheckSchema(ctx); err != nil {
+		slog.ErrorContext(ctx, "Readiness: esquema incompleto", "error", err)
+		migrations = checkDown
+	} else {
+		migrations = checkUp
+	}
+
+	providers := map[string]string{}
+	degraded := false
+	if h.providers != nil {
+		providers = h.providers.CircuitStates()
+		for _, state := range providers {
+			degraded = degraded || state != services.CircuitClosed
+		}
+	}
+
+	lang := i18n.FromContext(c)
+	status, code, message := "ready", http.StatusOK, i18n.T(lang, "Library API is running")
+	switch {
+	case database != checkUp || migrations != checkUp:
+		status, code, message = "not_ready", http.StatusServiceUnavailable, i18n.T(lang, "Library API is not ready")
+	case degraded:
+		status, message = "degraded", i18n.T(lang, "Some external providers are unavailable")
+	}
+
+	c.JSON(code, gin.H{
+		"status":  status,
+		"message": message,
+		"checks": gin.H{
+			"database":   database,
+			"migrations": migrations,
+			"providers":  providers,
+		},
+	})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"library-api/i18n"
+	"library-api/jobs"
+	"library-api/models"
+	"library-api/problem"
+	"library-api/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	store   storage.Store
+	manager *jobs.Manager
+}
+
+func NewJobHandler(store storage.Store, manager *jobs.Manager) *JobHandler {
+	return &JobHandler{
+		store:   store,
+		manager: manager,
+	}
+}
+
+// BulkImportBooks - Encolar una importación masiva desde APIs externas
+func (h *JobHandler) BulkImportBooks(c *gin.Context) {
+	var req models.BulkImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, err)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	createdBy, _ := userID.(string)
+
+	job, err := h.manager.SubmitBulkImport(c.Request.Context(), models.JobParams{
+		Source: req.Source,
+		Query:  req.Query,
+		ISBNs:  req.ISBNs,
+		Limit:  req.Limit,
+		Filter: req.Filter,
+	}, createdBy)
+	if err != nil {
+		storeError(c, "Error creating job", err)
+		return
+	}
+
+	c.Header("Location", "/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": i18n.T(i18n.FromContext(c), "Import job queued"),
+		"job_id":  job.ID,
+		"job":     *job,
+	})
+}
+
+// GetJobs - Listar trabajos (filtro opcional ?status=). El administrador ve
+// todos; el resto de usuarios, solo los que crearon
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	var statuses []string
+	if status := c.Query("status"); status != "" {
+		statuses = append(statuses, status)
+	}
+
+	jobList, err := h.store.GetJobs(c.Request.Context(), statuses...)
+	if err != nil {
+		storeError(c, "Error getting jobs", err)
+		return
+	}
+
+	visible := make([]models.Job, 0, len(jobList))
+	for _, job := range jobList {
+		if canAccessJob(c, job) {
+			visible = append(visible, job)
+		}
+	}
+
+	c.JSON(http.StatusOK, visible)
+}
+
+// GetJob - Consultar el progreso de un trabajo
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := h.findJob(c)
+	if err != nil {
+		storeError(c, "Error getting job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, *job)
+}
+
+// CancelJob - Cancelar un trabajo pendiente o en ejecución
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	if _, err := h.findJob(c); err != nil {
+		storeError(c, "Error cancelling job", err)
+		return
+	}
+
+	job, err := h.manager.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobFinished) {
+			fail(c, problem.New(http.StatusConflict, problem.CodeJobFinished, "Job already finished").With("job", job))
+		} else {
+			storeError(c, "Error cancelling job", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": i18n.T(i18n.FromContext(c), "Job cancellation requested"),
+		"job":     *job,
+	})
+}
+
+// findJob - Trabajo de :id si el usuario puede verlo; los de otros usuarios
+// responden como inexistentes (404) para no revelar sus IDs
+func (h *JobHandler) findJob(c *gin.Context) (*models.Job, error) {
+	job, err := h.store.GetJobByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return nil, err
+	}
+	if !canAccessJob(c, *job) {
+		return nil, storage.ErrJobNotFound
+	}
+	return job, nil
+}
+
+// canAccessJob - El administrador accede a todos los trabajos; el resto de
+// usuarios, a los que crearon
+func canAccessJob(c *gin.Context, job models.Job) bool {
+	return c.GetString("role") == "admin" || job.CreatedBy == c.GetString("user_id")
+}
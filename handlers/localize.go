@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"library-api/i18n"
+	"library-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// localizeBook - Título y descripción en el idioma pedido con Accept-Language,
+// si el libro tiene esa traducción. Sin la cabecera se responde el original
+func localizeBook(c *gin.Context, book *models.Book) {
+	if c.GetHeader("Accept-Language") != "" {
+		book.Localize(i18n.FromContext(c))
+	}
+}
+
+// localizeBooks - localizeBook para cada libro de la lista
+func localizeBooks(c *gin.Context, books []models.Book) {
+	for i := range books {
+		localizeBook(c, &books[i])
+	}
+}
+
+// localizeLoans - localizeBook para el libro de cada préstamo
+func localizeLoans(c *gin.Context, loans []models.LoanWithBook) {
+	for i := range loans {
+		localizeBook(c, &loans[i].Book)
+	}
+}
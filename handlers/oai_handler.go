@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"library-api/oai"
+	"library-api/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAIHandler struct {
+	provider *oai.Provider
+}
+
+func NewOAIHandler(provider *oai.Provider) *OAIHandler {
+	return &OAIHandler{provider: provider}
+}
+
+// Handle - Punto de acceso OAI-PMH (GET con query o POST con formulario)
+func (h *OAIHandler) Handle(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		fail(c, problem.Newf(http.StatusBadRequest, problem.CodeBadRequest, "Invalid request: %v", err))
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := c.GetHeader("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	requestURL := scheme + "://" + c.Request.Host + c.Request.URL.Path
+
+	resp, err := h.provider.Handle(c.Request.Context(), c.Request.Form, requestURL)
+	if err != nil {
+		storeError(c, "Error processing OAI-PMH request", err)
+		return
+	}
+
+	data, err := xml.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		fail(c, fmt.Errorf("error encoding OAI-PMH response: %w", err))
+		return
+	}
+
+	// Los errores OAI-PMH se informan dentro del documento, siempre con 200
+	c.Header("Content-Type", "text/xml; charset=utf-8")
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", append([]byte(xml.Header), data...))
+}
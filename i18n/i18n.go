@@ -0,0 +1,67 @@
+// Package i18n - Catálogo de mensajes de la API en español e inglés y
+// negociación del idioma con Accept-Language
+package i18n
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// Idiomas soportados; el inglés es el predeterminado (los mensajes del código
+// están en inglés y son la clave del catálogo)
+const (
+	English = "en"
+	Spanish = "es"
+	Default = English
+)
+
+// ContextKey - Clave del idioma negociado en el contexto de Gin
+const ContextKey = "language"
+
+// Supported - Idiomas con catálogo, en orden de preferencia ante un empate
+var Supported = []string{English, Spanish}
+
+// catalogues - Traducciones por idioma: mensaje en inglés → traducción
+var catalogues = map[string]map[string]string{
+	Spanish: spanish,
+}
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Spanish})
+
+// Negotiate - Idioma soportado que mejor atiende la cabecera Accept-Language
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+	tag, _, _ := matcher.Match(tags...)
+	base, _ := tag.Base()
+	if _, ok := catalogues[base.String()]; ok {
+		return base.String()
+	}
+	return Default
+}
+
+// FromContext - Idioma de la petición: el que fijó middleware.Locale o, si
+// no pasó por él, el negociado con su Accept-Language
+func FromContext(c *gin.Context) string {
+	if lang := c.GetString(ContextKey); lang != "" {
+		return lang
+	}
+	return Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// T - Traducción de un mensaje; sin traducción se devuelve el original
+func T(lang, message string) string {
+	if translated, ok := catalogues[lang][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Sprintf - Como fmt.Sprintf con el formato traducido
+func Sprintf(lang, format string, args ...any) string {
+	return fmt.Sprintf(T(lang, format), args...)
+}
@@ -0,0 +1,36 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"library-api/i18n"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          i18n.English,
+		"es":                        i18n.Spanish,
+		"es-MX,es;q=0.9":            i18n.Spanish,
+		"fr-FR,es;q=0.8,en;q=0.5":   i18n.Spanish,
+		"en-US,en;q=0.9,es;q=0.8":   i18n.English,
+		"de":                        i18n.English,
+		"not a language header ;;;": i18n.English,
+	}
+	for header, want := range cases {
+		if got := i18n.Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q): got %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestSprintfFallsBackToEnglish(t *testing.T) {
+	if got := i18n.Sprintf(i18n.Spanish, "Error contacting %s", "Open Library"); got != "Error consultando Open Library" {
+		t.Errorf("Sprintf es: got %q", got)
+	}
+	if got := i18n.Sprintf(i18n.English, "Error contacting %s", "Open Library"); got != "Error contacting Open Library" {
+		t.Errorf("Sprintf en: got %q", got)
+	}
+	if got := i18n.T(i18n.Spanish, "a message without translation"); got != "a message without translation" {
+		t.Errorf("T without translation: got %q", got)
+	}
+}
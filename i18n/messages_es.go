@@ -0,0 +1,107 @@
+package i18n
+
+// spanish - Catálogo en español. La clave es el texto exacto en inglés (o el
+// formato, con sus verbos) que usa el código
+var spanish = map[string]string{
+	// Títulos de los problemas (http.StatusText)
+	"Bad Request":              "Petición incorrecta",
+	"Unauthorized":             "No autorizado",
+	"Forbidden":                "Prohibido",
+	"Not Found":                "No encontrado",
+	"Method Not Allowed":       "Método no permitido",
+	"Conflict":                 "Conflicto",
+	"Request Entity Too Large": "Petición demasiado grande",
+	"Unsupported Media Type":   "Tipo de contenido no soportado",
+	"Unprocessable Entity":     "Entidad no procesable",
+	"Internal Server Error":    "Error interno del servidor",
+	"Bad Gateway":              "Error en el servicio externo",
+	"Service Unavailable":      "Servicio no disponible",
+	"Gateway Timeout":          "Tiempo de espera agotado",
+	"Too Many Requests":        "Demasiadas peticiones",
+
+	// Errores del dominio (problem.sentinels)
+	"Book not found":                         "Libro no encontrado",
+	"Books not found":                        "Libros no encontrados",
+	"Loan not found or already returned":     "Préstamo no encontrado o ya devuelto",
+	"Author not found":                       "Autor no encontrado",
+	"Subject not found":                      "Materia no encontrada",
+	"Job not found":                          "Trabajo no encontrado",
+	"Book is not available":                  "El libro no está disponible",
+	"A book with this ISBN already exists":   "Ya existe un libro con este ISBN",
+	"Username already exists":                "El nombre de usuario ya existe",
+	"Invalid credentials":                    "Credenciales incorrectas",
+	"User not found":                         "Usuario no encontrado",
+	"Job already finished":                   "El trabajo ya terminó",
+	"The job queue is not accepting jobs":    "La cola de trabajos no acepta trabajos nuevos",
+	"The merge patch must be a JSON object":  "El parche debe ser un objeto JSON",
+	"Backup not found":                       "Respaldo no encontrado",
+	"Invalid ISBN":                           "ISBN inválido",
+	"Invalid job parameters":                 "Parámetros del trabajo inválidos",
+	"Unsupported backup format":              "Formato de respaldo no soportado",
+	"Malformed JSON body":                    "El cuerpo JSON está mal formado",
+	"Empty request body":                     "El cuerpo de la petición está vacío",
+	"Field %s must be %s":                    "El campo %s debe ser de tipo %s",
+	"Request body exceeds %d bytes":          "El cuerpo de la petición supera los %d bytes",
+	"The database query timed out":           "La consulta a la base de datos tardó demasiado",
+	"An unexpected error occurred":           "Ocurrió un error inesperado",
+	"Route not found":                        "Ruta no encontrada",
+	"Method not allowed":                     "Método no permitido",
+	"Use Content-Type %s or %s":              "Usa Content-Type %s o %s",
+	"Origin %s is not allowed":               "El origen %s no está permitido",
+	"Too many requests, retry in %d seconds": "Demasiadas peticiones, reintenta en %d segundos",
+
+	// Autenticación
+	"Authorization header required":                      "Falta la cabecera Authorization",
+	"Authorization header format must be Bearer {token}": "La cabecera Authorization debe tener el formato Bearer {token}",
+	"Invalid token":          "Token inválido",
+	"Admin access required":  "Se requiere acceso de administrador",
+	"User not authenticated": "Usuario no autenticado",
+
+	// Libros, préstamos e importación
+	"Book deleted successfully":                           "Libro eliminado correctamente",
+	"Book returned successfully":                          "Libro devuelto correctamente",
+	"User %s already has %d active loans (limit %d)":      "El usuario %s ya tiene %d préstamos activos (límite %d)",
+	"Library API is running":                              "La API de la biblioteca está funcionando",
+	"Library API is not ready":                            "La API de la biblioteca no está lista",
+	"Some external providers are unavailable":             "Algunos proveedores externos no están disponibles",
+	"Book imported successfully":                          "Libro importado correctamente",
+	"Book already existed; missing fields were completed": "El libro ya existía; se completaron los campos faltantes",
+	"Book already exists in database":                     "El libro ya existe en la base de datos",
+	"Error importing book: %s":                            "Error importando el libro: %s",
+	"Query parameter 'q' is required":                     "El parámetro 'q' es obligatorio",
+	"Parameters 'source' and 'id' are required":           "Los parámetros 'source' e 'id' son obligatorios",
+	"Book not found in Open Library":                      "Libro no encontrado en Open Library",
+	"Book not found in any source":                        "Libro no encontrado en ninguna fuente",
+	"Invalid source. Use 'google' or 'openlibrary'":       "Fuente inválida. Usa 'google' u 'openlibrary'",
+	"Error contacting %s":                                 "Error consultando %s",
+	"%s is temporarily unavailable":                       "%s no está disponible por el momento",
+
+	// Catálogo, trabajos, respaldos y OAI-PMH
+	"File too large":             "El archivo es demasiado grande",
+	"Invalid mapping: %v":        "Mapeo de columnas inválido: %v",
+	"Error reading %s file: %v":  "Error leyendo el archivo %s: %v",
+	"Unsupported format: %s":     "Formato no soportado: %s",
+	"Import job queued":          "Trabajo de importación en cola",
+	"Job cancellation requested": "Se solicitó la cancelación del trabajo",
+	"Backup created":             "Respaldo creado",
+	"Invalid request: %v":        "Petición inválida: %v",
+	"Book CRUD + external APIs (Open Library, Google Books)": "CRUD de libros + APIs externas (Open Library, Google Books)",
+
+	// Validación (paquete validation)
+	"The request has invalid fields":                                 "La petición tiene campos inválidos",
+	"%[1]s is required":                                              "%[1]s es obligatorio",
+	"%[1]s is required when %[2]s is missing":                        "%[1]s es obligatorio si falta %[2]s",
+	"%[1]s must be at least %[2]s characters long":                   "%[1]s debe tener al menos %[2]s caracteres",
+	"%[1]s must be at least %[2]s":                                   "%[1]s debe ser como mínimo %[2]s",
+	"%[1]s must have at least %[2]s items":                           "%[1]s debe tener al menos %[2]s elementos",
+	"%[1]s must be at most %[2]s characters long":                    "%[1]s debe tener como máximo %[2]s caracteres",
+	"%[1]s must be at most %[2]s":                                    "%[1]s debe ser como máximo %[2]s",
+	"%[1]s must have at most %[2]s items":                            "%[1]s debe tener como máximo %[2]s elementos",
+	"%[1]s must be one of: %[2]s":                                    "%[1]s debe ser uno de: %[2]s",
+	"%[1]s must be a valid URL":                                      "%[1]s debe ser una URL válida",
+	"%[1]s must be a BCP 47 language tag (es, en, pt-BR...)":         "%[1]s debe ser una etiqueta de idioma BCP 47 (es, en, pt-BR...)",
+	"%[1]s is not a valid ISBN-10 or ISBN-13 (check digit included)": "%[1]s no es un ISBN-10 o ISBN-13 válido (revisa el dígito de control)",
+	"%[1]s must be a year between %[4]d and %[5]d":                   "%[1]s debe ser un año entre %[4]d y %[5]d",
+	"%[1]s contains a genre outside the catalogue vocabulary: %[3]s": "%[1]s contiene un género fuera del vocabulario del catálogo: %[3]s",
+	"%[1]s failed the %[2]s rule":                                    "%[1]s no cumple la regla %[2]s",
+}
@@ -0,0 +1,174 @@
+// Package isbn - Validación, normalización y conversión de ISBN-10 / ISBN-13
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errores de validación
+var (
+	ErrEmpty           = errors.New("isbn is empty")
+	ErrInvalidLength   = errors.New("isbn must have 10 or 13 digits")
+	ErrInvalidChar     = errors.New("isbn contains invalid characters")
+	ErrInvalidChecksum = errors.New("isbn checksum is invalid")
+	ErrNotConvertible  = errors.New("only 978-prefixed ISBN-13 can be converted to ISBN-10")
+)
+
+// Clean - Elimina guiones, espacios y el prefijo "ISBN" sin validar
+func Clean(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "ISBN-13")
+	s = strings.TrimPrefix(s, "ISBN-10")
+	s = strings.TrimPrefix(s, "ISBN")
+	s = strings.TrimLeft(s, ": ")
+
+	var b strings.Builder
+	for _, r := range s {
+		if r == '-' || r == ' ' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Validate - Verifica formato y dígito de control de un ISBN-10 o ISBN-13
+func Validate(s string) error {
+	code := Clean(s)
+	switch len(code) {
+	case 0:
+		return ErrEmpty
+	case 10:
+		return validate10(code)
+	case 13:
+		return validate13(code)
+	default:
+		return ErrInvalidLength
+	}
+}
+
+// IsValid - Indica si la cadena es un ISBN válido
+func IsValid(s string) bool {
+	return Validate(s) == nil
+}
+
+// Normalize - Devuelve la forma canónica (ISBN-13 sin guiones)
+func Normalize(s string) (string, error) {
+	return To13(s)
+}
+
+// To13 - Convierte un ISBN válido a ISBN-13
+func To13(s string) (string, error) {
+	if err := Validate(s); err != nil {
+		return "", err
+	}
+
+	code := Clean(s)
+	if len(code) == 13 {
+		return code, nil
+	}
+
+	base := "978" + code[:9]
+	return base + string(checkDigit13(base)), nil
+}
+
+// To10 - Convierte un ISBN válido a ISBN-10 (solo prefijo 978)
+func To10(s string) (string, error) {
+	if err := Validate(s); err != nil {
+		return "", err
+	}
+
+	code := Clean(s)
+	if len(code) == 10 {
+		return code, nil
+	}
+
+	if !strings.HasPrefix(code, "978") {
+		return "", ErrNotConvertible
+	}
+
+	base := code[3:12]
+	return base + string(checkDigit10(base)), nil
+}
+
+// Equal - Compara dos ISBN sin importar su forma (10/13, guiones)
+func Equal(a, b string) bool {
+	na, errA := Normalize(a)
+	nb, errB := Normalize(b)
+	if errA != nil || errB != nil {
+		return Clean(a) == Clean(b)
+	}
+	return na == nb
+}
+
+// Hyphenate13 - Formato legible 978-XXXXXXXXX-X (sin rangos de grupo/editor)
+func Hyphenate13(s string) (string, error) {
+	code, err := To13(s)
+	if err != nil {
+		return "", err
+	}
+	return code[:3] + "-" + code[3:12] + "-" + code[12:], nil
+}
+
+// ==============================================
+// DÍGITOS DE CONTROL
+// ==============================================
+
+func validate10(code string) error {
+	for i, r := range code {
+		if r >= '0' && r <= '9' {
+			continue
+		}
+		if r == 'X' && i == 9 {
+			continue
+		}
+		return ErrInvalidChar
+	}
+
+	if checkDigit10(code[:9]) != code[9] {
+		return ErrInvalidChecksum
+	}
+	return nil
+}
+
+func validate13(code string) error {
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return ErrInvalidChar
+		}
+	}
+
+	if checkDigit13(code[:12]) != code[12] {
+		return ErrInvalidChecksum
+	}
+	return nil
+}
+
+// checkDigit10 - Dígito de control para los primeros 9 dígitos (módulo 11)
+func checkDigit10(base string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(base[i]-'0') * (10 - i)
+	}
+
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// checkDigit13 - Dígito de control para los primeros 12 dígitos (módulo 10)
+func checkDigit13(base string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(base[i]-'0') * weight
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
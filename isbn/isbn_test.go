@@ -0,0 +1,122 @@
+package isbn_test
+
+import (
+	"errors"
+	"testing"
+
+	"library-api/isbn"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"9780306406157", nil},
+		{"978-0-306-40615-7", nil},
+		{" 978 0 306 40615 7 ", nil},
+		{"ISBN 978-84-376-0457-2", nil},
+		{"ISBN-13: 9788437604572", nil},
+		{"0306406152", nil},
+		{"0-306-40615-2", nil},
+		{"ISBN-10: 0-8044-2957-X", nil},
+		{"080442957x", nil},
+		{"979-10-90636-07-1", nil},
+		{"9780306406158", isbn.ErrInvalidChecksum},
+		{"0306406153", isbn.ErrInvalidChecksum},
+		{"0804429579", isbn.ErrInvalidChecksum},
+		{"979-10-90636-07-2", isbn.ErrInvalidChecksum},
+		{"", isbn.ErrEmpty},
+		{" - ", isbn.ErrEmpty},
+		{"978030640615", isbn.ErrInvalidLength},
+		{"97803064061570", isbn.ErrInvalidLength},
+		{"08044X2957", isbn.ErrInvalidChar},
+		{"978030640615X", isbn.ErrInvalidChar},
+		{"978-0-306-4O615-7", isbn.ErrInvalidChar},
+	}
+
+	for _, tt := range tests {
+		if err := isbn.Validate(tt.input); !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+			t.Errorf("Validate(%q) = %v, want %v", tt.input, err, tt.want)
+		}
+		if valid := isbn.IsValid(tt.input); valid != (tt.want == nil) {
+			t.Errorf("IsValid(%q) = %v", tt.input, valid)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		input      string
+		normalized string
+		isbn10     string
+		hyphenated string
+	}{
+		{"9780306406157", "9780306406157", "0306406152", "978-030640615-7"},
+		{"0-306-40615-2", "9780306406157", "0306406152", "978-030640615-7"},
+		{"0-8044-2957-X", "9780804429573", "080442957X", "978-080442957-3"},
+		{"978-0-8044-2957-3", "9780804429573", "080442957X", "978-080442957-3"},
+		{"ISBN 978-84-376-0457-2", "9788437604572", "8437604575", "978-843760457-2"},
+		// 979: sin forma ISBN-10
+		{"979-10-90636-07-1", "9791090636071", "", "979-109063607-1"},
+	}
+
+	for _, tt := range tests {
+		normalized, err := isbn.Normalize(tt.input)
+		if err != nil || normalized != tt.normalized {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tt.input, normalized, err, tt.normalized)
+		}
+
+		isbn10, err := isbn.To10(tt.input)
+		if tt.isbn10 == "" {
+			if !errors.Is(err, isbn.ErrNotConvertible) {
+				t.Errorf("To10(%q) = %q, %v, want ErrNotConvertible", tt.input, isbn10, err)
+			}
+		} else if err != nil || isbn10 != tt.isbn10 {
+			t.Errorf("To10(%q) = %q, %v, want %q", tt.input, isbn10, err, tt.isbn10)
+		}
+
+		hyphenated, err := isbn.Hyphenate13(tt.input)
+		if err != nil || hyphenated != tt.hyphenated {
+			t.Errorf("Hyphenate13(%q) = %q, %v, want %q", tt.input, hyphenated, err, tt.hyphenated)
+		}
+
+		// Ida y vuelta: cada forma vuelve al mismo ISBN-13
+		for _, form := range []string{tt.isbn10, tt.hyphenated} {
+			if form == "" {
+				continue
+			}
+			if back, err := isbn.Normalize(form); err != nil || back != tt.normalized {
+				t.Errorf("Normalize(%q) = %q, %v, want %q", form, back, err, tt.normalized)
+			}
+			if !isbn.Equal(form, tt.input) {
+				t.Errorf("Equal(%q, %q) = false", form, tt.input)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"9780306406158", "0306406153", "123"} {
+		if _, err := isbn.Normalize(invalid); err == nil {
+			t.Errorf("Normalize(%q): no error", invalid)
+		}
+		if _, err := isbn.To10(invalid); err == nil || errors.Is(err, isbn.ErrNotConvertible) {
+			t.Errorf("To10(%q): got %v, want a validation error", invalid, err)
+		}
+		if _, err := isbn.Hyphenate13(invalid); err == nil {
+			t.Errorf("Hyphenate13(%q): no error", invalid)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !isbn.Equal("0-306-40615-2", "ISBN 978 0 306 40615 7") {
+		t.Error("ISBN-10 and ISBN-13 forms of the same book are not equal")
+	}
+	if isbn.Equal("9780306406157", "9788437604572") {
+		t.Error("different ISBNs are equal")
+	}
+	// Inválidos: se comparan sin guiones ni espacios
+	if !isbn.Equal("123-45", "12345") || isbn.Equal("12345", "12346") {
+		t.Error("invalid ISBNs are not compared by their cleaned form")
+	}
+}
@@ -0,0 +1,220 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"library-api/isbn"
+	"library-api/models"
+	"library-api/storage"
+)
+
+// validateBulkImport - Verifica fuente, consulta y límites
+func validateBulkImport(params models.JobParams) error {
+	if params.Source != "google" && params.Source != "openlibrary" {
+		return storage.InvalidValue(ErrInvalidJobInput, "source", params.Source, "source must be 'google' or 'openlibrary'")
+	}
+	if params.Query == "" && len(params.ISBNs) == 0 {
+		return storage.InvalidValue(ErrInvalidJobInput, "query", params.Query, "'query' or 'isbns' is required")
+	}
+	if len(params.ISBNs) > MaxISBNs {
+		return storage.InvalidValue(ErrInvalidJobInput, "isbns", len(params.ISBNs), fmt.Sprintf("at most %d isbns per job", MaxISBNs))
+	}
+	if params.Limit < 0 || params.Limit > MaxQueryResults {
+		return storage.InvalidValue(ErrInvalidJobInput, "limit", params.Limit, fmt.Sprintf("limit must be between 1 and %d, or 0 for the default of %d", MaxQueryResults, DefaultQueryResults))
+	}
+	return nil
+}
+
+// runBulkImport - Importa los libros de una búsqueda o lista de ISBN,
+// continuando desde job.Processed. La primera ejecución resuelve la lista de
+// elementos y la guarda una sola vez (SaveJobItems): al reanudar se sigue con
+// esa misma lista, aunque la búsqueda externa ya devuelva otros resultados.
+// Los puntos de control guardan solo el progreso.
+func (m *Manager) runBulkImport(ctx context.Context, job *models.Job) error {
+	items, err := m.store.GetJobItems(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	if items == nil {
+		items, err = m.bulkItems(ctx, job.Params)
+		if err != nil {
+			return err
+		}
+		if err := m.store.SaveJobItems(ctx, job.ID, items); err != nil {
+			return err
+		}
+	}
+
+	if job.Total != len(items) {
+		job.Total = len(items)
+		if err := m.checkpoint(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	for i := job.Processed; i < len(items); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result := m.importItem(ctx, items[i], job.Params.Source)
+		if err := ctx.Err(); err != nil {
+			// Interrumpido a medias: el elemento no cuenta y se repite al
+			// reanudar
+			return err
+		}
+		recordResult(job, i, result)
+		job.Processed = i + 1
+
+		if job.Processed%checkpointEvery == 0 {
+			if err := m.checkpoint(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bulkItems - Lista de elementos a procesar según los parámetros
+func (m *Manager) bulkItems(ctx context.Context, params models.JobParams) (models.JobItems, error) {
+	if len(params.ISBNs) > 0 {
+		items := make(models.JobItems, 0, len(params.ISBNs))
+		for _, code := range params.ISBNs {
+			items = append(items, models.JobItem{ISBN: code})
+		}
+		return items, nil
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = DefaultQueryResults
+	}
+
+	var books []models.Book
+	var err error
+
+	switch params.Source {
+	case "google":
+		// Google Books devuelve como máximo 40 resultados por consulta
+		if limit > 40 {
+			limit = 40
+		}
+		books, err = m.external.SearchGoogleBooks(ctx, params.Query, limit)
+	case "openlibrary":
+		books, err = m.external.SearchOpenLibrary(ctx, params.Query, limit)
+	default:
+		return nil, fmt.Errorf("invalid source %q", params.Source)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(models.JobItems, 0, len(books))
+	for i := range books {
+		if matchesFilter(books[i], params.Filter) {
+			items = append(items, models.JobItem{Book: &books[i]})
+		}
+	}
+	return items, nil
+}
+
+// importItem - Obtiene (si hace falta) e importa un elemento
+func (m *Manager) importItem(ctx context.Context, item models.JobItem, source string) models.ImportItemResult {
+	book := item.Book
+	if book == nil {
+		if err := isbn.Validate(item.ISBN); err != nil {
+			return models.ImportItemResult{
+				Status: models.ImportFailed,
+				ISBN:   item.ISBN,
+				Error:  storage.ErrInvalidISBN.Error() + ": " + err.Error(),
+			}
+		}
+
+		var found []models.Book
+		var err error
+
+		switch source {
+		case "google":
+			found, err = m.external.SearchGoogleBooksByISBN(ctx, item.ISBN, 1)
+		default:
+			found, err = m.external.SearchOpenLibraryByISBN(ctx, item.ISBN, 1)
+		}
+
+		if err != nil || len(found) == 0 {
+			message := "book not found in " + source
+			if err != nil {
+				message = err.Error()
+			}
+			return models.ImportItemResult{
+				Status: models.ImportFailed,
+				ISBN:   item.ISBN,
+				Error:  message,
+			}
+		}
+
+		book = &found[0]
+		if book.ISBN == "" {
+			book.ISBN = item.ISBN
+		}
+	}
+
+	// Buscar por ISBN y crear o completar en la misma transacción
+	var result models.ImportItemResult
+	err := m.store.WithTx(ctx, func(tx storage.Store) error {
+		result = storage.UpsertBook(ctx, tx, *book, nil)
+		return nil
+	})
+	if err != nil {
+		return models.ImportItemResult{
+			Status: models.ImportFailed,
+			ISBN:   book.ISBN,
+			Title:  book.Title,
+			Error:  err.Error(),
+		}
+	}
+	return result
+}
+
+// recordResult - Actualiza los contadores del trabajo con un resultado
+func recordResult(job *models.Job, index int, result models.ImportItemResult) {
+	switch result.Status {
+	case models.ImportCreated:
+		job.Created++
+	case models.ImportUpdated:
+		job.Updated++
+	case models.ImportSkipped:
+		job.Skipped++
+	default:
+		job.Failed++
+		if len(job.ItemErrors) < maxItemErrors {
+			job.ItemErrors = append(job.ItemErrors, models.JobItemError{
+				Index: index,
+				ISBN:  result.ISBN,
+				Title: result.Title,
+				Error: result.Error,
+			})
+		}
+	}
+}
+
+// matchesFilter - Filtra los resultados de búsqueda según los campos presentes
+func matchesFilter(book models.Book, filter string) bool {
+	switch filter {
+	case "title":
+		return book.Title != ""
+	case "author":
+		return book.Author != ""
+	case "year":
+		return book.Published > 0
+	case "isbn":
+		return book.ISBN != ""
+	case "complete":
+		return book.Title != "" && book.Author != "" && book.ISBN != ""
+	default:
+		return true
+	}
+}
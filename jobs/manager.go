@@ -0,0 +1,297 @@
+// Package jobs - Ejecución de trabajos en segundo plano con estado persistido
+// en el store (las importaciones masivas sobreviven a un reinicio).
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+// Errores del gestor de trabajos
+var (
+	ErrJobFinished     = storage.ErrJobFinished
+	ErrManagerStopped  = errors.New("job manager is stopped")
+	ErrInvalidJobInput = errors.New("invalid job parameters")
+)
+
+// Límites de una importación masiva
+const (
+	// DefaultQueryResults - Resultados de la búsqueda si no se indica limit
+	DefaultQueryResults = 5
+	MaxQueryResults     = 1000
+	MaxISBNs            = 10000
+	maxItemErrors       = 500
+	checkpointEvery     = 10
+)
+
+// ExternalSearcher - Fuentes externas usadas por las importaciones
+type ExternalSearcher interface {
+	SearchGoogleBooks(ctx context.Context, query string, maxResults int) ([]models.Book, error)
+	SearchOpenLibrary(ctx context.Context, query string, limit int) ([]models.Book, error)
+	SearchGoogleBooksByISBN(ctx context.Context, isbn string, maxResults int) ([]models.Book, error)
+	SearchOpenLibraryByISBN(ctx context.Context, isbn string, limit int) ([]models.Book, error)
+}
+
+// Manager - Pool de workers que procesa los trabajos encolados
+type Manager struct {
+	store    storage.Store
+	external ExternalSearcher
+	workers  int
+
+	queue   chan string
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	started bool
+}
+
+// NewManager - Constructor
+func NewManager(store storage.Store, external ExternalSearcher, workers int) *Manager {
+	if workers <= 0 {
+		workers = 2
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	return &Manager{
+		store:    store,
+		external: external,
+		workers:  workers,
+		queue:    make(chan string, 256),
+		ctx:      ctx,
+		stop:     stop,
+		cancels:  make(map[string]context.CancelFunc),
+	}
+}
+
+// Start - Inicia los workers y reanuda los trabajos pendientes o interrumpidos
+func (m *Manager) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	m.started = true
+	m.mu.Unlock()
+
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+
+	pending, err := m.store.GetJobs(m.ctx, models.JobPending, models.JobRunning)
+	if err != nil {
+		return fmt.Errorf("error loading pending jobs: %w", err)
+	}
+
+	for _, job := range pending {
+		slog.Info("Reanudando trabajo", "job_id", job.ID, "processed", job.Processed, "total", job.Total)
+		m.enqueue(job.ID)
+	}
+
+	return nil
+}
+
+// Stop - Detiene los workers; los trabajos en curso quedan pendientes y se
+// reanudan en el próximo arranque
+func (m *Manager) Stop() {
+	m.stop()
+	m.wg.Wait()
+}
+
+// SubmitBulkImport - Registra una importación masiva y la encola
+func (m *Manager) SubmitBulkImport(ctx context.Context, params models.JobParams, createdBy string) (*models.Job, error) {
+	if err := validateBulkImport(params); err != nil {
+		return nil, err
+	}
+
+	if m.ctx.Err() != nil {
+		return nil, ErrManagerStopped
+	}
+
+	job, err := m.store.CreateJob(ctx, models.Job{
+		Type:       models.JobTypeBulkImport,
+		Status:     models.JobPending,
+		Params:     params,
+		ItemErrors: models.JobItemErrors{},
+		CreatedBy:  createdBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.enqueue(job.ID)
+	return job, nil
+}
+
+// Cancel - Cancela un trabajo pendiente o en ejecución. El store marca la
+// cancelación sin tocar el progreso: un pendiente queda cancelado y uno en
+// ejecución se detiene aquí mismo o, si lo ejecuta otra instancia, en su
+// próximo punto de control
+func (m *Manager) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	job, err := m.store.CancelJob(ctx, id)
+	if err != nil {
+		return job, err
+	}
+
+	m.mu.Lock()
+	if cancel, running := m.cancels[id]; running {
+		cancel()
+	}
+	m.mu.Unlock()
+
+	return job, nil
+}
+
+// ==============================================
+// WORKERS
+// ==============================================
+
+func (m *Manager) enqueue(id string) {
+	go func() {
+		select {
+		case m.queue <- id:
+		case <-m.ctx.Done():
+		}
+	}()
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case id := <-m.queue:
+			m.run(id)
+		}
+	}
+}
+
+// run - Ejecuta un trabajo desde su último punto de control
+func (m *Manager) run(id string) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	m.mu.Lock()
+	m.cancels[id] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.cancels, id)
+		m.mu.Unlock()
+	}()
+
+	job, err := m.start(ctx, id)
+	if errors.Is(err, context.Canceled) {
+		// Cancelado antes de empezar (el store ya lo dejó cancelado) o
+		// apagado del servidor (sigue pendiente y se reanuda)
+		return
+	}
+	if err != nil {
+		slog.Error("No se pudo iniciar el trabajo", "job_id", id, "error", err)
+		return
+	}
+
+	if job.Finished() {
+		return
+	}
+	if job.CancelRequested {
+		m.finish(job, models.JobCancelled, "")
+		return
+	}
+
+	switch job.Type {
+	case models.JobTypeBulkImport:
+		err = m.runBulkImport(ctx, job)
+	default:
+		err = fmt.Errorf("unknown job type %q", job.Type)
+	}
+
+	switch {
+	case err == nil:
+		m.finish(job, models.JobCompleted, "")
+	case errors.Is(err, context.Canceled) && m.ctx.Err() != nil:
+		// Apagado del servidor: dejar el trabajo pendiente para reanudarlo
+		job.Status = models.JobPending
+		if _, err := m.store.UpdateJob(context.Background(), *job); err != nil {
+			slog.Error("No se pudo guardar el trabajo interrumpido", "job_id", id, "error", err)
+		}
+	case errors.Is(err, context.Canceled):
+		m.finish(job, models.JobCancelled, "")
+	default:
+		m.finish(job, models.JobFailed, err.Error())
+	}
+}
+
+// start - Pasa el trabajo a running, salvo que ya haya terminado o tenga
+// una cancelación pedida: lee y escribe en la misma transacción para no
+// pisar un Cancel que llegue entre medias
+func (m *Manager) start(ctx context.Context, id string) (*models.Job, error) {
+	var job *models.Job
+	err := m.store.WithTx(ctx, func(tx storage.Store) error {
+		stored, err := tx.GetJobByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		job = stored
+		if job.Finished() || job.CancelRequested {
+			return nil
+		}
+
+		now := time.Now()
+		job.Status = models.JobRunning
+		if job.StartedAt == nil {
+			job.StartedAt = &now
+		}
+		job, err = tx.UpdateJob(ctx, *job)
+		return err
+	})
+	return job, err
+}
+
+// finish - Marca el trabajo como terminado con el estado indicado. Se guarda
+// aunque el contexto del trabajo ya esté cancelado.
+func (m *Manager) finish(job *models.Job, status, message string) {
+	now := time.Now()
+	job.Status = status
+	job.Error = message
+	job.FinishedAt = &now
+
+	if _, err := m.store.UpdateJob(context.Background(), *job); err != nil {
+		slog.Error("No se pudo finalizar el trabajo", "job_id", job.ID, "error", err)
+		return
+	}
+
+	slog.Info("Trabajo terminado", "job_id", job.ID, "status", status,
+		"created", job.Created, "updated", job.Updated, "skipped", job.Skipped, "failed", job.Failed)
+}
+
+// checkpoint - Guarda el progreso y detecta cancelaciones pedidas en el store
+func (m *Manager) checkpoint(ctx context.Context, job *models.Job) error {
+	stored, err := m.store.GetJobByID(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	if stored.CancelRequested {
+		job.CancelRequested = true
+		return context.Canceled
+	}
+
+	updated, err := m.store.UpdateJob(ctx, *job)
+	if err != nil {
+		return err
+	}
+	*job = *updated
+	return nil
+}
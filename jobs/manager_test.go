@@ -0,0 +1,264 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"library-api/jobs"
+	"library-api/models"
+	"library-api/storage"
+)
+
+// fakeSearcher - Fuente externa de pruebas: la búsqueda devuelve books y la
+// consulta por ISBN los busca entre ellos
+type fakeSearcher struct {
+	mu       sync.Mutex
+	books    []models.Book
+	searches int
+}
+
+func (f *fakeSearcher) SearchGoogleBooks(ctx context.Context, query string, maxResults int) ([]models.Book, error) {
+	return f.SearchOpenLibrary(ctx, query, maxResults)
+}
+
+func (f *fakeSearcher) SearchOpenLibrary(ctx context.Context, query string, limit int) ([]models.Book, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.searches++
+	if limit > len(f.books) {
+		limit = len(f.books)
+	}
+	return append([]models.Book(nil), f.books[:limit]...), nil
+}
+
+func (f *fakeSearcher) SearchGoogleBooksByISBN(ctx context.Context, code string, maxResults int) ([]models.Book, error) {
+	return f.SearchOpenLibraryByISBN(ctx, code, maxResults)
+}
+
+func (f *fakeSearcher) SearchOpenLibraryByISBN(ctx context.Context, code string, limit int) ([]models.Book, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, book := range f.books {
+		if book.ISBN == code {
+			return []models.Book{book}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeSearcher) searchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.searches
+}
+
+// gatedStore - Store cuyas transacciones se detienen, a partir de la número
+// blockAt, hasta que se cancele su contexto; reached se cierra al llegar
+type gatedStore struct {
+	storage.Store
+	blockAt int32
+	calls   atomic.Int32
+	reached chan struct{}
+	once    sync.Once
+}
+
+func newGatedStore(store storage.Store, blockAt int32) *gatedStore {
+	return &gatedStore{Store: store, blockAt: blockAt, reached: make(chan struct{})}
+}
+
+func (s *gatedStore) WithTx(ctx context.Context, fn func(tx storage.Store) error) error {
+	if s.blockAt > 0 && s.calls.Add(1) >= s.blockAt {
+		s.once.Do(func() { close(s.reached) })
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.Store.WithTx(ctx, fn)
+}
+
+// testBooks - n libros con ISBN válidos
+func testBooks(n int) []models.Book {
+	books := make([]models.Book, n)
+	for i := range books {
+		base := fmt.Sprintf("978000000%03d", i)
+		sum := 0
+		for j, digit := range base {
+			weight := 1
+			if j%2 == 1 {
+				weight = 3
+			}
+			sum += int(digit-'0') * weight
+		}
+		books[i] = models.Book{Title: fmt.Sprintf("Libro %d", i), Author: "Autor", ISBN: base + fmt.Sprint((10-sum%10)%10)}
+	}
+	return books
+}
+
+// waitForJob - Espera a que el trabajo cumpla done
+func waitForJob(t *testing.T, store storage.Store, id string, done func(job *models.Job) bool) *models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := store.GetJobByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetJobByID: %v", err)
+		}
+		if done(job) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s: still %s after %d of %d items", id, job.Status, job.Processed, job.Total)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func finished(job *models.Job) bool { return job.Finished() }
+
+// waitForChan - Espera a que se cierre ch
+func waitForChan(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestBulkImportResumesFromSavedItems(t *testing.T) {
+	memory := storage.NewMemoryStore()
+	searcher := &fakeSearcher{books: testBooks(25)}
+
+	// La transacción 1 pasa el trabajo a running; la 1+n importa el elemento n
+	gated := newGatedStore(memory, 1+13)
+	manager := jobs.NewManager(gated, searcher, 1)
+	if err := manager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	job, err := manager.SubmitBulkImport(context.Background(), models.JobParams{Source: "openlibrary", Query: "autor", Limit: 25}, "ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForChan(t, gated.reached, "the 13th item")
+	manager.Stop()
+
+	stopped, err := memory.GetJobByID(context.Background(), job.ID)
+	if err != nil || stopped.Status != models.JobPending || stopped.Processed != 12 || stopped.Total != 25 {
+		t.Fatalf("after Stop: got %+v, %v", stopped, err)
+	}
+	if items, err := memory.GetJobItems(context.Background(), job.ID); err != nil || len(items) != 25 {
+		t.Fatalf("after Stop: got %d items, %v", len(items), err)
+	}
+
+	// La búsqueda ya daría otros resultados: se reanuda con la lista guardada
+	searcher.books = testBooks(30)[10:]
+	manager = jobs.NewManager(memory, searcher, 1)
+	if err := manager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	done := waitForJob(t, memory, job.ID, finished)
+	if done.Status != models.JobCompleted || done.Total != 25 || done.Processed != 25 || done.Created != 25 || done.Failed != 0 {
+		t.Errorf("resumed job: got %+v", done)
+	}
+	if searches := searcher.searchCount(); searches != 1 {
+		t.Errorf("external search ran %d times, want once", searches)
+	}
+	for _, book := range testBooks(25) {
+		if _, err := memory.GetBookByISBN(context.Background(), book.ISBN); err != nil {
+			t.Errorf("book %s: %v", book.ISBN, err)
+		}
+	}
+}
+
+func TestBulkImportISBNList(t *testing.T) {
+	memory := storage.NewMemoryStore()
+	books := testBooks(3)
+	searcher := &fakeSearcher{books: books[:2]}
+	if _, err := memory.CreateBook(context.Background(), books[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := jobs.NewManager(memory, searcher, 1)
+	if err := manager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	// Nuevo, ya en el catálogo, sin resultados en la fuente y no válido
+	isbns := []string{books[0].ISBN, books[1].ISBN, books[2].ISBN, "9780000000001"}
+	job, err := manager.SubmitBulkImport(context.Background(), models.JobParams{Source: "openlibrary", ISBNs: isbns}, "ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := waitForJob(t, memory, job.ID, finished)
+	if done.Status != models.JobCompleted || done.Total != 4 || done.Created != 1 || done.Skipped+done.Updated != 1 || done.Failed != 2 {
+		t.Errorf("ISBN list: got %+v", done)
+	}
+	if len(done.ItemErrors) != 2 || done.ItemErrors[0].Index != 2 || done.ItemErrors[1].Index != 3 {
+		t.Errorf("item errors: got %+v", done.ItemErrors)
+	}
+	if searcher.searchCount() != 0 {
+		t.Errorf("an ISBN list ran %d searches", searcher.searchCount())
+	}
+}
+
+func TestCancelPendingJob(t *testing.T) {
+	memory := storage.NewMemoryStore()
+	searcher := &fakeSearcher{books: testBooks(5)}
+	manager := jobs.NewManager(memory, searcher, 1)
+
+	// Sin arrancar el gestor el trabajo queda pendiente
+	job, err := manager.SubmitBulkImport(context.Background(), models.JobParams{Source: "openlibrary", Query: "autor"}, "ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := manager.Cancel(context.Background(), job.ID)
+	if err != nil || cancelled.Status != models.JobCancelled || !cancelled.CancelRequested || cancelled.FinishedAt == nil {
+		t.Fatalf("Cancel: got %+v, %v", cancelled, err)
+	}
+	if _, err := manager.Cancel(context.Background(), job.ID); !errors.Is(err, jobs.ErrJobFinished) {
+		t.Errorf("second Cancel: got %v, want ErrJobFinished", err)
+	}
+
+	if err := manager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	manager.Stop()
+	if searcher.searchCount() != 0 {
+		t.Errorf("a cancelled job ran %d searches", searcher.searchCount())
+	}
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	memory := storage.NewMemoryStore()
+	searcher := &fakeSearcher{books: testBooks(20)}
+	gated := newGatedStore(memory, 1+5)
+	manager := jobs.NewManager(gated, searcher, 1)
+	if err := manager.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	job, err := manager.SubmitBulkImport(context.Background(), models.JobParams{Source: "openlibrary", Query: "autor", Limit: 20}, "ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForChan(t, gated.reached, "the 5th item")
+
+	requested, err := manager.Cancel(context.Background(), job.ID)
+	if err != nil || !requested.CancelRequested {
+		t.Fatalf("Cancel: got %+v, %v", requested, err)
+	}
+
+	done := waitForJob(t, memory, job.ID, finished)
+	if done.Status != models.JobCancelled || !done.CancelRequested || done.Processed != 4 || done.Created != 4 || done.Total != 20 {
+		t.Errorf("cancelled job: got %+v", done)
+	}
+}
@@ -0,0 +1,103 @@
+// Package logging - Logs estructurados con log/slog: nivel y formato (texto o
+// JSON) configurables y el ID de la petición en todas las líneas que se
+// registran con su contexto (slog.InfoContext(ctx, ...) y similares)
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Formatos de salida
+const (
+	// FormatAuto - JSON con GIN_MODE=release, texto en otro caso
+	FormatAuto = "auto"
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// RequestIDKey - Atributo con el ID de la petición
+const RequestIDKey = "request_id"
+
+// ParseLevel - debug, info, warn o error (sin distinguir mayúsculas)
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: must be debug, info, warn or error", value)
+	}
+	return level, nil
+}
+
+// New - Logger que escribe en w con el nivel y el formato indicados (text o
+// json; auto se resuelve antes, ver Setup)
+func New(w io.Writer, level slog.Leveler, format string) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if format == FormatJSON {
+		handler = slog.NewJSONHandler(w, options)
+	} else {
+		handler = slog.NewTextHandler(w, options)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// Setup - Crea el logger y lo deja como predeterminado de slog y del paquete
+// log (lo que escriban con log otras librerías, como net/http, sale con
+// nivel info y el mismo formato)
+func Setup(w io.Writer, level, format string, release bool) (*slog.Logger, error) {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatAuto {
+		format = FormatText
+		if release {
+			format = FormatJSON
+		}
+	}
+	logger := New(w, parsed, format)
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// ==============================================
+// ID DE LA PETICIÓN
+// ==============================================
+
+type requestIDKey struct{}
+
+// WithRequestID - Contexto que lleva el ID de la petición a los logs
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID - ID de la petición del contexto ("" si no tiene)
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler - Agrega request_id a los registros cuyo contexto lo tiene
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			record.AddAttrs(slog.String(RequestIDKey, id))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,89 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"library-api/logging"
+)
+
+func TestParseLevel(t *testing.T) {
+	for value, want := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		if got, err := logging.ParseLevel(value); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := logging.ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(\"verbose\"): expected an error")
+	}
+}
+
+func TestJSONWithRequestID(t *testing.T) {
+	var out bytes.Buffer
+	logger := logging.New(&out, slog.LevelInfo, logging.FormatJSON)
+
+	ctx := logging.WithRequestID(context.Background(), "req-123")
+	logger.DebugContext(ctx, "no se registra")
+	logger.With("component", "store").WarnContext(ctx, "consulta lenta", "operation", "GetBooks")
+	logger.Info("sin petición")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), out.String())
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	for key, want := range map[string]string{
+		"level":              "WARN",
+		"msg":                "consulta lenta",
+		"component":          "store",
+		"operation":          "GetBooks",
+		logging.RequestIDKey: "req-123",
+	} {
+		if first[key] != want {
+			t.Errorf("%s: got %v, want %q", key, first[key], want)
+		}
+	}
+	if strings.Contains(lines[1], logging.RequestIDKey) {
+		t.Errorf("line without a request context has a request ID: %s", lines[1])
+	}
+}
+
+func TestSetupFormat(t *testing.T) {
+	var out bytes.Buffer
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	if _, err := logging.Setup(&out, "info", logging.FormatAuto, true); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("arranque", "port", "8080")
+	if !json.Valid(bytes.TrimSpace(out.Bytes())) {
+		t.Errorf("release mode: expected JSON, got %s", out.String())
+	}
+
+	out.Reset()
+	if _, err := logging.Setup(&out, "info", logging.FormatAuto, false); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("arranque", "port", "8080")
+	if !strings.Contains(out.String(), `msg=arranque port=8080`) {
+		t.Errorf("debug mode: expected text, got %s", out.String())
+	}
+
+	if _, err := logging.Setup(&out, "loud", logging.FormatText, false); err == nil {
+		t.Error("invalid level: expected an error")
+	}
+}
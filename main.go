@@ -1,73 +1,208 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"library-api/auth"
+	"library-api/backup"
+	"library-api/config"
 	"library-api/handlers"
+	"library-api/i18n"
+	"library-api/jobs"
+	"library-api/logging"
+	"library-api/metrics"
 	"library-api/middleware"
 	"library-api/models"
+	"library-api/oai"
+	"library-api/openapi"
+	"library-api/problem"
+	"library-api/ratelimit"
+	"library-api/server"
 	"library-api/services"
 	"library-api/storage"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
-	"time"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Cargar variables de entorno desde .env
-	if err := godotenv.Load(); err != nil {
-		log.Println("⚠️  No se cargó el archivo .env, usando variables del sistema")
+	migrateISBNs := flag.Bool("migrate-isbns", false, "Normalizar los ISBN existentes a ISBN-13 y salir")
+	createBackup := flag.Bool("backup", false, "Crear un respaldo en BACKUP_DIR y salir")
+	restorePath := flag.String("restore", "", "Restaurar un respaldo (.db o .json con su manifiesto) y salir; con STORAGE_TYPE=memory la API arranca con los datos restaurados")
+	printConfig := flag.Bool("print-config", false, "Mostrar la configuración efectiva (sin secretos), validarla y salir")
+	configLoader := config.NewLoader(flag.CommandLine)
+	flag.Parse()
+
+	// Cargar variables de entorno desde .env (el aviso sale cuando ya está
+	// configurado el logger)
+	envErr := godotenv.Load()
+
+	// Configuración: valores por defecto < archivo < entorno < flags
+	cfg, err := configLoader.Load()
+	if err != nil {
+		fatal("Error leyendo la configuración", "error", err)
+	}
+	if *printConfig {
+		fmt.Print(cfg.Dump())
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal("Configuración inválida", "error", err)
+	}
+	if *printConfig {
+		return
 	}
 
-	_ = time.Now()
+	// Logs estructurados: nivel de log.level, JSON en producción (log.format)
+	logger, err := logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Format, cfg.Server.Mode == config.ModeRelease)
+	if err != nil {
+		fatal("Configuración de logs inválida", "error", err)
+	}
+	if envErr != nil {
+		slog.Info("No se cargó el archivo .env, usando variables del sistema")
+	}
+	slog.Info("Configuración efectiva", "file", cfg.File, "config", cfg.Dump())
 
-	// Configurar desde variables de entorno
-	port := getEnv("PORT", "8080")
-	storageType := getEnv("STORAGE_TYPE", "sqlite") // Cambiado a sqlite por defecto
-	ginMode := getEnv("GIN_MODE", "release")
-	googleAPIKey := getEnv("GOOGLE_BOOKS_API_KEY", "")
+	port := cfg.Server.Port
+	storageType := cfg.Storage.Type
+	ginMode := cfg.Server.Mode
+	googleAPIKey := cfg.Providers.GoogleBooksAPIKey
 
-	// Configurar Gin
+	// Configurar Gin y la firma de los tokens
 	gin.SetMode(ginMode)
+	auth.Configure(cfg.Auth.JWTSecret, cfg.Auth.TokenTTL)
+
+	// Restaurar un respaldo (con la API detenida): una copia .db reemplaza
+	// el archivo de SQLite antes de abrirlo
+	var restoreManifest *backup.Manifest
+	if *restorePath != "" {
+		*restorePath = strings.TrimSuffix(*restorePath, ".manifest.json")
+		restoreManifest = verifyBackup(*restorePath)
+		switch restoreManifest.Format {
+		case backup.FormatSQLite:
+			restoreSQLiteBackup(*restorePath, restoreManifest, storageType, cfg.Storage.SQLitePath)
+			return
+		case backup.FormatJSON:
+		default:
+			fatal("Formato de respaldo desconocido", "format", restoreManifest.Format)
+		}
+	}
 
 	// Crear store según configuración
-	var store storage.Store
-	if storageType == "sqlite" {
-		dbPath := getEnv("DB_PATH", "./data/library.db")
-		sqliteStore, err := storage.NewSQLiteStore(dbPath)
+	store, err := storage.Open(cfg.StorageFor(storageType))
+	if err != nil && restoreManifest != nil {
+		fatal("No se pudo abrir el almacenamiento para restaurar", "storage", storageType, "error", err)
+	}
+	if err != nil {
+		// Pasar a memoria solo si se pidió explícitamente: con STORAGE_FALLBACK=memory
+		// (y sin MEMORY_DATA_DIR) los datos se pierden al reiniciar
+		if cfg.Storage.Fallback != "memory" {
+			fatal("No se pudo inicializar el almacenamiento (STORAGE_FALLBACK=memory permite seguir en memoria)",
+				"storage", storageType, "error", err)
+		}
+		slog.Warn("No se pudo inicializar el almacenamiento", "storage", storageType, "error", err)
+		store, err = storage.Open(cfg.StorageFor("memory"))
 		if err != nil {
-			log.Printf("⚠️  No se pudo inicializar SQLite: %v", err)
-			log.Println("✅ Usando MemoryStore como fallback")
-			store = storage.NewMemoryStore()
-		} else {
-			store = sqliteStore
-			log.Println("✅ Usando SQLiteStore:", dbPath)
+			fatal("No se pudo inicializar el almacenamiento de respaldo en memoria", "error", err)
 		}
+		slog.Warn("STORAGE_FALLBACK=memory: usando MemoryStore", "persistence", memoryPersistenceNote(cfg.Storage.MemoryDir))
+		storageType = "memory"
+	} else if storageType == "memory" {
+		slog.Info("Usando almacenamiento", "storage", storageType, "persistence", memoryPersistenceNote(cfg.Storage.MemoryDir))
 	} else {
-		store = storage.NewMemoryStore()
-		log.Println("✅ Usando MemoryStore")
+		slog.Info("Usando almacenamiento", "storage", storageType)
+	}
+	// Si el servidor falla se sale con error, pero después de los demás defer
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+	// Se cierra al final, después de detener los trabajos y los respaldos
+	defer closeStore(store)
+
+	// Un volcado .json se carga en el store ya abierto; en memoria la API
+	// sigue funcionando con los datos restaurados, si no termina
+	if restoreManifest != nil {
+		restoreDump(context.Background(), store, *restorePath, restoreManifest)
+		if storageType != "memory" {
+			return
+		}
+	}
+
+	// Migración única de ISBN (reporta colisiones sin modificarlas)
+	if *migrateISBNs {
+		runISBNMigration(context.Background(), store)
+		return
 	}
 
+	// Respaldos: a pedido (POST /admin/backup o -backup) y programados
+	backupManager := backup.NewManager(store, cfg.BackupFor(storageType))
+	if *createBackup {
+		if _, err := backupManager.Create(context.Background(), ""); err != nil {
+			fatal("Error creando respaldo", "error", err)
+		}
+		return
+	}
+	backupManager.Start()
+	defer backupManager.Stop()
+
+	// Métricas para /metrics y logs de cada operación. Los handlers y los
+	// trabajos usan el store instrumentado; los respaldos y las migraciones,
+	// el original (sus capacidades opcionales no pasan por Instrument)
+	registry := metrics.NewRegistry()
+	appStore := storage.Instrument(store, storeObserver(registry))
+
 	// Crear servicio externo de libros
-	externalService := services.NewExternalBookService(googleAPIKey)
+	providerConfig := cfg.ProviderConfig()
+	providerConfig.Observer = providerMetrics(registry)
+	externalService := services.NewExternalBookServiceWithConfig(providerConfig)
 	if googleAPIKey == "" {
-		log.Println("⚠️  GOOGLE_BOOKS_API_KEY no configurada, usando solo Open Library (gratuito)")
+		slog.Warn("GOOGLE_BOOKS_API_KEY no configurada, usando solo Open Library (gratuito)")
 	} else {
-		log.Println("✅ Google Books API configurada")
+		slog.Info("Google Books API configurada")
+	}
+
+	// Gestor de trabajos en segundo plano (importaciones masivas)
+	jobManager := jobs.NewManager(appStore, externalService, cfg.Jobs.Workers)
+	if err := jobManager.Start(); err != nil {
+		slog.Warn("Error reanudando trabajos pendientes", "error", err)
 	}
+	defer jobManager.Stop()
 
 	// Inicializar handlers CON el servicio externo
-	bookHandler := handlers.NewBookHandler(store, externalService)
-	authHandler := handlers.NewAuthHandler(store)
+	bookHandler := handlers.NewBookHandler(appStore, externalService)
+	bookHandler.SetLoanPolicy(handlers.LoanPolicy{MaxActiveLoans: cfg.Loans.MaxActivePerUser})
+	authHandler := handlers.NewAuthHandler(appStore)
+	jobHandler := handlers.NewJobHandler(appStore, jobManager)
+	backupHandler := handlers.NewBackupHandler(backupManager)
+	healthHandler := handlers.NewHealthHandler(appStore, externalService)
+	catalogMetrics(registry, appStore, externalService, cfg.Loans.Period)
+
+	// Proveedor OAI-PMH para catálogos colectivos
+	oaiHandler := handlers.NewOAIHandler(oai.NewProvider(appStore, cfg.OAIProviderConfig()))
 
 	// Agregar datos de ejemplo solo si no hay datos
-	if err := addSampleData(store); err != nil {
-		log.Println("⚠️ Warning:", err)
+	if err := addSampleData(context.Background(), store); err != nil {
+		slog.Warn("Error agregando los datos de ejemplo", "error", err)
 	}
 
-	// Crear router
-	router := gin.Default()
+	// Crear router (sin el logger de Gin: el access log es el de slog)
+	router := gin.New()
+
+	// X-Forwarded-For solo de los proxies de server.trusted_proxies: si no,
+	// cualquiera elegiría su IP y con ella su cuota
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("server.trusted_proxies inválido", "error", err)
+	}
 
 	// Middleware para headers UTF-8
 	router.Use(func(c *gin.Context) {
@@ -75,100 +210,161 @@ func main() {
 		c.Next()
 	})
 
-	// Middleware estándar
-	if ginMode != "release" {
-		router.Use(gin.Logger())
+	// ID de la petición (X-Request-ID) para todos los logs, una línea por
+	// petición (las sondas y /metrics solo con log.level debug) y panics
+	// como 500 problem+json
+	router.Use(middleware.RequestID())
+	router.Use(middleware.AccessLog(logger, "/healthz", "/readyz", "/metrics"))
+	router.Use(middleware.Recovery())
+
+	// Peticiones y latencia por ruta (/metrics)
+	router.Use(middleware.HTTPMetrics(registry))
+
+	// CORS: orígenes de cors.allowed_origins; los preflight se responden con
+	// los métodos registrados en cada ruta
+	cors, err := middleware.CORS(cfg.CORSPolicy(), router.Routes)
+	if err != nil {
+		fatal("Configuración CORS inválida", "error", err)
 	}
-	router.Use(gin.Recovery())
+	router.Use(cors)
 
-	// Configurar CORS
-	router.Use(corsMiddleware())
+	// Idioma de la respuesta (Accept-Language: es/en)
+	router.Use(middleware.Locale())
 
-	// ==================== RUTAS PÚBLICAS ====================
-	setupRoutes(router, bookHandler, authHandler)
+	// Errores uniformes (problem+json) para todos los handlers
+	router.Use(middleware.ErrorHandler())
 
-	// ==================== INICIAR SERVIDOR ====================
-	fullPort := ":" + port
-	log.Println("🚀 Server starting on port", port)
-	log.Println("📦 Storage:", storageType)
-	log.Println("🔐 Default: admin / admin123")
-	log.Println("🔍 External APIs: Open Library" + func() string {
-		if googleAPIKey != "" {
-			return " + Google Books"
+	// Cuerpo máximo de las peticiones (la importación de catálogos tiene el suyo)
+	router.Use(middleware.BodyLimit(int64(cfg.Server.MaxBodyBytes), "/books/import"))
+
+	// Cuotas por cliente y ruta (las búsquedas externas gastan nuestra cuota
+	// de Google Books)
+	if cfg.RateLimit.Enabled {
+		policy, err := cfg.RateLimitPolicy()
+		if err != nil {
+			fatal("Configuración de ratelimit inválida", "error", err)
 		}
-		return ""
-	}())
-	log.Println("🌐 http://localhost:" + port)
+		router.Use(middleware.RateLimit(ratelimit.NewMemoryStore(), policy))
+		slog.Info("Límite de peticiones activo", "default", cfg.RateLimit.Default, "route_rules", len(policy.Rules))
+	}
+
+	// ==================== RUTAS PÚBLICAS ====================
+	setupRoutes(router, bookHandler, authHandler, jobHandler, oaiHandler, backupHandler, healthHandler, registry.Handler(), cfg.MetricsAccess())
 
-	if err := router.Run(fullPort); err != nil {
-		log.Fatal("❌ Failed to start server:", err)
+	// ==================== INICIAR SERVIDOR ====================
+	providers := "Open Library"
+	if googleAPIKey != "" {
+		providers += " + Google Books"
+	}
+	scheme := "http"
+	if cfg.HTTPServer().TLS() {
+		scheme = "https"
 	}
+	slog.Info("Servidor iniciado", "port", port, "storage", storageType, "admin", cfg.Auth.AdminUsername,
+		"providers", providers, "metrics", cfg.MetricsAccess(), "url", scheme+"://localhost:"+port)
+
+	// SIGTERM (docker stop, deploy) o Ctrl+C: se dejan de aceptar conexiones,
+	// se terminan las peticiones en curso y los defer detienen los trabajos,
+	// los respaldos y cierran el store
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.Run(ctx, router, cfg.HTTPServer()); err != nil {
+		slog.Error("Error del servidor", "error", err)
+		exitCode = 1
+		return
+	}
+	slog.Info("Servidor detenido")
 }
 
 // ==================== FUNCIONES AUXILIARES ====================
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// memoryPersistenceNote - Aclaración para los logs del backend en memoria
+func memoryPersistenceNote(dir string) string {
+	if dir != "" {
+		return "persistente en " + dir
 	}
-	return defaultValue
+	return "sin storage.memory_dir: los datos se pierden al reiniciar"
 }
 
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	}
+// fatal - Registra el error y termina con código 1, como log.Fatal (sin
+// ejecutar los defer)
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
 
-func setupRoutes(router *gin.Engine, bookHandler *handlers.BookHandler, authHandler *handlers.AuthHandler) {
-	// Ruta raíz - Documentación de la API
+func setupRoutes(router *gin.Engine, bookHandler *handlers.BookHandler, authHandler *handlers.AuthHandler, jobHandler *handlers.JobHandler, oaiHandler *handlers.OAIHandler, backupHandler *handlers.BackupHandler, healthHandler *handlers.HealthHandler, metricsHandler http.Handler, metricsAccess string) {
+	// Rutas y métodos inexistentes también responden con problem+json
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		middleware.WriteProblem(c, problem.New(http.StatusNotFound, problem.CodeNotFound, "Route not found"))
+	})
+	router.NoMethod(func(c *gin.Context) {
+		middleware.WriteProblem(c, problem.New(http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "Method not allowed"))
+	})
+
+	// Ruta raíz - Documentación de la API (lista generada desde apiRoutes)
+	routes := apiRoutes()
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
-			"message":  "📚 Library Digital API",
-			"version":  "1.1.0",
-			"features": "CRUD de libros + APIs externas (Open Library, Google Books)",
-			"endpoints": map[string]string{
-				"home":                   "GET /",
-				"health":                 "GET /health",
-				"auth_register":          "POST /register",
-				"auth_login":             "POST /login",
-				"books_list":             "GET /books",
-				"book_detail":            "GET /books/:id",
-				"book_search":            "GET /books/search?title=...&author=...",
-				"external_search":        "GET /api/external/search?q=harry+potter&source=openlibrary",
-				"external_import":        "GET /api/external/import?source=openlibrary&id=OL1234567M",
-				"book_details":           "GET /api/books/:id/details?enrich=google",
-				"protected_books_create": "POST /books (requiere auth)",
-				"protected_bulk_import":  "POST /api/external/import/bulk (requiere auth)",
-			},
+			"message":   "📚 Library Digital API",
+			"version":   apiVersion,
+			"features":  i18n.T(i18n.FromContext(c), "Book CRUD + external APIs (Open Library, Google Books)"),
+			"docs":      "/docs",
+			"openapi":   "/openapi.json",
+			"endpoints": endpointIndex(routes),
 		})
 	})
 
+	// Especificación OpenAPI 3.1 y documentación interactiva
+	spec := openapi.Build(apiInfo, routes, apiErrorBody)
+	spec.ExternalDocs = &openapi.ExternalDocs{Description: "Open Library API", URL: "https://openlibrary.org/developers/api"}
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(200, spec)
+	})
+	docsPage := openapi.SwaggerUI(apiInfo.Title, "/openapi.json")
+	router.GET("/docs", func(c *gin.Context) {
+		// El middleware UTF-8 ya fijó Content-Type JSON; c.Data no lo reemplaza
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Data(200, "text/html; charset=utf-8", docsPage)
+	})
+
 	// Autenticación
 	router.POST("/register", authHandler.Register)
 	router.POST("/login", authHandler.Login)
 	router.POST("/api/register", authHandler.Register)
 
-	// Salud del sistema
-	router.GET("/health", bookHandler.HealthCheck)
+	// Salud del sistema: liveness, readiness (/health es el nombre anterior
+	// de /readyz) y métricas de Prometheus según metrics.access
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+	router.GET("/health", healthHandler.Readiness)
+	switch metricsAccess {
+	case config.MetricsPublic:
+		router.GET("/metrics", gin.WrapH(metricsHandler))
+	case config.MetricsAdmin:
+		router.GET("/metrics", middleware.AuthMiddleware(), middleware.AdminMiddleware(), gin.WrapH(metricsHandler))
+	}
 
 	// ==================== RUTAS PÚBLICAS DE LIBROS ====================
 	// Libros en nuestra base de datos
 	router.GET("/books", bookHandler.GetBooks)
 	router.GET("/books/search", bookHandler.SearchBooks)
+	router.GET("/books/export", bookHandler.ExportBooks)
+	router.POST("/books/export", bookHandler.ExportSelectedBooks)
 	router.GET("/books/:id", bookHandler.GetBook)
 
+	// Autores y materias del catálogo
+	router.GET("/authors", bookHandler.GetAuthors)
+	router.GET("/authors/:id/books", bookHandler.GetAuthorBooks)
+	router.GET("/subjects", bookHandler.GetSubjects)
+	router.GET("/subjects/:id/books", bookHandler.GetSubjectBooks)
+
+	// Cosecha OAI-PMH (Dublin Core)
+	router.GET("/oai", oaiHandler.Handle)
+	router.POST("/oai", oaiHandler.Handle)
+
 	// ==================== NUEVAS RUTAS PARA APIS EXTERNAS ====================
 	// Buscar en APIs externas (público)
 	router.GET("/api/external/search", bookHandler.SearchExternalBooks)
@@ -186,10 +382,19 @@ func setupRoutes(router *gin.Engine, bookHandler *handlers.BookHandler, authHand
 		// CRUD de libros en nuestra base
 		protected.POST("/books", bookHandler.CreateBook)
 		protected.PUT("/books/:id", bookHandler.UpdateBook)
+		protected.PATCH("/books/:id", bookHandler.PatchBook)
 		protected.DELETE("/books/:id", bookHandler.DeleteBook)
 
-		// Importación masiva desde APIs externas (protegida)
-		protected.POST("/api/external/import/bulk", bookHandler.BulkImportBooks)
+		// Importación de catálogos desde archivos (CSV, XLSX, MARC21, MARCXML)
+		protected.POST("/books/import", bookHandler.ImportBooks)
+
+		// Importación masiva desde APIs externas (protegida, en segundo plano)
+		protected.POST("/api/external/import/bulk", jobHandler.BulkImportBooks)
+
+		// Trabajos en segundo plano
+		protected.GET("/jobs", jobHandler.GetJobs)
+		protected.GET("/jobs/:id", jobHandler.GetJob)
+		protected.POST("/jobs/:id/cancel", jobHandler.CancelJob)
 
 		// Sistema de préstamos
 		protected.POST("/books/:id/borrow", bookHandler.BorrowBook)
@@ -200,26 +405,20 @@ func setupRoutes(router *gin.Engine, bookHandler *handlers.BookHandler, authHand
 		protected.GET("/me", authHandler.Me)
 	}
 
-	// Ruta de documentación Swagger/OpenAPI (si la agregas después)
-	router.GET("/docs", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"openapi": "3.0.0",
-			"info": gin.H{
-				"title":       "Library Digital API",
-				"version":     "1.1.0",
-				"description": "API para gestión de biblioteca digital con integración de APIs externas",
-			},
-			"externalDocs": gin.H{
-				"description": "Open Library API",
-				"url":         "https://openlibrary.org/developers/api",
-			},
-		})
-	})
+	// ==================== RUTAS DE ADMINISTRACIÓN ====================
+	admin := router.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		// Respaldos del almacenamiento
+		admin.POST("/backup", backupHandler.CreateBackup)
+		admin.GET("/backups", backupHandler.GetBackups)
+		admin.GET("/backups/:name", backupHandler.DownloadBackup)
+	}
 }
 
-func addSampleData(store storage.Store) error {
+func addSampleData(ctx context.Context, store storage.Store) error {
 	// Verificar si ya hay libros
-	books, err := store.GetBooks()
+	books, err := store.GetBooks(ctx)
 	if err != nil {
 		return err
 	}
@@ -248,7 +447,7 @@ func addSampleData(store storage.Store) error {
 			{
 				Title:       "Don Quijote de la Mancha",
 				Author:      "Miguel de Cervantes",
-				ISBN:        "978-8424113296",
+				ISBN:        "978-8420412146",
 				Published:   1605,
 				Genre:       "Novela, Aventura, Sátira",
 				Description: "Las aventuras de un hidalgo que enloquece leyendo libros de caballerías.",
@@ -258,40 +457,87 @@ func addSampleData(store storage.Store) error {
 
 		count := 0
 		for _, book := range sampleBooks {
-			if _, err := store.CreateBook(book); err == nil {
+			if _, err := store.CreateBook(ctx, book); err == nil {
 				count++
 			} else {
-				log.Printf("⚠️  Error creando libro de ejemplo: %v", err)
+				slog.Warn("Error creando libro de ejemplo", "title", book.Title, "error", err)
 			}
 		}
 
 		if count > 0 {
-			log.Printf("📚 Se agregaron %d libros de ejemplo", count)
-		}
-
-		// Crear usuario admin por defecto si no existe
-		adminUser := models.User{
-			Username: "admin",
-			Password: "admin123", // En un caso real, esto estaría hasheado
-			Role:     "admin",
+			slog.Info("Se agregaron libros de ejemplo", "books", count)
 		}
 
-		if _, err := store.CreateUser(adminUser); err != nil {
-			log.Printf("⚠️  Error creando usuario admin: %v", err)
-		} else {
-			log.Println("👤 Usuario admin creado (admin / admin123)")
-		}
+		// El usuario admin lo crea el store (auth.admin_username / auth.admin_password)
 	}
 
 	return nil
 }
 
-// Función auxiliar para crear respuesta estandarizada
-func apiResponse(c *gin.Context, status int, message string, data interface{}) {
-	c.JSON(status, gin.H{
-		"status":    status >= 200 && status < 300,
-		"message":   message,
-		"data":      data,
-		"timestamp": time.Now().Format(time.RFC3339),
-	})
+// closeStore - Cierra el store al apagar la API
+func closeStore(store storage.Store) {
+	if err := store.Close(); err != nil {
+		slog.Warn("Error cerrando el almacenamiento", "error", err)
+		return
+	}
+	slog.Info("Almacenamiento cerrado")
+}
+
+// runISBNMigration - Normaliza los ISBN guardados e imprime el reporte
+func runISBNMigration(ctx context.Context, store storage.Store) {
+	migrator, ok := store.(storage.ISBNMigrator)
+	if !ok {
+		fatal("El store configurado no soporta la migración de ISBN")
+	}
+
+	report, err := migrator.MigrateISBNs(ctx)
+	if err != nil {
+		fatal("Error migrando ISBN", "error", err)
+	}
+
+	output, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(output))
+
+	slog.Info("ISBN migrados", "checked", report.Checked, "normalized", report.Normalized,
+		"invalid", len(report.Invalid), "collisions", len(report.Collisions))
+}
+
+// verifyBackup - Comprueba un respaldo contra su manifiesto antes de restaurarlo
+func verifyBackup(path string) *backup.Manifest {
+	manifest, err := backup.Verify(path)
+	if err != nil {
+		fatal("Respaldo no válido", "error", err)
+	}
+	return manifest
+}
+
+// restoreSQLiteBackup - Reemplaza el archivo de SQLite por una copia .db
+// (antes de abrir el almacenamiento)
+func restoreSQLiteBackup(path string, manifest *backup.Manifest, storageType, dbPath string) {
+	if storageType != "sqlite" {
+		fatal("Este respaldo solo se restaura con STORAGE_TYPE=sqlite (usar un respaldo json)", "format", manifest.Format)
+	}
+
+	if err := backup.RestoreSQLiteFile(path, dbPath); err != nil {
+		fatal("Error restaurando respaldo", "error", err)
+	}
+	slog.Info("Base de datos restaurada", "path", dbPath, "backup", manifest.Name)
+}
+
+// restoreDump - Carga un volcado .json en el almacenamiento configurado
+// (cualquier backend)
+func restoreDump(ctx context.Context, store storage.Store, path string, manifest *backup.Manifest) {
+	dump, err := backup.ReadDump(path)
+	if err != nil {
+		fatal("Error leyendo respaldo", "error", err)
+	}
+
+	dumper, ok := store.(storage.Dumper)
+	if !ok {
+		fatal("El store configurado no soporta restaurar volcados")
+	}
+	if err := dumper.Load(ctx, dump); err != nil {
+		fatal("Error restaurando respaldo", "error", err)
+	}
+	slog.Info("Almacenamiento restaurado", "backup", manifest.Name, "books", len(dump.Books), "loans", len(dump.Loans))
 }
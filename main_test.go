@@ -0,0 +1,537 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"library-api/auth"
+	"library-api/backup"
+	"library-api/config"
+	"library-api/handlers"
+	"library-api/isbn"
+	"library-api/jobs"
+	"library-api/mergepatch"
+	"library-api/metrics"
+	"library-api/middleware"
+	"library-api/models"
+	"library-api/oai"
+	"library-api/openapi"
+	"library-api/problem"
+	"library-api/services"
+	"library-api/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter - Router completo sobre un MemoryStore, sin arrancar
+// trabajos y con /metrics público
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	return newTestRouterWithMetrics(t, config.MetricsPublic)
+}
+
+// newTestRouterWithMetrics - Como newTestRouter, con el acceso a /metrics
+// indicado
+func newTestRouterWithMetrics(t *testing.T, metricsAccess string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	registry := metrics.NewRegistry()
+	store := storage.Instrument(storage.NewMemoryStore(), storeObserver(registry))
+	externalService := services.NewExternalBookService("")
+	catalogMetrics(registry, store, externalService, 14*24*time.Hour)
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.HTTPMetrics(registry), middleware.Locale(), middleware.ErrorHandler())
+	setupRoutes(router,
+		handlers.NewBookHandler(store, externalService),
+		handlers.NewAuthHandler(store),
+		handlers.NewJobHandler(store, jobs.NewManager(store, externalService, 1)),
+		handlers.NewOAIHandler(oai.NewProvider(store, oai.Config{RepositoryIdentifier: "test.local"})),
+		handlers.NewBackupHandler(backup.NewManager(storage.Unwrap(store), backup.Config{Dir: t.TempDir()})),
+		handlers.NewHealthHandler(store, externalService),
+		registry.Handler(),
+		metricsAccess,
+	)
+	return router
+}
+
+// TestOpenAPICoversRoutes - Toda ruta registrada en Gin está en la
+// especificación y la especificación no documenta rutas inexistentes
+func TestOpenAPICoversRoutes(t *testing.T) {
+	router := newTestRouter(t)
+	spec := openapi.Build(apiInfo, apiRoutes(), apiErrorBody)
+
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		operation := route.Method + " " + openapi.Path(route.Path)
+		registered[operation] = true
+		if item, ok := spec.Paths[openapi.Path(route.Path)]; !ok || item[strings.ToLower(route.Method)] == nil {
+			t.Errorf("route %s %s is missing from the OpenAPI spec (add it to apiRoutes)", route.Method, route.Path)
+		}
+	}
+
+	for _, operation := range spec.Operations() {
+		if !registered[operation] {
+			t.Errorf("OpenAPI spec documents %s, which is not registered", operation)
+		}
+	}
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json: status %d", rec.Code)
+	}
+	var spec struct {
+		OpenAPI    string                     `json:"openapi"`
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("GET /openapi.json: invalid JSON: %v", err)
+	}
+	if spec.OpenAPI != openapi.Version || spec.Paths["/books/{id}"] == nil {
+		t.Errorf("GET /openapi.json: got openapi %q with %d paths", spec.OpenAPI, len(spec.Paths))
+	}
+	for _, name := range []string{"CreateBookRequest", "UpdateBookRequest", "LoanWithBook", "LoginResponse", "Problem"} {
+		if spec.Components.Schemas[name] == nil {
+			t.Errorf("GET /openapi.json: schema %s missing", name)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(rec.Body.String(), "/openapi.json") {
+		t.Errorf("GET /docs: status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+// TestErrorsAreProblems - Los errores responden problem+json con código estable
+func TestErrorsAreProblems(t *testing.T) {
+	router := newTestRouter(t)
+
+	cases := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{http.MethodGet, "/books/999", "", http.StatusNotFound, problem.CodeBookNotFound},
+		{http.MethodPost, "/login", `{"username": "admin"`, http.StatusBadRequest, problem.CodeBadRequest},
+		{http.MethodPost, "/login", `{}`, http.StatusBadRequest, problem.CodeValidationFailed},
+		{http.MethodDelete, "/books/1", "", http.StatusUnauthorized, problem.CodeUnauthorized},
+		{http.MethodGet, "/no-such-route", "", http.StatusNotFound, problem.CodeNotFound},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+
+		var body problem.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: invalid JSON: %v", tc.method, tc.path, err)
+		}
+		if rec.Code != tc.status || body.Code != tc.code || rec.Header().Get("Content-Type") != problem.ContentType {
+			t.Errorf("%s %s: got %d %q (%s)", tc.method, tc.path, rec.Code, body.Code, rec.Header().Get("Content-Type"))
+		}
+		if body.Instance != tc.path {
+			t.Errorf("%s %s: got instance %q", tc.method, tc.path, body.Instance)
+		}
+		if tc.code == problem.CodeValidationFailed && len(body.Errors) != 2 {
+			t.Errorf("%s %s: got field errors %+v", tc.method, tc.path, body.Errors)
+		}
+	}
+}
+
+// serveJSON - Petición de prueba con un token de administrador
+func serveJSON(t *testing.T, router *gin.Engine, method, path, contentType, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := auth.GenerateToken("1", "admin")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestPatchBookMergePatch - null borra el campo, lo ausente no cambia y los
+// errores de validación se traducen según Accept-Language
+func TestPatchBookMergePatch(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := serveJSON(t, router, http.MethodPost, "/books", "application/json",
+		`{"title": "Rayuela", "author": "Julio Cortázar", "isbn": "978-84-376-0494-7", "published": 1963, "genre": "Novela", "description": "Antinovela"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /books: status %d: %s", rec.Code, rec.Body)
+	}
+	var book models.Book
+	json.Unmarshal(rec.Body.Bytes(), &book)
+
+	rec = serveJSON(t, router, http.MethodPatch, "/books/"+book.ID, mergepatch.ContentType, `{"description": null, "published": 1968}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH: status %d: %s", rec.Code, rec.Body)
+	}
+	var patched models.Book
+	json.Unmarshal(rec.Body.Bytes(), &patched)
+	if patched.Description != "" || patched.Published != 1968 || patched.Title != "Rayuela" || patched.Author != "Julio Cortázar" {
+		t.Errorf("PATCH: got %+v", patched)
+	}
+
+	rec = serveJSON(t, router, http.MethodPatch, "/books/"+book.ID, mergepatch.ContentType,
+		`{"isbn": "9788437604948", "title": null, "genre": "Novela, Cosas"}`, "Accept-Language", "es-AR,es;q=0.9,en;q=0.5")
+	var body problem.Problem
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusBadRequest || body.Code != problem.CodeValidationFailed || len(body.Errors) != 3 {
+		t.Fatalf("invalid PATCH: got %d %+v", rec.Code, body)
+	}
+	rules := make(map[string]string)
+	for _, fe := range body.Errors {
+		rules[fe.Field] = fe.Rule
+	}
+	if rules["isbn"] != "isbn" || rules["title"] != "required" || rules["genre"] != "genre" || body.Errors[0].Message == "" ||
+		body.Detail != "La petición tiene campos inválidos" {
+		t.Errorf("invalid PATCH: got %+v", body)
+	}
+
+	rec = serveJSON(t, router, http.MethodPatch, "/books/"+book.ID, "text/plain", `{}`)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("PATCH text/plain: status %d", rec.Code)
+	}
+}
+
+// TestLocalizedResponses - Los mensajes y los títulos de los libros siguen
+// Accept-Language, con el original si no hay traducción
+func TestLocalizedResponses(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := serveJSON(t, router, http.MethodPost, "/books", "application/json",
+		`{"title": "Rayuela", "author": "Julio Cortázar", "isbn": "978-84-376-0494-7", "description": "Antinovela",
+		  "translations": {"en": {"title": "Hopscotch"}}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /books: status %d: %s", rec.Code, rec.Body)
+	}
+	var book models.Book
+	json.Unmarshal(rec.Body.Bytes(), &book)
+
+	for _, tc := range []struct{ acceptLanguage, title, description string }{
+		{"", "Rayuela", "Antinovela"},
+		{"en-GB,en;q=0.9", "Hopscotch", "Antinovela"},
+		{"es-AR", "Rayuela", "Antinovela"},
+	} {
+		rec = serveJSON(t, router, http.MethodGet, "/books/"+book.ID, "", "", "Accept-Language", tc.acceptLanguage)
+		var got models.Book
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		if got.Title != tc.title || got.Description != tc.description || got.Translations["en"].Title != "Hopscotch" {
+			t.Errorf("Accept-Language %q: got %q / %q", tc.acceptLanguage, got.Title, got.Description)
+		}
+	}
+
+	rec = serveJSON(t, router, http.MethodGet, "/books/missing", "", "", "Accept-Language", "es")
+	var body problem.Problem
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Detail != "Libro no encontrado" || body.Title != "No encontrado" || rec.Header().Get("Content-Language") != "es" {
+		t.Errorf("problem in Spanish: got %+v (Content-Language %q)", body, rec.Header().Get("Content-Language"))
+	}
+
+	rec = serveJSON(t, router, http.MethodPatch, "/books/"+book.ID, mergepatch.ContentType, `{"translations": {"not a tag!": {"title": "x"}}}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "bcp47_language_tag") {
+		t.Errorf("invalid translation tag: got %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestBorrowLoanLimit - Con loans.max_active_per_user el préstamo que lo
+// supera es un 409 loan_limit_reached
+func TestBorrowLoanLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	bookHandler := handlers.NewBookHandler(store, services.NewExternalBookService(""))
+	bookHandler.SetLoanPolicy(handlers.LoanPolicy{MaxActiveLoans: 1})
+
+	router := gin.New()
+	router.Use(middleware.Locale(), middleware.ErrorHandler())
+	router.POST("/books/:id/borrow", bookHandler.BorrowBook)
+
+	var ids []string
+	for _, isbn := range []string{"978-84-376-0494-7", "978-0-307-47472-8"} {
+		book, err := store.CreateBook(t.Context(), models.Book{Title: "Libro " + isbn, Author: "Autor", ISBN: isbn, Available: true})
+		if err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+		ids = append(ids, book.ID)
+	}
+
+	borrow := func(id, user string) *httptest.ResponseRecorder {
+		return serveJSON(t, router, http.MethodPost, "/books/"+id+"/borrow", "application/json",
+			`{"book_id": "`+id+`", "user": "`+user+`"}`, "Accept-Language", "es")
+	}
+	if rec := borrow(ids[0], "ana"); rec.Code != http.StatusCreated {
+		t.Fatalf("first loan: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := borrow(ids[1], "ana")
+	var body problem.Problem
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusConflict || body.Code != problem.CodeLoanLimitReached || !strings.Contains(body.Detail, "préstamos activos") {
+		t.Errorf("second loan: got %d %q %q", rec.Code, body.Code, body.Detail)
+	}
+
+	if rec := borrow(ids[1], "luis"); rec.Code != http.StatusCreated {
+		t.Errorf("another user: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// TestHealthAndMetrics - Sondas y métricas sobre el router completo
+func TestHealthAndMetrics(t *testing.T) {
+	router := newTestRouter(t)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("GET /healthz: status %d", rec.Code)
+	}
+
+	var ready struct {
+		Status string `json:"status"`
+		Checks struct {
+			Database   string            `json:"database"`
+			Migrations string            `json:"migrations"`
+			Providers  map[string]string `json:"providers"`
+		} `json:"checks"`
+	}
+	rec := get("/readyz")
+	if err := json.Unmarshal(rec.Body.Bytes(), &ready); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /readyz: %d %s", rec.Code, rec.Body)
+	}
+	if ready.Status != "ready" || ready.Checks.Database != "up" || ready.Checks.Migrations != "up" || ready.Checks.Providers[services.ProviderGoogleBooks] != services.CircuitClosed {
+		t.Errorf("GET /readyz: got %+v", ready)
+	}
+
+	get("/books/missing")
+	body := get("/metrics").Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/books/:id",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/readyz"} 1`,
+		`library_store_operation_duration_seconds_count{operation="GetBookByID"} 1`,
+		"library_books 0",
+		`library_provider_circuit_open{provider="open_library"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET /metrics: missing %q in\n%s", want, body)
+		}
+	}
+	// Un libro inexistente no es un fallo del almacenamiento
+	if strings.Contains(body, `library_store_operation_errors_total{operation="GetBookByID"}`) {
+		t.Errorf("GET /metrics: a 404 counted as a store error\n%s", body)
+	}
+}
+
+// TestJobOwnership - Cada usuario ve y cancela solo sus trabajos; el
+// administrador, todos
+func TestJobOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	jobHandler := handlers.NewJobHandler(store, jobs.NewManager(store, services.NewExternalBookService(""), 1))
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	protected := router.Group("/", middleware.AuthMiddleware())
+	protected.GET("/jobs", jobHandler.GetJobs)
+	protected.GET("/jobs/:id", jobHandler.GetJob)
+	protected.POST("/jobs/:id/cancel", jobHandler.CancelJob)
+
+	ids := make(map[string]string)
+	for _, user := range []string{"ana", "luis"} {
+		job, err := store.CreateJob(t.Context(), models.Job{Type: models.JobTypeBulkImport, Params: models.JobParams{Source: "openlibrary", Query: user}, CreatedBy: user})
+		if err != nil {
+			t.Fatalf("CreateJob: %v", err)
+		}
+		ids[user] = job.ID
+		if err := store.SaveJobItems(t.Context(), job.ID, models.JobItems{{ISBN: "9780306406157"}}); err != nil {
+			t.Fatalf("SaveJobItems: %v", err)
+		}
+	}
+
+	serve := func(userID, role, method, path string) *httptest.ResponseRecorder {
+		token, err := auth.GenerateToken(userID, role)
+		if err != nil {
+			t.Fatalf("GenerateToken: %v", err)
+		}
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	listed := func(rec *httptest.ResponseRecorder) []string {
+		var list []models.Job
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatalf("GET /jobs: %v: %s", err, rec.Body)
+		}
+		var owners []string
+		for _, job := range list {
+			owners = append(owners, job.CreatedBy)
+		}
+		return owners
+	}
+
+	if owners := listed(serve("ana", "user", http.MethodGet, "/jobs")); strings.Join(owners, ",") != "ana" {
+		t.Errorf("ana's list: got jobs of %v", owners)
+	}
+	if owners := listed(serve("1", "admin", http.MethodGet, "/jobs")); len(owners) != 2 {
+		t.Errorf("admin's list: got jobs of %v", owners)
+	}
+
+	if rec := serve("ana", "user", http.MethodGet, "/jobs/"+ids["ana"]); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "9780306406157") {
+		t.Errorf("ana's job: status %d, want progress without the items: %s", rec.Code, rec.Body)
+	}
+	if rec := serve("ana", "user", http.MethodGet, "/jobs/"+ids["luis"]); rec.Code != http.StatusNotFound {
+		t.Errorf("luis's job seen by ana: status %d, want 404", rec.Code)
+	}
+	if rec := serve("ana", "user", http.MethodPost, "/jobs/"+ids["luis"]+"/cancel"); rec.Code != http.StatusNotFound {
+		t.Errorf("luis's job cancelled by ana: status %d, want 404", rec.Code)
+	}
+	if job, _ := store.GetJobByID(t.Context(), ids["luis"]); job.Status != models.JobPending {
+		t.Errorf("luis's job after ana's cancel: %s", job.Status)
+	}
+	if rec := serve("1", "admin", http.MethodPost, "/jobs/"+ids["luis"]+"/cancel"); rec.Code != http.StatusOK {
+		t.Errorf("luis's job cancelled by the admin: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// failingTxStore - Store cuya transacción número failAt falla sin escribir
+type failingTxStore struct {
+	storage.Store
+	failAt int
+	calls  int
+}
+
+func (s *failingTxStore) WithTx(ctx context.Context, fn func(tx storage.Store) error) error {
+	s.calls++
+	if s.calls == s.failAt {
+		return errors.New("database is locked")
+	}
+	return s.Store.WithTx(ctx, fn)
+}
+
+// TestCatalogImportBatches - La importación se guarda en lotes: un lote que
+// falla queda como filas fallidas sin deshacer los demás
+func TestCatalogImportBatches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memory := storage.NewMemoryStore()
+	store := &failingTxStore{Store: memory, failAt: 2}
+	bookHandler := handlers.NewBookHandler(store, services.NewExternalBookService(""))
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.POST("/books/import", bookHandler.ImportBooks)
+
+	// 250 libros válidos: lotes de 100, 100 y 50
+	var csv strings.Builder
+	csv.WriteString("title,author,isbn\n")
+	for i := 0; i < 250; i++ {
+		code := ""
+		for digit := 0; digit < 10 && code == ""; digit++ {
+			if candidate := fmt.Sprintf("978000001%03d%d", i, digit); isbn.IsValid(candidate) {
+				code = candidate
+			}
+		}
+		fmt.Fprintf(&csv, "Libro %d,Autor,%s\n", i, code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/books/import?format=csv", strings.NewReader(csv.String()))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var report models.CatalogImportReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("import: status %d, %v: %s", rec.Code, err, rec.Body)
+	}
+	if report.Created != 150 || report.Failed != 100 || report.FailedBatches != 1 {
+		t.Errorf("report: created %d, failed %d, failed batches %d", report.Created, report.Failed, report.FailedBatches)
+	}
+	if failed := report.Results[100]; failed.Status != models.ImportFailed || failed.Row != 102 || len(failed.Errors) != 1 || strings.Contains(failed.Errors[0], "locked") {
+		t.Errorf("first row of the failed batch: got %+v", failed)
+	}
+	if books, err := memory.GetBooks(t.Context()); err != nil || len(books) != 150 {
+		t.Errorf("books after import: got %d, %v", len(books), err)
+	}
+
+	// Si no se guarda ningún lote es un error del almacenamiento
+	store.failAt, store.calls = 1, 0
+	tiny := strings.Join(strings.SplitN(csv.String(), "\n", 3)[:2], "\n") + "\n"
+	req = httptest.NewRequest(http.MethodPost, "/books/import?format=csv", strings.NewReader(tiny))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code < http.StatusInternalServerError {
+		t.Errorf("import with every batch failing: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// TestMetricsAccess - /metrics solo para administradores o desactivado
+func TestMetricsAccess(t *testing.T) {
+	get := func(router *gin.Engine, role string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if role != "" {
+			token, err := auth.GenerateToken("1", role)
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	admin := newTestRouterWithMetrics(t, config.MetricsAdmin)
+	if code := get(admin, ""); code != http.StatusUnauthorized {
+		t.Errorf("admin access without a token: status %d, want 401", code)
+	}
+	if code := get(admin, "user"); code != http.StatusForbidden {
+		t.Errorf("admin access with a user token: status %d, want 403", code)
+	}
+	if code := get(admin, "admin"); code != http.StatusOK {
+		t.Errorf("admin access with an admin token: status %d, want 200", code)
+	}
+
+	off := newTestRouterWithMetrics(t, config.MetricsOff)
+	if code := get(off, "admin"); code != http.StatusNotFound {
+		t.Errorf("metrics off: status %d, want 404", code)
+	}
+
+	// auto: desactivado en modo release
+	cfg := config.Default()
+	if access := cfg.MetricsAccess(); access != config.MetricsPublic {
+		t.Errorf("auto in debug mode: got %s, want public", access)
+	}
+	cfg.Server.Mode = config.ModeRelease
+	if access := cfg.MetricsAccess(); access != config.MetricsOff {
+		t.Errorf("auto in release mode: got %s, want off", access)
+	}
+	cfg.Metrics.Access = config.MetricsAdmin
+	if access := cfg.MetricsAccess(); access != config.MetricsAdmin {
+		t.Errorf("admin in release mode: got %s", access)
+	}
+}
@@ -0,0 +1,73 @@
+// Package mergepatch - JSON Merge Patch (RFC 7396): los miembros del parche
+// reemplazan a los del documento, null los elimina y los objetos se combinan
+// recursivamente
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ContentType - Tipo de contenido de un parche JSON Merge Patch
+const ContentType = "application/merge-patch+json"
+
+// ErrNotObject - El parche no es un objeto JSON. El RFC lo permite (reemplaza
+// el documento completo) pero la API solo acepta parches de campos
+var ErrNotObject = errors.New("merge patch must be a JSON object")
+
+// Apply - Aplica el parche al documento y devuelve el documento resultante
+func Apply(document, patch []byte) ([]byte, error) {
+	var patchValue any
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("error reading merge patch: %w", err)
+	}
+	if _, ok := patchValue.(map[string]any); !ok {
+		return nil, ErrNotObject
+	}
+
+	var target any
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, fmt.Errorf("error reading document: %w", err)
+	}
+
+	return json.Marshal(merge(target, patchValue))
+}
+
+// Keys - Miembros de primer nivel del parche, incluidos los que valen null
+func Keys(patch []byte) (map[string]bool, error) {
+	var patchValue any
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("error reading merge patch: %w", err)
+	}
+	members, ok := patchValue.(map[string]any)
+	if !ok {
+		return nil, ErrNotObject
+	}
+	keys := make(map[string]bool, len(members))
+	for key := range members {
+		keys[key] = true
+	}
+	return keys, nil
+}
+
+// merge - Algoritmo MergePatch(Target, Patch) de la sección 2 del RFC 7396
+func merge(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = merge(targetObject[key], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,58 @@
+package mergepatch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"library-api/mergepatch"
+)
+
+// Ejemplos del apéndice A del RFC 7396 (los parches que son objetos)
+func TestApplyRFCExamples(t *testing.T) {
+	cases := []struct{ document, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tc := range cases {
+		got, err := mergepatch.Apply([]byte(tc.document), []byte(tc.patch))
+		if err != nil {
+			t.Errorf("Apply(%s, %s): %v", tc.document, tc.patch, err)
+			continue
+		}
+		var gotValue, wantValue any
+		json.Unmarshal(got, &gotValue)
+		json.Unmarshal([]byte(tc.want), &wantValue)
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("Apply(%s, %s): got %s, want %s", tc.document, tc.patch, got, tc.want)
+		}
+	}
+}
+
+func TestApplyRejectsNonObjectPatch(t *testing.T) {
+	for _, patch := range []string{`["a"]`, `"text"`, `null`} {
+		if _, err := mergepatch.Apply([]byte(`{"a":"b"}`), []byte(patch)); !errors.Is(err, mergepatch.ErrNotObject) {
+			t.Errorf("Apply with patch %s: got %v, want ErrNotObject", patch, err)
+		}
+	}
+}
+
+func TestKeysIncludesNulls(t *testing.T) {
+	keys, err := mergepatch.Keys([]byte(`{"title": "Rayuela", "description": null}`))
+	if err != nil || len(keys) != 2 || !keys["title"] || !keys["description"] {
+		t.Errorf("Keys: got %v, %v", keys, err)
+	}
+	if _, err := mergepatch.Keys([]byte(`[1]`)); !errors.Is(err, mergepatch.ErrNotObject) {
+		t.Errorf("Keys([1]): got %v, want ErrNotObject", err)
+	}
+}
@@ -0,0 +1,267 @@
+// Package metrics - Métricas en el formato de texto de Prometheus: contadores,
+// gauges e histogramas con etiquetas, agrupados en un Registry que se sirve
+// en /metrics. Solo lo que usa la API; sin dependencias externas
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets - Límites de los histogramas de latencia, en segundos
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry - Conjunto de métricas que se exportan juntas
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+	names    map[string]bool
+	// collectors - Se ejecutan antes de cada exportación (gauges que se
+	// calculan al consultar, como los libros del catálogo)
+	collectors []func(ctx context.Context)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Counter - Registra un contador (solo crece) con las etiquetas indicadas
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, "counter", nil, labels)}
+}
+
+// Gauge - Registra un valor que sube y baja
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, "gauge", nil, labels)}
+}
+
+// Histogram - Registra un histograma con los límites indicados (nil:
+// DefaultBuckets)
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Histogram{r.register(name, help, "histogram", buckets, labels)}
+}
+
+// OnCollect - Agrega una función que actualiza métricas justo antes de cada
+// exportación
+func (r *Registry) OnCollect(fn func(ctx context.Context)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, fn)
+}
+
+// register - Un nombre repetido es un error de programación: panic, como
+// en el registro de rutas de Gin
+func (r *Registry) register(name, help, kind string, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: %s is already registered", name))
+	}
+	r.names[name] = true
+
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	r.families = append(r.families, f)
+	return f
+}
+
+// ==============================================
+// TIPOS DE MÉTRICAS
+// ==============================================
+
+// family - Una métrica con todas sus combinaciones de etiquetas
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series - Valores de una combinación de etiquetas
+type series struct {
+	labels []string
+	value  float64
+	// counts, sum - Solo en histogramas (counts[i]: observaciones en el
+	// bucket i, sin acumular; la última posición es +Inf)
+	counts []uint64
+	sum    float64
+}
+
+// with - Serie de los valores de etiqueta indicados (se crea la primera vez)
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter - Contador con etiquetas
+type Counter struct{ f *family }
+
+// Inc - Suma uno a la serie de las etiquetas indicadas
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add - Suma value (no negativo)
+func (c *Counter) Add(value float64, labels ...string) {
+	if value < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.f.name))
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.with(labels).value += value
+}
+
+// Gauge - Valor instantáneo con etiquetas
+type Gauge struct{ f *family }
+
+// Set - Fija el valor de la serie de las etiquetas indicadas
+func (g *Gauge) Set(value float64, labels ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.with(labels).value = value
+}
+
+// Histogram - Distribución de observaciones (latencias, en segundos)
+type Histogram struct{ f *family }
+
+// Observe - Registra una observación en la serie de las etiquetas indicadas
+func (h *Histogram) Observe(value float64, labels ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+
+	s := h.f.with(labels)
+	i := sort.SearchFloat64s(h.f.buckets, value)
+	s.counts[i]++
+	s.sum += value
+}
+
+// ==============================================
+// EXPORTACIÓN
+// ==============================================
+
+// ContentType - Formato de texto de Prometheus
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler - Sirve las métricas (GET /metrics)
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := r.Write(req.Context(), w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Write - Ejecuta los collectors y escribe todas las métricas en el formato
+// de texto, en orden de registro y con las series ordenadas
+func (r *Registry) Write(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]func(context.Context){}, r.collectors...)
+	families := append([]*family{}, r.families...)
+	r.mu.Unlock()
+
+	for _, collect := range collectors {
+		collect(ctx)
+	}
+
+	var b strings.Builder
+	for _, f := range families {
+		f.write(&b)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (f *family) write(b *strings.Builder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool { return slices.Compare(all[i].labels, all[j].labels) < 0 })
+
+	for _, s := range all {
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, f.labelSet(s.labels, ""), formatValue(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, count := range s.counts {
+			cumulative += count
+			le := math.Inf(1)
+			if i < len(f.buckets) {
+				le = f.buckets[i]
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, f.labelSet(s.labels, formatValue(le)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, f.labelSet(s.labels, ""), formatValue(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, f.labelSet(s.labels, ""), cumulative)
+	}
+}
+
+// labelSet - {name="value",...}, con le al final en los buckets
+func (f *family) labelSet(values []string, le string) string {
+	if len(values) == 0 && le == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+1)
+	for i, value := range values {
+		pairs = append(pairs, f.labels[i]+`="`+escapeLabel(value)+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string { return labelEscaper.Replace(value) }
+func escapeHelp(value string) string  { return helpEscaper.Replace(value) }
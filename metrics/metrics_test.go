@@ -0,0 +1,79 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"library-api/metrics"
+)
+
+func TestRegistryTextFormat(t *testing.T) {
+	registry := metrics.NewRegistry()
+	requests := registry.Counter("http_requests_total", "Peticiones atendidas", "route", "status")
+	latency := registry.Histogram("http_request_duration_seconds", "Latencia", []float64{0.5, 0.1}, "route")
+	books := registry.Gauge("library_books", "Libros del catálogo")
+
+	requests.Inc("/books/:id", "200")
+	requests.Add(2, "/books/:id", "200")
+	requests.Inc("/books", "500")
+	latency.Observe(0.0625, "/books")
+	latency.Observe(0.25, "/books")
+	latency.Observe(7, "/books")
+
+	collected := 0
+	registry.OnCollect(func(ctx context.Context) {
+		collected++
+		books.Set(42)
+	})
+
+	var out strings.Builder
+	if err := registry.Write(context.Background(), &out); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := `# HELP http_requests_total Peticiones atendidas
+# TYPE http_requests_total counter
+http_requests_total{route="/books",status="500"} 1
+http_requests_total{route="/books/:id",status="200"} 3
+# HELP http_request_duration_seconds Latencia
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{route="/books",le="0.1"} 1
+http_request_duration_seconds_bucket{route="/books",le="0.5"} 2
+http_request_duration_seconds_bucket{route="/books",le="+Inf"} 3
+http_request_duration_seconds_sum{route="/books"} 7.3125
+http_request_duration_seconds_count{route="/books"} 3
+# HELP library_books Libros del catálogo
+# TYPE library_books gauge
+library_books 42
+`
+	if out.String() != want {
+		t.Errorf("Write:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if collected != 1 {
+		t.Errorf("collectors ran %d times, want 1", collected)
+	}
+}
+
+func TestRegistryHandlerAndEscaping(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Counter("errors_total", "Errores\npor causa", "cause").Inc(`say "hi"\n`)
+
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Header().Get("Content-Type") != metrics.ContentType {
+		t.Errorf("Content-Type: got %q", rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `# HELP errors_total Errores\npor causa`) || !strings.Contains(body, `errors_total{cause="say \"hi\"\\n"} 1`) {
+		t.Errorf("escaping: got\n%s", body)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice: expected a panic")
+		}
+	}()
+	registry.Gauge("errors_total", "")
+}
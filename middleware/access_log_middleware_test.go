@@ -0,0 +1,108 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"library-api/logging"
+	"library-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAccessLogRouter - Router con ID de petición y access log en JSON; las
+// líneas quedan en out
+func newAccessLogRouter(t *testing.T, out *bytes.Buffer) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := logging.New(out, slog.LevelInfo, logging.FormatJSON)
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.AccessLog(logger, "/healthz"), middleware.Recovery())
+	router.GET("/books/:id", func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+		logger.InfoContext(c.Request.Context(), "consulta del store")
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+	router.GET("/healthz", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+	return router
+}
+
+// logLines - Líneas JSON registradas
+func logLines(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var out bytes.Buffer
+	router := newAccessLogRouter(t, &out)
+
+	rec := request(router, http.MethodGet, "/books/42", middleware.RequestIDHeader, "abc-123")
+	if got := rec.Header().Get(middleware.RequestIDHeader); got != "abc-123" {
+		t.Errorf("propagated ID: got %q, want abc-123", got)
+	}
+
+	lines := logLines(t, &out)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want the handler's and the access log:\n%s", len(lines), out.String())
+	}
+	for _, line := range lines {
+		if line[logging.RequestIDKey] != "abc-123" {
+			t.Errorf("line %v: missing request ID", line["msg"])
+		}
+	}
+	access := lines[1]
+	if access["route"] != "/books/:id" || access["status"] != float64(200) || access["user_id"] != "user-1" || access["latency_ms"] == nil {
+		t.Errorf("access log: got %v", access)
+	}
+
+	// Sin cabecera, o con una que no es segura para los logs, se genera otro
+	for _, header := range []string{"", "bad id\nlevel=ERROR", strings.Repeat("x", 200)} {
+		rec := request(router, http.MethodGet, "/books/42", middleware.RequestIDHeader, header)
+		if got := rec.Header().Get(middleware.RequestIDHeader); got == "" || got == header {
+			t.Errorf("X-Request-ID %q: got %q, want a generated ID", header, got)
+		}
+	}
+}
+
+func TestAccessLogLevels(t *testing.T) {
+	var out bytes.Buffer
+	router := newAccessLogRouter(t, &out)
+
+	// Las sondas salen con debug: no con nivel info
+	request(router, http.MethodGet, "/healthz")
+	if out.Len() != 0 {
+		t.Errorf("/healthz logged at info level: %s", out.String())
+	}
+
+	request(router, http.MethodGet, "/missing")
+	rec := request(router, http.MethodGet, "/panic")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Header().Get("Content-Type"), "problem+json") {
+		t.Errorf("panic: got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var levels []string
+	for _, line := range logLines(t, &out) {
+		levels = append(levels, line["level"].(string))
+	}
+	if got := strings.Join(levels, " "); got != "WARN ERROR" {
+		t.Errorf("levels: got %q, want the 404 at WARN and the panic's access log at ERROR", got)
+	}
+}
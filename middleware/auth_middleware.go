@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 	"library-api/auth"
+	"library-api/problem"
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,23 +12,20 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			abortWithProblem(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "Authorization header required"))
 			return
 		}
 		
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
-			c.Abort()
+			abortWithProblem(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "Authorization header format must be Bearer {token}"))
 			return
 		}
 		
 		token := parts[1]
 		claims, err := auth.ValidateToken(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			abortWithProblem(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token"))
 			return
 		}
 		
@@ -42,8 +40,7 @@ func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
+			abortWithProblem(c, problem.New(http.StatusForbidden, problem.CodeForbidden, "Admin access required"))
 			return
 		}
 		c.Next()
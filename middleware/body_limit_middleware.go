@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit - Limita el cuerpo de las peticiones a limit bytes: al superarlo
+// la lectura falla con *http.MaxBytesError, que ErrorHandler convierte en un
+// 413. Las rutas de exempt (como /books/import) aplican su propio límite
+func BodyLimit(limit int64, exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if limit > 0 && c.Request.Body != nil && !skip[c.FullPath()] {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"library-api/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig - Política CORS de la API
+type CORSConfig struct {
+	// AllowedOrigins - Orígenes exactos ("https://biblioteca.example.org") o
+	// patrones con * en el host o el puerto ("https://*.example.org",
+	// "http://localhost:*"). "*" admite cualquiera
+	AllowedOrigins []string
+	// AllowedHeaders - Cabeceras que el navegador puede enviar
+	AllowedHeaders []string
+	// ExposedHeaders - Cabeceras de la respuesta visibles para el JavaScript
+	ExposedHeaders []string
+	// AllowCredentials - Permite cookies y autenticación HTTP (no hace falta
+	// para el token Bearer). No se combina con "*"
+	AllowCredentials bool
+	// MaxAge - Tiempo que el navegador guarda la respuesta a un preflight
+	MaxAge time.Duration
+}
+
+// CORS - Aplica la política: las respuestas a orígenes permitidos llevan
+// Access-Control-Allow-Origin y los preflight (OPTIONS con
+// Access-Control-Request-Method) se responden aquí con los métodos que tiene
+// registrados la ruta pedida. routes es router.Routes: se consulta en el
+// primer preflight, cuando ya están todas las rutas
+func CORS(config CORSConfig, routes func() gin.RoutesInfo) (gin.HandlerFunc, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	origins, _ := compileOrigins(config.AllowedOrigins)
+
+	allowedHeaders := strings.Join(config.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	var once sync.Once
+	var table *routeMethods
+	methodsFor := func(path string) []string {
+		once.Do(func() { table = newRouteMethods(routes()) })
+		return table.lookup(path)
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// Con "*" la respuesta es igual para todos; si no, depende del origen
+		if !origins.any {
+			header.Add("Vary", "Origin")
+		}
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !origins.allows(origin) {
+			if preflight {
+				WriteProblem(c, problem.Newf(http.StatusForbidden, problem.CodeForbidden, "Origin %s is not allowed", origin))
+				c.Abort()
+				return
+			}
+			// Sin cabeceras CORS: el navegador no deja leer la respuesta
+			c.Next()
+			return
+		}
+
+		if origins.any {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposedHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposedHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		// Preflight de una ruta inexistente: sigue hasta el 404 de NoRoute
+		methods := methodsFor(c.Request.URL.Path)
+		if len(methods) == 0 {
+			c.Next()
+			return
+		}
+		header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if allowedHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowedHeaders)
+		}
+		if config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}, nil
+}
+
+// Validate - Orígenes bien formados y sin credenciales junto a "*"
+func (config CORSConfig) Validate() error {
+	origins, err := compileOrigins(config.AllowedOrigins)
+	if err != nil {
+		return err
+	}
+	if origins.any && config.AllowCredentials {
+		return fmt.Errorf("CORS: allowing credentials requires an explicit list of origins, not *")
+	}
+	return nil
+}
+
+// ==============================================
+// ORÍGENES PERMITIDOS
+// ==============================================
+
+// originMatcher - Orígenes exactos y patrones ya compilados
+type originMatcher struct {
+	any      bool
+	exact    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// wildcardPart - Lo que puede reemplazar un * de un patrón: etiquetas de un
+// host o un puerto (nunca /, @, ? ni #, que permitirían otro host)
+const wildcardPart = `[a-z0-9-]+(\.[a-z0-9-]+)*`
+
+// compileOrigins - Valida y prepara la lista de orígenes
+func compileOrigins(allowed []string) (*originMatcher, error) {
+	matcher := &originMatcher{exact: make(map[string]bool)}
+
+	for _, origin := range allowed {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "*":
+			matcher.any = true
+			continue
+		case !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://"):
+			return nil, fmt.Errorf("CORS: origin %q must start with http:// or https://", origin)
+		case strings.Contains(strings.SplitN(origin, "://", 2)[1], "/"):
+			return nil, fmt.Errorf("CORS: origin %q must not contain a path", origin)
+		}
+
+		if !strings.Contains(origin, "*") {
+			matcher.exact[origin] = true
+			continue
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, wildcardPart)
+		matcher.patterns = append(matcher.patterns, regexp.MustCompile("^"+pattern+"$"))
+	}
+
+	return matcher, nil
+}
+
+// allows - El origen está en la lista o coincide con algún patrón
+func (m *originMatcher) allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	if m.any || m.exact[origin] {
+		return true
+	}
+	for _, pattern := range m.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// ==============================================
+// MÉTODOS DE CADA RUTA
+// ==============================================
+
+// routeMethods - Métodos registrados por patrón de ruta de Gin
+type routeMethods struct {
+	patterns [][]string
+	methods  map[string][]string
+}
+
+func newRouteMethods(routes gin.RoutesInfo) *routeMethods {
+	table := &routeMethods{methods: make(map[string][]string)}
+	for _, route := range routes {
+		if _, ok := table.methods[route.Path]; !ok {
+			table.patterns = append(table.patterns, strings.Split(route.Path, "/"))
+		}
+		table.methods[route.Path] = append(table.methods[route.Path], route.Method)
+	}
+	for _, methods := range table.methods {
+		sort.Strings(methods)
+	}
+	return table
+}
+
+// lookup - Métodos de la ruta que atiende path. Como en Gin, los segmentos
+// fijos ganan a los parámetros (/books/export antes que /books/:id)
+func (t *routeMethods) lookup(path string) []string {
+	segments := strings.Split(path, "/")
+
+	best, bestScore := "", -1
+	for _, pattern := range t.patterns {
+		score, ok := matchRoute(pattern, segments)
+		if ok && score > bestScore {
+			best, bestScore = strings.Join(pattern, "/"), score
+		}
+	}
+	if bestScore < 0 {
+		return nil
+	}
+	return t.methods[best]
+}
+
+// matchRoute - Si los segmentos corresponden al patrón y cuántos son fijos
+func matchRoute(pattern, segments []string) (int, bool) {
+	score := 0
+	for i, part := range pattern {
+		if strings.HasPrefix(part, "*") {
+			return score, true
+		}
+		if i >= len(segments) {
+			return 0, false
+		}
+		switch {
+		case strings.HasPrefix(part, ":"):
+			if segments[i] == "" {
+				return 0, false
+			}
+		case part == segments[i]:
+			score++
+		default:
+			return 0, false
+		}
+	}
+	return score, len(pattern) == len(segments)
+}
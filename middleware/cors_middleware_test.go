@@ -0,0 +1,161 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"library-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCORSRouter - Rutas de libros con la política indicada
+func newCORSRouter(t *testing.T, config middleware.CORSConfig) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	cors, err := middleware.CORS(config, router.Routes)
+	if err != nil {
+		t.Fatalf("CORS: %v", err)
+	}
+	router.Use(cors, middleware.ErrorHandler())
+
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) }
+	router.GET("/books", ok)
+	router.POST("/books", ok)
+	router.GET("/books/:id", ok)
+	router.PUT("/books/:id", ok)
+	router.PATCH("/books/:id", ok)
+	router.DELETE("/books/:id", ok)
+	router.GET("/books/export", ok)
+	router.POST("/books/export", ok)
+	return router
+}
+
+// kioskPolicy - El frontend detrás de nginx y la app del kiosco
+var kioskPolicy = middleware.CORSConfig{
+	AllowedOrigins: []string{"http://localhost:3000", "https://*.kiosk.example.org"},
+	AllowedHeaders: []string{"Content-Type", "Authorization"},
+	ExposedHeaders: []string{"Content-Disposition"},
+	MaxAge:         10 * time.Minute,
+}
+
+func request(router *gin.Engine, method, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSAllowedAndDeniedOrigins(t *testing.T) {
+	router := newCORSRouter(t, kioskPolicy)
+
+	cases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"http://localhost:3000", true},
+		{"https://lobby.kiosk.example.org", true},
+		{"https://a.b.kiosk.example.org", true},
+		{"HTTPS://Lobby.Kiosk.Example.org", true},
+		{"http://localhost:3001", false},
+		{"http://lobby.kiosk.example.org", false},
+		{"https://kiosk.example.org.evil.test", false},
+		{"https://evil.test/.kiosk.example.org", false},
+		{"null", false},
+	}
+	for _, tc := range cases {
+		rec := request(router, http.MethodGet, "/books", "Origin", tc.origin)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET from %s: status %d", tc.origin, rec.Code)
+		}
+		got := rec.Header().Get("Access-Control-Allow-Origin")
+		if tc.allowed && (got != tc.origin || rec.Header().Get("Access-Control-Expose-Headers") != "Content-Disposition") {
+			t.Errorf("GET from %s: got Allow-Origin %q, Expose-Headers %q", tc.origin, got, rec.Header().Get("Access-Control-Expose-Headers"))
+		}
+		if !tc.allowed && got != "" {
+			t.Errorf("GET from denied %s: got Allow-Origin %q", tc.origin, got)
+		}
+		if !strings.Contains(strings.Join(rec.Header().Values("Vary"), ","), "Origin") {
+			t.Errorf("GET from %s: missing Vary: Origin", tc.origin)
+		}
+		if rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("GET from %s: credentials allowed without AllowCredentials", tc.origin)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	router := newCORSRouter(t, kioskPolicy)
+
+	cases := []struct {
+		path, methods string
+	}{
+		{"/books", "GET, POST"},
+		{"/books/42", "DELETE, GET, PATCH, PUT"},
+		{"/books/export", "GET, POST"},
+	}
+	for _, tc := range cases {
+		rec := request(router, http.MethodOptions, tc.path,
+			"Origin", "http://localhost:3000", "Access-Control-Request-Method", "PATCH")
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("preflight %s: status %d", tc.path, rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tc.methods {
+			t.Errorf("preflight %s: got methods %q, want %q", tc.path, got, tc.methods)
+		}
+		if rec.Header().Get("Access-Control-Max-Age") != "600" || rec.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" {
+			t.Errorf("preflight %s: got headers %v", tc.path, rec.Header())
+		}
+	}
+
+	// Origen no permitido: 403 sin cabeceras CORS
+	rec := request(router, http.MethodOptions, "/books",
+		"Origin", "https://evil.test", "Access-Control-Request-Method", "POST")
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight from denied origin: got %d %v", rec.Code, rec.Header())
+	}
+
+	// Un OPTIONS que no es preflight ya no se responde con 204
+	if rec := request(router, http.MethodOptions, "/books"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("plain OPTIONS: got %d, want 405", rec.Code)
+	}
+
+	// Preflight de una ruta inexistente
+	rec = request(router, http.MethodOptions, "/nothing",
+		"Origin", "http://localhost:3000", "Access-Control-Request-Method", "GET")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("preflight of an unknown route: got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestCORSWildcardAndCredentials(t *testing.T) {
+	router := newCORSRouter(t, middleware.CORSConfig{AllowedOrigins: []string{"*"}})
+	rec := request(router, http.MethodGet, "/books", "Origin", "https://anywhere.test")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Vary") != "" {
+		t.Errorf("wildcard: got %v", rec.Header())
+	}
+
+	if _, err := middleware.CORS(middleware.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, nil); err == nil {
+		t.Error("* with credentials: expected an error")
+	}
+	for _, origin := range []string{"localhost:3000", "https://app.example.org/path"} {
+		if _, err := middleware.CORS(middleware.CORSConfig{AllowedOrigins: []string{origin}}, nil); err == nil {
+			t.Errorf("origin %q: expected an error", origin)
+		}
+	}
+
+	router = newCORSRouter(t, middleware.CORSConfig{AllowedOrigins: []string{"http://localhost:3000"}, AllowCredentials: true})
+	rec = request(router, http.MethodGet, "/books", "Origin", "http://localhost:3000")
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" || rec.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Errorf("credentials: got %v", rec.Header())
+	}
+}
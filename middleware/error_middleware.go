@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"library-api/i18n"
+	"library-api/problem"
+	"library-api/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest - El cliente cerró la conexión antes de la
+// respuesta (código no estándar usado por nginx)
+const statusClientClosedRequest = 499
+
+// ErrorHandler - Responde con problem+json el último error que registró el
+// handler con c.Error. Debe ir antes que las rutas y que AuthMiddleware
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+
+		// Si el cliente ya se fue no hay a quién responder
+		if ctxErr := c.Request.Context().Err(); ctxErr != nil && !storage.IsTimeout(ctxErr) {
+			c.Status(statusClientClosedRequest)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+
+		WriteProblem(c, problem.From(err))
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			slog.ErrorContext(c.Request.Context(), "Error en la petición",
+				"method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+		}
+	}
+}
+
+// Recovery - Convierte un panic de un handler en un 500 problem+json y lo
+// registra con su stack y el ID de la petición
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		slog.ErrorContext(c.Request.Context(), "Panic en la petición",
+			"method", c.Request.Method, "path", c.Request.URL.Path,
+			"panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		if !c.Writer.Written() {
+			abortWithProblem(c, problem.From(fmt.Errorf("panic: %v", recovered)))
+			return
+		}
+		c.Abort()
+	})
+}
+
+// WriteProblem - Escribe un problema traducido al idioma de la petición, con
+// su Content-Type y la ruta como instance
+func WriteProblem(c *gin.Context, p *problem.Problem) {
+	p.Localize(i18n.FromContext(c))
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	// El middleware UTF-8 de main.go ya fijó Content-Type JSON: se reemplaza
+	c.Header("Content-Type", problem.ContentType)
+	c.JSON(p.Status, p)
+}
+
+// abortWithProblem - Corta la cadena de handlers respondiendo con un problema
+func abortWithProblem(c *gin.Context, p *problem.Problem) {
+	c.Abort()
+	WriteProblem(c, p)
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"library-api/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale - Negocia el idioma de la respuesta con Accept-Language y lo deja en
+// el contexto (i18n.FromContext) para los mensajes y los datos de los libros
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(i18n.ContextKey, lang)
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
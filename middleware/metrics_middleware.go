@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"library-api/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute - Etiqueta route de las peticiones sin ruta (404)
+const unmatchedRoute = "unmatched"
+
+// HTTPMetrics - Cuenta las peticiones y mide su latencia por método y ruta.
+// La ruta es el patrón de Gin (/books/:id), no la URL: así las series no
+// crecen con cada ID
+func HTTPMetrics(registry *metrics.Registry) gin.HandlerFunc {
+	requests := registry.Counter("http_requests_total", "Peticiones HTTP atendidas", "method", "route", "status")
+	duration := registry.Histogram("http_request_duration_seconds", "Latencia de las peticiones HTTP", nil, "method", "route")
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := metricMethod(c.Request.Method)
+		requests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+		duration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
+
+// metricMethod - Los métodos estándar tal cual; cualquier otro, OTHER (un
+// cliente podría inventar métodos y crear series sin límite)
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
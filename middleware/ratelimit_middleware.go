@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"library-api/auth"
+	"library-api/problem"
+	"library-api/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader - Cabecera con la API key de un cliente registrado
+const APIKeyHeader = "X-API-Key"
+
+// RateLimitConfig - Cuotas de la API
+type RateLimitConfig struct {
+	// Default - Cuota de las rutas sin regla propia, por cliente (Requests
+	// 0: sin límite)
+	Default ratelimit.Limit
+	// Rules - Cuotas propias de algunas rutas (búsquedas externas, login...)
+	Rules []ratelimit.Rule
+	// APIKeys - API keys conocidas → nombre del cliente. Una key desconocida
+	// no cuenta: si no, bastaría con inventar keys para tener cuota nueva
+	APIKeys map[string]string
+}
+
+// RateLimit - Limita las peticiones de cada cliente con token bucket.
+// Responde con las cabeceras RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset y RateLimit-Policy y, al agotar la cuota, con un 429 y
+// Retry-After. Si el store falla la petición pasa (mejor eso que tirar la API)
+func RateLimit(store ratelimit.Store, config RateLimitConfig) gin.HandlerFunc {
+	rules := make(map[string]ratelimit.Rule, len(config.Rules))
+	for _, rule := range config.Rules {
+		rules[rule.Method+" "+rule.Path] = rule
+	}
+
+	return func(c *gin.Context) {
+		policy, limit, by := "default", config.Default, ratelimit.ByClient
+		route := c.Request.Method + " " + c.FullPath()
+		if rule, ok := rules[route]; ok {
+			policy, limit, by = route, rule.Limit, rule.By
+		}
+		if limit.Requests <= 0 {
+			c.Next()
+			return
+		}
+
+		key := policy + "|" + clientIdentity(c, by, config.APIKeys)
+		result, err := store.Take(c.Request.Context(), key, limit)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Error en el limitador de peticiones, se deja pasar", "key", key, "error", err)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		header.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(ceilSeconds(limit.Per)))
+
+		if !result.Allowed {
+			retryAfter := max(ceilSeconds(result.RetryAfter), 1)
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
+			WriteProblem(c, problem.Newf(http.StatusTooManyRequests, problem.CodeRateLimited,
+				"Too many requests, retry in %d seconds", retryAfter).With("retry_after", retryAfter))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// clientIdentity - Clave del cliente: una API key conocida, el usuario de un
+// token válido o la IP (con by == ratelimit.ByIP, siempre la IP)
+func clientIdentity(c *gin.Context, by string, apiKeys map[string]string) string {
+	if by != ratelimit.ByIP {
+		if name, ok := apiKeys[c.GetHeader(APIKeyHeader)]; ok {
+			return "key:" + name
+		}
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			if claims, err := auth.ValidateToken(token); err == nil {
+				return "user:" + claims.UserID
+			}
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds - Segundos enteros, redondeando hacia arriba
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
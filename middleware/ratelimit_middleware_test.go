@@ -0,0 +1,118 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"library-api/auth"
+	"library-api/middleware"
+	"library-api/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRateLimitRouter - Búsqueda externa con regla propia, login por IP y el
+// resto con la cuota por defecto
+func newRateLimitRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	search, _ := ratelimit.ParseRule("GET /api/external/search=2/1m")
+	login, _ := ratelimit.ParseRule("POST /login=1/1m@ip")
+
+	router := gin.New()
+	router.Use(middleware.Locale(), middleware.ErrorHandler(), middleware.RateLimit(ratelimit.NewMemoryStore(), middleware.RateLimitConfig{
+		Default: ratelimit.Limit{Requests: 3, Per: time.Minute},
+		Rules:   []ratelimit.Rule{search, login},
+		APIKeys: map[string]string{"s3cr3t": "kiosk"},
+	}))
+
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) }
+	router.GET("/books", ok)
+	router.GET("/api/external/search", ok)
+	router.POST("/login", ok)
+	return router
+}
+
+func TestRateLimitHeadersAndRetryAfter(t *testing.T) {
+	router := newRateLimitRouter(t)
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := request(router, http.MethodGet, "/api/external/search?q=go")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %d: status %d", i+1, rec.Code)
+		}
+		h := rec.Header()
+		if h.Get("RateLimit-Limit") != "2" || h.Get("RateLimit-Remaining") != remaining || h.Get("RateLimit-Policy") != "2;w=60" {
+			t.Errorf("search %d: got headers %v", i+1, h)
+		}
+	}
+
+	rec := request(router, http.MethodGet, "/api/external/search?q=go", "Accept-Language", "es")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: status %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" || rec.Header().Get("RateLimit-Reset") != "60" {
+		t.Errorf("over the limit: got headers %v", rec.Header())
+	}
+	var body struct {
+		Code       string `json:"code"`
+		Detail     string `json:"detail"`
+		RetryAfter int    `json:"retry_after"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("problem body: %v", err)
+	}
+	if body.Code != "rate_limited" || body.RetryAfter != 30 || body.Detail != "Demasiadas peticiones, reintenta en 30 segundos" {
+		t.Errorf("problem body: got %+v", body)
+	}
+
+	// Las demás rutas tienen la cuota por defecto, aparte
+	if rec := request(router, http.MethodGet, "/books"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "3" {
+		t.Errorf("default policy: got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	router := newRateLimitRouter(t)
+	token, err := auth.GenerateToken("7", "user")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	drain := func(header ...string) {
+		for i := 0; i < 2; i++ {
+			request(router, http.MethodGet, "/api/external/search", header...)
+		}
+	}
+	blocked := func(header ...string) bool {
+		return request(router, http.MethodGet, "/api/external/search", header...).Code == http.StatusTooManyRequests
+	}
+
+	// Anónimo: por IP. El usuario del token y la API key tienen cuota propia
+	drain()
+	if !blocked() {
+		t.Fatal("anonymous: expected 429")
+	}
+	if blocked("Authorization", "Bearer "+token) {
+		t.Error("authenticated user: limited by the IP bucket")
+	}
+	if blocked("X-API-Key", "s3cr3t") {
+		t.Error("API key: limited by the IP bucket")
+	}
+
+	// Un token inválido o una key desconocida cuentan como la IP
+	if !blocked("Authorization", "Bearer nope") || !blocked("X-API-Key", "invented") {
+		t.Error("invalid credentials: expected the IP bucket")
+	}
+
+	// Las reglas @ip ignoran el token
+	if rec := request(router, http.MethodPost, "/login", "Authorization", "Bearer "+token); rec.Code != http.StatusOK {
+		t.Fatalf("login: status %d", rec.Code)
+	}
+	if rec := request(router, http.MethodPost, "/login"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("login by IP: got %d, want 429", rec.Code)
+	}
+}
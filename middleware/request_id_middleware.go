@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"library-api/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader - Cabecera con el ID de la petición (se acepta la del
+// cliente o del proxy y se devuelve siempre)
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey - Clave del ID de la petición en el contexto de Gin
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength - Un ID más largo se reemplaza por uno nuevo
+const maxRequestIDLength = 128
+
+// RequestID - Toma el X-Request-ID de la petición (o genera un UUID) y lo deja
+// en el contexto de Gin, en el de la petición (los logs del store y de los
+// proveedores lo incluyen) y en la respuesta. Debe ir primero
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		c.Set(RequestIDKey, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID - Solo letras, dígitos y -_.:/+= (el ID va a los logs: nada
+// de espacios, comillas ni saltos de línea)
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':' || r == '/' || r == '+' || r == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
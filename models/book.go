@@ -1,6 +1,10 @@
 package models
 
 import (
+	"database/sql/driver"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -15,6 +19,47 @@ type Book struct {
 	Available   bool      `json:"available" db:"available"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// Datos bibliográficos extendidos
+	Authors   []string `json:"authors" db:"-"`
+	Subjects  []string `json:"subjects" db:"-"`
+	Publisher string   `json:"publisher" db:"publisher"`
+	Language  string   `json:"language" db:"language"`
+	PageCount int      `json:"page_count" db:"page_count"`
+	Edition   string   `json:"edition" db:"edition"`
+	CoverURL  string   `json:"cover_url" db:"cover_url"`
+
+	// Identificadores externos
+	GoogleID string `json:"google_id" db:"google_id"`
+	OLID     string `json:"olid" db:"olid"`
+	LCCN     string `json:"lccn" db:"lccn"`
+	OCLC     string `json:"oclc" db:"oclc"`
+
+	// Título y descripción en otros idiomas (ver Localize)
+	Translations BookTranslations `json:"translations,omitempty" db:"translations"`
+}
+
+// BookTranslation - Título y descripción de un libro en otro idioma
+type BookTranslation struct {
+	Title       string `json:"title,omitempty" binding:"max=300"`
+	Description string `json:"description,omitempty" binding:"max=5000"`
+}
+
+// BookTranslations - Traducciones por etiqueta de idioma (es, en, pt-BR...)
+type BookTranslations map[string]BookTranslation
+
+// Author - Autor registrado en el catálogo
+type Author struct {
+	ID        string `json:"id" db:"id"`
+	Name      string `json:"name" db:"name"`
+	BookCount int    `json:"book_count" db:"book_count"`
+}
+
+// Subject - Materia (tema o género) registrada en el catálogo
+type Subject struct {
+	ID        string `json:"id" db:"id"`
+	Name      string `json:"name" db:"name"`
+	BookCount int    `json:"book_count" db:"book_count"`
 }
 
 type Loan struct {
@@ -26,23 +71,112 @@ type Loan struct {
 	Returned   bool       `json:"returned" db:"returned"`
 }
 
+// CreateBookRequest - Datos de un libro nuevo. Las reglas isbn, pubyear y
+// genre están en el paquete validation
 type CreateBookRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Author      string `json:"author" binding:"required"`
-	ISBN        string `json:"isbn" binding:"required"`
-	Published   int    `json:"published"`
-	Genre       string `json:"genre"`
-	Description string `json:"description"`
+	Title       string   `json:"title" binding:"required,max=300"`
+	Author      string   `json:"author" binding:"required_without=Authors,max=300"`
+	Authors     []string `json:"authors" binding:"max=20,dive,required,max=200"`
+	ISBN        string   `json:"isbn" binding:"required,isbn"`
+	Published   int      `json:"published" binding:"omitempty,pubyear"`
+	Genre       string   `json:"genre" binding:"omitempty,max=300,genre"`
+	Subjects    []string `json:"subjects" binding:"max=20,dive,genre"`
+	Description string   `json:"description" binding:"max=5000"`
+	Publisher   string   `json:"publisher" binding:"max=200"`
+	Language    string   `json:"language" binding:"max=35"`
+	PageCount   int      `json:"page_count" binding:"omitempty,min=1,max=50000"`
+	Edition     string   `json:"edition" binding:"max=100"`
+	CoverURL    string   `json:"cover_url" binding:"omitempty,url,max=2048"`
+	GoogleID    string   `json:"google_id" binding:"max=50"`
+	OLID        string   `json:"olid" binding:"max=50"`
+	LCCN        string   `json:"lccn" binding:"max=50"`
+	OCLC        string   `json:"oclc" binding:"max=50"`
+
+	Translations BookTranslations `json:"translations" binding:"max=20,dive,keys,bcp47_language_tag,endkeys"`
 }
 
+// UpdateBookRequest - Actualización con PUT: los campos vacíos no se tocan
+// (para borrar un campo hay que usar PATCH con null)
 type UpdateBookRequest struct {
-	Title       string `json:"title"`
-	Author      string `json:"author"`
-	ISBN        string `json:"isbn"`
-	Published   int    `json:"published"`
-	Genre       string `json:"genre"`
-	Description string `json:"description"`
-	Available   *bool  `json:"available"`
+	Title       string   `json:"title" binding:"max=300"`
+	Author      string   `json:"author" binding:"max=300"`
+	Authors     []string `json:"authors" binding:"max=20,dive,required,max=200"`
+	ISBN        string   `json:"isbn" binding:"omitempty,isbn"`
+	Published   int      `json:"published" binding:"omitempty,pubyear"`
+	Genre       string   `json:"genre" binding:"omitempty,max=300,genre"`
+	Subjects    []string `json:"subjects" binding:"max=20,dive,genre"`
+	Description string   `json:"description" binding:"max=5000"`
+	Publisher   string   `json:"publisher" binding:"max=200"`
+	Language    string   `json:"language" binding:"max=35"`
+	PageCount   int      `json:"page_count" binding:"omitempty,min=1,max=50000"`
+	Edition     string   `json:"edition" binding:"max=100"`
+	CoverURL    string   `json:"cover_url" binding:"omitempty,url,max=2048"`
+	GoogleID    string   `json:"google_id" binding:"max=50"`
+	OLID        string   `json:"olid" binding:"max=50"`
+	LCCN        string   `json:"lccn" binding:"max=50"`
+	OCLC        string   `json:"oclc" binding:"max=50"`
+	Available   *bool    `json:"available"`
+
+	// Translations - Si se envía reemplaza todas las traducciones
+	Translations BookTranslations `json:"translations" binding:"max=20,dive,keys,bcp47_language_tag,endkeys"`
+}
+
+// BookDocument - Campos editables de un libro: el documento JSON sobre el que
+// se aplica un PATCH (JSON Merge Patch) antes de validarlo
+type BookDocument struct {
+	CreateBookRequest
+	Available bool `json:"available"`
+}
+
+// Document - Campos editables del libro como BookDocument
+func (b Book) Document() BookDocument {
+	return BookDocument{
+		CreateBookRequest: CreateBookRequest{
+			Title:       b.Title,
+			Author:      b.Author,
+			Authors:     b.Authors,
+			ISBN:        b.ISBN,
+			Published:   b.Published,
+			Genre:       b.Genre,
+			Subjects:    b.Subjects,
+			Description: b.Description,
+			Publisher:   b.Publisher,
+			Language:    b.Language,
+			PageCount:   b.PageCount,
+			Edition:     b.Edition,
+			CoverURL:    b.CoverURL,
+			GoogleID:    b.GoogleID,
+			OLID:        b.OLID,
+			LCCN:        b.LCCN,
+			OCLC:        b.OCLC,
+
+			Translations: b.Translations,
+		},
+		Available: b.Available,
+	}
+}
+
+// ApplyDocument - Reemplaza los campos editables del libro por los del documento
+func (b *Book) ApplyDocument(doc BookDocument) {
+	b.Title = doc.Title
+	b.Author = doc.Author
+	b.Authors = doc.Authors
+	b.ISBN = doc.ISBN
+	b.Published = doc.Published
+	b.Genre = doc.Genre
+	b.Subjects = doc.Subjects
+	b.Description = doc.Description
+	b.Publisher = doc.Publisher
+	b.Language = doc.Language
+	b.PageCount = doc.PageCount
+	b.Edition = doc.Edition
+	b.CoverURL = doc.CoverURL
+	b.GoogleID = doc.GoogleID
+	b.OLID = doc.OLID
+	b.LCCN = doc.LCCN
+	b.OCLC = doc.OCLC
+	b.Translations = doc.Translations
+	b.Available = doc.Available
 }
 
 type LoanRequest struct {
@@ -55,5 +189,142 @@ type LoanWithBook struct {
 	Book Book `json:"book"`
 }
 
+// NormalizeContributors - Mantiene sincronizados los campos planos (Author, Genre)
+// con las listas de autores y materias. Las listas tienen prioridad; si están
+// vacías se derivan de los campos planos separados por comas.
+func (b *Book) NormalizeContributors() {
+	if len(b.Authors) > 0 {
+		b.Authors = cleanList(b.Authors)
+		b.Author = strings.Join(b.Authors, ", ")
+	} else {
+		b.Authors = SplitList(b.Author)
+	}
+
+	if len(b.Subjects) > 0 {
+		b.Subjects = cleanList(b.Subjects)
+		b.Genre = strings.Join(b.Subjects, ", ")
+	} else {
+		b.Subjects = SplitList(b.Genre)
+	}
+}
+
+// FillMissing - Completa los campos vacíos del libro con los de otro registro
+// (por ejemplo, datos de una API externa). Devuelve true si cambió algo.
+func (b *Book) FillMissing(other Book) bool {
+	changed := false
+
+	fillString := func(dst *string, src string) {
+		if *dst == "" && src != "" {
+			*dst = src
+			changed = true
+		}
+	}
+
+	fillString(&b.Title, other.Title)
+	fillString(&b.Description, other.Description)
+	fillString(&b.Publisher, other.Publisher)
+	fillString(&b.Language, other.Language)
+	fillString(&b.Edition, other.Edition)
+	fillString(&b.CoverURL, other.CoverURL)
+	fillString(&b.GoogleID, other.GoogleID)
+	fillString(&b.OLID, other.OLID)
+	fillString(&b.LCCN, other.LCCN)
+	fillString(&b.OCLC, other.OCLC)
+
+	if b.Author == "" && len(b.Authors) == 0 && (other.Author != "" || len(other.Authors) > 0) {
+		b.Author = other.Author
+		b.Authors = other.Authors
+		changed = true
+	}
+	if b.Genre == "" && len(b.Subjects) == 0 && (other.Genre != "" || len(other.Subjects) > 0) {
+		b.Genre = other.Genre
+		b.Subjects = other.Subjects
+		changed = true
+	}
+	if b.Published == 0 && other.Published > 0 {
+		b.Published = other.Published
+		changed = true
+	}
+	if b.PageCount == 0 && other.PageCount > 0 {
+		b.PageCount = other.PageCount
+		changed = true
+	}
+
+	return changed
+}
+
+// Localize - Reemplaza el título y la descripción por los de la traducción al
+// idioma indicado (es, en...). Una etiqueta regional (es-AR) sirve para su
+// idioma base; los campos sin traducir conservan el original
+func (b *Book) Localize(lang string) {
+	translation, ok := b.Translations.Lookup(lang)
+	if !ok {
+		return
+	}
+	if translation.Title != "" {
+		b.Title = translation.Title
+	}
+	if translation.Description != "" {
+		b.Description = translation.Description
+	}
+}
+
+// Lookup - Traducción exacta al idioma o, si no hay, la de una variante
+// regional del mismo idioma base
+func (t BookTranslations) Lookup(lang string) (BookTranslation, bool) {
+	if translation, ok := t[lang]; ok {
+		return translation, true
+	}
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	for _, tag := range slices.Sorted(maps.Keys(t)) {
+		if tagBase, _, _ := strings.Cut(strings.ToLower(tag), "-"); tagBase == base {
+			return t[tag], true
+		}
+	}
+	return BookTranslation{}, false
+}
+
+// Value - Implementa driver.Valuer (columna JSON)
+func (t BookTranslations) Value() (driver.Value, error) {
+	if t == nil {
+		t = BookTranslations{}
+	}
+	return marshalJSONColumn(t)
+}
+
+// Scan - Implementa sql.Scanner
+func (t *BookTranslations) Scan(src interface{}) error {
+	return unmarshalJSONColumn(src, t)
+}
+
+// SplitList - Separa una cadena por comas eliminando vacíos y duplicados
+func SplitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return []string{}
+	}
+	return cleanList(strings.Split(s, ","))
+}
+
+func cleanList(items []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		key := strings.ToLower(item)
+		if item == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, item)
+	}
+	return result
+}
+
 // NOTA: Eliminamos la importación de uuid de aquí
 // porque solo se usa en storage/memory_store.go
+
+// ExportBooksRequest - Libros a incluir en una bibliografía
+type ExportBooksRequest struct {
+	IDs    []string `json:"ids" binding:"required,min=1,max=1000"`
+	Format string   `json:"format"` // bibtex (por defecto), ris, csl-json, marcxml...
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// DeletedBook - Constancia de un libro eliminado (registro "deleted" de OAI-PMH)
+type DeletedBook struct {
+	ID        string    `json:"id" db:"id"`
+	ISBN      string    `json:"isbn" db:"isbn"`
+	Title     string    `json:"title" db:"title"`
+	DeletedAt time.Time `json:"deleted_at" db:"deleted_at"`
+}
+
+// BookChange - Alta, modificación o baja de un libro en el catálogo
+type BookChange struct {
+	ID        string    `json:"id"`
+	Datestamp time.Time `json:"datestamp"`
+	Deleted   bool      `json:"deleted"`
+	Book      *Book     `json:"book,omitempty"` // nil si el libro fue eliminado
+}
+
+// BookChangeQuery - Filtro para recorrer los cambios del catálogo en orden
+// (datestamp, id). After/AfterID continúan un recorrido anterior.
+type BookChangeQuery struct {
+	From    *time.Time // inclusivo
+	Before  *time.Time // exclusivo
+	After   *time.Time
+	AfterID string
+	Limit   int
+}
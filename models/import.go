@@ -0,0 +1,48 @@
+package models
+
+// Estados posibles de un elemento importado
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// ImportItemResult - Resultado de importar un libro individual
+type ImportItemResult struct {
+	Status string `json:"status"`
+	ISBN   string `json:"isbn"`
+	Title  string `json:"title"`
+	Book   *Book  `json:"book,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportInvalid - Fila descartada por errores de validación
+const ImportInvalid = "invalid"
+
+// CatalogImportRow - Resultado de una fila de un archivo de catálogo
+type CatalogImportRow struct {
+	Row    int      `json:"row"`
+	Status string   `json:"status"`
+	ISBN   string   `json:"isbn,omitempty"`
+	Title  string   `json:"title,omitempty"`
+	BookID string   `json:"book_id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// CatalogImportReport - Resumen de la importación de un archivo de catálogo
+type CatalogImportReport struct {
+	DryRun  bool               `json:"dry_run"`
+	Format  string             `json:"format"`
+	Rows    int                `json:"rows"`
+	Invalid int                `json:"invalid"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Results []CatalogImportRow `json:"results"`
+
+	// FailedBatches - Lotes que no se pudieron guardar (sus filas cuentan en
+	// Failed): la importación se guarda en lotes independientes
+	FailedBatches int `json:"failed_batches,omitempty"`
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Tipos de trabajo
+const (
+	JobTypeBulkImport = "bulk_import"
+)
+
+// Estados de un trabajo
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job - Trabajo en segundo plano con su progreso persistido
+type Job struct {
+	ID              string        `json:"id" db:"id"`
+	Type            string        `json:"type" db:"type"`
+	Status          string        `json:"status" db:"status"`
+	Params          JobParams     `json:"params" db:"params"`
+	Total           int           `json:"total" db:"total"`
+	Processed       int           `json:"processed" db:"processed"`
+	Created         int           `json:"created" db:"created_count"`
+	Updated         int           `json:"updated" db:"updated_count"`
+	Skipped         int           `json:"skipped" db:"skipped_count"`
+	Failed          int           `json:"failed" db:"failed_count"`
+	ItemErrors      JobItemErrors `json:"item_errors" db:"item_errors"`
+	Error           string        `json:"error,omitempty" db:"error"`
+	CancelRequested bool          `json:"cancel_requested" db:"cancel_requested"`
+	CreatedBy       string        `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at" db:"updated_at"`
+	StartedAt       *time.Time    `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt      *time.Time    `json:"finished_at,omitempty" db:"finished_at"`
+
+	// Items - Elementos a importar, resueltos en la primera ejecución para
+	// que al reanudar no se repita la búsqueda externa (nil: sin resolver).
+	// No salen en la API ni los guarda UpdateJob: se escriben una sola vez
+	// con Store.SaveJobItems y se leen con Store.GetJobItems.
+	Items JobItems `json:"-" db:"items"`
+}
+
+// Finished - Indica si el trabajo terminó (con o sin éxito)
+func (j Job) Finished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// JobParams - Parámetros de una importación masiva
+type JobParams struct {
+	Source string   `json:"source"`
+	Query  string   `json:"query,omitempty"`
+	ISBNs  []string `json:"isbns,omitempty"`
+	Limit  int      `json:"limit,omitempty"`
+	Filter string   `json:"filter,omitempty"`
+}
+
+// JobItemError - Error de un elemento individual dentro de un trabajo
+type JobItemError struct {
+	Index int    `json:"index"`
+	ISBN  string `json:"isbn,omitempty"`
+	Title string `json:"title,omitempty"`
+	Error string `json:"error"`
+}
+
+// JobItemErrors - Lista de errores guardada como JSON
+type JobItemErrors []JobItemError
+
+// JobItem - Elemento de una importación masiva: un ISBN a consultar o un
+// libro ya obtenido de la búsqueda
+type JobItem struct {
+	ISBN string `json:"isbn,omitempty"`
+	Book *Book  `json:"book,omitempty"`
+}
+
+// JobItems - Lista de elementos guardada como JSON (NULL si aún no se
+// resolvió; una lista vacía es una búsqueda sin resultados)
+type JobItems []JobItem
+
+// BulkImportRequest - Solicitud de importación masiva en segundo plano
+type BulkImportRequest struct {
+	Source string   `json:"source" binding:"required"`
+	Query  string   `json:"query" binding:"required_without=ISBNs"`
+	ISBNs  []string `json:"isbns" binding:"required_without=Query"`
+	Limit  int      `json:"limit"`
+	Filter string   `json:"filter"`
+}
+
+// ==============================================
+// SERIALIZACIÓN JSON PARA BASE DE DATOS
+// ==============================================
+
+// Value - Implementa driver.Valuer
+func (p JobParams) Value() (driver.Value, error) {
+	return marshalJSONColumn(p)
+}
+
+// Scan - Implementa sql.Scanner
+func (p *JobParams) Scan(src interface{}) error {
+	return unmarshalJSONColumn(src, p)
+}
+
+// Value - Implementa driver.Valuer
+func (e JobItemErrors) Value() (driver.Value, error) {
+	if e == nil {
+		e = JobItemErrors{}
+	}
+	return marshalJSONColumn(e)
+}
+
+// Scan - Implementa sql.Scanner
+func (e *JobItemErrors) Scan(src interface{}) error {
+	return unmarshalJSONColumn(src, e)
+}
+
+// Value - Implementa driver.Valuer
+func (items JobItems) Value() (driver.Value, error) {
+	if items == nil {
+		return nil, nil
+	}
+	return marshalJSONColumn(items)
+}
+
+// Scan - Implementa sql.Scanner
+func (items *JobItems) Scan(src interface{}) error {
+	*items = nil
+	return unmarshalJSONColumn(src, items)
+}
+
+func marshalJSONColumn(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func unmarshalJSONColumn(src interface{}, dst interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), dst)
+	case []byte:
+		return json.Unmarshal(data, dst)
+	default:
+		return fmt.Errorf("unsupported type %T for JSON column", src)
+	}
+}
@@ -0,0 +1,70 @@
+package oai
+
+import (
+	"strconv"
+
+	"library-api/models"
+)
+
+// DublinCore - Registro oai_dc (Dublin Core simple, 15 elementos)
+type DublinCore struct {
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Title          []string `xml:"dc:title"`
+	Creator        []string `xml:"dc:creator"`
+	Subject        []string `xml:"dc:subject"`
+	Description    []string `xml:"dc:description"`
+	Publisher      []string `xml:"dc:publisher"`
+	Date           []string `xml:"dc:date"`
+	Type           []string `xml:"dc:type"`
+	Format         []string `xml:"dc:format"`
+	Identifier     []string `xml:"dc:identifier"`
+	Language       []string `xml:"dc:language"`
+}
+
+// BookToDublinCore - Construye el registro oai_dc de un libro
+func BookToDublinCore(book models.Book) *DublinCore {
+	book.NormalizeContributors()
+
+	dc := &DublinCore{
+		XmlnsOAIDC:     oaiDCNamespace,
+		XmlnsDC:        dcNamespace,
+		XmlnsXSI:       xsiNamespace,
+		SchemaLocation: oaiDCNamespace + " " + oaiDCSchema,
+		Title:          nonEmpty(book.Title),
+		Creator:        book.Authors,
+		Subject:        book.Subjects,
+		Description:    nonEmpty(book.Description),
+		Publisher:      nonEmpty(book.Publisher),
+		Type:           []string{"Text"},
+		Language:       nonEmpty(book.Language),
+	}
+
+	if book.Published != 0 {
+		dc.Date = []string{strconv.Itoa(book.Published)}
+	}
+	if book.PageCount > 0 {
+		dc.Format = []string{strconv.Itoa(book.PageCount) + " p."}
+	}
+
+	if book.ISBN != "" {
+		dc.Identifier = append(dc.Identifier, "urn:isbn:"+book.ISBN)
+	}
+	if book.LCCN != "" {
+		dc.Identifier = append(dc.Identifier, "info:lccn/"+book.LCCN)
+	}
+	if book.OCLC != "" {
+		dc.Identifier = append(dc.Identifier, "info:oclcnum/"+book.OCLC)
+	}
+
+	return dc
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
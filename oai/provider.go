@@ -0,0 +1,393 @@
+package oai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+// metadataPrefixDC - Único formato de metadatos servido (obligatorio en OAI-PMH)
+const metadataPrefixDC = "oai_dc"
+
+// datestampLayout - Granularidad de segundos en UTC
+const datestampLayout = "2006-01-02T15:04:05Z"
+
+// Config - Identificación del repositorio
+type Config struct {
+	RepositoryName       string
+	RepositoryIdentifier string // dominio de los identificadores oai:<dominio>:<id>
+	BaseURL              string // si está vacío se usa la URL de la petición
+	AdminEmail           string
+	PageSize             int
+}
+
+// Provider - Proveedor de datos OAI-PMH sobre el catálogo
+type Provider struct {
+	store  storage.Store
+	config Config
+}
+
+func NewProvider(store storage.Store, config Config) *Provider {
+	if config.PageSize <= 0 {
+		config.PageSize = 100
+	}
+	return &Provider{store: store, config: config}
+}
+
+// verbArguments - Argumentos permitidos por verbo. "exclusive" no admite
+// ningún otro argumento.
+var verbArguments = map[string]struct {
+	required  []string
+	optional  []string
+	exclusive string
+}{
+	"Identify":            {},
+	"ListMetadataFormats": {optional: []string{"identifier"}},
+	"ListSets":            {exclusive: "resumptionToken"},
+	"GetRecord":           {required: []string{"identifier", "metadataPrefix"}},
+	"ListIdentifiers":     {required: []string{"metadataPrefix"}, optional: []string{"from", "until", "set"}, exclusive: "resumptionToken"},
+	"ListRecords":         {required: []string{"metadataPrefix"}, optional: []string{"from", "until", "set"}, exclusive: "resumptionToken"},
+}
+
+// Handle - Atiende una petición OAI-PMH (argumentos de GET o POST)
+func (p *Provider) Handle(ctx context.Context, args url.Values, requestURL string) (*Response, error) {
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = requestURL
+	}
+
+	resp := &Response{
+		Xmlns:          oaiNamespace,
+		XmlnsXSI:       xsiNamespace,
+		SchemaLocation: oaiNamespace + " " + oaiSchema,
+		ResponseDate:   time.Now().UTC().Format(datestampLayout),
+		Request:        Request{BaseURL: baseURL},
+	}
+
+	verb, errCode, message := validateArguments(args)
+	if errCode != "" {
+		resp.Errors = []Error{{Code: errCode, Message: message}}
+		return resp, nil
+	}
+
+	resp.Request = Request{
+		Verb:            verb,
+		Identifier:      args.Get("identifier"),
+		MetadataPrefix:  args.Get("metadataPrefix"),
+		From:            args.Get("from"),
+		Until:           args.Get("until"),
+		Set:             args.Get("set"),
+		ResumptionToken: args.Get("resumptionToken"),
+		BaseURL:         baseURL,
+	}
+
+	var err error
+	switch verb {
+	case "Identify":
+		err = p.identify(ctx, resp, baseURL)
+	case "ListMetadataFormats":
+		err = p.listMetadataFormats(ctx, resp, args.Get("identifier"))
+	case "ListSets":
+		resp.fail(ErrNoSetHierarchy, "This repository does not support sets")
+	case "GetRecord":
+		err = p.getRecord(ctx, resp, args.Get("identifier"), args.Get("metadataPrefix"))
+	case "ListIdentifiers", "ListRecords":
+		err = p.list(ctx, resp, verb, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Con badArgument (como unas fechas mal formadas) la petición no lleva
+	// atributos, igual que si la rechazó validateArguments
+	for _, e := range resp.Errors {
+		if e.Code == ErrBadArgument {
+			resp.Request = Request{BaseURL: baseURL}
+		}
+	}
+
+	return resp, nil
+}
+
+func (r *Response) fail(code, message string) {
+	r.Errors = append(r.Errors, Error{Code: code, Message: message})
+}
+
+// validateArguments - Comprueba el verbo y sus argumentos
+func validateArguments(args url.Values) (verb, code, message string) {
+	verbs := args["verb"]
+	if len(verbs) != 1 {
+		return "", ErrBadVerb, "Missing or repeated verb argument"
+	}
+	verb = verbs[0]
+
+	spec, ok := verbArguments[verb]
+	if !ok {
+		return "", ErrBadVerb, fmt.Sprintf("Illegal verb %q", verb)
+	}
+
+	allowed := map[string]bool{"verb": true}
+	for _, name := range append(append([]string{}, spec.required...), spec.optional...) {
+		allowed[name] = true
+	}
+	if spec.exclusive != "" {
+		allowed[spec.exclusive] = true
+	}
+
+	for name, values := range args {
+		if !allowed[name] {
+			return "", ErrBadArgument, fmt.Sprintf("Illegal argument %q for verb %s", name, verb)
+		}
+		if len(values) > 1 {
+			return "", ErrBadArgument, fmt.Sprintf("Repeated argument %q", name)
+		}
+	}
+
+	if spec.exclusive != "" && args.Get(spec.exclusive) != "" {
+		if len(args) > 2 {
+			return "", ErrBadArgument, spec.exclusive + " is an exclusive argument"
+		}
+		return verb, "", ""
+	}
+
+	for _, name := range spec.required {
+		if args.Get(name) == "" {
+			return "", ErrBadArgument, fmt.Sprintf("Missing required argument %q", name)
+		}
+	}
+
+	return verb, "", ""
+}
+
+// ==============================================
+// VERBOS
+// ==============================================
+
+func (p *Provider) identify(ctx context.Context, resp *Response, baseURL string) error {
+	earliest := time.Now().UTC()
+	changes, err := p.store.GetBookChanges(ctx, models.BookChangeQuery{Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		earliest = changes[0].Datestamp
+	}
+
+	resp.Identify = &Identify{
+		RepositoryName:    p.config.RepositoryName,
+		BaseURL:           baseURL,
+		ProtocolVersion:   "2.0",
+		AdminEmail:        p.config.AdminEmail,
+		EarliestDatestamp: formatDatestamp(earliest),
+		DeletedRecord:     "persistent",
+		Granularity:       granularity,
+	}
+	return nil
+}
+
+func (p *Provider) listMetadataFormats(ctx context.Context, resp *Response, identifier string) error {
+	if identifier != "" {
+		if _, err := p.findChange(ctx, identifier); err != nil {
+			if errors.Is(err, storage.ErrBookNotFound) {
+				resp.fail(ErrIDDoesNotExist, "Unknown identifier "+identifier)
+				return nil
+			}
+			return err
+		}
+	}
+
+	resp.ListMetadataFormats = &ListMetadataFormats{
+		Formats: []MetadataFormat{{
+			MetadataPrefix:    metadataPrefixDC,
+			Schema:            oaiDCSchema,
+			MetadataNamespace: oaiDCNamespace,
+		}},
+	}
+	return nil
+}
+
+func (p *Provider) getRecord(ctx context.Context, resp *Response, identifier, prefix string) error {
+	if prefix != metadataPrefixDC {
+		resp.fail(ErrCannotDisseminateFormat, "Unsupported metadataPrefix "+prefix)
+		return nil
+	}
+
+	change, err := p.findChange(ctx, identifier)
+	if err != nil {
+		if errors.Is(err, storage.ErrBookNotFound) {
+			resp.fail(ErrIDDoesNotExist, "Unknown identifier "+identifier)
+			return nil
+		}
+		return err
+	}
+
+	resp.GetRecord = &GetRecord{Record: p.record(*change)}
+	return nil
+}
+
+// list - ListIdentifiers y ListRecords, paginados con resumptionToken
+func (p *Provider) list(ctx context.Context, resp *Response, verb string, args url.Values) error {
+	var state resumptionState
+
+	if token := args.Get("resumptionToken"); token != "" {
+		var ok bool
+		if state, ok = decodeToken(token); !ok {
+			resp.fail(ErrBadResumptionToken, "Invalid or expired resumptionToken")
+			return nil
+		}
+	} else {
+		state.Prefix = args.Get("metadataPrefix")
+		if args.Get("set") != "" {
+			resp.fail(ErrNoSetHierarchy, "This repository does not support sets")
+			return nil
+		}
+		if state.Prefix != metadataPrefixDC {
+			resp.fail(ErrCannotDisseminateFormat, "Unsupported metadataPrefix "+state.Prefix)
+			return nil
+		}
+
+		var code, message string
+		state.From, state.Before, code, message = parseRange(args.Get("from"), args.Get("until"))
+		if code != "" {
+			resp.fail(code, message)
+			return nil
+		}
+	}
+
+	query := models.BookChangeQuery{
+		From:   state.From,
+		Before: state.Before,
+		Limit:  p.config.PageSize + 1,
+	}
+	if state.AfterID != "" {
+		query.After = &state.After
+		query.AfterID = state.AfterID
+	}
+
+	changes, err := p.store.GetBookChanges(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 && state.AfterID == "" {
+		resp.fail(ErrNoRecordsMatch, "No records match the request")
+		return nil
+	}
+
+	// Token para la página siguiente (vacío en la última página de un recorrido paginado)
+	var token *ResumptionToken
+	if len(changes) > p.config.PageSize {
+		changes = changes[:p.config.PageSize]
+		next := state
+		last := changes[len(changes)-1]
+		next.After, next.AfterID = last.Datestamp, last.ID
+		next.Cursor = state.Cursor + len(changes)
+		token = &ResumptionToken{Cursor: state.Cursor, Value: encodeToken(next)}
+	} else if state.AfterID != "" {
+		token = &ResumptionToken{Cursor: state.Cursor}
+	}
+
+	if verb == "ListIdentifiers" {
+		list := &ListIdentifiers{ResumptionToken: token}
+		for _, change := range changes {
+			list.Headers = append(list.Headers, p.header(change))
+		}
+		resp.ListIdentifiers = list
+		return nil
+	}
+
+	list := &ListRecords{ResumptionToken: token}
+	for _, change := range changes {
+		list.Records = append(list.Records, p.record(change))
+	}
+	resp.ListRecords = list
+	return nil
+}
+
+// ==============================================
+// FUNCIONES AUXILIARES
+// ==============================================
+
+// findChange - Cambio del libro correspondiente a un identificador OAI
+func (p *Provider) findChange(ctx context.Context, identifier string) (*models.BookChange, error) {
+	prefix := "oai:" + p.config.RepositoryIdentifier + ":"
+	if !strings.HasPrefix(identifier, prefix) || len(identifier) == len(prefix) {
+		return nil, storage.ErrBookNotFound
+	}
+	return p.store.GetBookChange(ctx, strings.TrimPrefix(identifier, prefix))
+}
+
+func (p *Provider) header(change models.BookChange) Header {
+	header := Header{
+		Identifier: "oai:" + p.config.RepositoryIdentifier + ":" + change.ID,
+		Datestamp:  formatDatestamp(change.Datestamp),
+	}
+	if change.Deleted {
+		header.Status = "deleted"
+	}
+	return header
+}
+
+// record - Los registros eliminados solo llevan cabecera
+func (p *Provider) record(change models.BookChange) Record {
+	record := Record{Header: p.header(change)}
+	if !change.Deleted && change.Book != nil {
+		record.Metadata = &Metadata{DC: BookToDublinCore(*change.Book)}
+	}
+	return record
+}
+
+func formatDatestamp(t time.Time) string {
+	return t.UTC().Format(datestampLayout)
+}
+
+// parseRange - from/until inclusivos, con granularidad de día o de segundo.
+// Devuelve el límite superior como exclusivo.
+func parseRange(fromArg, untilArg string) (from, before *time.Time, code, message string) {
+	fromTime, fromDay, ok := parseDatestamp(fromArg)
+	if !ok {
+		return nil, nil, ErrBadArgument, fmt.Sprintf("Illegal from date %q", fromArg)
+	}
+	untilTime, untilDay, ok := parseDatestamp(untilArg)
+	if !ok {
+		return nil, nil, ErrBadArgument, fmt.Sprintf("Illegal until date %q", untilArg)
+	}
+
+	if fromArg != "" && untilArg != "" {
+		if fromDay != untilDay {
+			return nil, nil, ErrBadArgument, "from and until must have the same granularity"
+		}
+		if untilTime.Before(*fromTime) {
+			return nil, nil, ErrBadArgument, "from must not be later than until"
+		}
+	}
+
+	if untilTime != nil {
+		end := untilTime.Add(time.Second)
+		if untilDay {
+			end = untilTime.AddDate(0, 0, 1)
+		}
+		before = &end
+	}
+
+	return fromTime, before, "", ""
+}
+
+func parseDatestamp(value string) (*time.Time, bool, bool) {
+	if value == "" {
+		return nil, false, true
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return &t, true, true
+	}
+	if t, err := time.Parse(datestampLayout, value); err == nil {
+		return &t, false, true
+	}
+	return nil, false, false
+}
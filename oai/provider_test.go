@@ -0,0 +1,224 @@
+package oai_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"library-api/models"
+	"library-api/oai"
+	"library-api/storage"
+)
+
+// catalogISBNs - ISBN válidos de los libros de prueba
+var catalogISBNs = []string{"9780306406157", "9788437604572", "9780804429573", "9791090636071", "9780000000002"}
+
+// newProvider - Proveedor sobre un catálogo de len(catalogISBNs) libros, con
+// páginas de pageSize registros
+func newProvider(t *testing.T, pageSize int) (*oai.Provider, storage.Store, []string) {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	var ids []string
+	for i, code := range catalogISBNs {
+		book, err := store.CreateBook(context.Background(), models.Book{Title: "Libro " + code, Author: "Autor", ISBN: code, Available: true})
+		if err != nil {
+			t.Fatalf("CreateBook %d: %v", i, err)
+		}
+		ids = append(ids, book.ID)
+	}
+	return oai.NewProvider(store, oai.Config{RepositoryIdentifier: "test.local", PageSize: pageSize}), store, ids
+}
+
+// harvest - Petición OAI-PMH con los argumentos como pares nombre, valor
+func harvest(t *testing.T, provider *oai.Provider, args ...string) *oai.Response {
+	t.Helper()
+	values := url.Values{}
+	for i := 0; i+1 < len(args); i += 2 {
+		values.Add(args[i], args[i+1])
+	}
+	resp, err := provider.Handle(context.Background(), values, "http://test.local/oai")
+	if err != nil {
+		t.Fatalf("Handle(%v): %v", values, err)
+	}
+	return resp
+}
+
+// errorCode - Código del primer error de la respuesta ("" si no hay)
+func errorCode(resp *oai.Response) string {
+	if len(resp.Errors) == 0 {
+		return ""
+	}
+	return resp.Errors[0].Code
+}
+
+func TestVerbErrors(t *testing.T) {
+	provider, _, ids := newProvider(t, 10)
+	identifier := "oai:test.local:" + ids[0]
+	stateless := base64.RawURLEncoding.EncodeToString([]byte(`{"p":"oai_dc"}`))
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"missing verb", nil, oai.ErrBadVerb},
+		{"unknown verb", []string{"verb", "Harvest"}, oai.ErrBadVerb},
+		{"repeated verb", []string{"verb", "Identify", "verb", "Identify"}, oai.ErrBadVerb},
+		{"Identify with arguments", []string{"verb", "Identify", "metadataPrefix", "oai_dc"}, oai.ErrBadArgument},
+		{"GetRecord without identifier", []string{"verb", "GetRecord", "metadataPrefix", "oai_dc"}, oai.ErrBadArgument},
+		{"GetRecord without metadataPrefix", []string{"verb", "GetRecord", "identifier", identifier}, oai.ErrBadArgument},
+		{"GetRecord with a repeated argument", []string{"verb", "GetRecord", "identifier", identifier, "identifier", identifier, "metadataPrefix", "oai_dc"}, oai.ErrBadArgument},
+		{"GetRecord in MARC", []string{"verb", "GetRecord", "identifier", identifier, "metadataPrefix", "marc21"}, oai.ErrCannotDisseminateFormat},
+		{"GetRecord of an unknown book", []string{"verb", "GetRecord", "identifier", "oai:test.local:missing", "metadataPrefix", "oai_dc"}, oai.ErrIDDoesNotExist},
+		{"GetRecord of another repository", []string{"verb", "GetRecord", "identifier", "oai:other.org:" + ids[0], "metadataPrefix", "oai_dc"}, oai.ErrIDDoesNotExist},
+		{"ListMetadataFormats of an unknown book", []string{"verb", "ListMetadataFormats", "identifier", "oai:test.local:missing"}, oai.ErrIDDoesNotExist},
+		{"ListSets", []string{"verb", "ListSets"}, oai.ErrNoSetHierarchy},
+		{"ListRecords without metadataPrefix", []string{"verb", "ListRecords"}, oai.ErrBadArgument},
+		{"ListRecords in MARC", []string{"verb", "ListRecords", "metadataPrefix", "marc21"}, oai.ErrCannotDisseminateFormat},
+		{"ListRecords of a set", []string{"verb", "ListRecords", "metadataPrefix", "oai_dc", "set", "novels"}, oai.ErrNoSetHierarchy},
+		{"ListRecords with a bad date", []string{"verb", "ListRecords", "metadataPrefix", "oai_dc", "from", "2024-13-01"}, oai.ErrBadArgument},
+		{"ListRecords with from after until", []string{"verb", "ListRecords", "metadataPrefix", "oai_dc", "from", "2024-02-01", "until", "2024-01-01"}, oai.ErrBadArgument},
+		{"ListRecords with mixed granularity", []string{"verb", "ListRecords", "metadataPrefix", "oai_dc", "from", "2024-01-01", "until", "2024-02-01T00:00:00Z"}, oai.ErrBadArgument},
+		{"ListRecords before the first book", []string{"verb", "ListRecords", "metadataPrefix", "oai_dc", "until", "2000-01-01"}, oai.ErrNoRecordsMatch},
+		{"ListIdentifiers in the future", []string{"verb", "ListIdentifiers", "metadataPrefix", "oai_dc", "from", "2999-01-01T00:00:00Z"}, oai.ErrNoRecordsMatch},
+		{"token with other arguments", []string{"verb", "ListRecords", "metadataPrefix", "oai_dc", "resumptionToken", "abc"}, oai.ErrBadArgument},
+		{"token that is not base64", []string{"verb", "ListRecords", "resumptionToken", "not a token!"}, oai.ErrBadResumptionToken},
+		{"token that is not JSON", []string{"verb", "ListIdentifiers", "resumptionToken", base64.RawURLEncoding.EncodeToString([]byte("page=2"))}, oai.ErrBadResumptionToken},
+		{"token without position", []string{"verb", "ListIdentifiers", "resumptionToken", stateless}, oai.ErrBadResumptionToken},
+	}
+
+	for _, tt := range tests {
+		resp := harvest(t, provider, tt.args...)
+		if got := errorCode(resp); got != tt.want {
+			t.Errorf("%s: got error %q (%v), want %q", tt.name, got, resp.Errors, tt.want)
+		}
+		if tt.want == oai.ErrBadVerb || tt.want == oai.ErrBadArgument {
+			if resp.Request.Verb != "" || resp.Request.MetadataPrefix != "" {
+				t.Errorf("%s: request echoes arguments %+v", tt.name, resp.Request)
+			}
+		}
+	}
+
+	// Y los mismos verbos sin errores
+	for _, args := range [][]string{
+		{"verb", "Identify"},
+		{"verb", "ListMetadataFormats"},
+		{"verb", "ListMetadataFormats", "identifier", identifier},
+		{"verb", "GetRecord", "identifier", identifier, "metadataPrefix", "oai_dc"},
+		{"verb", "ListRecords", "metadataPrefix", "oai_dc"},
+	} {
+		if resp := harvest(t, provider, args...); len(resp.Errors) != 0 {
+			t.Errorf("%v: got errors %v", args, resp.Errors)
+		}
+	}
+}
+
+func TestResumptionAcrossDelete(t *testing.T) {
+	provider, store, ids := newProvider(t, 2)
+
+	first := harvest(t, provider, "verb", "ListIdentifiers", "metadataPrefix", "oai_dc")
+	if first.ListIdentifiers == nil || len(first.ListIdentifiers.Headers) != 2 || first.ListIdentifiers.ResumptionToken == nil {
+		t.Fatalf("first page: got %+v, errors %v", first.ListIdentifiers, first.Errors)
+	}
+	seen := map[string]string{}
+	for _, header := range first.ListIdentifiers.Headers {
+		seen[header.Identifier] = header.Status
+	}
+
+	// Se borra un libro que aún no se cosechó: sale al final como eliminado
+	var deleted string
+	for _, id := range ids {
+		if _, harvested := seen["oai:test.local:"+id]; !harvested {
+			deleted = "oai:test.local:" + id
+			if err := store.DeleteBook(context.Background(), id); err != nil {
+				t.Fatalf("DeleteBook: %v", err)
+			}
+			break
+		}
+	}
+
+	token := first.ListIdentifiers.ResumptionToken
+	cursor := 0
+	for pages := 1; token.Value != ""; pages++ {
+		if pages > len(ids) {
+			t.Fatalf("more than %d pages", len(ids))
+		}
+		cursor += 2
+		resp := harvest(t, provider, "verb", "ListIdentifiers", "resumptionToken", token.Value)
+		if resp.ListIdentifiers == nil {
+			t.Fatalf("page %d: errors %v", pages+1, resp.Errors)
+		}
+		token = resp.ListIdentifiers.ResumptionToken
+		if token == nil || token.Cursor != cursor {
+			t.Fatalf("page %d: got token %+v, want cursor %d", pages+1, token, cursor)
+		}
+		for _, header := range resp.ListIdentifiers.Headers {
+			if _, repeated := seen[header.Identifier]; repeated {
+				t.Errorf("page %d: %s harvested twice", pages+1, header.Identifier)
+			}
+			seen[header.Identifier] = header.Status
+		}
+	}
+
+	if len(seen) != len(ids) {
+		t.Errorf("harvested %d identifiers, want %d", len(seen), len(ids))
+	}
+	if seen[deleted] != "deleted" {
+		t.Errorf("deleted book %s: status %q", deleted, seen[deleted])
+	}
+
+	// El registro eliminado solo lleva cabecera
+	record := harvest(t, provider, "verb", "GetRecord", "identifier", deleted, "metadataPrefix", "oai_dc")
+	if record.GetRecord == nil || record.GetRecord.Record.Header.Status != "deleted" || record.GetRecord.Record.Metadata != nil {
+		t.Errorf("GetRecord of a deleted book: got %+v, errors %v", record.GetRecord, record.Errors)
+	}
+}
+
+func TestDatestampGranularity(t *testing.T) {
+	provider, _, _ := newProvider(t, 10)
+
+	all := harvest(t, provider, "verb", "ListIdentifiers", "metadataPrefix", "oai_dc")
+	if all.ListIdentifiers == nil || len(all.ListIdentifiers.Headers) != len(catalogISBNs) {
+		t.Fatalf("ListIdentifiers: got %+v, errors %v", all.ListIdentifiers, all.Errors)
+	}
+	if all.ListIdentifiers.ResumptionToken != nil {
+		t.Errorf("a single page has a resumption token")
+	}
+
+	identify := harvest(t, provider, "verb", "Identify")
+	if identify.Identify == nil || identify.Identify.Granularity != "YYYY-MM-DDThh:mm:ssZ" {
+		t.Fatalf("Identify: got %+v", identify.Identify)
+	}
+
+	header := all.ListIdentifiers.Headers[0]
+	if len(header.Datestamp) != len("2006-01-02T15:04:05Z") || !strings.HasSuffix(header.Datestamp, "Z") {
+		t.Fatalf("datestamp %q does not have seconds granularity in UTC", header.Datestamp)
+	}
+	day := header.Datestamp[:len("2006-01-02")]
+
+	// until es inclusivo en las dos granularidades
+	count := func(args ...string) int {
+		resp := harvest(t, provider, append([]string{"verb", "ListIdentifiers", "metadataPrefix", "oai_dc"}, args...)...)
+		if resp.ListIdentifiers == nil {
+			return 0
+		}
+		return len(resp.ListIdentifiers.Headers)
+	}
+	if n := count("from", header.Datestamp, "until", header.Datestamp); n < 1 {
+		t.Errorf("from = until = %s (seconds): got %d records", header.Datestamp, n)
+	}
+	if n := count("from", day, "until", day); n < 1 {
+		t.Errorf("from = until = %s (day): got %d records", day, n)
+	}
+	if n := count("until", day); n < 1 {
+		t.Errorf("until %s (day): got %d records", day, n)
+	}
+	if n := count("from", "2000-01-01", "until", "2000-01-01"); n != 0 {
+		t.Errorf("a day without changes: got %d records", n)
+	}
+	if n := count("until", "2000-01-01T23:59:59Z"); n != 0 {
+		t.Errorf("a second without changes: got %d records", n)
+	}
+}
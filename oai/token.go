@@ -0,0 +1,34 @@
+package oai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// resumptionState - Estado de un recorrido paginado, codificado en el token
+type resumptionState struct {
+	Prefix  string     `json:"p"`
+	From    *time.Time `json:"f,omitempty"`
+	Before  *time.Time `json:"b,omitempty"`
+	After   time.Time  `json:"a"`
+	AfterID string     `json:"i"`
+	Cursor  int        `json:"c"`
+}
+
+func encodeToken(state resumptionState) string {
+	data, _ := json.Marshal(state)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeToken(token string) (resumptionState, bool) {
+	var state resumptionState
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return state, false
+	}
+	if err := json.Unmarshal(data, &state); err != nil || state.Prefix == "" || state.AfterID == "" {
+		return state, false
+	}
+	return state, true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"library-api/handlers"
+	"library-api/metrics"
+	"library-api/problem"
+	"library-api/services"
+	"library-api/storage"
+)
+
+// ==============================================
+// MÉTRICAS DE LA API (/metrics)
+// ==============================================
+// Las de las peticiones HTTP las registra middleware.HTTPMetrics; aquí están
+// las del almacenamiento, los proveedores externos y el catálogo
+
+// storeObserver - Duración de cada operación del store y fallos inesperados
+// (un libro inexistente o un ISBN repetido no cuentan: son respuestas 4xx).
+// Cada operación va a los logs con el contexto, y así con el ID de la
+// petición: con nivel debug, o warn si falló
+func storeObserver(registry *metrics.Registry) storage.ObserveFunc {
+	duration := registry.Histogram("library_store_operation_duration_seconds",
+		"Duración de las operaciones del almacenamiento", nil, "operation")
+	failures := registry.Counter("library_store_operation_errors_total",
+		"Operaciones del almacenamiento que fallaron por un error inesperado", "operation")
+
+	return func(ctx context.Context, operation string, elapsed time.Duration, err error) {
+		duration.Observe(elapsed.Seconds(), operation)
+		if err != nil && problem.From(err).Status >= 500 {
+			failures.Inc(operation)
+			slog.WarnContext(ctx, "Error en el almacenamiento", "operation", operation,
+				"duration_ms", elapsed.Milliseconds(), "error", err)
+			return
+		}
+		slog.DebugContext(ctx, "Operación del almacenamiento", "operation", operation, "duration_ms", elapsed.Milliseconds())
+	}
+}
+
+// providerMetrics - Llamadas a Google Books y Open Library por resultado (ok,
+// error, cache_hit, circuit_open) y duración de las que llegaron al proveedor
+func providerMetrics(registry *metrics.Registry) services.ProviderObserver {
+	calls := registry.Counter("library_provider_requests_total",
+		"Consultas a los proveedores externos por resultado", "provider", "outcome")
+	duration := registry.Histogram("library_provider_request_duration_seconds",
+		"Duración de las llamadas a los proveedores externos", nil, "provider")
+
+	return func(provider, outcome string, elapsed time.Duration) {
+		calls.Inc(provider, outcome)
+		if outcome == services.OutcomeOK || outcome == services.OutcomeError {
+			duration.Observe(elapsed.Seconds(), provider)
+		}
+	}
+}
+
+// catalogMetrics - Libros, préstamos activos y vencidos (los activos desde
+// hace más de loanPeriod) y circuitos abiertos, calculados en cada consulta
+func catalogMetrics(registry *metrics.Registry, store storage.Store, providers handlers.ProviderCircuits, loanPeriod time.Duration) {
+	books := registry.Gauge("library_books", "Libros del catálogo")
+	available := registry.Gauge("library_books_available", "Libros disponibles para préstamo")
+	activeLoans := registry.Gauge("library_loans_active", "Préstamos sin devolver")
+	overdueLoans := registry.Gauge("library_loans_overdue", "Préstamos sin devolver con el plazo vencido")
+	circuitOpen := registry.Gauge("library_provider_circuit_open", "1 si el circuito del proveedor está abierto o a prueba", "provider")
+
+	registry.OnCollect(func(ctx context.Context) {
+		stats, err := store.GetStats(ctx, time.Now().Add(-loanPeriod))
+		if err != nil {
+			// Se conservan los últimos valores; el error ya cuenta en
+			// library_store_operation_errors_total
+			slog.WarnContext(ctx, "Error calculando las métricas del catálogo", "error", err)
+		} else {
+			books.Set(float64(stats.Books))
+			available.Set(float64(stats.AvailableBooks))
+			activeLoans.Set(float64(stats.ActiveLoans))
+			overdueLoans.Set(float64(stats.OverdueLoans))
+		}
+
+		for provider, state := range providers.CircuitStates() {
+			open := 0.0
+			if state != services.CircuitClosed {
+				open = 1
+			}
+			circuitOpen.Set(open, provider)
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Access - Quién puede llamar a una operación
+type Access int
+
+const (
+	Public Access = iota
+	User          // token JWT válido
+	Admin         // token JWT con rol admin
+)
+
+// bearerScheme - Nombre del esquema de seguridad JWT en components
+const bearerScheme = "bearerAuth"
+
+// Route - Documentación de una ruta registrada en Gin
+type Route struct {
+	Method      string
+	Path        string // con la sintaxis de Gin: /books/:id
+	Tag         string
+	Summary     string
+	Description string
+	Access      Access
+	Params      []Param // query y cabeceras; los de la ruta se deducen del Path
+	Body        any     // valor de ejemplo del cuerpo JSON, o Content
+	Responses   []Response
+}
+
+// Param - Parámetro de query (por defecto) o de cabecera
+type Param struct {
+	Name        string
+	In          string // "query" si está vacío
+	Description string
+	Required    bool
+	Type        string // "string" si está vacío
+	Enum        []string
+}
+
+// Response - Respuesta documentada de una ruta
+type Response struct {
+	Status      int
+	Description string            // texto de http.StatusText si está vacío
+	Body        any               // valor de ejemplo del cuerpo JSON, o Content
+	Headers     map[string]string // nombre → descripción
+}
+
+// Build - Genera el documento OpenAPI a partir de la tabla de rutas.
+// errorBody documenta (como Route.Body) las respuestas de error comunes:
+// 401, 403 y default
+func Build(info Info, routes []Route, errorBody any) *Document {
+	registry := newSchemaRegistry()
+	errorContent := registry.content(errorBody)
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: registry.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerScheme: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Token obtenido en POST /login",
+				},
+			},
+		},
+	}
+
+	seenTags := make(map[string]bool)
+	for _, route := range routes {
+		path := Path(route.Path)
+		op := &Operation{
+			OperationID: operationID(route.Method, route.Path),
+			Summary:     route.Summary,
+			Description: route.Description,
+			Parameters:  pathParams(route.Path),
+			Responses:   make(map[string]*ResponseObject),
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+			if !seenTags[route.Tag] {
+				seenTags[route.Tag] = true
+				doc.Tags = append(doc.Tags, Tag{Name: route.Tag})
+			}
+		}
+
+		for _, param := range route.Params {
+			op.Parameters = append(op.Parameters, buildParam(param))
+		}
+		if route.Body != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: registry.content(route.Body)}
+		}
+
+		for _, response := range route.Responses {
+			op.Responses[strconv.Itoa(response.Status)] = registry.response(response)
+		}
+
+		// Errores comunes: autenticación, permisos y fallos del almacenamiento
+		if route.Access >= User {
+			op.Security = []map[string][]string{{bearerScheme: {}}}
+			addError(op, http.StatusUnauthorized, "Missing or invalid token", errorContent)
+		}
+		if route.Access == Admin {
+			addError(op, http.StatusForbidden, "Admin role required", errorContent)
+		}
+		op.Responses["default"] = &ResponseObject{
+			Description: "Unexpected error (500, or 504 when a database query times out)",
+			Content:     errorContent,
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return doc
+}
+
+// Path - Convierte una ruta de Gin (/books/:id, /files/*path) a la sintaxis
+// de OpenAPI (/books/{id})
+func Path(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Operations - Pares "MÉTODO /ruta" documentados, ordenados
+func (d *Document) Operations() []string {
+	var operations []string
+	for path, item := range d.Paths {
+		for method := range item {
+			operations = append(operations, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// ==================== FUNCIONES AUXILIARES ====================
+
+// content - Tipos de contenido de un cuerpo: JSON salvo que sea un Content
+func (r *schemaRegistry) content(body any) map[string]MediaType {
+	if types, ok := body.(Content); ok {
+		content := make(map[string]MediaType, len(types))
+		for mediaType, value := range types {
+			content[mediaType] = MediaType{Schema: r.schemaOf(value)}
+		}
+		return content
+	}
+	return map[string]MediaType{"application/json": {Schema: r.schemaOf(body)}}
+}
+
+func (r *schemaRegistry) response(response Response) *ResponseObject {
+	description := response.Description
+	if description == "" {
+		description = http.StatusText(response.Status)
+	}
+
+	result := &ResponseObject{Description: description}
+	if response.Body != nil {
+		result.Content = r.content(response.Body)
+	}
+	if len(response.Headers) > 0 {
+		result.Headers = make(map[string]Header, len(response.Headers))
+		for name, text := range response.Headers {
+			result.Headers[name] = Header{Description: text, Schema: &Schema{Type: "string"}}
+		}
+	}
+	return result
+}
+
+// addError - Agrega una respuesta de error si la ruta no la documentó
+func addError(op *Operation, status int, description string, content map[string]MediaType) {
+	key := strconv.Itoa(status)
+	if _, ok := op.Responses[key]; ok {
+		return
+	}
+	op.Responses[key] = &ResponseObject{Description: description, Content: content}
+}
+
+// pathParams - Parámetros obligatorios deducidos de los segmentos :nombre
+func pathParams(ginPath string) []Parameter {
+	var params []Parameter
+	for _, segment := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, Parameter{
+				Name:     segment[1:],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+	return params
+}
+
+func buildParam(param Param) Parameter {
+	in := param.In
+	if in == "" {
+		in = "query"
+	}
+	schemaType := param.Type
+	if schemaType == "" {
+		schemaType = "string"
+	}
+	return Parameter{
+		Name:        param.Name,
+		In:          in,
+		Description: param.Description,
+		Required:    param.Required,
+		Schema:      &Schema{Type: schemaType, Enum: param.Enum},
+	}
+}
+
+// operationID - Identificador estable: GET /books/:id/borrow → getBooksByIdBorrow
+func operationID(method, ginPath string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(ginPath, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			b.WriteString("By")
+			segment = segment[1:]
+		}
+		upper := true
+		for _, r := range segment {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == len(method) {
+		b.WriteString("Root")
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"library-api/models"
+	"library-api/openapi"
+)
+
+func TestBuildSchemasFromTypes(t *testing.T) {
+	doc := openapi.Build(openapi.Info{Title: "test", Version: "1"}, []openapi.Route{
+		{Method: "POST", Path: "/books/:id/borrow", Access: openapi.User, Body: models.LoanRequest{},
+			Responses: []openapi.Response{{Status: 201, Body: models.LoanWithBook{}}}},
+		{Method: "POST", Path: "/register", Body: models.RegisterRequest{}},
+		{Method: "POST", Path: "/books/export", Body: models.ExportBooksRequest{}},
+	}, openapi.Content{"application/problem+json": openapi.Object{"code": ""}})
+
+	op := doc.Paths["/books/{id}/borrow"]["post"]
+	if op == nil {
+		t.Fatalf("paths: got %v", doc.Operations())
+	}
+	if op.OperationID != "postBooksByIdBorrow" || len(op.Parameters) != 1 || op.Parameters[0].In != "path" {
+		t.Errorf("operation: got id %q, parameters %+v", op.OperationID, op.Parameters)
+	}
+	if len(op.Security) != 1 || op.Responses["401"] == nil || op.Responses["default"].Content["application/problem+json"].Schema == nil {
+		t.Errorf("protected operation: security %v, responses %v", op.Security, op.Responses)
+	}
+	if ref := op.Responses["201"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/LoanWithBook" {
+		t.Errorf("201 schema: got ref %q", ref)
+	}
+
+	// Los campos del Loan embebido quedan al nivel de LoanWithBook
+	loanWithBook := doc.Components.Schemas["LoanWithBook"]
+	for _, name := range []string{"id", "book_id", "return_date", "book"} {
+		if loanWithBook.Properties[name] == nil {
+			t.Errorf("LoanWithBook: property %s missing", name)
+		}
+	}
+	if format := loanWithBook.Properties["loan_date"].Format; format != "date-time" {
+		t.Errorf("loan_date: got format %q", format)
+	}
+	if !slices.Equal(loanWithBook.Required, []string{"book_id", "user"}) {
+		t.Errorf("LoanWithBook required: got %v", loanWithBook.Required)
+	}
+
+	if password := doc.Components.Schemas["RegisterRequest"].Properties["password"]; password.MinLength == nil || *password.MinLength != 6 {
+		t.Errorf("password: got %+v", password)
+	}
+	if ids := doc.Components.Schemas["ExportBooksRequest"].Properties["ids"]; ids.MinItems == nil || ids.MaxItems == nil || *ids.MaxItems != 1000 {
+		t.Errorf("ids: got %+v", ids)
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Errorf("json.Marshal: %v", err)
+	}
+}
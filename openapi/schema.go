@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Object - Cuerpo JSON sin tipo propio (las respuestas gin.H): cada
+// propiedad se documenta con el esquema de su valor de ejemplo
+type Object map[string]any
+
+// Content - Cuerpo con varios tipos de contenido; el valor de cada tipo se
+// documenta como en Route.Body (nil es texto, Binary un archivo)
+type Content map[string]any
+
+// binary - Marca de contenido binario (archivos subidos o descargados)
+type binary struct{}
+
+// Binary - Valor para documentar un archivo (string con formato binary)
+var Binary = binary{}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage(nil))
+	objectType  = reflect.TypeOf(Object(nil))
+	binaryType  = reflect.TypeOf(Binary)
+)
+
+// schemaRegistry - Genera esquemas por reflexión y guarda los tipos con
+// nombre en components/schemas
+type schemaRegistry struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: make(map[string]*Schema)}
+}
+
+// schemaOf - Esquema de un valor de ejemplo (nil es texto plano)
+func (r *schemaRegistry) schemaOf(v any) *Schema {
+	if v == nil {
+		return &Schema{Type: "string"}
+	}
+	if object, ok := v.(Object); ok {
+		return r.objectSchema(object)
+	}
+	return r.schemaFor(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawJSONType:
+		return &Schema{}
+	case binaryType:
+		return &Schema{Type: "string", Format: "binary"}
+	case objectType:
+		return &Schema{Type: "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := r.schemas[name]; !ok {
+			// Reservar el nombre antes de recorrer los campos (tipos recursivos)
+			r.schemas[name] = &Schema{}
+			*r.schemas[name] = *r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// interface{} y tipos sin equivalente JSON: cualquier valor
+	return &Schema{}
+}
+
+// structSchema - Objeto con los campos exportados según sus etiquetas json;
+// los embebidos sin nombre aportan sus campos como hace encoding/json
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				inner := r.structSchema(embedded)
+				for key, value := range inner.Properties {
+					schema.Properties[key] = value
+				}
+				schema.Required = append(schema.Required, inner.Required...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := r.schemaFor(field.Type)
+		if required := applyBinding(property, field.Tag.Get("binding")); required {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// objectSchema - Objeto en línea a partir de valores de ejemplo
+func (r *schemaRegistry) objectSchema(object Object) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema, len(object))}
+	for name, value := range object {
+		schema.Properties[name] = r.schemaOf(value)
+	}
+	return schema
+}
+
+// applyBinding - Traslada las reglas de validación de Gin (binding:"...")
+// al esquema; devuelve true si el campo es obligatorio
+func applyBinding(schema *Schema, binding string) bool {
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		n, err := strconv.Atoi(value)
+		switch {
+		case key == "dive":
+			return required // las reglas siguientes son de cada elemento
+		case key == "required":
+			required = true
+		case key == "url":
+			schema.Format = "uri"
+		case key == "min" && err == nil && schema.Type == "string":
+			schema.MinLength = &n
+		case key == "max" && err == nil && schema.Type == "string":
+			schema.MaxLength = &n
+		case key == "min" && err == nil && schema.Type == "integer":
+			schema.Minimum = &n
+		case key == "max" && err == nil && schema.Type == "integer":
+			schema.Maximum = &n
+		case key == "min" && err == nil && schema.Type == "array":
+			schema.MinItems = &n
+		case key == "max" && err == nil && schema.Type == "array":
+			schema.MaxItems = &n
+		}
+	}
+	return required
+}
@@ -0,0 +1,44 @@
+package openapi
+
+import (
+	"html/template"
+	"strings"
+)
+
+// swaggerUIVersion - Versión de swagger-ui-dist servida desde el CDN
+const swaggerUIVersion = "5.17.14"
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@{{.Version}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@{{.Version}}/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: {{.SpecURL}},
+      dom_id: "#swagger-ui",
+      deepLinking: true,
+      persistAuthorization: true,
+      tryItOutEnabled: true
+    });
+  </script>
+</body>
+</html>
+`))
+
+// SwaggerUI - Página HTML con Swagger UI para probar la API desde el navegador
+func SwaggerUI(title, specURL string) []byte {
+	var page strings.Builder
+	swaggerUITemplate.Execute(&page, map[string]string{
+		"Title":   title,
+		"Version": swaggerUIVersion,
+		"SpecURL": specURL,
+	})
+	return []byte(page.String())
+}
@@ -0,0 +1,106 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"library-api/backup"
+	"library-api/i18n"
+	"library-api/jobs"
+	"library-api/mergepatch"
+	"library-api/storage"
+	"library-api/validation"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// sentinel - Error conocido del dominio y el problema con que se responde.
+// El detalle es fijo (y traducible): el texto del error nunca llega al
+// cliente. De un storage.InvalidValueError se agrega solo el valor rechazado
+type sentinel struct {
+	err    error
+	status int
+	code   string
+	detail string
+}
+
+// sentinels - Mapeo central de los errores Err* de los paquetes a problemas
+var sentinels = []sentinel{
+	{storage.ErrBookNotFound, http.StatusNotFound, CodeBookNotFound, "Book not found"},
+	{storage.ErrLoanNotFound, http.StatusNotFound, CodeLoanNotFound, "Loan not found or already returned"},
+	{storage.ErrAuthorNotFound, http.StatusNotFound, CodeAuthorNotFound, "Author not found"},
+	{storage.ErrSubjectNotFound, http.StatusNotFound, CodeSubjectNotFound, "Subject not found"},
+	{storage.ErrJobNotFound, http.StatusNotFound, CodeJobNotFound, "Job not found"},
+	{storage.ErrBookNotAvailable, http.StatusBadRequest, CodeBookUnavailable, "Book is not available"},
+	{storage.ErrDuplicateISBN, http.StatusConflict, CodeISBNConflict, "A book with this ISBN already exists"},
+	{storage.ErrInvalidISBN, http.StatusBadRequest, CodeInvalidISBN, "Invalid ISBN"},
+	{storage.ErrUserAlreadyExists, http.StatusConflict, CodeUsernameTaken, "Username already exists"},
+	{storage.ErrInvalidCredentials, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid credentials"},
+	{storage.ErrUserNotFound, http.StatusNotFound, CodeUserNotFound, "User not found"},
+	{jobs.ErrJobFinished, http.StatusConflict, CodeJobFinished, "Job already finished"},
+	{jobs.ErrInvalidJobInput, http.StatusBadRequest, CodeValidationFailed, "Invalid job parameters"},
+	{jobs.ErrManagerStopped, http.StatusServiceUnavailable, CodeUnavailable, "The job queue is not accepting jobs"},
+	{backup.ErrUnsupportedFormat, http.StatusBadRequest, CodeUnsupportedFormat, "Unsupported backup format"},
+	{mergepatch.ErrNotObject, http.StatusBadRequest, CodeBadRequest, "The merge patch must be a JSON object"},
+	{backup.ErrBackupNotFound, http.StatusNotFound, CodeBackupNotFound, "Backup not found"},
+}
+
+// From - Convierte cualquier error en un problema. Los errores desconocidos
+// son 500 con un detalle genérico: el texto original solo va a los logs
+func From(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return Validation(validationErrors)
+	}
+
+	for _, known := range sentinels {
+		if errors.Is(err, known.err) {
+			p := New(known.status, known.code, known.detail).Wrap(err)
+			var invalid *storage.InvalidValueError
+			if errors.As(err, &invalid) {
+				p.With(invalid.Field, invalid.Value)
+			}
+			return p
+		}
+	}
+
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &syntaxError), errors.Is(err, io.ErrUnexpectedEOF):
+		return BadRequest("Malformed JSON body").Wrap(err)
+	case errors.As(err, &typeError):
+		return Newf(http.StatusBadRequest, CodeValidationFailed, "Field %s must be %s", typeError.Field, typeError.Type).Wrap(err)
+	case errors.Is(err, io.EOF):
+		return BadRequest("Empty request body").Wrap(err)
+	case errors.As(err, &tooLarge):
+		return Newf(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Request body exceeds %d bytes", tooLarge.Limit).Wrap(err)
+	case storage.IsTimeout(err):
+		return New(http.StatusGatewayTimeout, CodeQueryTimeout, "The database query timed out").Wrap(err)
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "An unexpected error occurred").Wrap(err)
+}
+
+// Validation - Problema validation_failed con un detalle por campo (en inglés;
+// Localize lo traduce)
+func Validation(validationErrors validator.ValidationErrors) *Problem {
+	p := New(http.StatusBadRequest, CodeValidationFailed, validation.Detail(i18n.English))
+	for _, fe := range validationErrors {
+		p.Errors = append(p.Errors, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: validation.Message(fe, i18n.English),
+		})
+	}
+	return p.Wrap(validationErrors)
+}
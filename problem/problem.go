@@ -0,0 +1,172 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"library-api/i18n"
+	"library-api/validation"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ContentType - Tipo de contenido de los errores (RFC 7807)
+const ContentType = "application/problem+json"
+
+// typePrefix - Prefijo del campo type; el sufijo es el código estable
+const typePrefix = "urn:library-api:problem:"
+
+// Códigos estables de error: los clientes deben usarlos en lugar del texto
+const (
+	CodeBadRequest           = "bad_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnsupportedFormat    = "unsupported_format"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInvalidISBN          = "invalid_isbn"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeBookNotFound         = "book_not_found"
+	CodeLoanNotFound         = "loan_not_found"
+	CodeAuthorNotFound       = "author_not_found"
+	CodeSubjectNotFound      = "subject_not_found"
+	CodeJobNotFound          = "job_not_found"
+	CodeUserNotFound         = "user_not_found"
+	CodeBackupNotFound       = "backup_not_found"
+	CodeBookUnavailable      = "book_unavailable"
+	CodeLoanLimitReached     = "loan_limit_reached"
+	CodeRateLimited          = "rate_limited"
+	CodeISBNConflict         = "isbn_conflict"
+	CodeUsernameTaken        = "username_taken"
+	CodeJobFinished          = "job_finished"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeImportFailed         = "import_failed"
+	CodeExternalService      = "external_service_error"
+	CodeUnavailable          = "service_unavailable"
+	CodeQueryTimeout         = "query_timeout"
+	CodeInternal             = "internal_error"
+)
+
+// Problem - Error de la API en formato problem+json. Es un error de Go: los
+// handlers lo registran con c.Error y el middleware de errores lo responde
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+
+	// Extensions - Miembros adicionales (por ejemplo "missing" o "job")
+	Extensions map[string]any `json:"-"`
+
+	cause error
+
+	// format y args - Detalle sin formatear, para traducirlo con i18n
+	format string
+	args   []any
+}
+
+// FieldError - Detalle de un campo que no pasó la validación
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// New - Crea un problema con su código estable
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		format: detail,
+	}
+}
+
+// Newf - Como New con el detalle formateado
+func Newf(status int, code, format string, args ...any) *Problem {
+	p := New(status, code, fmt.Sprintf(format, args...))
+	p.format, p.args = format, args
+	return p
+}
+
+// BadRequest - Parámetros o cuerpo de la petición inválidos
+func BadRequest(detail string) *Problem {
+	return New(http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+// With - Agrega un miembro de extensión
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Wrap - Conserva el error original (para los logs; no se envía al cliente)
+func (p *Problem) Wrap(err error) *Problem {
+	p.cause = err
+	return p
+}
+
+// Localize - Traduce el título y el detalle al idioma indicado (es, en); los
+// errores de validación traducen además el mensaje de cada campo
+func (p *Problem) Localize(lang string) {
+	p.Title = i18n.T(lang, http.StatusText(p.Status))
+	switch {
+	case p.args != nil:
+		p.Detail = i18n.Sprintf(lang, p.format, p.args...)
+	case p.format != "":
+		p.Detail = i18n.T(lang, p.format)
+	}
+
+	var validationErrors validator.ValidationErrors
+	if p.Code == CodeValidationFailed && errors.As(p.cause, &validationErrors) && len(validationErrors) == len(p.Errors) {
+		p.Detail = validation.Detail(lang)
+		for i, fe := range validationErrors {
+			p.Errors[i].Message = validation.Message(fe, lang)
+		}
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.cause != nil {
+		return p.Code + ": " + p.cause.Error()
+	}
+	return p.Code + ": " + p.Detail
+}
+
+func (p *Problem) Unwrap() error {
+	return p.cause
+}
+
+// MarshalJSON - Los miembros de extensión van al mismo nivel que los estándar
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	data, err := json.Marshal((*plain)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	members := make(map[string]any)
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	extensions := maps.Clone(p.Extensions)
+	for key := range members {
+		delete(extensions, key) // los miembros estándar no se pisan
+	}
+	maps.Copy(members, extensions)
+	return json.Marshal(members)
+}
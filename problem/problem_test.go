@@ -0,0 +1,75 @@
+package problem_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"library-api/problem"
+	"library-api/storage"
+)
+
+func TestFromMapsSentinels(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("error getting book: %w", storage.ErrBookNotFound), http.StatusNotFound, problem.CodeBookNotFound},
+		{storage.ErrBookNotAvailable, http.StatusBadRequest, problem.CodeBookUnavailable},
+		{storage.ErrDuplicateISBN, http.StatusConflict, problem.CodeISBNConflict},
+		{fmt.Errorf("error listing books: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, problem.CodeQueryTimeout},
+		{errors.New("disk on fire"), http.StatusInternalServerError, problem.CodeInternal},
+	}
+	for _, tc := range cases {
+		p := problem.From(tc.err)
+		if p.Status != tc.status || p.Code != tc.code || p.Type != "urn:library-api:problem:"+tc.code {
+			t.Errorf("From(%v): got %d %s (%s)", tc.err, p.Status, p.Code, p.Type)
+		}
+		if tc.code == problem.CodeInternal && p.Detail == tc.err.Error() {
+			t.Errorf("From(%v): internal detail leaked to the client", tc.err)
+		}
+	}
+}
+
+func TestProblemJSONExtensions(t *testing.T) {
+	p := problem.New(http.StatusNotFound, problem.CodeBookNotFound, "Some books were not found").
+		With("missing", []int{7}).
+		With("code", "overridden")
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if body["code"] != problem.CodeBookNotFound || body["status"] != float64(http.StatusNotFound) {
+		t.Errorf("standard members: got %v", body)
+	}
+	if missing, ok := body["missing"].([]any); !ok || len(missing) != 1 {
+		t.Errorf("extension missing: got %v", body["missing"])
+	}
+}
+
+func TestFromInvalidValueHidesErrorText(t *testing.T) {
+	err := fmt.Errorf("error creating book: %w",
+		storage.InvalidValue(storage.ErrInvalidISBN, "isbn", "123", `"123": internal parser detail`))
+
+	p := problem.From(err)
+	if p.Code != problem.CodeInvalidISBN || p.Detail != "Invalid ISBN" {
+		t.Errorf("From: got %s %q, want invalid_isbn with a fixed detail", p.Code, p.Detail)
+	}
+	if p.Extensions["isbn"] != "123" || len(p.Extensions) != 1 {
+		t.Errorf("extensions: got %v, want only isbn", p.Extensions)
+	}
+
+	p.Localize("es")
+	if p.Detail != "ISBN inválido" {
+		t.Errorf("Localize(es): got %q", p.Detail)
+	}
+}
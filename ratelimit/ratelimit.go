@@ -0,0 +1,122 @@
+// Package ratelimit - Límites de peticiones con token bucket: cada clave
+// (usuario, API key o IP en una regla) tiene un bucket de Requests fichas que
+// se repone a ritmo constante durante Per. El estado vive en un Store: en
+// memoria para una instancia o compartido (Redis...) para varias
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit - Cuota: Requests peticiones cada Per (también es la ráfaga máxima)
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit - Lee una cuota escrita como "30/1m" (peticiones/período)
+func ParseLimit(value string) (Limit, error) {
+	requests, per, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: use requests/period, e.g. 30/1m", value)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: the number of requests must be positive", value)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: the period must be a positive duration", value)
+	}
+
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// String - La cuota en el formato de ParseLimit
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// rate - Fichas que se reponen por segundo
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result - Resultado de consumir una ficha
+type Result struct {
+	Allowed bool
+	// Limit - Tamaño del bucket
+	Limit int
+	// Remaining - Fichas que quedan tras esta petición
+	Remaining int
+	// Reset - Tiempo hasta que el bucket vuelve a estar lleno
+	Reset time.Duration
+	// RetryAfter - Tiempo hasta la próxima ficha (solo si no se permitió)
+	RetryAfter time.Duration
+}
+
+// Store - Backend de los buckets. Take consume una ficha del bucket de key
+// si la hay. Un backend compartido entre instancias implementa esta misma
+// interfaz
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ==============================================
+// REGLAS POR RUTA
+// ==============================================
+
+// Claves de los buckets de una regla
+const (
+	// ByClient - API key conocida, si no el usuario del token y si no la IP
+	ByClient = "client"
+	// ByIP - Siempre la IP (login y registro: el usuario aún no existe)
+	ByIP = "ip"
+)
+
+// Rule - Cuota propia de una ruta de Gin
+type Rule struct {
+	Method string
+	Path   string
+	Limit  Limit
+	By     string
+}
+
+// ParseRule - Lee una regla escrita como "GET /api/external/search=30/1m",
+// con "@ip" al final para contar por IP ("POST /login=10/1m@ip")
+func ParseRule(value string) (Rule, error) {
+	route, quota, ok := strings.Cut(strings.TrimSpace(value), "=")
+	method, path, okRoute := strings.Cut(strings.TrimSpace(route), " ")
+	if !ok || !okRoute || !strings.HasPrefix(path, "/") {
+		return Rule{}, fmt.Errorf("invalid rate limit rule %q: use \"METHOD /path=requests/period[@ip]\"", value)
+	}
+
+	rule := Rule{Method: strings.ToUpper(method), Path: strings.TrimSpace(path), By: ByClient}
+	if before, after, found := strings.Cut(quota, "@"); found {
+		if after != ByIP && after != ByClient {
+			return Rule{}, fmt.Errorf("invalid rate limit rule %q: the key must be @ip or @client", value)
+		}
+		quota, rule.By = before, after
+	}
+
+	limit, err := ParseLimit(quota)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid rate limit rule %q: %w", value, err)
+	}
+	rule.Limit = limit
+	return rule, nil
+}
+
+// String - La regla en el formato de ParseRule
+func (r Rule) String() string {
+	rule := r.Method + " " + r.Path + "=" + r.Limit.String()
+	if r.By == ByIP {
+		rule += "@" + ByIP
+	}
+	return rule
+}
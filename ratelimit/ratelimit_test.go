@@ -0,0 +1,116 @@
+package ratelimit_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"library-api/ratelimit"
+)
+
+func TestParseLimitAndRule(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("30/1m")
+	if err != nil || limit != (ratelimit.Limit{Requests: 30, Per: time.Minute}) {
+		t.Fatalf("ParseLimit: got %+v, %v", limit, err)
+	}
+	for _, bad := range []string{"", "30", "0/1m", "-1/1m", "x/1m", "30/0s", "30/minute"} {
+		if _, err := ratelimit.ParseLimit(bad); err == nil {
+			t.Errorf("ParseLimit(%q): expected an error", bad)
+		}
+	}
+
+	rule, err := ratelimit.ParseRule("post /login=10/1m@ip")
+	if err != nil {
+		t.Fatalf("ParseRule: %v", err)
+	}
+	want := ratelimit.Rule{Method: "POST", Path: "/login", Limit: ratelimit.Limit{Requests: 10, Per: time.Minute}, By: ratelimit.ByIP}
+	if rule != want || rule.String() != "POST /login=10/1m0s@ip" {
+		t.Errorf("ParseRule: got %+v (%s)", rule, rule)
+	}
+	if rule, _ := ratelimit.ParseRule("GET /api/external/search=30/1m"); rule.By != ratelimit.ByClient {
+		t.Errorf("rule without @: got by %q", rule.By)
+	}
+	for _, bad := range []string{"/login=10/1m", "GET login=10/1m", "GET /login", "GET /login=10/1m@user", "GET /login=ten/1m"} {
+		if _, err := ratelimit.ParseRule(bad); err == nil {
+			t.Errorf("ParseRule(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := ratelimit.NewMemoryStore()
+	store.SetClock(func() time.Time { return now })
+	limit := ratelimit.Limit{Requests: 3, Per: 3 * time.Second}
+	ctx := context.Background()
+
+	// La ráfaga completa y después nada
+	for i := 2; i >= 0; i-- {
+		result, _ := store.Take(ctx, "ip:1", limit)
+		if !result.Allowed || result.Remaining != i || result.Limit != 3 {
+			t.Fatalf("take %d: got %+v", 3-i, result)
+		}
+	}
+	result, _ := store.Take(ctx, "ip:1", limit)
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Fatalf("over the limit: got %+v", result)
+	}
+
+	// Otra clave tiene su propio bucket
+	if result, _ := store.Take(ctx, "ip:2", limit); !result.Allowed {
+		t.Error("another key: expected to be allowed")
+	}
+
+	// Una ficha por segundo
+	now = now.Add(time.Second)
+	if result, _ := store.Take(ctx, "ip:1", limit); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after a second: got %+v", result)
+	}
+	if result, _ := store.Take(ctx, "ip:1", limit); result.Allowed {
+		t.Errorf("after a second, second take: got %+v", result)
+	}
+
+	// Los buckets llenos se descartan
+	now = now.Add(10 * time.Minute)
+	if result, _ := store.Take(ctx, "ip:3", limit); !result.Allowed {
+		t.Error("after the sweep: expected to be allowed")
+	}
+	if n := store.Len(); n != 1 {
+		t.Errorf("after the sweep: got %d buckets, want 1", n)
+	}
+}
+
+func TestMemoryStoreConcurrent(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 50, Per: time.Hour}
+
+	allowed := make(chan bool, 200)
+	done := make(chan struct{})
+	for w := 0; w < 4; w++ {
+		go func() {
+			for i := 0; i < 50; i++ {
+				result, err := store.Take(context.Background(), "user:1", limit)
+				if err != nil {
+					panic(fmt.Sprint("Take: ", err))
+				}
+				allowed <- result.Allowed
+			}
+			done <- struct{}{}
+		}()
+	}
+	for w := 0; w < 4; w++ {
+		<-done
+	}
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	if count != 50 {
+		t.Errorf("got %d allowed requests, want 50", count)
+	}
+}
@@ -0,0 +1,122 @@
+// Package server - Servidor HTTP de la API: plazos de lectura y escritura,
+// límite de cabeceras, apagado ordenado y TLS opcional con recarga del
+// certificado
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Config - Parámetros del servidor HTTP
+type Config struct {
+	// Addr - Dirección de escucha (":8080")
+	Addr string
+	// ReadTimeout - Plazo para leer la petición completa, cuerpo incluido
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout - Plazo para leer las cabeceras (frena a los clientes lentos)
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout - Plazo para escribir la respuesta (0: sin límite)
+	WriteTimeout time.Duration
+	// IdleTimeout - Tiempo que una conexión keep-alive puede quedar inactiva
+	IdleTimeout time.Duration
+	// MaxHeaderBytes - Tamaño máximo de las cabeceras de una petición
+	MaxHeaderBytes int
+	// ShutdownTimeout - Plazo para terminar las peticiones en curso al apagar
+	ShutdownTimeout time.Duration
+	// TLSCertFile y TLSKeyFile - Certificado y clave en PEM (vacíos: HTTP sin
+	// TLS). Se recargan al cambiar los archivos o con SIGHUP
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// DefaultConfig - Plazos razonables para una API JSON detrás de un proxy
+var DefaultConfig = Config{
+	Addr:              ":8080",
+	ReadTimeout:       30 * time.Second,
+	ReadHeaderTimeout: 10 * time.Second,
+	WriteTimeout:      2 * time.Minute,
+	IdleTimeout:       2 * time.Minute,
+	MaxHeaderBytes:    1 << 20,
+	ShutdownTimeout:   30 * time.Second,
+}
+
+// TLS - Indica si el servidor atiende con TLS
+func (c Config) TLS() bool {
+	return c.TLSCertFile != "" || c.TLSKeyFile != ""
+}
+
+// New - http.Server con los plazos y límites de config
+func New(handler http.Handler, config Config) *http.Server {
+	return &http.Server{
+		Addr:              config.Addr,
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+	}
+}
+
+// Run - Atiende peticiones hasta que ctx se cancela (SIGTERM, SIGINT) y
+// entonces deja de aceptar conexiones y espera, hasta ShutdownTimeout, a que
+// terminen las peticiones en curso. Devuelve nil tras un apagado ordenado
+func Run(ctx context.Context, handler http.Handler, config Config) error {
+	listener, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", config.Addr, err)
+	}
+	return Serve(ctx, listener, handler, config)
+}
+
+// Serve - Como Run, sobre un listener ya abierto
+func Serve(ctx context.Context, listener net.Listener, handler http.Handler, config Config) error {
+	srv := New(handler, config)
+
+	if config.TLS() {
+		certs, err := NewCertReloader(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		go certs.Watch(ctx, certCheckInterval)
+		srv.TLSConfig = certs.TLSConfig()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			// Certificado y clave salen de TLSConfig.GetCertificate
+			serveErr <- srv.ServeTLS(listener, "", "")
+		} else {
+			serveErr <- srv.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("error serving HTTP: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Apagando el servidor, esperando a las peticiones en curso", "timeout", config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Plazo vencido: se cortan las conexiones que quedan
+		srv.Close()
+		return fmt.Errorf("error draining requests: %w", err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving HTTP: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// certCheckInterval - Cada cuánto se revisa si el certificado cambió en disco
+const certCheckInterval = time.Minute
+
+// CertReloader - Certificado TLS que se puede reemplazar sin reiniciar: las
+// conexiones nuevas usan siempre el último cargado (p. ej. tras renovarlo
+// con certbot)
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader - Carga el certificado y la clave (PEM)
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("TLS requires both a certificate and a key file")
+	}
+
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload - Vuelve a leer el certificado; si falla se conserva el anterior
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading TLS certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = r.filesModTime()
+	return nil
+}
+
+// GetCertificate - Para tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig - Configuración TLS que sirve el certificado vigente
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// Watch - Recarga el certificado con SIGHUP o cuando cambian los archivos
+// (revisados cada interval), hasta que ctx se cancela
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			r.reloadAndLog("SIGHUP")
+		case <-ticker.C:
+			r.mu.RLock()
+			changed := r.filesModTime().After(r.modTime)
+			r.mu.RUnlock()
+			if changed {
+				r.reloadAndLog("archivos modificados")
+			}
+		}
+	}
+}
+
+// reloadAndLog - Reload con el resultado en los logs
+func (r *CertReloader) reloadAndLog(reason string) {
+	if err := r.Reload(); err != nil {
+		slog.Warn("No se pudo recargar el certificado TLS, se mantiene el anterior", "reason", reason, "error", err)
+		return
+	}
+	slog.Info("Certificado TLS recargado", "reason", reason)
+}
+
+// filesModTime - Última modificación del certificado o de la clave
+func (r *CertReloader) filesModTime() time.Time {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
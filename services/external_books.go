@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,33 +14,93 @@ import (
 	"library-api/models"
 )
 
-// ExternalBookService - Servicio para consumir APIs de libros externas
+// ExternalBookService - Servicio para consumir APIs de libros externas. El
+// contexto cancela la llamada al proveedor y lleva el ID de la petición a
+// los logs
 type ExternalBookService interface {
-	SearchGoogleBooks(query string, maxResults int) ([]models.Book, error)
-	SearchOpenLibrary(query string, limit int) ([]models.Book, error)
-	GetGoogleBook(bookID string) (models.Book, error)
-	GetOpenLibraryBook(bookID string) (models.Book, error)
-	SearchGoogleBooksByISBN(isbn string, maxResults int) ([]models.Book, error)
-	SearchOpenLibraryByISBN(isbn string, limit int) ([]models.Book, error)
+	SearchGoogleBooks(ctx context.Context, query string, maxResults int) ([]models.Book, error)
+	SearchOpenLibrary(ctx context.Context, query string, limit int) ([]models.Book, error)
+	GetGoogleBook(ctx context.Context, bookID string) (models.Book, error)
+	GetOpenLibraryBook(ctx context.Context, bookID string) (models.Book, error)
+	SearchGoogleBooksByISBN(ctx context.Context, isbn string, maxResults int) ([]models.Book, error)
+	SearchOpenLibraryByISBN(ctx context.Context, isbn string, limit int) ([]models.Book, error)
+	// CircuitStates - Estado del circuito de cada proveedor
+	CircuitStates() map[string]string
+}
+
+// ProviderConfig - URLs de los proveedores y plazo de sus peticiones
+type ProviderConfig struct {
+	GoogleAPIKey string
+	// GoogleBooksURL - Endpoint de volúmenes de Google Books
+	GoogleBooksURL string
+	// OpenLibraryURL - Raíz de Open Library (se le agrega /search.json)
+	OpenLibraryURL string
+	// OpenLibraryCoversURL - Raíz del servicio de portadas de Open Library
+	OpenLibraryCoversURL string
+	// Timeout - Plazo de cada petición a un proveedor
+	Timeout time.Duration
+	// CacheTTL - Vigencia de las respuestas guardadas (0: sin caché)
+	CacheTTL time.Duration
+	// CircuitThreshold - Fallos seguidos que abren el circuito de un
+	// proveedor (0: sin circuito)
+	CircuitThreshold int
+	// CircuitCooldown - Espera con el circuito abierto antes de probar de nuevo
+	CircuitCooldown time.Duration
+	// Observer - Recibe cada llamada (métricas); puede ser nil
+	Observer ProviderObserver
+}
+
+// DefaultProviderConfig - Servicios públicos de Google Books y Open Library
+var DefaultProviderConfig = ProviderConfig{
+	GoogleBooksURL:       "https://www.googleapis.com/books/v1/volumes",
+	OpenLibraryURL:       "https://openlibrary.org",
+	OpenLibraryCoversURL: "https://covers.openlibrary.org",
+	Timeout:              15 * time.Second,
+	CacheTTL:             10 * time.Minute,
+	CircuitThreshold:     5,
+	CircuitCooldown:      30 * time.Second,
 }
 
 // externalBookServiceImpl - Implementación concreta
 type externalBookServiceImpl struct {
 	googleAPIKey string
+	config       ProviderConfig
 	client       *http.Client
+	circuits     map[string]*circuitBreaker
+	cache        *responseCache
 }
 
-// NewExternalBookService - Constructor
+// NewExternalBookService - Constructor con los proveedores por defecto
 func NewExternalBookService(googleAPIKey string) ExternalBookService {
+	config := DefaultProviderConfig
+	config.GoogleAPIKey = googleAPIKey
+	return NewExternalBookServiceWithConfig(config)
+}
+
+// NewExternalBookServiceWithConfig - Constructor con URLs y plazo propios
+func NewExternalBookServiceWithConfig(config ProviderConfig) ExternalBookService {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultProviderConfig.Timeout
+	}
+	config.GoogleBooksURL = strings.TrimSuffix(config.GoogleBooksURL, "/")
+	config.OpenLibraryURL = strings.TrimSuffix(config.OpenLibraryURL, "/")
+	config.OpenLibraryCoversURL = strings.TrimSuffix(config.OpenLibraryCoversURL, "/")
+
 	return &externalBookServiceImpl{
-		googleAPIKey: googleAPIKey,
+		googleAPIKey: config.GoogleAPIKey,
+		config:       config,
 		client: &http.Client{
-			Timeout: 15 * time.Second,
+			Timeout: config.Timeout,
 			Transport: &http.Transport{
 				MaxIdleConns:    10,
 				IdleConnTimeout: 30 * time.Second,
 			},
 		},
+		circuits: map[string]*circuitBreaker{
+			ProviderGoogleBooks: newCircuitBreaker(config.CircuitThreshold, config.CircuitCooldown),
+			ProviderOpenLibrary: newCircuitBreaker(config.CircuitThreshold, config.CircuitCooldown),
+		},
+		cache: newResponseCache(config.CacheTTL),
 	}
 }
 
@@ -68,8 +129,18 @@ type googleBookItem struct {
 	} `json:"volumeInfo"`
 }
 
-func (s *externalBookServiceImpl) SearchGoogleBooks(query string, maxResults int) ([]models.Book, error) {
-	baseURL := "https://www.googleapis.com/books/v1/volumes"
+func (s *externalBookServiceImpl) SearchGoogleBooks(ctx context.Context, query string, maxResults int) ([]models.Book, error) {
+	value, err := s.call(ctx, ProviderGoogleBooks, fmt.Sprintf("search %d %s", maxResults, query), func() (interface{}, error) {
+		return s.searchGoogleBooks(ctx, query, maxResults)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyBooks(value.([]models.Book)), nil
+}
+
+func (s *externalBookServiceImpl) searchGoogleBooks(ctx context.Context, query string, maxResults int) ([]models.Book, error) {
+	baseURL := s.config.GoogleBooksURL
 
 	params := url.Values{}
 	params.Add("q", query)
@@ -81,7 +152,7 @@ func (s *externalBookServiceImpl) SearchGoogleBooks(query string, maxResults int
 
 	url := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
@@ -89,7 +160,7 @@ func (s *externalBookServiceImpl) SearchGoogleBooks(query string, maxResults int
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error calling Google Books API: %v", err)
+		return nil, fmt.Errorf("error calling Google Books API: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -109,13 +180,23 @@ func (s *externalBookServiceImpl) SearchGoogleBooks(query string, maxResults int
 	return s.convertGoogleBooks(result.Items), nil
 }
 
-func (s *externalBookServiceImpl) GetGoogleBook(bookID string) (models.Book, error) {
-	url := fmt.Sprintf("https://www.googleapis.com/books/v1/volumes/%s", bookID)
+func (s *externalBookServiceImpl) GetGoogleBook(ctx context.Context, bookID string) (models.Book, error) {
+	value, err := s.call(ctx, ProviderGoogleBooks, "volume "+bookID, func() (interface{}, error) {
+		return s.getGoogleBook(ctx, bookID)
+	})
+	if err != nil {
+		return models.Book{}, err
+	}
+	return value.(models.Book), nil
+}
+
+func (s *externalBookServiceImpl) getGoogleBook(ctx context.Context, bookID string) (models.Book, error) {
+	url := fmt.Sprintf("%s/%s", s.config.GoogleBooksURL, bookID)
 	if s.googleAPIKey != "" {
 		url += fmt.Sprintf("?key=%s", s.googleAPIKey)
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return models.Book{}, fmt.Errorf("error creating request: %v", err)
 	}
@@ -123,13 +204,13 @@ func (s *externalBookServiceImpl) GetGoogleBook(bookID string) (models.Book, err
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return models.Book{}, fmt.Errorf("error calling Google Books API: %v", err)
+		return models.Book{}, fmt.Errorf("error calling Google Books API: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode == http.StatusNotFound {
-			return models.Book{}, fmt.Errorf("book not found")
+			return models.Book{}, errBookNotFound
 		}
 		body, _ := io.ReadAll(resp.Body)
 		return models.Book{}, fmt.Errorf("Google Books API error: %s - %s", resp.Status, string(body))
@@ -143,8 +224,8 @@ func (s *externalBookServiceImpl) GetGoogleBook(bookID string) (models.Book, err
 	return s.convertGoogleBook(item), nil
 }
 
-func (s *externalBookServiceImpl) SearchGoogleBooksByISBN(isbn string, maxResults int) ([]models.Book, error) {
-	return s.SearchGoogleBooks(fmt.Sprintf("isbn:%s", isbn), maxResults)
+func (s *externalBookServiceImpl) SearchGoogleBooksByISBN(ctx context.Context, isbn string, maxResults int) ([]models.Book, error) {
+	return s.SearchGoogleBooks(ctx, fmt.Sprintf("isbn:%s", isbn), maxResults)
 }
 
 // ==============================================
@@ -158,21 +239,41 @@ type openLibraryDoc struct {
 	PublishYear   []int    `json:"publish_year"`
 	ISBN          []string `json:"isbn"`
 	Subject       []string `json:"subject"`
-	NumberOfPages int      `json:"number_of_pages"`
+	NumberOfPages int      `json:"number_of_pages_median"`
 	Description   string   `json:"description"`
+	Publisher     []string `json:"publisher"`
+	Language      []string `json:"language"`
+	CoverID       int      `json:"cover_i"`
+	EditionKey    []string `json:"edition_key"`
+	LCCN          []string `json:"lccn"`
+	OCLC          []string `json:"oclc"`
+}
+
+// openLibraryCoverPath - Ruta de la portada a partir del ID de portada de Open Library
+const openLibraryCoverPath = "/b/id/%d-M.jpg"
+
+func (s *externalBookServiceImpl) SearchOpenLibrary(ctx context.Context, query string, limit int) ([]models.Book, error) {
+	value, err := s.call(ctx, ProviderOpenLibrary, fmt.Sprintf("search %d %s", limit, query), func() (interface{}, error) {
+		return s.searchOpenLibrary(ctx, query, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyBooks(value.([]models.Book)), nil
 }
 
-func (s *externalBookServiceImpl) SearchOpenLibrary(query string, limit int) ([]models.Book, error) {
-	baseURL := "https://openlibrary.org/search.json"
+func (s *externalBookServiceImpl) searchOpenLibrary(ctx context.Context, query string, limit int) ([]models.Book, error) {
+	baseURL := s.config.OpenLibraryURL + "/search.json"
 
 	params := url.Values{}
 	params.Add("q", query)
 	params.Add("limit", strconv.Itoa(limit))
-	params.Add("fields", "key,title,author_name,publish_year,isbn,subject,number_of_pages,description")
+	params.Add("fields", "key,title,author_name,publish_year,isbn,subject,number_of_pages_median,description,"+
+		"publisher,language,cover_i,edition_key,lccn,oclc")
 
 	url := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
@@ -180,7 +281,7 @@ func (s *externalBookServiceImpl) SearchOpenLibrary(query string, limit int) ([]
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error calling Open Library API: %v", err)
+		return nil, fmt.Errorf("error calling Open Library API: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -199,22 +300,22 @@ func (s *externalBookServiceImpl) SearchOpenLibrary(query string, limit int) ([]
 	return s.convertOpenLibraryBooks(result.Docs), nil
 }
 
-func (s *externalBookServiceImpl) GetOpenLibraryBook(bookID string) (models.Book, error) {
+func (s *externalBookServiceImpl) GetOpenLibraryBook(ctx context.Context, bookID string) (models.Book, error) {
 	// Para Open Library, usamos búsqueda por ID/título
-	books, err := s.SearchOpenLibrary(bookID, 1)
+	books, err := s.SearchOpenLibrary(ctx, bookID, 1)
 	if err != nil {
 		return models.Book{}, err
 	}
 
 	if len(books) == 0 {
-		return models.Book{}, fmt.Errorf("book not found")
+		return models.Book{}, errBookNotFound
 	}
 
 	return books[0], nil
 }
 
-func (s *externalBookServiceImpl) SearchOpenLibraryByISBN(isbn string, limit int) ([]models.Book, error) {
-	return s.SearchOpenLibrary(fmt.Sprintf("isbn:%s", isbn), limit)
+func (s *externalBookServiceImpl) SearchOpenLibraryByISBN(ctx context.Context, isbn string, limit int) ([]models.Book, error) {
+	return s.SearchOpenLibrary(ctx, fmt.Sprintf("isbn:%s", isbn), limit)
 }
 
 // ==============================================
@@ -266,6 +367,9 @@ func (s *externalBookServiceImpl) convertGoogleBook(item googleBookItem) models.
 		genre = strings.Join(item.VolumeInfo.Categories, ", ")
 	}
 
+	// Portada (Google devuelve URLs http)
+	coverURL := strings.Replace(item.VolumeInfo.ImageLinks.Thumbnail, "http://", "https://", 1)
+
 	// Limpiar descripción (puede tener HTML)
 	description := item.VolumeInfo.Description
 	if len(description) > 500 {
@@ -276,10 +380,17 @@ func (s *externalBookServiceImpl) convertGoogleBook(item googleBookItem) models.
 		ID:          item.ID,
 		Title:       item.VolumeInfo.Title,
 		Author:      authors,
+		Authors:     item.VolumeInfo.Authors,
 		ISBN:        isbn,
 		Published:   publishedYear,
 		Genre:       genre,
+		Subjects:    item.VolumeInfo.Categories,
 		Description: description,
+		Publisher:   item.VolumeInfo.Publisher,
+		Language:    item.VolumeInfo.Language,
+		PageCount:   item.VolumeInfo.PageCount,
+		CoverURL:    coverURL,
+		GoogleID:    item.ID,
 		Available:   true,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
@@ -355,16 +466,37 @@ func (s *externalBookServiceImpl) convertOpenLibraryBook(doc openLibraryDoc) mod
 		}
 	}
 
+	coverURL := ""
+	if doc.CoverID > 0 {
+		coverURL = s.config.OpenLibraryCoversURL + fmt.Sprintf(openLibraryCoverPath, doc.CoverID)
+	}
+
 	return models.Book{
 		ID:          doc.Key,
 		Title:       doc.Title,
 		Author:      author,
+		Authors:     doc.AuthorName,
 		ISBN:        isbn,
 		Published:   published,
 		Genre:       genre,
 		Description: description,
+		Publisher:   firstOf(doc.Publisher),
+		Language:    firstOf(doc.Language),
+		PageCount:   doc.NumberOfPages,
+		CoverURL:    coverURL,
+		OLID:        firstOf(doc.EditionKey),
+		LCCN:        firstOf(doc.LCCN),
+		OCLC:        firstOf(doc.OCLC),
 		Available:   true,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 }
+
+// firstOf - Primer elemento de una lista o cadena vacía
+func firstOf(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"library-api/models"
+)
+
+// Nombres de los proveedores (etiqueta provider de las métricas)
+const (
+	ProviderGoogleBooks = "google_books"
+	ProviderOpenLibrary = "open_library"
+)
+
+// Resultado de una llamada a un proveedor
+const (
+	OutcomeOK = "ok"
+	// OutcomeError - El proveedor falló o no respondió a tiempo
+	OutcomeError = "error"
+	// OutcomeCacheHit - Respondió la caché, sin llamar al proveedor
+	OutcomeCacheHit = "cache_hit"
+	// OutcomeRejected - No se llamó: el circuito está abierto
+	OutcomeRejected = "circuit_open"
+)
+
+// Estados del circuito de un proveedor
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// ProviderObserver - Recibe cada llamada a un proveedor con su resultado y su
+// duración (cero si no se llamó)
+type ProviderObserver func(provider, outcome string, duration time.Duration)
+
+// ErrProviderUnavailable - El circuito del proveedor está abierto: falló
+// varias veces seguidas y no se lo consulta hasta que pase la espera
+var ErrProviderUnavailable = errors.New("provider temporarily unavailable")
+
+// errBookNotFound - El proveedor respondió, pero no tiene el libro (no
+// cuenta como fallo para el circuito)
+var errBookNotFound = errors.New("book not found")
+
+// ==============================================
+// CIRCUITO
+// ==============================================
+
+// circuitBreaker - Deja de llamar a un proveedor tras threshold fallos
+// seguidos; pasado cooldown deja pasar una llamada de prueba (half_open) y
+// según su resultado se cierra o vuelve a abrirse
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	// probing - Hay una llamada de prueba en curso
+	probing bool
+	now     func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: CircuitClosed, now: time.Now}
+}
+
+// allow - Si se puede llamar al proveedor ahora
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record - Resultado de una llamada permitida por allow; true si con ella el
+// circuito se abrió
+func (b *circuitBreaker) record(failed bool) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !failed {
+		b.state = CircuitClosed
+		b.failures = 0
+		return false
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		opened := b.state != CircuitOpen
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+		return opened
+	}
+	return false
+}
+
+// cancel - Una llamada permitida por allow que no terminó (el cliente se
+// fue): no cuenta, pero libera la llamada de prueba
+func (b *circuitBreaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State - closed, open o half_open
+func (b *circuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// ==============================================
+// CACHÉ DE RESPUESTAS
+// ==============================================
+
+// maxCacheEntries - Respuestas guardadas como máximo
+const maxCacheEntries = 1000
+
+// responseCache - Respuestas recientes de los proveedores: la misma búsqueda
+// repetida no gasta cuota de Google Books (ttl 0: sin caché)
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[string]cacheEntry), now: time.Now}
+}
+
+func (c *responseCache) get(key string) (interface{}, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *responseCache) put(key string, value interface{}) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= maxCacheEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	// Llena de respuestas vigentes: se descarta cualquiera
+	for k := range c.entries {
+		if len(c.entries) < maxCacheEntries {
+			break
+		}
+		delete(c.entries, k)
+	}
+	c.entries[key] = cacheEntry{value: value, expires: now.Add(c.ttl)}
+}
+
+// ==============================================
+// LLAMADAS A LOS PROVEEDORES
+// ==============================================
+
+// call - Consulta la caché; si no está, llama a fetch a través del circuito
+// del proveedor, informa el resultado al observer y lo registra en los logs
+// con el contexto (y así con el ID de la petición)
+func (s *externalBookServiceImpl) call(ctx context.Context, provider, key string, fetch func() (interface{}, error)) (interface{}, error) {
+	key = provider + " " + key
+	if value, ok := s.cache.get(key); ok {
+		s.observe(provider, OutcomeCacheHit, 0)
+		slog.DebugContext(ctx, "Respuesta del proveedor desde la caché", "provider", provider, "key", key)
+		return value, nil
+	}
+
+	circuit := s.circuits[provider]
+	if !circuit.allow() {
+		s.observe(provider, OutcomeRejected, 0)
+		slog.WarnContext(ctx, "Proveedor no consultado: circuito abierto", "provider", provider)
+		return nil, ErrProviderUnavailable
+	}
+
+	start := time.Now()
+	value, err := fetch()
+	elapsed := time.Since(start)
+
+	// El cliente se fue: no es un fallo del proveedor
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		circuit.cancel()
+		return nil, err
+	}
+
+	failed := err != nil && !errors.Is(err, errBookNotFound)
+	opened := circuit.record(failed)
+
+	if failed {
+		s.observe(provider, OutcomeError, elapsed)
+		slog.WarnContext(ctx, "Error consultando al proveedor", "provider", provider, "key", key,
+			"duration_ms", elapsed.Milliseconds(), "error", err)
+		if opened {
+			slog.WarnContext(ctx, "Circuito del proveedor abierto", "provider", provider, "cooldown", circuit.cooldown)
+		}
+		return nil, err
+	}
+	s.observe(provider, OutcomeOK, elapsed)
+	slog.DebugContext(ctx, "Consulta al proveedor", "provider", provider, "key", key, "duration_ms", elapsed.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(key, value)
+	return value, nil
+}
+
+func (s *externalBookServiceImpl) observe(provider, outcome string, duration time.Duration) {
+	if s.config.Observer != nil {
+		s.config.Observer(provider, outcome, duration)
+	}
+}
+
+// CircuitStates - Estado del circuito de cada proveedor (sonda /readyz)
+func (s *externalBookServiceImpl) CircuitStates() map[string]string {
+	states := make(map[string]string, len(s.circuits))
+	for provider, circuit := range s.circuits {
+		states[provider] = circuit.State()
+	}
+	return states
+}
+
+// copyBooks - Copia de una respuesta guardada en la caché, para que quien la
+// recibe pueda modificarla
+func copyBooks(books []models.Book) []models.Book {
+	return append([]models.Book(nil), books...)
+}
@@ -0,0 +1,138 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"library-api/services"
+)
+
+// fakeOpenLibrary - Open Library de pruebas que falla mientras failing sea true
+func fakeOpenLibrary(t *testing.T, failing *atomic.Bool, calls *atomic.Int32) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"docs": [{"key": "/works/OL1W", "title": "Rayuela", "author_name": ["Julio Cortázar"]}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// outcomes - Resultados informados al observer
+type outcomes struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (o *outcomes) observe(provider, outcome string, duration time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.seen = append(o.seen, provider+":"+outcome)
+}
+
+func (o *outcomes) last() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.seen[len(o.seen)-1]
+}
+
+func TestProviderCacheHits(t *testing.T) {
+	var failing atomic.Bool
+	var calls atomic.Int32
+	observed := &outcomes{}
+	config := services.DefaultProviderConfig
+	config.OpenLibraryURL = fakeOpenLibrary(t, &failing, &calls)
+	config.Observer = observed.observe
+	service := services.NewExternalBookServiceWithConfig(config)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		books, err := service.SearchOpenLibrary(ctx, "rayuela", 5)
+		if err != nil || len(books) != 1 || books[0].Title != "Rayuela" {
+			t.Fatalf("search %d: got %+v, %v", i+1, books, err)
+		}
+		books[0].Title = "modified by the caller"
+	}
+	if calls.Load() != 1 || observed.last() != "open_library:cache_hit" {
+		t.Errorf("got %d calls to the provider, outcomes %v", calls.Load(), observed.seen)
+	}
+
+	// Otra búsqueda no está en la caché
+	if _, err := service.SearchOpenLibrary(ctx, "rayuela", 10); err != nil || calls.Load() != 2 {
+		t.Errorf("different limit: got %d calls, %v", calls.Load(), err)
+	}
+}
+
+func TestProviderCircuitBreaker(t *testing.T) {
+	var failing atomic.Bool
+	var calls atomic.Int32
+	failing.Store(true)
+	observed := &outcomes{}
+	config := services.DefaultProviderConfig
+	config.OpenLibraryURL = fakeOpenLibrary(t, &failing, &calls)
+	config.CircuitThreshold = 2
+	config.CircuitCooldown = 50 * time.Millisecond
+	config.CacheTTL = 0
+	config.Observer = observed.observe
+	service := services.NewExternalBookServiceWithConfig(config)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.SearchOpenLibrary(ctx, "rayuela", 5); err == nil || errors.Is(err, services.ErrProviderUnavailable) {
+			t.Fatalf("failure %d: got %v", i+1, err)
+		}
+	}
+	if state := service.CircuitStates()[services.ProviderOpenLibrary]; state != services.CircuitOpen {
+		t.Fatalf("after %d failures: circuit %s", config.CircuitThreshold, state)
+	}
+
+	// Abierto: no se llama al proveedor
+	if _, err := service.SearchOpenLibrary(ctx, "rayuela", 5); !errors.Is(err, services.ErrProviderUnavailable) || calls.Load() != 2 {
+		t.Errorf("open circuit: got %v after %d calls", err, calls.Load())
+	}
+	if observed.last() != "open_library:circuit_open" {
+		t.Errorf("open circuit: outcomes %v", observed.seen)
+	}
+	if state := service.CircuitStates()[services.ProviderGoogleBooks]; state != services.CircuitClosed {
+		t.Errorf("Google Books circuit: got %s, want closed", state)
+	}
+
+	// Pasada la espera, una llamada de prueba que funciona lo cierra
+	time.Sleep(config.CircuitCooldown)
+	failing.Store(false)
+	if _, err := service.SearchOpenLibrary(ctx, "rayuela", 5); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if state := service.CircuitStates()[services.ProviderOpenLibrary]; state != services.CircuitClosed {
+		t.Errorf("after a successful probe: circuit %s", state)
+	}
+}
+
+func TestProviderCanceledRequestKeepsCircuitClosed(t *testing.T) {
+	var failing atomic.Bool
+	var calls atomic.Int32
+	config := services.DefaultProviderConfig
+	config.OpenLibraryURL = fakeOpenLibrary(t, &failing, &calls)
+	config.CircuitThreshold = 1
+	service := services.NewExternalBookServiceWithConfig(config)
+
+	// El cliente se fue antes de la respuesta: no es culpa del proveedor
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.SearchOpenLibrary(ctx, "rayuela", 5); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled request: got %v", err)
+	}
+	if state := service.CircuitStates()[services.ProviderOpenLibrary]; state != services.CircuitClosed {
+		t.Errorf("after a canceled request: circuit %s, want closed", state)
+	}
+}
@@ -0,0 +1,411 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"library-api/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DumpVersion - Versión del formato de Dump; Load rechaza las demás
+const DumpVersion = 1
+
+// ErrUnsupportedDump - El volcado es de una versión de formato desconocida
+var ErrUnsupportedDump = fmt.Errorf("unsupported dump version")
+
+// Dump - Copia completa de los datos en un formato común a todos los
+// backends: sirve para respaldos en JSON y para pasar datos de un backend a
+// otro. Conserva los IDs, las fechas y los hashes de las contraseñas.
+type Dump struct {
+	Version      int                  `json:"version"`
+	CreatedAt    time.Time            `json:"created_at"`
+	Users        []models.User        `json:"users"`
+	Books        []models.Book        `json:"books"`
+	Authors      []models.Author      `json:"authors"`
+	Subjects     []models.Subject     `json:"subjects"`
+	Loans        []models.Loan        `json:"loans"`
+	DeletedBooks []models.DeletedBook `json:"deleted_books"`
+	Jobs         []models.Job         `json:"jobs"`
+
+	// JobItems - Elementos resueltos de los trabajos, por ID de trabajo
+	JobItems map[string]models.JobItems `json:"job_items,omitempty"`
+}
+
+// Dumper - Backend capaz de exportar e importar todos sus datos. Dump es una
+// foto consistente (un único instante) y Load reemplaza todos los datos por
+// los del volcado, de forma atómica.
+type Dumper interface {
+	Dump(ctx context.Context) (*Dump, error)
+	Load(ctx context.Context, dump *Dump) error
+}
+
+// FileBackuper - Backend que puede copiar su base de datos a un archivo en
+// su formato nativo, que se restaura reemplazando el archivo original
+type FileBackuper interface {
+	BackupTo(ctx context.Context, path string) error
+}
+
+// newDump - Volcado vacío de la versión actual
+func newDump() *Dump {
+	return &Dump{
+		Version:      DumpVersion,
+		CreatedAt:    dbNow(),
+		Users:        []models.User{},
+		Books:        []models.Book{},
+		Authors:      []models.Author{},
+		Subjects:     []models.Subject{},
+		Loans:        []models.Loan{},
+		DeletedBooks: []models.DeletedBook{},
+		Jobs:         []models.Job{},
+		JobItems:     map[string]models.JobItems{},
+	}
+}
+
+// checkDump - Valida la versión de un volcado antes de cargarlo
+func checkDump(dump *Dump) error {
+	if dump == nil || dump.Version != DumpVersion {
+		version := 0
+		if dump != nil {
+			version = dump.Version
+		}
+		return fmt.Errorf("%w: %d (expected %d)", ErrUnsupportedDump, version, DumpVersion)
+	}
+	return nil
+}
+
+// ==============================================
+// MEMORIA
+// ==============================================
+
+// Dump - Copia de todos los mapas, ordenada para que dos volcados de los
+// mismos datos sean idénticos
+func (s *MemoryStore) Dump(ctx context.Context) (*Dump, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.dumpData(), nil
+}
+
+// dumpData - Volcado de los mapas (con el lock tomado)
+func (s *MemoryStore) dumpData() *Dump {
+	dump := newDump()
+	for _, user := range s.users {
+		dump.Users = append(dump.Users, user)
+	}
+	for _, book := range s.books {
+		dump.Books = append(dump.Books, book)
+	}
+	for _, author := range s.authors {
+		author.BookCount = 0
+		dump.Authors = append(dump.Authors, author)
+	}
+	for _, subject := range s.subjects {
+		subject.BookCount = 0
+		dump.Subjects = append(dump.Subjects, subject)
+	}
+	for _, loan := range s.loans {
+		dump.Loans = append(dump.Loans, loan)
+	}
+	for _, deleted := range s.deleted {
+		dump.DeletedBooks = append(dump.DeletedBooks, deleted)
+	}
+	for _, job := range s.jobs {
+		dump.Jobs = append(dump.Jobs, job)
+	}
+	for id, items := range s.jobItems {
+		dump.JobItems[id] = items
+	}
+
+	sortDump(dump)
+	return dump
+}
+
+// Load - Reemplaza todos los datos por los del volcado. Si el store es
+// persistente guarda además una foto: el journal solo registra cambios
+// sueltos, no un reemplazo completo.
+func (s *MemoryStore) Load(ctx context.Context, dump *Dump) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	err := s.loadData(dump)
+	s.changes = nil
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.Snapshot()
+}
+
+// loadData - Reemplaza los mapas por los del volcado (con el lock de
+// escritura tomado, o antes de compartir el store)
+func (s *MemoryStore) loadData(dump *Dump) error {
+	if err := checkDump(dump); err != nil {
+		return err
+	}
+
+	loaded := &MemoryStore{
+		books:    make(map[string]models.Book, len(dump.Books)),
+		loans:    make(map[string]models.Loan, len(dump.Loans)),
+		users:    make(map[string]models.User, len(dump.Users)),
+		authors:  make(map[string]models.Author, len(dump.Authors)),
+		subjects: make(map[string]models.Subject, len(dump.Subjects)),
+		isbns:    make(map[string]string, len(dump.Books)),
+		deleted:  make(map[string]models.DeletedBook, len(dump.DeletedBooks)),
+		jobs:     make(map[string]models.Job, len(dump.Jobs)),
+		jobItems: make(map[string]models.JobItems, len(dump.JobItems)),
+	}
+
+	for _, user := range dump.Users {
+		loaded.users[user.ID] = user
+	}
+	for _, author := range dump.Authors {
+		author.BookCount = 0
+		loaded.authors[strings.ToLower(author.Name)] = author
+	}
+	for _, subject := range dump.Subjects {
+		subject.BookCount = 0
+		loaded.subjects[strings.ToLower(subject.Name)] = subject
+	}
+	for _, book := range dump.Books {
+		book.NormalizeContributors()
+		loaded.books[book.ID] = book
+		loaded.isbns[book.ISBN] = book.ID
+		// Autores y materias que el volcado no trae en sus listas
+		loaded.registerContributors(book)
+	}
+	for _, loan := range dump.Loans {
+		loaded.loans[loan.ID] = loan
+	}
+	for _, deleted := range dump.DeletedBooks {
+		loaded.deleted[deleted.ID] = deleted
+	}
+	for _, job := range dump.Jobs {
+		job.Items = nil
+		loaded.jobs[job.ID] = job
+	}
+	for id, items := range dump.JobItems {
+		if _, exists := loaded.jobs[id]; exists && items != nil {
+			loaded.jobItems[id] = items
+		}
+	}
+
+	s.books, s.loans, s.users = loaded.books, loaded.loans, loaded.users
+	s.authors, s.subjects, s.isbns = loaded.authors, loaded.subjects, loaded.isbns
+	s.deleted, s.jobs, s.jobItems = loaded.deleted, loaded.jobs, loaded.jobItems
+	return nil
+}
+
+// ==============================================
+// SQL
+// ==============================================
+
+// Dump - Lee todas las tablas dentro de una transacción de solo lectura, así
+// el volcado corresponde a un único instante aunque haya escrituras en curso.
+// Como ForEachBook, recorre tablas enteras y no tiene plazo por operación.
+func (s *sqlStore) Dump(ctx context.Context) (*Dump, error) {
+	if s.tx != nil {
+		return s.withoutTimeout().dumpTables(ctx)
+	}
+
+	tx, err := s.pool.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	txStore := &sqlStore{db: tx, pool: s.pool, tx: tx, dialect: s.dialect}
+	return txStore.dumpTables(ctx)
+}
+
+// dumpTables - Lee todas las tablas con la conexión actual
+func (s *sqlStore) dumpTables(ctx context.Context) (*Dump, error) {
+	dump := newDump()
+
+	if err := s.db.SelectContext(ctx, &dump.Users, `SELECT * FROM users`); err != nil {
+		return nil, fmt.Errorf("error dumping users: %w", err)
+	}
+
+	books, err := s.GetBooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error dumping books: %w", err)
+	}
+	dump.Books = append(dump.Books, books...)
+
+	if err := s.db.SelectContext(ctx, &dump.Authors, `SELECT id, name FROM authors`); err != nil {
+		return nil, fmt.Errorf("error dumping authors: %w", err)
+	}
+	if err := s.db.SelectContext(ctx, &dump.Subjects, `SELECT id, name FROM subjects`); err != nil {
+		return nil, fmt.Errorf("error dumping subjects: %w", err)
+	}
+	if err := s.db.SelectContext(ctx, &dump.Loans, `SELECT * FROM loans`); err != nil {
+		return nil, fmt.Errorf("error dumping loans: %w", err)
+	}
+	if err := s.db.SelectContext(ctx, &dump.DeletedBooks, `SELECT * FROM deleted_books`); err != nil {
+		return nil, fmt.Errorf("error dumping deleted books: %w", err)
+	}
+	if err := s.db.SelectContext(ctx, &dump.Jobs, `SELECT `+jobColumns+` FROM jobs`); err != nil {
+		return nil, fmt.Errorf("error dumping jobs: %w", err)
+	}
+	var jobItems []struct {
+		ID    string          `db:"id"`
+		Items models.JobItems `db:"items"`
+	}
+	if err := s.db.SelectContext(ctx, &jobItems, `SELECT id, items FROM jobs WHERE items IS NOT NULL`); err != nil {
+		return nil, fmt.Errorf("error dumping job items: %w", err)
+	}
+	for _, row := range jobItems {
+		dump.JobItems[row.ID] = row.Items
+	}
+
+	sortDump(dump)
+	return dump, nil
+}
+
+// Load - Vacía las tablas e inserta el volcado en una sola transacción: si
+// algo falla la base de datos queda como estaba
+func (s *sqlStore) Load(ctx context.Context, dump *Dump) error {
+	if err := checkDump(dump); err != nil {
+		return err
+	}
+
+	// Sin plazo por operación: carga todas las tablas
+	return s.withoutTimeout().WithTx(ctx, func(tx Store) error {
+		return tx.(*sqlStore).loadTables(ctx, dump)
+	})
+}
+
+// loadTables - Reemplaza el contenido de las tablas (dentro de WithTx)
+func (s *sqlStore) loadTables(ctx context.Context, dump *Dump) error {
+	// Primero las tablas de relación, que referencian a libros, autores y materias
+	tables := []string{"book_authors", "book_subjects", "authors", "subjects", "books", "loans", "deleted_books", "jobs", "users"}
+	for _, table := range tables {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+			return fmt.Errorf("error clearing %s: %w", table, err)
+		}
+	}
+
+	for _, user := range dump.Users {
+		user.CreatedAt, user.UpdatedAt = user.CreatedAt.UTC(), user.UpdatedAt.UTC()
+		query := `INSERT INTO users (id, username, password, role, created_at, updated_at)
+              VALUES (:id, :username, :password, :role, :created_at, :updated_at)`
+		if _, err := s.db.NamedExecContext(ctx, query, user); err != nil {
+			return fmt.Errorf("error loading user %s: %w", user.Username, err)
+		}
+	}
+
+	// Autores y materias con sus IDs originales; replaceBookLinks los
+	// encuentra por nombre al vincular los libros
+	for _, author := range dump.Authors {
+		query := `INSERT INTO authors (id, name) VALUES (?, ?)`
+		if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), author.ID, author.Name); err != nil {
+			return fmt.Errorf("error loading author %s: %w", author.Name, err)
+		}
+	}
+	for _, subject := range dump.Subjects {
+		query := `INSERT INTO subjects (id, name) VALUES (?, ?)`
+		if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), subject.ID, subject.Name); err != nil {
+			return fmt.Errorf("error loading subject %s: %w", subject.Name, err)
+		}
+	}
+
+	for _, book := range dump.Books {
+		book.NormalizeContributors()
+		book.CreatedAt, book.UpdatedAt = book.CreatedAt.UTC(), book.UpdatedAt.UTC()
+		query := `INSERT INTO books (id, title, author, isbn, published, genre, description, available,
+              publisher, language, page_count, edition, cover_url, google_id, olid, lccn, oclc, translations, created_at, updated_at)
+              VALUES (:id, :title, :author, :isbn, :published, :genre, :description, :available,
+              :publisher, :language, :page_count, :edition, :cover_url, :google_id, :olid, :lccn, :oclc, :translations, :created_at, :updated_at)`
+		if _, err := s.db.NamedExecContext(ctx, query, book); err != nil {
+			return fmt.Errorf("error loading book %s: %w", book.ID, err)
+		}
+		if err := s.saveBookContributors(ctx, s.db, book); err != nil {
+			return err
+		}
+	}
+
+	for _, loan := range dump.Loans {
+		loan.LoanDate = loan.LoanDate.UTC()
+		if loan.ReturnDate != nil {
+			returned := loan.ReturnDate.UTC()
+			loan.ReturnDate = &returned
+		}
+		query := `INSERT INTO loans (id, book_id, "user", loan_date, return_date, returned)
+              VALUES (?, ?, ?, ?, ?, ?)`
+		_, err := s.db.ExecContext(ctx, s.db.Rebind(query), loan.ID, loan.BookID, loan.User, loan.LoanDate, loan.ReturnDate, loan.Returned)
+		if err != nil {
+			return fmt.Errorf("error loading loan %s: %w", loan.ID, err)
+		}
+	}
+
+	for _, deleted := range dump.DeletedBooks {
+		deleted.DeletedAt = deleted.DeletedAt.UTC()
+		query := `INSERT INTO deleted_books (id, isbn, title, deleted_at)
+              VALUES (:id, :isbn, :title, :deleted_at)`
+		if _, err := s.db.NamedExecContext(ctx, query, deleted); err != nil {
+			return fmt.Errorf("error loading deleted book %s: %w", deleted.ID, err)
+		}
+	}
+
+	for _, job := range dump.Jobs {
+		job.CreatedAt, job.UpdatedAt = job.CreatedAt.UTC(), job.UpdatedAt.UTC()
+		job.Items = dump.JobItems[job.ID]
+		query := `INSERT INTO jobs (id, type, status, params, total, processed, created_count, updated_count,
+              skipped_count, failed_count, item_errors, items, error, cancel_requested, created_by,
+              created_at, updated_at, started_at, finished_at)
+              VALUES (:id, :type, :status, :params, :total, :processed, :created_count, :updated_count,
+              :skipped_count, :failed_count, :item_errors, :items, :error, :cancel_requested, :created_by,
+              :created_at, :updated_at, :started_at, :finished_at)`
+		if _, err := s.db.NamedExecContext(ctx, query, job); err != nil {
+			return fmt.Errorf("error loading job %s: %w", job.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// ==============================================
+// AUXILIARES
+// ==============================================
+
+// withoutTimeout - Copia del store sin plazo por operación, para las que
+// recorren la base de datos entera
+func (s *sqlStore) withoutTimeout() *sqlStore {
+	unlimited := *s
+	unlimited.queryTimeout = 0
+	return &unlimited
+}
+
+// sortDump - Orden estable de cada lista (por ID)
+func sortDump(dump *Dump) {
+	sort.Slice(dump.Users, func(i, j int) bool { return dump.Users[i].ID < dump.Users[j].ID })
+	sort.Slice(dump.Books, func(i, j int) bool { return dump.Books[i].ID < dump.Books[j].ID })
+	sort.Slice(dump.Authors, func(i, j int) bool { return dump.Authors[i].ID < dump.Authors[j].ID })
+	sort.Slice(dump.Subjects, func(i, j int) bool { return dump.Subjects[i].ID < dump.Subjects[j].ID })
+	sort.Slice(dump.Loans, func(i, j int) bool { return dump.Loans[i].ID < dump.Loans[j].ID })
+	sort.Slice(dump.DeletedBooks, func(i, j int) bool { return dump.DeletedBooks[i].ID < dump.DeletedBooks[j].ID })
+	sort.Slice(dump.Jobs, func(i, j int) bool { return dump.Jobs[i].ID < dump.Jobs[j].ID })
+}
+
+// Counts - Cantidad de registros de cada tipo, para los manifiestos de
+// respaldo
+func (d *Dump) Counts() map[string]int {
+	return map[string]int{
+		"users":         len(d.Users),
+		"books":         len(d.Books),
+		"authors":       len(d.Authors),
+		"subjects":      len(d.Subjects),
+		"loans":         len(d.Loans),
+		"deleted_books": len(d.DeletedBooks),
+		"jobs":          len(d.Jobs),
+	}
+}
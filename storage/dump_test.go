@@ -0,0 +1,102 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+
+	"library-api/storage"
+	"library-api/storage/storetest"
+)
+
+// TestDumpMovesBetweenBackends - Un volcado de memoria cargado en SQLite y
+// devuelto a memoria conserva IDs, contraseñas, autores y préstamos
+func TestDumpMovesBetweenBackends(t *testing.T) {
+	memory := storage.NewMemoryStore()
+	storetest.SeedDumpData(t, memory)
+
+	original, err := memory.Dump(t.Context())
+	if err != nil {
+		t.Fatalf("memory Dump: %v", err)
+	}
+
+	sqlite, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	if err := sqlite.Load(t.Context(), original); err != nil {
+		t.Fatalf("sqlite Load: %v", err)
+	}
+
+	fromSQLite, err := sqlite.Dump(t.Context())
+	if err != nil {
+		t.Fatalf("sqlite Dump: %v", err)
+	}
+
+	back := storage.NewMemoryStore()
+	if err := back.Load(t.Context(), fromSQLite); err != nil {
+		t.Fatalf("memory Load: %v", err)
+	}
+	final, err := back.Dump(t.Context())
+	if err != nil {
+		t.Fatalf("memory Dump after round trip: %v", err)
+	}
+
+	for _, dump := range []*storage.Dump{fromSQLite, final} {
+		if got, want := dumpSummary(dump), dumpSummary(original); !slices.Equal(got, want) {
+			t.Errorf("round trip summary:\n got %v\nwant %v", got, want)
+		}
+	}
+
+	admin, err := sqlite.GetUserByUsername(t.Context(), "admin")
+	if err != nil {
+		t.Fatalf("GetUserByUsername(admin): %v", err)
+	}
+	for _, user := range original.Users {
+		if user.Username == "admin" && (user.ID != admin.ID || user.Password != admin.Password) {
+			t.Errorf("admin after load: got id %q, want %q (password hash kept: %v)", admin.ID, user.ID, user.Password == admin.Password)
+		}
+	}
+
+	active, err := back.GetActiveLoans(t.Context())
+	if err != nil || len(active) != 1 {
+		t.Errorf("GetActiveLoans after round trip: got %d, %v", len(active), err)
+	}
+}
+
+// dumpSummary - Lo que debe sobrevivir a un cambio de backend, sin las
+// fechas (cada backend las guarda con su precisión y zona horaria)
+func dumpSummary(dump *storage.Dump) []string {
+	var summary []string
+	for _, user := range dump.Users {
+		summary = append(summary, "user "+user.ID+" "+user.Username+" "+user.Role+" "+user.Password)
+	}
+	for _, book := range dump.Books {
+		summary = append(summary, "book "+book.ID+" "+book.Title+" "+book.ISBN+" "+strconv.FormatBool(book.Available))
+		for _, author := range book.Authors {
+			summary = append(summary, "book "+book.ID+" author "+author)
+		}
+		for _, subject := range book.Subjects {
+			summary = append(summary, "book "+book.ID+" subject "+subject)
+		}
+	}
+	for _, author := range dump.Authors {
+		summary = append(summary, "author "+author.ID+" "+author.Name)
+	}
+	for _, subject := range dump.Subjects {
+		summary = append(summary, "subject "+subject.ID+" "+subject.Name)
+	}
+	for _, loan := range dump.Loans {
+		summary = append(summary, "loan "+loan.ID+" "+loan.BookID+" "+loan.User+" "+strconv.FormatBool(loan.Returned))
+	}
+	for _, deleted := range dump.DeletedBooks {
+		summary = append(summary, "deleted "+deleted.ID+" "+deleted.ISBN)
+	}
+	for _, job := range dump.Jobs {
+		summary = append(summary, "job "+job.ID+" "+job.Status+" "+job.Params.Source)
+	}
+	sort.Strings(summary)
+	return summary
+}
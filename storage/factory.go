@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrUnknownStorage - STORAGE_TYPE no corresponde a ningún backend registrado
+var ErrUnknownStorage = fmt.Errorf("unknown storage type")
+
+// Config - Parámetros para abrir un Store
+type Config struct {
+	// Type - Backend: "sqlite", "postgres" o "memory"
+	Type string
+	// SQLitePath - Archivo de la base de datos SQLite
+	SQLitePath string
+	// PostgresDSN - Cadena de conexión de PostgreSQL
+	PostgresDSN string
+	// Postgres - Pool de conexiones de PostgreSQL
+	Postgres PostgresConfig
+	// QueryTimeout - Plazo de cada operación en los backends SQL (0: el de
+	// DefaultQueryTimeout; negativo: sin límite)
+	QueryTimeout time.Duration
+	// Memory - Persistencia del backend "memory" (Dir vacío: sin persistencia,
+	// los datos se pierden al reiniciar)
+	Memory MemoryPersistence
+	// Admin - Usuario admin que se crea si el almacenamiento está vacío
+	// (vacío: DefaultAdmin)
+	Admin AdminCredentials
+}
+
+// Opener - Abre un Store a partir de la configuración
+type Opener func(config Config) (Store, error)
+
+var openers = map[string]Opener{
+	"memory": func(config Config) (Store, error) {
+		if config.Memory.Dir == "" {
+			return NewMemoryStore(), nil
+		}
+		return OpenMemoryStore(config.Memory)
+	},
+	"sqlite": func(config Config) (Store, error) {
+		store, err := NewSQLiteStore(config.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		applyQueryTimeout(store.sqlStore, config)
+		return store, nil
+	},
+	"postgres": func(config Config) (Store, error) {
+		if config.PostgresDSN == "" {
+			return nil, fmt.Errorf("postgres storage requires a connection string")
+		}
+		store, err := NewPostgresStore(config.PostgresDSN, config.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		applyQueryTimeout(store.sqlStore, config)
+		return store, nil
+	},
+}
+
+// applyQueryTimeout - Aplica config.QueryTimeout si se indicó
+func applyQueryTimeout(store *sqlStore, config Config) {
+	if config.QueryTimeout != 0 {
+		store.SetQueryTimeout(config.QueryTimeout)
+	}
+}
+
+// Register - Agrega (o reemplaza) un backend de almacenamiento
+func Register(name string, opener Opener) {
+	openers[name] = opener
+}
+
+// Types - Backends disponibles, ordenados
+func Types() []string {
+	names := make([]string, 0, len(openers))
+	for name := range openers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open - Abre el Store indicado por config.Type
+func Open(config Config) (Store, error) {
+	opener, ok := openers[config.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (available: %v)", ErrUnknownStorage, config.Type, Types())
+	}
+
+	if config.Admin.Username != "" {
+		adminCredentials = config.Admin
+	}
+
+	store, err := opener(config)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s storage: %w", config.Type, err)
+	}
+
+	return store, nil
+}
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"library-api/models"
+	"time"
+)
+
+// ObserveFunc - Recibe el contexto, el nombre, la duración y el error de cada
+// operación de un store instrumentado
+type ObserveFunc func(ctx context.Context, operation string, duration time.Duration, err error)
+
+// instrumentedStore - Store que mide sus operaciones (ver Instrument)
+type instrumentedStore struct {
+	store   Store
+	observe ObserveFunc
+}
+
+// Instrument - Envuelve store para pasar cada operación a observe, también
+// las de las transacciones. Las capacidades opcionales (Dumper, ISBNMigrator,
+// FileBackuper) hay que buscarlas en el store original: ver Unwrap
+func Instrument(store Store, observe ObserveFunc) Store {
+	return &instrumentedStore{store: store, observe: observe}
+}
+
+// Unwrap - El store original de uno instrumentado (o el mismo store)
+func Unwrap(store Store) Store {
+	if instrumented, ok := store.(*instrumentedStore); ok {
+		return instrumented.store
+	}
+	return store
+}
+
+func (s *instrumentedStore) done(ctx context.Context, operation string, start time.Time, err *error) {
+	s.observe(ctx, operation, time.Since(start), *err)
+}
+
+// WithTx - La transacción completa cuenta como una operación y las de fn,
+// cada una por separado
+func (s *instrumentedStore) WithTx(ctx context.Context, fn func(tx Store) error) (err error) {
+	defer s.done(ctx, "WithTx", time.Now(), &err)
+	return s.store.WithTx(ctx, func(tx Store) error {
+		return fn(&instrumentedStore{store: tx, observe: s.observe})
+	})
+}
+
+func (s *instrumentedStore) Close() error {
+	return s.store.Close()
+}
+
+// loansWithBooks - Consultas de préstamos con sus libros, que tienen los dos
+// backends aunque no estén en Store (el handler de préstamos las busca)
+type loansWithBooks interface {
+	GetLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error)
+	GetActiveLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error)
+}
+
+func (s *instrumentedStore) GetLoansWithBooks(ctx context.Context) (result []models.LoanWithBook, err error) {
+	defer s.done(ctx, "GetLoansWithBooks", time.Now(), &err)
+	store, ok := s.store.(loansWithBooks)
+	if !ok {
+		return nil, fmt.Errorf("GetLoansWithBooks is not supported by %T", s.store)
+	}
+	return store.GetLoansWithBooks(ctx)
+}
+
+func (s *instrumentedStore) GetActiveLoansWithBooks(ctx context.Context) (result []models.LoanWithBook, err error) {
+	defer s.done(ctx, "GetActiveLoansWithBooks", time.Now(), &err)
+	store, ok := s.store.(loansWithBooks)
+	if !ok {
+		return nil, fmt.Errorf("GetActiveLoansWithBooks is not supported by %T", s.store)
+	}
+	return store.GetActiveLoansWithBooks(ctx)
+}
+
+// ==============================================
+// OPERACIONES DE Store
+// ==============================================
+
+func (s *instrumentedStore) CreateUser(ctx context.Context, user models.User) (result *models.User, err error) {
+	defer s.done(ctx, "CreateUser", time.Now(), &err)
+	return s.store.CreateUser(ctx, user)
+}
+
+func (s *instrumentedStore) GetUserByUsername(ctx context.Context, username string) (result *models.User, err error) {
+	defer s.done(ctx, "GetUserByUsername", time.Now(), &err)
+	return s.store.GetUserByUsername(ctx, username)
+}
+
+func (s *instrumentedStore) GetUserByID(ctx context.Context, id string) (result *models.User, err error) {
+	defer s.done(ctx, "GetUserByID", time.Now(), &err)
+	return s.store.GetUserByID(ctx, id)
+}
+
+func (s *instrumentedStore) UpdateUser(ctx context.Context, id string, user models.User) (result *models.User, err error) {
+	defer s.done(ctx, "UpdateUser", time.Now(), &err)
+	return s.store.UpdateUser(ctx, id, user)
+}
+
+func (s *instrumentedStore) DeleteUser(ctx context.Context, id string) (err error) {
+	defer s.done(ctx, "DeleteUser", time.Now(), &err)
+	return s.store.DeleteUser(ctx, id)
+}
+
+func (s *instrumentedStore) CreateBook(ctx context.Context, book models.Book) (result *models.Book, err error) {
+	defer s.done(ctx, "CreateBook", time.Now(), &err)
+	return s.store.CreateBook(ctx, book)
+}
+
+func (s *instrumentedStore) GetBooks(ctx context.Context) (result []models.Book, err error) {
+	defer s.done(ctx, "GetBooks", time.Now(), &err)
+	return s.store.GetBooks(ctx)
+}
+
+func (s *instrumentedStore) GetBookByID(ctx context.Context, id string) (result *models.Book, err error) {
+	defer s.done(ctx, "GetBookByID", time.Now(), &err)
+	return s.store.GetBookByID(ctx, id)
+}
+
+func (s *instrumentedStore) UpdateBook(ctx context.Context, id string, book models.Book) (result *models.Book, err error) {
+	defer s.done(ctx, "UpdateBook", time.Now(), &err)
+	return s.store.UpdateBook(ctx, id, book)
+}
+
+func (s *instrumentedStore) DeleteBook(ctx context.Context, id string) (err error) {
+	defer s.done(ctx, "DeleteBook", time.Now(), &err)
+	return s.store.DeleteBook(ctx, id)
+}
+
+func (s *instrumentedStore) SearchBooks(ctx context.Context, title, author, genre string, available *bool) (result []models.Book, err error) {
+	defer s.done(ctx, "SearchBooks", time.Now(), &err)
+	return s.store.SearchBooks(ctx, title, author, genre, available)
+}
+
+func (s *instrumentedStore) ForEachBook(ctx context.Context, title, author, genre string, available *bool, fn func(models.Book) error) (err error) {
+	defer s.done(ctx, "ForEachBook", time.Now(), &err)
+	return s.store.ForEachBook(ctx, title, author, genre, available, fn)
+}
+
+func (s *instrumentedStore) GetBookByISBN(ctx context.Context, isbn string) (result *models.Book, err error) {
+	defer s.done(ctx, "GetBookByISBN", time.Now(), &err)
+	return s.store.GetBookByISBN(ctx, isbn)
+}
+
+func (s *instrumentedStore) GetBooksByISBNs(ctx context.Context, isbns []string) (result map[string]models.Book, err error) {
+	defer s.done(ctx, "GetBooksByISBNs", time.Now(), &err)
+	return s.store.GetBooksByISBNs(ctx, isbns)
+}
+
+func (s *instrumentedStore) GetBookChanges(ctx context.Context, query models.BookChangeQuery) (result []models.BookChange, err error) {
+	defer s.done(ctx, "GetBookChanges", time.Now(), &err)
+	return s.store.GetBookChanges(ctx, query)
+}
+
+func (s *instrumentedStore) GetBookChange(ctx context.Context, id string) (result *models.BookChange, err error) {
+	defer s.done(ctx, "GetBookChange", time.Now(), &err)
+	return s.store.GetBookChange(ctx, id)
+}
+
+func (s *instrumentedStore) GetAuthors(ctx context.Context) (result []models.Author, err error) {
+	defer s.done(ctx, "GetAuthors", time.Now(), &err)
+	return s.store.GetAuthors(ctx)
+}
+
+func (s *instrumentedStore) GetAuthorByID(ctx context.Context, id string) (result *models.Author, err error) {
+	defer s.done(ctx, "GetAuthorByID", time.Now(), &err)
+	return s.store.GetAuthorByID(ctx, id)
+}
+
+func (s *instrumentedStore) GetBooksByAuthor(ctx context.Context, authorID string) (result []models.Book, err error) {
+	defer s.done(ctx, "GetBooksByAuthor", time.Now(), &err)
+	return s.store.GetBooksByAuthor(ctx, authorID)
+}
+
+func (s *instrumentedStore) GetSubjects(ctx context.Context) (result []models.Subject, err error) {
+	defer s.done(ctx, "GetSubjects", time.Now(), &err)
+	return s.store.GetSubjects(ctx)
+}
+
+func (s *instrumentedStore) GetSubjectByID(ctx context.Context, id string) (result *models.Subject, err error) {
+	defer s.done(ctx, "GetSubjectByID", time.Now(), &err)
+	return s.store.GetSubjectByID(ctx, id)
+}
+
+func (s *instrumentedStore) GetBooksBySubject(ctx context.Context, subjectID string) (result []models.Book, err error) {
+	defer s.done(ctx, "GetBooksBySubject", time.Now(), &err)
+	return s.store.GetBooksBySubject(ctx, subjectID)
+}
+
+func (s *instrumentedStore) CreateLoan(ctx context.Context, loan models.Loan) (result *models.Loan, err error) {
+	defer s.done(ctx, "CreateLoan", time.Now(), &err)
+	return s.store.CreateLoan(ctx, loan)
+}
+
+func (s *instrumentedStore) ReturnBook(ctx context.Context, loanID string) (err error) {
+	defer s.done(ctx, "ReturnBook", time.Now(), &err)
+	return s.store.ReturnBook(ctx, loanID)
+}
+
+func (s *instrumentedStore) GetLoans(ctx context.Context) (result []models.Loan, err error) {
+	defer s.done(ctx, "GetLoans", time.Now(), &err)
+	return s.store.GetLoans(ctx)
+}
+
+func (s *instrumentedStore) GetActiveLoans(ctx context.Context) (result []models.Loan, err error) {
+	defer s.done(ctx, "GetActiveLoans", time.Now(), &err)
+	return s.store.GetActiveLoans(ctx)
+}
+
+func (s *instrumentedStore) GetLoanByID(ctx context.Context, id string) (result *models.Loan, err error) {
+	defer s.done(ctx, "GetLoanByID", time.Now(), &err)
+	return s.store.GetLoanByID(ctx, id)
+}
+
+func (s *instrumentedStore) CreateJob(ctx context.Context, job models.Job) (result *models.Job, err error) {
+	defer s.done(ctx, "CreateJob", time.Now(), &err)
+	return s.store.CreateJob(ctx, job)
+}
+
+func (s *instrumentedStore) GetJobByID(ctx context.Context, id string) (result *models.Job, err error) {
+	defer s.done(ctx, "GetJobByID", time.Now(), &err)
+	return s.store.GetJobByID(ctx, id)
+}
+
+func (s *instrumentedStore) UpdateJob(ctx context.Context, job models.Job) (result *models.Job, err error) {
+	defer s.done(ctx, "UpdateJob", time.Now(), &err)
+	return s.store.UpdateJob(ctx, job)
+}
+
+func (s *instrumentedStore) SaveJobItems(ctx context.Context, id string, items models.JobItems) (err error) {
+	defer s.done(ctx, "SaveJobItems", time.Now(), &err)
+	return s.store.SaveJobItems(ctx, id, items)
+}
+
+func (s *instrumentedStore) GetJobItems(ctx context.Context, id string) (result models.JobItems, err error) {
+	defer s.done(ctx, "GetJobItems", time.Now(), &err)
+	return s.store.GetJobItems(ctx, id)
+}
+
+func (s *instrumentedStore) CancelJob(ctx context.Context, id string) (result *models.Job, err error) {
+	defer s.done(ctx, "CancelJob", time.Now(), &err)
+	return s.store.CancelJob(ctx, id)
+}
+
+func (s *instrumentedStore) GetJobs(ctx context.Context, statuses ...string) (result []models.Job, err error) {
+	defer s.done(ctx, "GetJobs", time.Now(), &err)
+	return s.store.GetJobs(ctx, statuses...)
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) (err error) {
+	defer s.done(ctx, "Ping", time.Now(), &err)
+	return s.store.Ping(ctx)
+}
+
+func (s *instrumentedStore) CheckSchema(ctx context.Context) (err error) {
+	defer s.done(ctx, "CheckSchema", time.Now(), &err)
+	return s.store.CheckSchema(ctx)
+}
+
+func (s *instrumentedStore) GetStats(ctx context.Context, overdueBefore time.Time) (result *Stats, err error) {
+	defer s.done(ctx, "GetStats", time.Now(), &err)
+	return s.store.GetStats(ctx, overdueBefore)
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"library-api/isbn"
+	"library-api/models"
+)
+
+// ISBNMigrator - Stores capaces de normalizar los ISBN ya guardados
+type ISBNMigrator interface {
+	MigrateISBNs(ctx context.Context) (*ISBNMigrationReport, error)
+}
+
+// ISBNMigrationReport - Resultado de la migración de ISBN existentes
+type ISBNMigrationReport struct {
+	Checked    int             `json:"checked"`
+	Normalized int             `json:"normalized"`
+	Unchanged  int             `json:"unchanged"`
+	Invalid    []ISBNIssue     `json:"invalid"`
+	Collisions []ISBNCollision `json:"collisions"`
+}
+
+// ISBNIssue - Libro cuyo ISBN no pudo normalizarse
+type ISBNIssue struct {
+	BookID string `json:"book_id"`
+	ISBN   string `json:"isbn"`
+	Reason string `json:"reason"`
+}
+
+// ISBNCollision - Varios libros que representan el mismo ISBN canónico
+type ISBNCollision struct {
+	ISBN    string   `json:"isbn"`
+	BookIDs []string `json:"book_ids"`
+}
+
+// normalizeBookISBN - Valida y convierte el ISBN del libro a ISBN-13
+func normalizeBookISBN(book *models.Book) error {
+	normalized, err := isbn.Normalize(book.ISBN)
+	if err != nil {
+		return InvalidValue(ErrInvalidISBN, "isbn", book.ISBN, fmt.Sprintf("%q: %v", book.ISBN, err))
+	}
+
+	book.ISBN = normalized
+	return nil
+}
+
+// planISBNMigration - Calcula qué libros deben actualizarse. Los ISBN
+// inválidos y las colisiones se reportan y no se modifican.
+func planISBNMigration(books []models.Book) (map[string]string, *ISBNMigrationReport) {
+	report := &ISBNMigrationReport{
+		Checked:    len(books),
+		Invalid:    []ISBNIssue{},
+		Collisions: []ISBNCollision{},
+	}
+
+	groups := make(map[string][]models.Book)
+	for _, book := range books {
+		normalized, err := isbn.Normalize(book.ISBN)
+		if err != nil {
+			report.Invalid = append(report.Invalid, ISBNIssue{
+				BookID: book.ID,
+				ISBN:   book.ISBN,
+				Reason: err.Error(),
+			})
+			continue
+		}
+		groups[normalized] = append(groups[normalized], book)
+	}
+
+	updates := make(map[string]string)
+	for normalized, group := range groups {
+		if len(group) > 1 {
+			collision := ISBNCollision{ISBN: normalized}
+			for _, book := range group {
+				collision.BookIDs = append(collision.BookIDs, book.ID)
+			}
+			sort.Strings(collision.BookIDs)
+			report.Collisions = append(report.Collisions, collision)
+			continue
+		}
+
+		if group[0].ISBN == normalized {
+			report.Unchanged++
+			continue
+		}
+		updates[group[0].ID] = normalized
+	}
+
+	sort.Slice(report.Collisions, func(i, j int) bool {
+		return report.Collisions[i].ISBN < report.Collisions[j].ISBN
+	})
+
+	return updates, report
+}
+
+// normalizeISBNList - Normaliza y elimina duplicados; los ISBN inválidos se omiten
+func normalizeISBNList(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	result := make([]string, 0, len(codes))
+	for _, code := range codes {
+		normalized, err := isbn.Normalize(code)
+		if err != nil || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		result = append(result, normalized)
+	}
+	return result
+}
+
+// isDuplicateISBNError - Detecta la violación del índice UNIQUE de books.isbn
+// (SQLite nombra la columna, PostgreSQL la restricción)
+func isDuplicateISBNError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "books.isbn") || strings.Contains(message, "books_isbn_key")
+}
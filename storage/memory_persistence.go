@@ -0,0 +1,557 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"library-api/models"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Persistencia opcional de MemoryStore: cada cierto tiempo se guarda una
+// foto completa de los datos (snapshot.json, un Dump) y entre fotos cada
+// registro creado, modificado o eliminado se agrega a un journal
+// (journal.jsonl, una línea JSON por cambio). Al abrir se carga la foto y se
+// reaplican los cambios del journal posteriores a ella.
+
+// Políticas de fsync del journal
+const (
+	// MemorySyncAlways - fsync tras cada operación: no se pierde nada
+	MemorySyncAlways = "always"
+	// MemorySyncInterval - fsync una vez por segundo: ante un corte de luz
+	// se puede perder el último segundo
+	MemorySyncInterval = "interval"
+	// MemorySyncNever - Sin fsync explícito, lo decide el sistema operativo
+	MemorySyncNever = "never"
+)
+
+// DefaultMemorySnapshotInterval - Frecuencia por defecto de las fotos
+const DefaultMemorySnapshotInterval = 5 * time.Minute
+
+// MemoryPersistence - Configuración de la persistencia de MemoryStore
+type MemoryPersistence struct {
+	// Dir - Directorio de la foto y el journal
+	Dir string
+	// SnapshotInterval - Frecuencia de las fotos (0: solo al abrir, al
+	// cargar un volcado y al cerrar)
+	SnapshotInterval time.Duration
+	// Sync - Política de fsync del journal ("" equivale a MemorySyncInterval)
+	Sync string
+}
+
+// Archivos del directorio de persistencia
+const (
+	memorySnapshotFile   = "snapshot.json"
+	memoryJournalFile    = "journal.jsonl"
+	memoryOldJournalFile = "journal.jsonl.old" // journal anterior a la foto en curso
+	memorySyncEvery      = time.Second
+)
+
+// Operaciones y tipos de registro del journal
+const (
+	journalPut    = "put"
+	journalDelete = "delete"
+
+	journalUser        = "user"
+	journalBook        = "book"
+	journalDeletedBook = "deleted_book"
+	journalAuthor      = "author"
+	journalSubject     = "subject"
+	journalLoan        = "loan"
+	journalJob         = "job"
+	journalJobItems    = "job_items"
+)
+
+// journalEntry - Un cambio del journal. Seq crece con cada cambio y permite
+// saltear los que ya están incluidos en la foto.
+type journalEntry struct {
+	Seq  uint64          `json:"seq"`
+	Op   string          `json:"op"`
+	Kind string          `json:"kind"`
+	ID   string          `json:"id"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// memorySnapshot - Foto completa con el último cambio que incluye
+type memorySnapshot struct {
+	Seq  uint64 `json:"seq"`
+	Dump *Dump  `json:"dump"`
+}
+
+// memoryPersister - Archivos de un MemoryStore persistente
+type memoryPersister struct {
+	config MemoryPersistence
+
+	mu    sync.Mutex // protege file, seq, dirty y broken
+	file  *os.File
+	seq   uint64
+	dirty bool // escrituras sin fsync
+	// broken - Falló una escritura y el journal puede terminar en una línea
+	// a medias: no se le agrega nada más hasta abrir uno nuevo en la próxima foto
+	broken bool
+
+	snapshotMu sync.Mutex // una foto a la vez
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// OpenMemoryStore - MemoryStore que guarda sus datos en config.Dir: carga la
+// foto y el journal existentes, guarda una foto nueva y empieza a registrar
+// los cambios
+func OpenMemoryStore(config MemoryPersistence) (*MemoryStore, error) {
+	if config.Sync == "" {
+		config.Sync = MemorySyncInterval
+	}
+	switch config.Sync {
+	case MemorySyncAlways, MemorySyncInterval, MemorySyncNever:
+	default:
+		return nil, fmt.Errorf("invalid memory sync policy %q (expected %s, %s or %s)",
+			config.Sync, MemorySyncAlways, MemorySyncInterval, MemorySyncNever)
+	}
+
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating memory data directory: %w", err)
+	}
+
+	store := NewMemoryStore()
+	persist := &memoryPersister{config: config}
+
+	snapshot, err := readMemorySnapshot(filepath.Join(config.Dir, memorySnapshotFile))
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		if err := store.loadData(snapshot.Dump); err != nil {
+			return nil, fmt.Errorf("error loading memory snapshot: %w", err)
+		}
+		persist.seq = snapshot.Seq
+	}
+
+	replayed := 0
+	for _, name := range []string{memoryOldJournalFile, memoryJournalFile} {
+		n, err := store.replayJournal(filepath.Join(config.Dir, name), &persist.seq)
+		if err != nil {
+			return nil, err
+		}
+		replayed += n
+	}
+
+	persist.file, err = os.OpenFile(filepath.Join(config.Dir, memoryJournalFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening memory journal: %w", err)
+	}
+
+	store.persist = persist
+	store.journaled = true
+
+	// Una foto nueva deja el journal vacío (y descarta una última línea
+	// incompleta si el proceso se cortó mientras la escribía)
+	if err := store.Snapshot(); err != nil {
+		persist.file.Close()
+		return nil, err
+	}
+
+	persist.start(store)
+
+	slog.Info("MemoryStore persistente", "dir", config.Dir, "books", len(store.books), "replayed", replayed, "fsync", config.Sync)
+	return store, nil
+}
+
+// start - Inicia el fsync periódico y las fotos programadas
+func (p *memoryPersister) start(store *MemoryStore) {
+	p.stop = make(chan struct{})
+
+	if p.config.Sync == MemorySyncInterval {
+		p.wg.Add(1)
+		go p.every(memorySyncEvery, func() {
+			if err := p.sync(); err != nil {
+				slog.Warn("Error sincronizando el journal", "error", err)
+			}
+		})
+	}
+
+	if p.config.SnapshotInterval > 0 {
+		p.wg.Add(1)
+		go p.every(p.config.SnapshotInterval, func() {
+			if err := store.Snapshot(); err != nil {
+				slog.Warn("Error guardando la foto de MemoryStore", "error", err)
+			}
+		})
+	}
+}
+
+// every - Ejecuta fn periódicamente hasta que se cierre el store
+func (p *memoryPersister) every(interval time.Duration, fn func()) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
+
+// record - Anota un cambio de la operación en curso (con el lock tomado)
+func (s *MemoryStore) record(op, kind, id string, value interface{}) {
+	if !s.journaled {
+		return
+	}
+
+	entry := journalEntry{Op: op, Kind: kind, ID: id}
+	if value != nil {
+		data, err := json.Marshal(value)
+		if err != nil {
+			slog.Warn("Error serializando un cambio para el journal", "kind", kind, "id", id, "error", err)
+			return
+		}
+		entry.Data = data
+	}
+	s.changes = append(s.changes, entry)
+}
+
+// flushChanges - Escribe en el journal los cambios anotados (con el lock de
+// escritura tomado). Si falla se conservan para el próximo intento; la
+// próxima foto los incluye de todos modos.
+func (s *MemoryStore) flushChanges() {
+	if len(s.changes) == 0 {
+		return
+	}
+	if err := s.persist.append(s.changes); err != nil {
+		slog.Warn("Error escribiendo el journal de MemoryStore", "error", err)
+		return
+	}
+	s.changes = nil
+}
+
+// append - Agrega los cambios al journal asignándoles número de secuencia
+func (p *memoryPersister) append(entries []journalEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.broken {
+		return errors.New("journal unavailable until the next snapshot")
+	}
+
+	var buf bytes.Buffer
+	seq := p.seq
+	for _, entry := range entries {
+		seq++
+		entry.Seq = seq
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if _, err := p.file.Write(buf.Bytes()); err != nil {
+		p.broken = true
+		return err
+	}
+	p.seq = seq
+
+	if p.config.Sync == MemorySyncAlways {
+		return p.file.Sync()
+	}
+	p.dirty = true
+	return nil
+}
+
+// sync - fsync del journal si hay escrituras pendientes
+func (p *memoryPersister) sync() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.dirty || p.file == nil {
+		return nil
+	}
+	p.dirty = false
+	return p.file.Sync()
+}
+
+// Snapshot - Guarda una foto completa de los datos y empieza un journal
+// nuevo (no hace nada si el store no es persistente)
+func (s *MemoryStore) Snapshot() error {
+	p := s.persist
+	if p == nil {
+		return nil
+	}
+
+	p.snapshotMu.Lock()
+	defer p.snapshotMu.Unlock()
+
+	// Foto y rotación del journal en el mismo instante: los cambios
+	// posteriores van al journal nuevo
+	s.mu.Lock()
+	s.flushChanges()
+	s.changes = nil // si el journal falló, la foto los incluye
+	snapshot := memorySnapshot{Dump: s.dumpData()}
+
+	p.mu.Lock()
+	snapshot.Seq = p.seq
+	err := p.rotate()
+	p.mu.Unlock()
+	s.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("error rotating memory journal: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(p.config.Dir, memorySnapshotFile), snapshot); err != nil {
+		return fmt.Errorf("error writing memory snapshot: %w", err)
+	}
+
+	// El journal anterior ya está incluido en la foto
+	if err := os.Remove(filepath.Join(p.config.Dir, memoryOldJournalFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing old memory journal: %w", err)
+	}
+	return nil
+}
+
+// rotate - Pasa el journal actual a journal.jsonl.old y abre uno vacío (con
+// p.mu tomado). Si ya hay un journal anterior (falló la foto previa) se
+// sigue escribiendo en el actual: sus cambios quedan cubiertos por Seq.
+func (p *memoryPersister) rotate() error {
+	dir := p.config.Dir
+	if _, err := os.Stat(filepath.Join(dir, memoryOldJournalFile)); err == nil {
+		return nil
+	}
+
+	if err := p.file.Sync(); err != nil {
+		return err
+	}
+	if err := p.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(dir, memoryJournalFile), filepath.Join(dir, memoryOldJournalFile)); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, memoryJournalFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	p.file = file
+	p.dirty = false
+	p.broken = false
+	return nil
+}
+
+// Close - Guarda una foto final y cierra el journal (no hace nada si el
+// store no es persistente)
+func (s *MemoryStore) Close() error {
+	p := s.persist
+	if p == nil {
+		return nil
+	}
+
+	close(p.stop)
+	p.wg.Wait()
+
+	err := s.Snapshot()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if closeErr := p.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.persist = nil
+	s.journaled = false
+	return err
+}
+
+// ==============================================
+// CARGA
+// ==============================================
+
+// readMemorySnapshot - Lee la foto guardada (nil si todavía no hay ninguna)
+func readMemorySnapshot(path string) (*memorySnapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading memory snapshot: %w", err)
+	}
+
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error reading memory snapshot: %w", err)
+	}
+	if err := checkDump(snapshot.Dump); err != nil {
+		return nil, fmt.Errorf("error reading memory snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// replayJournal - Reaplica los cambios del journal posteriores a *seq y
+// devuelve cuántos aplicó. Una última línea incompleta (corte durante la
+// escritura) se descarta; una línea dañada en el medio es un error.
+func (s *MemoryStore) replayJournal(path string, seq *uint64) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error opening memory journal: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	applied := 0
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry journalEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				if readErr != nil {
+					slog.Warn("Se descarta la última línea incompleta del journal", "file", filepath.Base(path))
+					break
+				}
+				return applied, fmt.Errorf("error reading memory journal %s line %d: %w", filepath.Base(path), lineNumber, err)
+			}
+
+			if entry.Seq > *seq {
+				if err := s.applyJournalEntry(entry); err != nil {
+					return applied, fmt.Errorf("error applying memory journal %s line %d: %w", filepath.Base(path), lineNumber, err)
+				}
+				*seq = entry.Seq
+				applied++
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	return applied, nil
+}
+
+// applyJournalEntry - Aplica un cambio del journal (sin volver a anotarlo)
+func (s *MemoryStore) applyJournalEntry(entry journalEntry) error {
+	if entry.Op != journalPut && entry.Op != journalDelete {
+		return fmt.Errorf("unknown journal operation %q", entry.Op)
+	}
+	deleting := entry.Op == journalDelete
+
+	switch entry.Kind {
+	case journalUser:
+		if deleting {
+			s.removeUser(entry.ID)
+			return nil
+		}
+		var user models.User
+		if err := json.Unmarshal(entry.Data, &user); err != nil {
+			return err
+		}
+		s.putUser(user)
+
+	case journalBook:
+		if deleting {
+			s.removeBook(entry.ID)
+			return nil
+		}
+		var book models.Book
+		if err := json.Unmarshal(entry.Data, &book); err != nil {
+			return err
+		}
+		s.putBook(book)
+
+	case journalDeletedBook:
+		var deleted models.DeletedBook
+		if err := json.Unmarshal(entry.Data, &deleted); err != nil {
+			return err
+		}
+		s.putDeletedBook(deleted)
+
+	case journalAuthor:
+		var author models.Author
+		if err := json.Unmarshal(entry.Data, &author); err != nil {
+			return err
+		}
+		s.putAuthor(author)
+
+	case journalSubject:
+		var subject models.Subject
+		if err := json.Unmarshal(entry.Data, &subject); err != nil {
+			return err
+		}
+		s.putSubject(subject)
+
+	case journalLoan:
+		var loan models.Loan
+		if err := json.Unmarshal(entry.Data, &loan); err != nil {
+			return err
+		}
+		s.putLoan(loan)
+
+	case journalJob:
+		var job models.Job
+		if err := json.Unmarshal(entry.Data, &job); err != nil {
+			return err
+		}
+		s.putJob(job)
+
+	case journalJobItems:
+		var items models.JobItems
+		if err := json.Unmarshal(entry.Data, &items); err != nil {
+			return err
+		}
+		s.putJobItems(entry.ID, items)
+
+	default:
+		return fmt.Errorf("unknown journal record type %q", entry.Kind)
+	}
+
+	return nil
+}
+
+// writeFileAtomic - Escribe value como JSON en path sin dejar nunca un
+// archivo a medias: archivo temporal, fsync y rename
+func writeFileAtomic(path string, value interface{}) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	if err := json.NewEncoder(file).Encode(value); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// fsync del directorio para que el rename sobreviva a un corte de luz
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
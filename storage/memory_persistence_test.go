@@ -0,0 +1,150 @@
+package storage_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+func openPersistentMemory(t *testing.T, dir string) *storage.MemoryStore {
+	t.Helper()
+	store, err := storage.OpenMemoryStore(storage.MemoryPersistence{Dir: dir, Sync: storage.MemorySyncAlways})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	return store
+}
+
+// TestMemoryStoreJournalSurvivesRestart - Sin Close (como tras un corte) los
+// cambios se recuperan del journal; lo deshecho por WithTx no queda
+func TestMemoryStoreJournalSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	store := openPersistentMemory(t, dir)
+
+	book, err := store.CreateBook(t.Context(), models.Book{Title: "Rayuela", Authors: []string{"Julio Cortázar"}, ISBN: "9788437604572"})
+	if err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	loan, err := store.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: "ana"})
+	if err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+	gone, err := store.CreateBook(t.Context(), models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"})
+	if err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	if err := store.DeleteBook(t.Context(), gone.ID); err != nil {
+		t.Fatalf("DeleteBook: %v", err)
+	}
+	rollback := store.WithTx(t.Context(), func(tx storage.Store) error {
+		if _, err := tx.CreateBook(t.Context(), models.Book{Title: "El Aleph", Author: "Jorge Luis Borges", ISBN: "9788420633121"}); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	if rollback == nil {
+		t.Fatal("WithTx: expected the error returned by fn")
+	}
+
+	// Snapshot intermedia: los cambios siguientes solo están en el journal
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if err := store.ReturnBook(t.Context(), loan.ID); err != nil {
+		t.Fatalf("ReturnBook: %v", err)
+	}
+
+	reopened := openPersistentMemory(t, dir)
+	t.Cleanup(func() { reopened.Close() })
+
+	got, err := reopened.GetBookByISBN(t.Context(), "978-84-376-0457-2")
+	if err != nil || got.ID != book.ID || !got.Available {
+		t.Errorf("GetBookByISBN after restart: got %+v, %v", got, err)
+	}
+	if returned, err := reopened.GetLoanByID(t.Context(), loan.ID); err != nil || !returned.Returned {
+		t.Errorf("GetLoanByID after restart: got %+v, %v", returned, err)
+	}
+	if _, err := reopened.GetBookByID(t.Context(), gone.ID); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("deleted book after restart: got %v, want ErrBookNotFound", err)
+	}
+	if _, err := reopened.GetBookByISBN(t.Context(), "9788420633121"); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("rolled back book after restart: got %v, want ErrBookNotFound", err)
+	}
+	if authors, err := reopened.GetAuthors(t.Context()); err != nil || len(authors) != 1 || authors[0].Name != "Julio Cortázar" {
+		t.Errorf("GetAuthors after restart: got %+v, %v", authors, err)
+	}
+}
+
+// TestMemoryStoreTornJournalLine - Una última línea a medias (corte durante
+// la escritura) se descarta y el resto del journal se recupera
+func TestMemoryStoreTornJournalLine(t *testing.T) {
+	dir := t.TempDir()
+	store := openPersistentMemory(t, dir)
+	if _, err := store.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"}); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+
+	journal, err := os.OpenFile(filepath.Join(dir, "journal.jsonl"), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.WriteString(`{"seq":99,"op":"put","kind":"book","id":"x","data":{"title":`)
+	journal.Close()
+
+	reopened := openPersistentMemory(t, dir)
+	if _, err := reopened.GetBookByISBN(t.Context(), "9788437604572"); err != nil {
+		t.Errorf("GetBookByISBN after torn journal: %v", err)
+	}
+
+	// La foto al abrir deja un journal nuevo: lo siguiente se recupera bien
+	if _, err := reopened.CreateBook(t.Context(), models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"}); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	again := openPersistentMemory(t, dir)
+	t.Cleanup(func() { again.Close() })
+	if books, err := again.GetBooks(t.Context()); err != nil || len(books) != 2 {
+		t.Errorf("GetBooks after second restart: got %d, %v", len(books), err)
+	}
+}
+
+// TestMemoryStoreLoadPersists - Un volcado cargado reemplaza también lo
+// guardado en disco
+func TestMemoryStoreLoadPersists(t *testing.T) {
+	source := storage.NewMemoryStore()
+	if _, err := source.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"}); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	dump, err := source.Dump(t.Context())
+	if err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+
+	dir := t.TempDir()
+	store := openPersistentMemory(t, dir)
+	if _, err := store.CreateBook(t.Context(), models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"}); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	if err := store.Load(t.Context(), dump); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened := openPersistentMemory(t, dir)
+	t.Cleanup(func() { reopened.Close() })
+	books, err := reopened.GetBooks(t.Context())
+	if err != nil || len(books) != 1 || books[0].Title != "Rayuela" {
+		t.Errorf("GetBooks after Load and restart: got %+v, %v", books, err)
+	}
+}
+
+func TestOpenMemoryStoreRejectsUnknownSync(t *testing.T) {
+	if _, err := storage.OpenMemoryStore(storage.MemoryPersistence{Dir: t.TempDir(), Sync: "sometimes"}); err == nil {
+		t.Error("OpenMemoryStore with unknown sync policy: got nil error")
+	}
+}
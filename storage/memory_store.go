@@ -1,50 +1,174 @@
 package storage
 
 import (
+	"context"
+	"fmt"
+	"library-api/isbn"
 	"library-api/models"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// MemoryStore - Store en memoria. Las operaciones no esperan a nada externo,
+// así que el contexto solo se consulta en WithTx y al recorrer ForEachBook.
 type MemoryStore struct {
-	books map[string]models.Book
-	loans map[string]models.Loan
-	users map[string]models.User
-	mu    sync.RWMutex
+	books    map[string]models.Book
+	loans    map[string]models.Loan
+	users    map[string]models.User
+	authors  map[string]models.Author  // clave: nombre en minúsculas
+	subjects map[string]models.Subject // clave: nombre en minúsculas
+	isbns    map[string]string         // índice secundario: ISBN-13 → ID de libro
+	deleted  map[string]models.DeletedBook
+	jobs     map[string]models.Job
+	jobItems map[string]models.JobItems // elementos resueltos, por trabajo
+	mu       sync.RWMutex
+
+	// persist - Snapshot y journal en disco (nil: solo en memoria, ver
+	// OpenMemoryStore). journaled indica si hay que registrar los cambios
+	// (también en los stores de WithTx) y changes guarda los de la operación
+	// en curso hasta escribirlos en el journal.
+	persist   *memoryPersister
+	journaled bool
+	changes   []journalEntry
+
+	// inTx indica que es el store de un WithTx y undo guarda cómo deshacer
+	// cada cambio de la transacción
+	inTx bool
+	undo []func()
 }
 
 func NewMemoryStore() *MemoryStore {
-	// Hashear la contraseña del admin
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+	// Usuario admin con la contraseña hasheada
+	admin, _ := defaultAdminUser()
+	admin.CreatedAt = time.Now()
+	admin.UpdatedAt = admin.CreatedAt
 
 	return &MemoryStore{
 		users: map[string]models.User{
-			"1": {
-				ID:        "1",
-				Username:  "admin",
-				Password:  string(hashedPassword), // ← CONTRASEÑA HASHED
-				Role:      "admin",
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			},
+			admin.ID: admin,
 		},
-		books: make(map[string]models.Book),
-		loans: make(map[string]models.Loan),
+		books:    make(map[string]models.Book),
+		loans:    make(map[string]models.Loan),
+		authors:  make(map[string]models.Author),
+		subjects: make(map[string]models.Subject),
+		isbns:    make(map[string]string),
+		deleted:  make(map[string]models.DeletedBook),
+		jobs:     make(map[string]models.Job),
+		jobItems: make(map[string]models.JobItems),
 	}
 }
 
+// ==============================================
+// TRANSACCIONES
+// ==============================================
+
+// WithTx - Ejecuta fn con el store bloqueado sobre los mismos mapas, sin
+// copiarlos: cada cambio anota cómo deshacerse. Si fn termina sin error (y
+// el contexto sigue vigente) los cambios quedan; si no, o si fn entra en
+// pánico, se deshacen en orden inverso. Dentro de fn solo debe usarse tx: el
+// store original está bloqueado hasta que fn termine.
+func (s *MemoryStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	tx := s.txStore()
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	committed = true
+
+	// Una transacción anidada pasa sus cambios a la de fuera, que aún
+	// puede deshacerlos
+	if s.inTx {
+		s.undo = append(s.undo, tx.undo...)
+	}
+	s.books, s.loans, s.users = tx.books, tx.loans, tx.users
+	s.authors, s.subjects, s.isbns = tx.authors, tx.subjects, tx.isbns
+	s.deleted, s.jobs, s.jobItems = tx.deleted, tx.jobs, tx.jobItems
+	s.changes = append(s.changes, tx.changes...)
+	return nil
+}
+
+// txStore - Store de una transacción sobre los mismos mapas (debe llamarse
+// con el lock tomado)
+func (s *MemoryStore) txStore() *MemoryStore {
+	return &MemoryStore{
+		books:    s.books,
+		loans:    s.loans,
+		users:    s.users,
+		authors:  s.authors,
+		subjects: s.subjects,
+		isbns:    s.isbns,
+		deleted:  s.deleted,
+		jobs:     s.jobs,
+		jobItems: s.jobItems,
+
+		journaled: s.journaled,
+		inTx:      true,
+	}
+}
+
+// rollback - Deshace los cambios de la transacción, del último al primero
+func (s *MemoryStore) rollback() {
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		s.undo[i]()
+	}
+	s.undo = nil
+	s.changes = nil
+}
+
+// remember - Anota cómo devolver m[key] a su valor actual si la transacción
+// en curso se deshace (fuera de WithTx no hace nada)
+func remember[V any](s *MemoryStore, m map[string]V, key string) {
+	if !s.inTx {
+		return
+	}
+
+	previous, existed := m[key]
+	s.undo = append(s.undo, func() {
+		if existed {
+			m[key] = previous
+		} else {
+			delete(m, key)
+		}
+	})
+}
+
+// unlock - Libera el lock de escritura; antes guarda en el journal los
+// cambios de la operación (si el store es persistente)
+func (s *MemoryStore) unlock() {
+	if s.persist != nil {
+		s.flushChanges()
+	}
+	s.mu.Unlock()
+}
+
 // ==============================================
 // MÉTODOS PARA USUARIOS (CORREGIDOS PARA DEVOLVER PUNTEROS)
 // ==============================================
 
 // CreateUser - Crear un nuevo usuario (DEVUELVE PUNTERO)
-func (s *MemoryStore) CreateUser(user models.User) (*models.User, error) {
+func (s *MemoryStore) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	// Verificar si el usuario ya existe
 	for _, u := range s.users {
@@ -61,12 +185,12 @@ func (s *MemoryStore) CreateUser(user models.User) (*models.User, error) {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
-	s.users[user.ID] = user
+	s.putUser(user)
 	return &user, nil // ← CORREGIDO: devolver puntero
 }
 
 // GetUserByUsername - Obtener usuario por nombre de usuario (DEVUELVE PUNTERO)
-func (s *MemoryStore) GetUserByUsername(username string) (*models.User, error) {
+func (s *MemoryStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -82,7 +206,7 @@ func (s *MemoryStore) GetUserByUsername(username string) (*models.User, error) {
 }
 
 // GetUserByID - Obtener usuario por ID (DEVUELVE PUNTERO)
-func (s *MemoryStore) GetUserByID(id string) (*models.User, error) {
+func (s *MemoryStore) GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -96,35 +220,42 @@ func (s *MemoryStore) GetUserByID(id string) (*models.User, error) {
 }
 
 // UpdateUser - Actualizar usuario (DEVUELVE PUNTERO)
-func (s *MemoryStore) UpdateUser(id string, updatedUser models.User) (*models.User, error) {
+func (s *MemoryStore) UpdateUser(ctx context.Context, id string, updatedUser models.User) (*models.User, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	user, exists := s.users[id]
 	if !exists {
 		return nil, ErrUserNotFound
 	}
 
+	// El nombre de usuario no puede pasar a ser el de otro usuario
+	for otherID, other := range s.users {
+		if otherID != id && other.Username == updatedUser.Username {
+			return nil, ErrUserAlreadyExists
+		}
+	}
+
 	updatedUser.ID = id
 	updatedUser.CreatedAt = user.CreatedAt
 	updatedUser.UpdatedAt = time.Now()
 
-	s.users[id] = updatedUser
+	s.putUser(updatedUser)
 
 	updatedUserCopy := updatedUser
 	return &updatedUserCopy, nil // ← CORREGIDO: devolver puntero
 }
 
 // DeleteUser - Eliminar usuario
-func (s *MemoryStore) DeleteUser(id string) error {
+func (s *MemoryStore) DeleteUser(ctx context.Context, id string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	if _, exists := s.users[id]; !exists {
 		return ErrUserNotFound
 	}
 
-	delete(s.users, id)
+	s.removeUser(id)
 	return nil
 }
 
@@ -133,21 +264,31 @@ func (s *MemoryStore) DeleteUser(id string) error {
 // ==============================================
 
 // CreateBook - Crear un nuevo libro (DEVUELVE PUNTERO)
-func (s *MemoryStore) CreateBook(book models.Book) (*models.Book, error) {
+func (s *MemoryStore) CreateBook(ctx context.Context, book models.Book) (*models.Book, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	book.ID = uuid.New().String()
 	book.CreatedAt = time.Now()
 	book.UpdatedAt = time.Now()
 	book.Available = true
+	book.NormalizeContributors()
 
-	s.books[book.ID] = book
+	if err := normalizeBookISBN(&book); err != nil {
+		return nil, err
+	}
+
+	if _, exists := s.isbns[book.ISBN]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateISBN, book.ISBN)
+	}
+
+	s.putBook(book)
+	s.registerContributors(book)
 	return &book, nil // ← CORREGIDO: devolver puntero
 }
 
 // GetBooks - Obtener todos los libros
-func (s *MemoryStore) GetBooks() ([]models.Book, error) {
+func (s *MemoryStore) GetBooks(ctx context.Context) ([]models.Book, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -155,11 +296,12 @@ func (s *MemoryStore) GetBooks() ([]models.Book, error) {
 	for _, book := range s.books {
 		books = append(books, book)
 	}
+	sortBooksByTitle(books)
 	return books, nil
 }
 
 // GetBookByID - Obtener libro por ID (DEVUELVE PUNTERO)
-func (s *MemoryStore) GetBookByID(id string) (*models.Book, error) {
+func (s *MemoryStore) GetBookByID(ctx context.Context, id string) (*models.Book, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -173,9 +315,9 @@ func (s *MemoryStore) GetBookByID(id string) (*models.Book, error) {
 }
 
 // UpdateBook - Actualizar libro (DEVUELVE PUNTERO)
-func (s *MemoryStore) UpdateBook(id string, updatedBook models.Book) (*models.Book, error) {
+func (s *MemoryStore) UpdateBook(ctx context.Context, id string, updatedBook models.Book) (*models.Book, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	book, exists := s.books[id]
 	if !exists {
@@ -185,28 +327,40 @@ func (s *MemoryStore) UpdateBook(id string, updatedBook models.Book) (*models.Bo
 	updatedBook.ID = id
 	updatedBook.CreatedAt = book.CreatedAt
 	updatedBook.UpdatedAt = time.Now()
+	updatedBook.NormalizeContributors()
+
+	if err := normalizeBookISBN(&updatedBook); err != nil {
+		return nil, err
+	}
+
+	if ownerID, exists := s.isbns[updatedBook.ISBN]; exists && ownerID != id {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateISBN, updatedBook.ISBN)
+	}
 
-	s.books[id] = updatedBook
+	s.putBook(updatedBook)
+	s.registerContributors(updatedBook)
 
 	updatedBookCopy := updatedBook
 	return &updatedBookCopy, nil // ← CORREGIDO: devolver puntero
 }
 
 // DeleteBook - Eliminar libro
-func (s *MemoryStore) DeleteBook(id string) error {
+func (s *MemoryStore) DeleteBook(ctx context.Context, id string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
-	if _, exists := s.books[id]; !exists {
+	book, exists := s.books[id]
+	if !exists {
 		return ErrBookNotFound
 	}
 
-	delete(s.books, id)
+	s.removeBook(id)
+	s.putDeletedBook(models.DeletedBook{ID: id, ISBN: book.ISBN, Title: book.Title, DeletedAt: time.Now()})
 	return nil
 }
 
 // SearchBooks - Buscar libros
-func (s *MemoryStore) SearchBooks(title, author, genre string, available *bool) ([]models.Book, error) {
+func (s *MemoryStore) SearchBooks(ctx context.Context, title, author, genre string, available *bool) ([]models.Book, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -227,13 +381,311 @@ func (s *MemoryStore) SearchBooks(title, author, genre string, available *bool)
 			results = append(results, book)
 		}
 	}
+	sortBooksByTitle(results)
 	return results, nil
 }
 
+// ForEachBook - Recorrer los libros filtrados ordenados por título. Se copia
+// la selección bajo el lock para no bloquear el store mientras fn escribe.
+func (s *MemoryStore) ForEachBook(ctx context.Context, title, author, genre string, available *bool, fn func(models.Book) error) error {
+	books, err := s.SearchBooks(ctx, title, author, genre, available)
+	if err != nil {
+		return err
+	}
+
+	for _, book := range books {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(book); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ==============================================
+// CAMBIOS INCREMENTALES (OAI-PMH)
+// ==============================================
+
+// GetBookChanges - Altas, modificaciones y bajas ordenadas por (fecha, id)
+func (s *MemoryStore) GetBookChanges(ctx context.Context, q models.BookChangeQuery) ([]models.BookChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var changes []models.BookChange
+	for id, book := range s.books {
+		bookCopy := book
+		changes = append(changes, models.BookChange{ID: id, Datestamp: book.UpdatedAt, Book: &bookCopy})
+	}
+	for id, deleted := range s.deleted {
+		changes = append(changes, models.BookChange{ID: id, Datestamp: deleted.DeletedAt, Deleted: true})
+	}
+
+	var results []models.BookChange
+	for _, change := range changes {
+		if q.From != nil && change.Datestamp.Before(*q.From) {
+			continue
+		}
+		if q.Before != nil && !change.Datestamp.Before(*q.Before) {
+			continue
+		}
+		if q.After != nil && (change.Datestamp.Before(*q.After) ||
+			(change.Datestamp.Equal(*q.After) && change.ID <= q.AfterID)) {
+			continue
+		}
+		results = append(results, change)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].Datestamp.Equal(results[j].Datestamp) {
+			return results[i].Datestamp.Before(results[j].Datestamp)
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results, nil
+}
+
+// GetBookChange - Último cambio de un libro (vigente o eliminado)
+func (s *MemoryStore) GetBookChange(ctx context.Context, id string) (*models.BookChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if book, exists := s.books[id]; exists {
+		return &models.BookChange{ID: id, Datestamp: book.UpdatedAt, Book: &book}, nil
+	}
+	if deleted, exists := s.deleted[id]; exists {
+		return &models.BookChange{ID: id, Datestamp: deleted.DeletedAt, Deleted: true}, nil
+	}
+	return nil, ErrBookNotFound
+}
+
+// GetBookByISBN - Obtener libro por ISBN usando el índice secundario
+func (s *MemoryStore) GetBookByISBN(ctx context.Context, code string) (*models.Book, error) {
+	normalized, err := isbn.Normalize(code)
+	if err != nil {
+		return nil, InvalidValue(ErrInvalidISBN, "isbn", code, err.Error())
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, exists := s.isbns[normalized]
+	if !exists {
+		return nil, ErrBookNotFound
+	}
+
+	bookCopy := s.books[id]
+	return &bookCopy, nil
+}
+
+// GetBooksByISBNs - Obtener los libros existentes para una lista de ISBN
+func (s *MemoryStore) GetBooksByISBNs(ctx context.Context, codes []string) (map[string]models.Book, error) {
+	normalized := normalizeISBNList(codes)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]models.Book, len(normalized))
+	for _, code := range normalized {
+		if id, exists := s.isbns[code]; exists {
+			result[code] = s.books[id]
+		}
+	}
+	return result, nil
+}
+
+// MigrateISBNs - Normaliza a ISBN-13 los ISBN guardados
+func (s *MemoryStore) MigrateISBNs(ctx context.Context) (*ISBNMigrationReport, error) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	books := make([]models.Book, 0, len(s.books))
+	for _, book := range s.books {
+		books = append(books, book)
+	}
+
+	updates, report := planISBNMigration(books)
+
+	now := time.Now()
+	for id, normalized := range updates {
+		book := s.books[id]
+		book.ISBN = normalized
+		book.UpdatedAt = now
+		s.putBook(book)
+	}
+
+	report.Normalized = len(updates)
+	return report, nil
+}
+
+// ==============================================
+// MÉTODOS PARA AUTORES Y MATERIAS
+// ==============================================
+
+// GetAuthors - Obtener autores que tienen al menos un libro
+func (s *MemoryStore) GetAuthors(ctx context.Context) ([]models.Author, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, book := range s.books {
+		for _, name := range book.Authors {
+			counts[strings.ToLower(name)]++
+		}
+	}
+
+	authors := []models.Author{}
+	for key, author := range s.authors {
+		if counts[key] > 0 {
+			author.BookCount = counts[key]
+			authors = append(authors, author)
+		}
+	}
+
+	sort.Slice(authors, func(i, j int) bool {
+		return strings.ToLower(authors[i].Name) < strings.ToLower(authors[j].Name)
+	})
+	return authors, nil
+}
+
+// GetAuthorByID - Obtener autor por ID
+func (s *MemoryStore) GetAuthorByID(ctx context.Context, id string) (*models.Author, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, author := range s.authors {
+		if author.ID == id {
+			author.BookCount = len(s.booksMatching(author.Name, authorsOf))
+			return &author, nil
+		}
+	}
+
+	return nil, ErrAuthorNotFound
+}
+
+// GetBooksByAuthor - Obtener todos los libros de un autor
+func (s *MemoryStore) GetBooksByAuthor(ctx context.Context, authorID string) ([]models.Book, error) {
+	author, err := s.GetAuthorByID(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	books := s.booksMatching(author.Name, authorsOf)
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].Published != books[j].Published {
+			return books[i].Published < books[j].Published
+		}
+		return books[i].Title < books[j].Title
+	})
+	return books, nil
+}
+
+// GetSubjects - Obtener materias que tienen al menos un libro
+func (s *MemoryStore) GetSubjects(ctx context.Context) ([]models.Subject, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, book := range s.books {
+		for _, name := range book.Subjects {
+			counts[strings.ToLower(name)]++
+		}
+	}
+
+	subjects := []models.Subject{}
+	for key, subject := range s.subjects {
+		if counts[key] > 0 {
+			subject.BookCount = counts[key]
+			subjects = append(subjects, subject)
+		}
+	}
+
+	sort.Slice(subjects, func(i, j int) bool {
+		return strings.ToLower(subjects[i].Name) < strings.ToLower(subjects[j].Name)
+	})
+	return subjects, nil
+}
+
+// GetSubjectByID - Obtener materia por ID
+func (s *MemoryStore) GetSubjectByID(ctx context.Context, id string) (*models.Subject, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, subject := range s.subjects {
+		if subject.ID == id {
+			subject.BookCount = len(s.booksMatching(subject.Name, subjectsOf))
+			return &subject, nil
+		}
+	}
+
+	return nil, ErrSubjectNotFound
+}
+
+// GetBooksBySubject - Obtener todos los libros de una materia
+func (s *MemoryStore) GetBooksBySubject(ctx context.Context, subjectID string) ([]models.Book, error) {
+	subject, err := s.GetSubjectByID(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	books := s.booksMatching(subject.Name, subjectsOf)
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].Title < books[j].Title
+	})
+	return books, nil
+}
+
+// registerContributors - Registra autores y materias nuevos de un libro
+// (debe llamarse con el lock de escritura tomado)
+func (s *MemoryStore) registerContributors(book models.Book) {
+	for _, name := range book.Authors {
+		key := strings.ToLower(name)
+		if _, exists := s.authors[key]; !exists {
+			s.putAuthor(models.Author{ID: uuid.New().String(), Name: name})
+		}
+	}
+
+	for _, name := range book.Subjects {
+		key := strings.ToLower(name)
+		if _, exists := s.subjects[key]; !exists {
+			s.putSubject(models.Subject{ID: uuid.New().String(), Name: name})
+		}
+	}
+}
+
+func authorsOf(book models.Book) []string  { return book.Authors }
+func subjectsOf(book models.Book) []string { return book.Subjects }
+
+// booksMatching - Libros cuya lista (autores o materias) contiene el nombre
+// (debe llamarse con el lock tomado)
+func (s *MemoryStore) booksMatching(name string, list func(models.Book) []string) []models.Book {
+	books := []models.Book{}
+	for _, book := range s.books {
+		for _, item := range list(book) {
+			if strings.EqualFold(item, name) {
+				books = append(books, book)
+				break
+			}
+		}
+	}
+	return books
+}
+
 // UpdateBookAvailability - Actualizar disponibilidad de libro
-func (s *MemoryStore) UpdateBookAvailability(bookID string, available bool) error {
+func (s *MemoryStore) UpdateBookAvailability(ctx context.Context, bookID string, available bool) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	book, exists := s.books[bookID]
 	if !exists {
@@ -242,7 +694,7 @@ func (s *MemoryStore) UpdateBookAvailability(bookID string, available bool) erro
 
 	book.Available = available
 	book.UpdatedAt = time.Now()
-	s.books[bookID] = book
+	s.putBook(book)
 
 	return nil
 }
@@ -252,9 +704,9 @@ func (s *MemoryStore) UpdateBookAvailability(bookID string, available bool) erro
 // ==============================================
 
 // CreateLoan - Crear préstamo (DEVUELVE PUNTERO)
-func (s *MemoryStore) CreateLoan(loan models.Loan) (*models.Loan, error) {
+func (s *MemoryStore) CreateLoan(ctx context.Context, loan models.Loan) (*models.Loan, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	// Verificar que el libro existe y está disponible
 	book, exists := s.books[loan.BookID]
@@ -270,20 +722,20 @@ func (s *MemoryStore) CreateLoan(loan models.Loan) (*models.Loan, error) {
 	loan.LoanDate = time.Now()
 	loan.Returned = false
 
-	s.loans[loan.ID] = loan
+	s.putLoan(loan)
 
 	// Marcar libro como no disponible
 	book.Available = false
 	book.UpdatedAt = time.Now()
-	s.books[loan.BookID] = book
+	s.putBook(book)
 
 	return &loan, nil // ← CORREGIDO: devolver puntero
 }
 
 // ReturnBook - Devolver libro
-func (s *MemoryStore) ReturnBook(loanID string) error {
+func (s *MemoryStore) ReturnBook(ctx context.Context, loanID string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	loan, exists := s.loans[loanID]
 	if !exists {
@@ -298,20 +750,20 @@ func (s *MemoryStore) ReturnBook(loanID string) error {
 	now := time.Now()
 	loan.Returned = true
 	loan.ReturnDate = &now
-	s.loans[loanID] = loan
+	s.putLoan(loan)
 
 	// Marcar libro como disponible
 	if book, exists := s.books[loan.BookID]; exists {
 		book.Available = true
 		book.UpdatedAt = now
-		s.books[loan.BookID] = book
+		s.putBook(book)
 	}
 
 	return nil
 }
 
 // GetLoans - Obtener todos los préstamos
-func (s *MemoryStore) GetLoans() ([]models.Loan, error) {
+func (s *MemoryStore) GetLoans(ctx context.Context) ([]models.Loan, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -319,11 +771,12 @@ func (s *MemoryStore) GetLoans() ([]models.Loan, error) {
 	for _, loan := range s.loans {
 		loans = append(loans, loan)
 	}
+	sortLoansByDate(loans)
 	return loans, nil
 }
 
 // GetActiveLoans - Obtener préstamos activos
-func (s *MemoryStore) GetActiveLoans() ([]models.Loan, error) {
+func (s *MemoryStore) GetActiveLoans(ctx context.Context) ([]models.Loan, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -333,11 +786,12 @@ func (s *MemoryStore) GetActiveLoans() ([]models.Loan, error) {
 			activeLoans = append(activeLoans, loan)
 		}
 	}
+	sortLoansByDate(activeLoans)
 	return activeLoans, nil
 }
 
 // GetLoanByID - Obtener préstamo por ID (DEVUELVE PUNTERO)
-func (s *MemoryStore) GetLoanByID(id string) (*models.Loan, error) {
+func (s *MemoryStore) GetLoanByID(ctx context.Context, id string) (*models.Loan, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -351,22 +805,24 @@ func (s *MemoryStore) GetLoanByID(id string) (*models.Loan, error) {
 }
 
 // GetLoansWithBooks - Obtener préstamos con información de libros
-func (s *MemoryStore) GetLoansWithBooks() ([]models.LoanWithBook, error) {
+func (s *MemoryStore) GetLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	loans := make([]models.Loan, 0, len(s.loans))
+	for _, loan := range s.loans {
+		loans = append(loans, loan)
+	}
+	sortLoansByDate(loans)
+
 	var loansWithBooks []models.LoanWithBook
 
-	for _, loan := range s.loans {
-		// Buscar el libro correspondiente
+	for _, loan := range loans {
+		// Buscar el libro correspondiente (los préstamos de libros
+		// eliminados se conservan)
 		book, exists := s.books[loan.BookID]
 		if !exists {
-			// Si no encontramos el libro, crear uno vacío
-			book = models.Book{
-				ID:        loan.BookID,
-				Title:     "Libro no encontrado",
-				Available: false,
-			}
+			book = DeletedLoanBook(loan)
 		}
 
 		loanWithBook := models.LoanWithBook{
@@ -381,8 +837,8 @@ func (s *MemoryStore) GetLoansWithBooks() ([]models.LoanWithBook, error) {
 }
 
 // GetActiveLoansWithBooks - Obtener préstamos activos con información de libros
-func (s *MemoryStore) GetActiveLoansWithBooks() ([]models.LoanWithBook, error) {
-	allLoans, err := s.GetLoansWithBooks()
+func (s *MemoryStore) GetActiveLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error) {
+	allLoans, err := s.GetLoansWithBooks(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -397,10 +853,280 @@ func (s *MemoryStore) GetActiveLoansWithBooks() ([]models.LoanWithBook, error) {
 	return activeLoans, nil
 }
 
+// ==============================================
+// ESTADO
+// ==============================================
+
+// Ping - En memoria siempre responde; si es persistente, falla mientras el
+// journal esté roto por una escritura fallida (los cambios no se guardan
+// hasta la próxima foto)
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	s.mu.RLock()
+	p := s.persist
+	s.mu.RUnlock()
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.broken {
+		return fmt.Errorf("memory journal is not accepting writes until the next snapshot")
+	}
+	return nil
+}
+
+// CheckSchema - Sin esquema que migrar
+func (s *MemoryStore) CheckSchema(ctx context.Context) error {
+	return nil
+}
+
+// GetStats - Cuenta libros y préstamos
+func (s *MemoryStore) GetStats(ctx context.Context, overdueBefore time.Time) (*Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := &Stats{Books: len(s.books)}
+	for _, book := range s.books {
+		if book.Available {
+			stats.AvailableBooks++
+		}
+	}
+	for _, loan := range s.loans {
+		if loan.Returned {
+			continue
+		}
+		stats.ActiveLoans++
+		if loan.LoanDate.Before(overdueBefore) {
+			stats.OverdueLoans++
+		}
+	}
+	return stats, nil
+}
+
+// ==============================================
+// MÉTODOS PARA TRABAJOS EN SEGUNDO PLANO
+// ==============================================
+
+// CreateJob - Registrar un nuevo trabajo
+func (s *MemoryStore) CreateJob(ctx context.Context, job models.Job) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+	if job.Status == "" {
+		job.Status = models.JobPending
+	}
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	job.Items = nil
+
+	s.putJob(job)
+	return &job, nil
+}
+
+// GetJobByID - Obtener trabajo por ID
+func (s *MemoryStore) GetJobByID(ctx context.Context, id string) (*models.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+
+	return &job, nil
+}
+
+// UpdateJob - Guardar el estado y progreso de un trabajo (sin los
+// elementos, ver SaveJobItems). Una cancelación pedida no se pierde aunque
+// el trabajo que se guarda no la tenga
+func (s *MemoryStore) UpdateJob(ctx context.Context, job models.Job) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	existing, exists := s.jobs[job.ID]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+
+	job.Type = existing.Type
+	job.CancelRequested = job.CancelRequested || existing.CancelRequested
+	job.CreatedBy = existing.CreatedBy
+	job.CreatedAt = existing.CreatedAt
+	job.UpdatedAt = time.Now()
+	job.Items = nil
+
+	s.putJob(job)
+	return &job, nil
+}
+
+// SaveJobItems - Guardar los elementos resueltos de un trabajo
+func (s *MemoryStore) SaveJobItems(ctx context.Context, id string, items models.JobItems) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if _, exists := s.jobs[id]; !exists {
+		return ErrJobNotFound
+	}
+
+	s.putJobItems(id, items)
+	return nil
+}
+
+// GetJobItems - Obtener los elementos de un trabajo (nil si aún no se
+// resolvieron)
+func (s *MemoryStore) GetJobItems(ctx context.Context, id string) (models.JobItems, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.jobs[id]; !exists {
+		return nil, ErrJobNotFound
+	}
+
+	return s.jobItems[id], nil
+}
+
+// CancelJob - Pide la cancelación de un trabajo: marca cancel_requested y,
+// si sigue pendiente, lo da por cancelado. Un trabajo ya terminado no cambia
+// (devuelve ErrJobFinished junto con el trabajo).
+func (s *MemoryStore) CancelJob(ctx context.Context, id string) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+	if job.Finished() {
+		return &job, ErrJobFinished
+	}
+
+	now := time.Now()
+	job.CancelRequested = true
+	if job.Status == models.JobPending {
+		job.Status = models.JobCancelled
+		job.FinishedAt = &now
+	}
+	job.UpdatedAt = now
+
+	s.putJob(job)
+	return &job, nil
+}
+
+// GetJobs - Listar trabajos, opcionalmente filtrados por estado
+func (s *MemoryStore) GetJobs(ctx context.Context, statuses ...string) ([]models.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := []models.Job{}
+	for _, job := range s.jobs {
+		if len(statuses) == 0 || containsString(statuses, job.Status) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// ==============================================
+// REGISTROS
+// ==============================================
+
+// Toda modificación de los mapas pasa por estas funciones (con el lock de
+// escritura tomado), que además la anotan para el journal y, dentro de
+// WithTx, para deshacerla. El índice de ISBN no se anota en el journal: se
+// reconstruye a partir de los libros.
+
+func (s *MemoryStore) putUser(user models.User) {
+	remember(s, s.users, user.ID)
+	s.users[user.ID] = user
+	s.record(journalPut, journalUser, user.ID, user)
+}
+
+func (s *MemoryStore) removeUser(id string) {
+	remember(s, s.users, id)
+	delete(s.users, id)
+	s.record(journalDelete, journalUser, id, nil)
+}
+
+func (s *MemoryStore) putBook(book models.Book) {
+	if previous, exists := s.books[book.ID]; exists && s.isbns[previous.ISBN] == book.ID {
+		remember(s, s.isbns, previous.ISBN)
+		delete(s.isbns, previous.ISBN)
+	}
+	remember(s, s.books, book.ID)
+	remember(s, s.isbns, book.ISBN)
+	s.books[book.ID] = book
+	s.isbns[book.ISBN] = book.ID
+	s.record(journalPut, journalBook, book.ID, book)
+}
+
+func (s *MemoryStore) removeBook(id string) {
+	if book, exists := s.books[id]; exists && s.isbns[book.ISBN] == id {
+		remember(s, s.isbns, book.ISBN)
+		delete(s.isbns, book.ISBN)
+	}
+	remember(s, s.books, id)
+	delete(s.books, id)
+	s.record(journalDelete, journalBook, id, nil)
+}
+
+func (s *MemoryStore) putDeletedBook(deleted models.DeletedBook) {
+	remember(s, s.deleted, deleted.ID)
+	s.deleted[deleted.ID] = deleted
+	s.record(journalPut, journalDeletedBook, deleted.ID, deleted)
+}
+
+func (s *MemoryStore) putAuthor(author models.Author) {
+	remember(s, s.authors, strings.ToLower(author.Name))
+	s.authors[strings.ToLower(author.Name)] = author
+	s.record(journalPut, journalAuthor, author.ID, author)
+}
+
+func (s *MemoryStore) putSubject(subject models.Subject) {
+	remember(s, s.subjects, strings.ToLower(subject.Name))
+	s.subjects[strings.ToLower(subject.Name)] = subject
+	s.record(journalPut, journalSubject, subject.ID, subject)
+}
+
+func (s *MemoryStore) putLoan(loan models.Loan) {
+	remember(s, s.loans, loan.ID)
+	s.loans[loan.ID] = loan
+	s.record(journalPut, journalLoan, loan.ID, loan)
+}
+
+func (s *MemoryStore) putJob(job models.Job) {
+	remember(s, s.jobs, job.ID)
+	s.jobs[job.ID] = job
+	s.record(journalPut, journalJob, job.ID, job)
+}
+
+func (s *MemoryStore) putJobItems(id string, items models.JobItems) {
+	remember(s, s.jobItems, id)
+	s.jobItems[id] = items
+	s.record(journalPut, journalJobItems, id, items)
+}
+
 // ==============================================
 // FUNCIONES AUXILIARES
 // ==============================================
 
+// sortBooksByTitle - Mismo orden que ORDER BY title
+func sortBooksByTitle(books []models.Book) {
+	sort.SliceStable(books, func(i, j int) bool { return books[i].Title < books[j].Title })
+}
+
+// sortLoansByDate - Préstamos del más reciente al más antiguo
+func sortLoansByDate(loans []models.Loan) {
+	sort.SliceStable(loans, func(i, j int) bool { return loans[i].LoanDate.After(loans[j].LoanDate) })
+}
+
 // Función helper para búsqueda
 func contains(s, substr string) bool {
 	if len(substr) > len(s) {
@@ -412,3 +1138,13 @@ func contains(s, substr string) bool {
 
 	return strings.Contains(s, substr)
 }
+
+// containsString - Indica si la lista contiene exactamente el valor
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
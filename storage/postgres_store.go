@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// PostgresStore - Store sobre PostgreSQL para despliegues centralizados con
+// varias instancias de la API compartiendo la base de datos
+type PostgresStore struct {
+	*sqlStore
+}
+
+// PostgresConfig - Parámetros del pool de conexiones
+type PostgresConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// DefaultPostgresConfig - Pool por defecto, pensado para unas pocas
+// instancias de la API por servidor de base de datos
+var DefaultPostgresConfig = PostgresConfig{
+	MaxOpenConns:    25,
+	MaxIdleConns:    5,
+	ConnMaxLifetime: 30 * time.Minute,
+	ConnMaxIdleTime: 5 * time.Minute,
+}
+
+// postgresDialect - Particularidades de PostgreSQL
+var postgresDialect = sqlDialect{
+	lower:         "lower",
+	timestampType: "TIMESTAMPTZ",
+	// Unicidad sin distinguir mayúsculas mediante un índice sobre lower(name)
+	nameColumn:   "name TEXT NOT NULL",
+	nameConflict: "((lower(name)))",
+	nameMatch:    "lower(name) = lower(?)",
+	forUpdate:    " FOR UPDATE",
+	columnCountQuery: `SELECT COUNT(*) FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+	indexes: []string{
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_authors_name_lower ON authors (lower(name));",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_subjects_name_lower ON subjects (lower(name));",
+	},
+	timeArg: func(t time.Time) interface{} { return t.UTC() },
+}
+
+// NewPostgresStore - Conecta a PostgreSQL (dsn en formato URL o clave=valor)
+// y crea el esquema si no existe
+func NewPostgresStore(dsn string, config PostgresConfig) (*PostgresStore, error) {
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	store, err := openSQLStore(db, postgresDialect)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &PostgresStore{sqlStore: store}, nil
+}
+
+// isPostgresQueryCanceled - PostgreSQL interrumpió la consulta porque se
+// canceló su contexto (lib/pq devuelve el error del servidor, no ctx.Err())
+func isPostgresQueryCanceled(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
@@ -0,0 +1,2078 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"library-api/isbn"
+	"library-api/models"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// sqlStore - Implementación de Store sobre SQL compartida por SQLiteStore y
+// PostgresStore. Las consultas se escriben con "?" y se adaptan al motor con
+// Rebind; el resto de diferencias las describe sqlDialect.
+type sqlStore struct {
+	// db - Donde se ejecutan las consultas: el pool de conexiones o, dentro
+	// de WithTx, la transacción en curso
+	db      sqlConn
+	pool    *sqlx.DB
+	tx      *sqlx.Tx
+	dialect sqlDialect
+	// queryTimeout - Plazo máximo de cada operación (0: sin límite propio,
+	// solo el del contexto recibido)
+	queryTimeout time.Duration
+	// writeLock - Turno de escritura, compartido por el store y sus
+	// transacciones (solo SQLite, nil en el resto). Ver beginTx
+	writeLock *writeLock
+}
+
+// sqlConn - Operaciones comunes a *sqlx.DB y *sqlx.Tx
+type sqlConn interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// sqlDialect - Diferencias de SQL entre los motores soportados
+type sqlDialect struct {
+	// lower - Función SQL que pasa un texto a minúsculas (también las letras
+	// acentuadas), para las búsquedas parciales
+	lower string
+	// timestampType - Tipo de las columnas de fecha
+	timestampType string
+	// nameColumn - Definición de la columna name de autores y materias, que
+	// no distingue mayúsculas
+	nameColumn string
+	// nameConflict y nameMatch - Destino de ON CONFLICT y condición para
+	// buscar autores y materias por nombre
+	nameConflict string
+	nameMatch    string
+	// forUpdate - Sufijo para bloquear las filas leídas en una transacción
+	forUpdate string
+	// columnCountQuery - Cuenta las columnas con un nombre en una tabla
+	columnCountQuery string
+	// indexes - Índices propios del motor
+	indexes []string
+	// timeArg - Fecha como parámetro de consulta
+	timeArg func(t time.Time) interface{}
+	// migrate - Migraciones propias del motor, tras crear las tablas
+	migrate func(db *sqlx.DB) error
+}
+
+var timestampColumn = regexp.MustCompile(`\bTIMESTAMP\b`)
+
+// columnTypes - Adapta los tipos de columna de una sentencia CREATE TABLE
+func (d sqlDialect) columnTypes(ddl string) string {
+	if d.timestampType == "" || d.timestampType == "TIMESTAMP" {
+		return ddl
+	}
+	return timestampColumn.ReplaceAllString(ddl, d.timestampType)
+}
+
+// openSQLStore - Crea el esquema, aplica las migraciones y prepara los datos
+// iniciales sobre una conexión ya abierta
+func openSQLStore(db *sqlx.DB, d sqlDialect) (*sqlStore, error) {
+	if err := createTables(db, d); err != nil {
+		return nil, fmt.Errorf("error creating tables: %w", err)
+	}
+
+	if d.migrate != nil {
+		if err := d.migrate(db); err != nil {
+			return nil, fmt.Errorf("error migrating database: %w", err)
+		}
+	}
+
+	// Crear usuario admin por defecto si no existe
+	ctx := context.Background()
+	store := &sqlStore{db: db, pool: db, dialect: d, queryTimeout: DefaultQueryTimeout}
+	_, _ = store.ensureAdminUser(ctx)
+
+	if err := store.hashPlaintextPasswords(ctx); err != nil {
+		return nil, fmt.Errorf("error migrating passwords: %w", err)
+	}
+
+	if err := store.backfillContributors(ctx); err != nil {
+		return nil, fmt.Errorf("error migrating contributors: %w", err)
+	}
+
+	return store, nil
+}
+
+// dbNow - Hora actual en UTC: las fechas guardadas como texto solo se
+// comparan bien si todas usan la misma zona horaria. Se recorta a
+// microsegundos, la precisión de TIMESTAMP en PostgreSQL.
+func dbNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// DefaultQueryTimeout - Plazo por defecto de cada operación del store
+const DefaultQueryTimeout = 10 * time.Second
+
+// SetQueryTimeout - Cambia el plazo máximo de cada operación (0: sin límite)
+func (s *sqlStore) SetQueryTimeout(timeout time.Duration) {
+	s.queryTimeout = timeout
+}
+
+// withTimeout - Contexto de una operación: el recibido, acotado por
+// queryTimeout
+func (s *sqlStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.tx == nil && s.writeLock.heldBy(ctx) {
+		// Dentro del WithTx que tiene el turno: sus escrituras fallan en
+		// lugar de esperar a la propia transacción (ver reentryGuard)
+		ctx = context.WithValue(ctx, reentryKey{}, true)
+	}
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// Close - Cierra el pool de conexiones (en SQLite, el archivo). El Store que
+// recibe fn en WithTx no cierra nada: la transacción la termina WithTx
+func (s *sqlStore) Close() error {
+	if s.tx != nil {
+		return nil
+	}
+	if err := s.pool.Close(); err != nil {
+		return fmt.Errorf("error closing database: %w", err)
+	}
+	return nil
+}
+
+// ==============================================
+// ESTADO
+// ==============================================
+
+// Ping - Comprueba la conexión con la base de datos
+func (s *sqlStore) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if err := s.pool.PingContext(ctx); err != nil {
+		return fmt.Errorf("error pinging database: %w", err)
+	}
+	return nil
+}
+
+// CheckSchema - Comprueba las tablas de createTables y las columnas que se
+// agregaron a books después de la versión inicial
+func (s *sqlStore) CheckSchema(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	for _, table := range schemaTables {
+		var count int
+		if err := s.db.GetContext(ctx, &count, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE 1 = 0", table)); err != nil {
+			return fmt.Errorf("error checking table %s: %w", table, err)
+		}
+	}
+
+	for _, column := range bookColumns {
+		var count int
+		if err := s.db.GetContext(ctx, &count, s.db.Rebind(s.dialect.columnCountQuery), "books", column.name); err != nil {
+			return fmt.Errorf("error inspecting table books: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("column books.%s is missing: the database is not fully migrated", column.name)
+		}
+	}
+
+	return nil
+}
+
+// GetStats - Cuenta libros y préstamos sin traer las filas
+func (s *sqlStore) GetStats(ctx context.Context, overdueBefore time.Time) (*Stats, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var books struct {
+		Total     int `db:"total"`
+		Available int `db:"available"`
+	}
+	query := `SELECT COUNT(*) AS total, COALESCE(SUM(CASE WHEN available THEN 1 ELSE 0 END), 0) AS available FROM books`
+	if err := s.db.GetContext(ctx, &books, query); err != nil {
+		return nil, fmt.Errorf("error counting books: %w", err)
+	}
+
+	var loans struct {
+		Active  int `db:"active"`
+		Overdue int `db:"overdue"`
+	}
+	query = `SELECT COUNT(*) AS active, COALESCE(SUM(CASE WHEN loan_date < ? THEN 1 ELSE 0 END), 0) AS overdue
+        FROM loans WHERE returned = FALSE`
+	if err := s.db.GetContext(ctx, &loans, s.db.Rebind(query), s.dialect.timeArg(overdueBefore)); err != nil {
+		return nil, fmt.Errorf("error counting loans: %w", err)
+	}
+
+	return &Stats{Books: books.Total, AvailableBooks: books.Available, ActiveLoans: loans.Active, OverdueLoans: loans.Overdue}, nil
+}
+
+// ==============================================
+// TRANSACCIONES
+// ==============================================
+
+// WithTx - Ejecuta fn dentro de una transacción: si fn devuelve error (o el
+// contexto se cancela) no queda nada de lo que hizo. El Store que recibe fn
+// no debe usarse fuera de ella ni desde varias goroutines; dentro de fn el
+// store original no debe usarse para escribir: en SQLite esperaría a la
+// propia transacción, así que falla con ErrTxReentry.
+func (s *sqlStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	if s.tx != nil {
+		// Transacción anidada: un savepoint dentro de la transacción en curso
+		sp, err := s.begin(ctx)
+		if err != nil {
+			return fmt.Errorf("error starting transaction: %w", err)
+		}
+		defer sp.Rollback()
+
+		if err := fn(s); err != nil {
+			return err
+		}
+		return sp.Commit()
+	}
+
+	tx, release, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer release()
+	defer tx.Rollback()
+
+	txStore := &sqlStore{db: tx, pool: s.pool, tx: tx, dialect: s.dialect, queryTimeout: s.queryTimeout, writeLock: s.writeLock}
+	if err := fn(txStore); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// beginTx - Inicia una transacción en el pool. SQLite admite un solo
+// escritor y, con _txlock=immediate, toda transacción lo es desde BEGIN: si
+// varias esperan el bloqueo dentro de SQLite (busy_timeout), la espera es
+// por sondeo y con mucha concurrencia alguna agota el plazo y falla con
+// SQLITE_BUSY. Con writeLock esperan aquí, en orden y hasta que venza el
+// contexto; si lo pide el store de fuera dentro del WithTx que tiene el
+// turno, falla enseguida con ErrTxReentry. release devuelve el turno; se
+// puede llamar más de una vez.
+func (s *sqlStore) beginTx(ctx context.Context) (tx *sqlx.Tx, release func(), err error) {
+	release = func() {}
+	if s.writeLock != nil {
+		// Con el store de fuera dentro de un WithTx: el turno es suyo
+		if ctx.Value(reentryKey{}) != nil || s.writeLock.heldBy(ctx) {
+			return nil, nil, ErrTxReentry
+		}
+		if release, err = s.writeLock.acquire(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	tx, err = s.pool.BeginTxx(ctx, nil)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return tx, release, nil
+}
+
+// writeLock - Turno de escritura de SQLite: turn admite a uno solo y holder
+// es el contexto con el que se tomó, para reconocer las escrituras que fn
+// hace con el store de fuera dentro de su propio WithTx
+type writeLock struct {
+	turn   chan struct{}
+	mu     sync.Mutex
+	holder context.Context
+}
+
+func newWriteLock() *writeLock {
+	return &writeLock{turn: make(chan struct{}, 1)}
+}
+
+// acquire - Espera el turno hasta que venza ctx. release lo devuelve y se
+// puede llamar más de una vez.
+func (l *writeLock) acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case l.turn <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	l.mu.Lock()
+	l.holder = ctx
+	l.mu.Unlock()
+
+	return sync.OnceFunc(func() {
+		l.mu.Lock()
+		l.holder = nil
+		l.mu.Unlock()
+		<-l.turn
+	}), nil
+}
+
+// heldBy - Indica si el turno lo tiene una transacción con ctx (el mismo
+// contexto o uno derivado que comparte su cancelación). Los contextos que no
+// se cancelan, como context.Background(), no se reconocen: los comparten
+// goroutines sin relación entre sí.
+func (l *writeLock) heldBy(ctx context.Context) bool {
+	if l == nil || ctx.Done() == nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.holder != nil && l.holder.Done() == ctx.Done()
+}
+
+// reentryKey - Marca del contexto de una operación del store de fuera hecha
+// dentro del WithTx que tiene el turno de escritura (ver withTimeout)
+type reentryKey struct{}
+
+// reentryGuard - Pool de SQLite que rechaza con ErrTxReentry las escrituras
+// marcadas con reentryKey: fuera de una transacción esperarían el bloqueo de
+// la propia transacción hasta agotar busy_timeout
+type reentryGuard struct {
+	sqlConn
+}
+
+func (g reentryGuard) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if ctx.Value(reentryKey{}) != nil {
+		return nil, ErrTxReentry
+	}
+	return g.sqlConn.ExecContext(ctx, query, args...)
+}
+
+func (g reentryGuard) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	if ctx.Value(reentryKey{}) != nil {
+		return nil, ErrTxReentry
+	}
+	return g.sqlConn.NamedExecContext(ctx, query, arg)
+}
+
+// lockRows - Dentro de WithTx las lecturas por ID bloquean la fila hasta el
+// final de la transacción: leer y después actualizar no pierde escrituras
+// concurrentes
+func (s *sqlStore) lockRows() string {
+	if s.tx == nil {
+		return ""
+	}
+	return s.dialect.forUpdate
+}
+
+// sqlTx - Transacción de una sola operación del store. Dentro de WithTx es
+// un savepoint de la transacción en curso, para que un error de la operación
+// deshaga solo lo que ella hizo.
+type sqlTx struct {
+	*sqlx.Tx
+	savepoint bool
+	done      bool
+	// release - Devuelve el turno de escritura (ver beginTx)
+	release func()
+}
+
+// savepointName - Los savepoints se anidan y liberan en orden inverso, así
+// que basta un nombre: RELEASE y ROLLBACK TO usan el más reciente
+const savepointName = "store_operation"
+
+// begin - Inicia la transacción de una operación
+func (s *sqlStore) begin(ctx context.Context) (*sqlTx, error) {
+	if s.tx == nil {
+		tx, release, err := s.beginTx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlTx{Tx: tx, release: release}, nil
+	}
+
+	if _, err := s.tx.ExecContext(ctx, `SAVEPOINT `+savepointName); err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: s.tx, savepoint: true}, nil
+}
+
+func (t *sqlTx) Commit() error {
+	if !t.savepoint {
+		defer t.release()
+		return t.Tx.Commit()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.Tx.Exec(`RELEASE SAVEPOINT ` + savepointName)
+	return err
+}
+
+func (t *sqlTx) Rollback() error {
+	if !t.savepoint {
+		defer t.release()
+		return t.Tx.Rollback()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if _, err := t.Tx.Exec(`ROLLBACK TO SAVEPOINT ` + savepointName); err != nil {
+		return err
+	}
+	_, err := t.Tx.Exec(`RELEASE SAVEPOINT ` + savepointName)
+	return err
+}
+
+// ensureAdminUser - Crear usuario admin si no existe
+func (s *sqlStore) ensureAdminUser(ctx context.Context) (*models.User, error) {
+	existingUser, err := s.GetUserByUsername(ctx, adminCredentials.Username)
+	if err == nil && existingUser != nil {
+		return existingUser, nil
+	}
+
+	adminUser, err := defaultAdminUser()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.CreateUser(ctx, adminUser)
+}
+
+// hashPlaintextPasswords - Reemplaza por su hash bcrypt las contraseñas
+// guardadas en texto plano por versiones anteriores (el admin por defecto)
+func (s *sqlStore) hashPlaintextPasswords(ctx context.Context) error {
+	var users []models.User
+	if err := s.db.SelectContext(ctx, &users, `SELECT * FROM users`); err != nil {
+		return fmt.Errorf("error getting users: %w", err)
+	}
+
+	for _, user := range users {
+		if isPasswordHash(user.Password) {
+			continue
+		}
+
+		hashed, err := hashPassword(user.Password)
+		if err != nil {
+			return err
+		}
+		query := `UPDATE users SET password = ?, updated_at = ? WHERE id = ?`
+		if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), hashed, dbNow(), user.ID); err != nil {
+			return fmt.Errorf("error hashing password of user %s: %w", user.Username, err)
+		}
+	}
+
+	return nil
+}
+
+// schemaTables - Tablas que crea createTables (las comprueba CheckSchema)
+var schemaTables = []string{"users", "books", "loans", "authors", "book_authors", "subjects", "book_subjects", "deleted_books", "jobs"}
+
+// bookColumns - Columnas bibliográficas agregadas después de la versión
+// inicial (se agregan también en bases de datos existentes)
+var bookColumns = []struct {
+	name       string
+	definition string
+}{
+	{"publisher", "TEXT NOT NULL DEFAULT ''"},
+	{"language", "TEXT NOT NULL DEFAULT ''"},
+	{"page_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"edition", "TEXT NOT NULL DEFAULT ''"},
+	{"cover_url", "TEXT NOT NULL DEFAULT ''"},
+	{"google_id", "TEXT NOT NULL DEFAULT ''"},
+	{"olid", "TEXT NOT NULL DEFAULT ''"},
+	{"lccn", "TEXT NOT NULL DEFAULT ''"},
+	{"oclc", "TEXT NOT NULL DEFAULT ''"},
+	{"translations", "TEXT NOT NULL DEFAULT '{}'"},
+}
+
+func createTables(db *sqlx.DB, d sqlDialect) error {
+	// Tabla de usuarios (NUEVA)
+	usersTable := `
+    CREATE TABLE IF NOT EXISTS users (
+        id TEXT PRIMARY KEY,
+        username TEXT UNIQUE NOT NULL,
+        password TEXT NOT NULL,
+        role TEXT DEFAULT 'user',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+    `
+
+	// Tabla de libros
+	booksTable := `
+    CREATE TABLE IF NOT EXISTS books (
+        id TEXT PRIMARY KEY,
+        title TEXT NOT NULL,
+        author TEXT NOT NULL,
+        isbn TEXT UNIQUE NOT NULL,
+        published INTEGER,
+        genre TEXT,
+        description TEXT,
+        available BOOLEAN DEFAULT TRUE,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+    `
+
+	// Tabla de préstamos (sin clave foránea: los préstamos de un libro
+	// eliminado se conservan en el historial)
+	loansTable := `
+    CREATE TABLE IF NOT EXISTS loans (
+        id TEXT PRIMARY KEY,
+        book_id TEXT NOT NULL,
+        "user" TEXT NOT NULL,
+        loan_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        return_date TIMESTAMP,
+        returned BOOLEAN DEFAULT FALSE
+    );
+    `
+
+	// Tablas de autores y materias (relación muchos a muchos con libros)
+	authorsTable := `
+    CREATE TABLE IF NOT EXISTS authors (
+        id TEXT PRIMARY KEY,
+        ` + d.nameColumn + `
+    );
+    `
+
+	bookAuthorsTable := `
+    CREATE TABLE IF NOT EXISTS book_authors (
+        book_id TEXT NOT NULL,
+        author_id TEXT NOT NULL,
+        position INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (book_id, author_id),
+        FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+        FOREIGN KEY (author_id) REFERENCES authors (id) ON DELETE CASCADE
+    );
+    `
+
+	subjectsTable := `
+    CREATE TABLE IF NOT EXISTS subjects (
+        id TEXT PRIMARY KEY,
+        ` + d.nameColumn + `
+    );
+    `
+
+	bookSubjectsTable := `
+    CREATE TABLE IF NOT EXISTS book_subjects (
+        book_id TEXT NOT NULL,
+        subject_id TEXT NOT NULL,
+        position INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (book_id, subject_id),
+        FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+        FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE CASCADE
+    );
+    `
+
+	// Libros eliminados: se conservan para informar las bajas a los
+	// cosechadores OAI-PMH
+	deletedBooksTable := `
+    CREATE TABLE IF NOT EXISTS deleted_books (
+        id TEXT PRIMARY KEY,
+        isbn TEXT NOT NULL DEFAULT '',
+        title TEXT NOT NULL DEFAULT '',
+        deleted_at TIMESTAMP NOT NULL
+    );
+    `
+
+	// Tabla de trabajos en segundo plano (importaciones masivas)
+	jobsTable := `
+    CREATE TABLE IF NOT EXISTS jobs (
+        id TEXT PRIMARY KEY,
+        type TEXT NOT NULL,
+        status TEXT NOT NULL,
+        params TEXT NOT NULL DEFAULT '{}',
+        total INTEGER NOT NULL DEFAULT 0,
+        processed INTEGER NOT NULL DEFAULT 0,
+        created_count INTEGER NOT NULL DEFAULT 0,
+        updated_count INTEGER NOT NULL DEFAULT 0,
+        skipped_count INTEGER NOT NULL DEFAULT 0,
+        failed_count INTEGER NOT NULL DEFAULT 0,
+        item_errors TEXT NOT NULL DEFAULT '[]',
+        items TEXT,
+        error TEXT NOT NULL DEFAULT '',
+        cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+        created_by TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        started_at TIMESTAMP,
+        finished_at TIMESTAMP
+    );
+    `
+
+	// Crear índices para búsquedas rápidas
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);",
+		"CREATE INDEX IF NOT EXISTS idx_books_title ON books(title);",
+		"CREATE INDEX IF NOT EXISTS idx_books_author ON books(author);",
+		"CREATE INDEX IF NOT EXISTS idx_books_genre ON books(genre);",
+		"CREATE INDEX IF NOT EXISTS idx_books_available ON books(available);",
+		"CREATE INDEX IF NOT EXISTS idx_loans_book_id ON loans(book_id);",
+		"CREATE INDEX IF NOT EXISTS idx_loans_returned ON loans(returned);",
+		"CREATE INDEX IF NOT EXISTS idx_book_authors_author_id ON book_authors(author_id);",
+		"CREATE INDEX IF NOT EXISTS idx_book_subjects_subject_id ON book_subjects(subject_id);",
+		"CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);",
+		"CREATE INDEX IF NOT EXISTS idx_books_updated_at ON books(updated_at, id);",
+		"CREATE INDEX IF NOT EXISTS idx_deleted_books_deleted_at ON deleted_books(deleted_at, id);",
+	}
+
+	// Ejecutar creación de tablas
+	tables := []string{usersTable, booksTable, loansTable, authorsTable, bookAuthorsTable, subjectsTable, bookSubjectsTable, deletedBooksTable, jobsTable}
+	for _, table := range tables {
+		if _, err := db.Exec(d.columnTypes(table)); err != nil {
+			return fmt.Errorf("error creating table: %w", err)
+		}
+	}
+
+	// Migrar columnas nuevas de libros
+	for _, column := range bookColumns {
+		if err := addColumnIfMissing(db, d, "books", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	// Migrar columnas nuevas de trabajos
+	if err := addColumnIfMissing(db, d, "jobs", "items", "TEXT"); err != nil {
+		return err
+	}
+
+	// Crear índices
+	indexes = append(indexes, d.indexes...)
+	for _, index := range indexes {
+		if _, err := db.Exec(index); err != nil {
+			return fmt.Errorf("error creating index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addColumnIfMissing - Agrega una columna a una tabla existente si aún no existe
+func addColumnIfMissing(db *sqlx.DB, d sqlDialect, table, column, definition string) error {
+	var count int
+	if err := db.Get(&count, db.Rebind(d.columnCountQuery), table, column); err != nil {
+		return fmt.Errorf("error inspecting table %s: %w", table, err)
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.Exec(alter); err != nil {
+		return fmt.Errorf("error adding column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
+
+// backfillContributors - Crea los vínculos de autores y materias para libros
+// guardados antes de que existieran las tablas de relación
+func (s *sqlStore) backfillContributors(ctx context.Context) error {
+	var books []models.Book
+	query := `SELECT * FROM books b
+        WHERE NOT EXISTS (SELECT 1 FROM book_authors ba WHERE ba.book_id = b.id)
+          AND NOT EXISTS (SELECT 1 FROM book_subjects bs WHERE bs.book_id = b.id)`
+
+	if err := s.db.SelectContext(ctx, &books, query); err != nil {
+		return fmt.Errorf("error finding books without contributors: %w", err)
+	}
+
+	if len(books) == 0 {
+		return nil
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, book := range books {
+		book.NormalizeContributors()
+		if err := s.saveBookContributors(ctx, tx, book); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ==============================================
+// MÉTODOS PARA USUARIOS (CORREGIDOS PARA DEVOLVER PUNTEROS)
+// ==============================================
+
+// CreateUser - Crear un nuevo usuario (DEVUELVE PUNTERO)
+func (s *sqlStore) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Verificar si el usuario ya existe
+	existingUser, _ := s.GetUserByUsername(ctx, user.Username)
+	if existingUser != nil {
+		return nil, ErrUserAlreadyExists
+	}
+
+	// Generar ID si no tiene
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+
+	user.CreatedAt = dbNow()
+	user.UpdatedAt = dbNow()
+
+	query := `INSERT INTO users (id, username, password, role, created_at, updated_at) 
+              VALUES (:id, :username, :password, :role, :created_at, :updated_at)`
+
+	_, err := s.db.NamedExecContext(ctx, query, user)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user: %w", err)
+	}
+
+	return &user, nil // ← CORREGIDO: devolver puntero
+}
+
+// GetUserByUsername - Obtener usuario por nombre de usuario (DEVUELVE PUNTERO)
+func (s *sqlStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+
+	query := `SELECT * FROM users WHERE username = ? LIMIT 1` + s.lockRows()
+	err := s.db.GetContext(ctx, &user, s.db.Rebind(query), username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	return &user, nil // ← CORREGIDO: devolver puntero
+}
+
+// GetUserByID - Obtener usuario por ID (DEVUELVE PUNTERO)
+func (s *sqlStore) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+
+	query := `SELECT * FROM users WHERE id = ? LIMIT 1` + s.lockRows()
+	err := s.db.GetContext(ctx, &user, s.db.Rebind(query), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	return &user, nil // ← CORREGIDO: devolver puntero
+}
+
+// UpdateUser - Actualizar usuario (DEVUELVE PUNTERO)
+func (s *sqlStore) UpdateUser(ctx context.Context, id string, user models.User) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.GetUserByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	// El nombre de usuario no puede pasar a ser el de otro usuario
+	if existingUser, err := s.GetUserByUsername(ctx, user.Username); err == nil && existingUser.ID != id {
+		return nil, ErrUserAlreadyExists
+	}
+
+	user.UpdatedAt = dbNow()
+
+	query := `UPDATE users SET 
+        username = :username, 
+        password = :password, 
+        role = :role, 
+        updated_at = :updated_at 
+        WHERE id = :id`
+
+	user.ID = id
+	_, err := s.db.NamedExecContext(ctx, query, user)
+	if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+
+	return s.GetUserByID(ctx, id)
+}
+
+// DeleteUser - Eliminar usuario
+func (s *sqlStore) DeleteUser(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM users WHERE id = ?`
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(query), id)
+	if err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ==============================================
+// MÉTODOS PARA LIBROS (CORREGIDOS PARA DEVOLVER PUNTEROS)
+// ==============================================
+
+// CreateBook implementación (DEVUELVE PUNTERO)
+func (s *sqlStore) CreateBook(ctx context.Context, book models.Book) (*models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	book.ID = uuid.New().String()
+	book.CreatedAt = dbNow()
+	book.UpdatedAt = dbNow()
+	book.Available = true
+	book.NormalizeContributors()
+
+	if err := normalizeBookISBN(&book); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO books (id, title, author, isbn, published, genre, description, available,
+              publisher, language, page_count, edition, cover_url, google_id, olid, lccn, oclc, translations, created_at, updated_at) 
+              VALUES (:id, :title, :author, :isbn, :published, :genre, :description, :available,
+              :publisher, :language, :page_count, :edition, :cover_url, :google_id, :olid, :lccn, :oclc, :translations, :created_at, :updated_at)`
+
+	_, err = tx.NamedExecContext(ctx, query, book)
+	if err != nil {
+		if isDuplicateISBNError(err) {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateISBN, book.ISBN)
+		}
+		return nil, fmt.Errorf("error creating book: %w", err)
+	}
+
+	if err := s.saveBookContributors(ctx, tx, book); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return &book, nil // ← CORREGIDO: devolver puntero
+}
+
+// GetBooks implementación
+func (s *sqlStore) GetBooks(ctx context.Context) ([]models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var books []models.Book
+	query := `SELECT 
+        id, 
+        title, 
+        author, 
+        isbn, 
+        published, 
+        genre, 
+        description, 
+        available, 
+        publisher, 
+        language, 
+        page_count, 
+        edition, 
+        cover_url, 
+        google_id, 
+        olid, 
+        lccn, 
+        oclc, 
+        translations, 
+        created_at, 
+        updated_at 
+        FROM books ORDER BY title`
+
+	err := s.db.SelectContext(ctx, &books, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting books: %w", err)
+	}
+
+	if err := s.loadContributors(ctx, books); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// GetBookByID implementación (DEVUELVE PUNTERO)
+func (s *sqlStore) GetBookByID(ctx context.Context, id string) (*models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var book models.Book
+	query := `SELECT * FROM books WHERE id = ?` + s.lockRows()
+
+	err := s.db.GetContext(ctx, &book, s.db.Rebind(query), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookNotFound
+		}
+		return nil, fmt.Errorf("error getting book: %w", err)
+	}
+
+	books := []models.Book{book}
+	if err := s.loadContributors(ctx, books); err != nil {
+		return nil, err
+	}
+
+	return &books[0], nil // ← CORREGIDO: devolver puntero
+}
+
+// UpdateBook implementación (DEVUELVE PUNTERO)
+func (s *sqlStore) UpdateBook(ctx context.Context, id string, updatedBook models.Book) (*models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Obtener libro existente
+	existingBook, err := s.GetBookByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mantener valores que no se actualizan
+	updatedBook.ID = id
+	updatedBook.CreatedAt = existingBook.CreatedAt
+	updatedBook.UpdatedAt = dbNow()
+	updatedBook.NormalizeContributors()
+
+	if err := normalizeBookISBN(&updatedBook); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE books SET 
+        title = :title, 
+        author = :author, 
+        isbn = :isbn, 
+        published = :published, 
+        genre = :genre, 
+        description = :description, 
+        available = :available,
+        publisher = :publisher,
+        language = :language,
+        page_count = :page_count,
+        edition = :edition,
+        cover_url = :cover_url,
+        google_id = :google_id,
+        olid = :olid,
+        lccn = :lccn,
+        oclc = :oclc,
+        translations = :translations,
+        updated_at = :updated_at
+        WHERE id = :id`
+
+	_, err = tx.NamedExecContext(ctx, query, updatedBook)
+	if err != nil {
+		if isDuplicateISBNError(err) {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateISBN, updatedBook.ISBN)
+		}
+		return nil, fmt.Errorf("error updating book: %w", err)
+	}
+
+	if err := s.saveBookContributors(ctx, tx, updatedBook); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return &updatedBook, nil // ← CORREGIDO: devolver puntero
+}
+
+// DeleteBook implementación
+func (s *sqlStore) DeleteBook(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deleted models.DeletedBook
+	err = tx.GetContext(ctx, &deleted, tx.Rebind(`SELECT id, isbn, title FROM books WHERE id = ?`), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrBookNotFound
+		}
+		return fmt.Errorf("error deleting book: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM books WHERE id = ?`), id); err != nil {
+		return fmt.Errorf("error deleting book: %w", err)
+	}
+
+	// Eliminar vínculos con autores y materias
+	for _, linkTable := range []string{"book_authors", "book_subjects"} {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM `+linkTable+` WHERE book_id = ?`), id); err != nil {
+			return fmt.Errorf("error deleting %s: %w", linkTable, err)
+		}
+	}
+
+	// Dejar constancia de la baja para los cosechadores
+	deleted.DeletedAt = dbNow()
+	query := `INSERT INTO deleted_books (id, isbn, title, deleted_at)
+              VALUES (:id, :isbn, :title, :deleted_at)
+              ON CONFLICT (id) DO UPDATE SET isbn = excluded.isbn, title = excluded.title, deleted_at = excluded.deleted_at`
+	if _, err := tx.NamedExecContext(ctx, query, deleted); err != nil {
+		return fmt.Errorf("error recording deleted book: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SearchBooks implementación
+func (s *sqlStore) SearchBooks(ctx context.Context, title, author, genre string, available *bool) ([]models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var books []models.Book
+	query, args := s.bookSearchQuery(title, author, genre, available)
+
+	err := s.db.SelectContext(ctx, &books, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error searching books: %w", err)
+	}
+
+	if err := s.loadContributors(ctx, books); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// ForEachBook - Recorrer los libros filtrados fila a fila, sin cargarlos todos
+// en memoria. Las listas de autores y materias se derivan de las columnas
+// author/genre para no consultar los enlaces por cada fila. No se aplica
+// queryTimeout: el recorrido dura lo que tarde fn (una exportación en
+// streaming), así que solo lo acota el contexto recibido.
+func (s *sqlStore) ForEachBook(ctx context.Context, title, author, genre string, available *bool, fn func(models.Book) error) error {
+	query, args := s.bookSearchQuery(title, author, genre, available)
+
+	rows, err := s.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error searching books: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// El driver puede tener filas ya leídas aunque el contexto se cancele
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var book models.Book
+		if err := rows.StructScan(&book); err != nil {
+			return fmt.Errorf("error scanning book: %w", err)
+		}
+		book.NormalizeContributors()
+
+		if err := fn(book); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// bookSearchQuery - Consulta de búsqueda compartida por SearchBooks y
+// ForEachBook: "contiene" sin distinguir mayúsculas, como MemoryStore
+func (s *sqlStore) bookSearchQuery(title, author, genre string, available *bool) (string, []interface{}) {
+	query := `SELECT * FROM books WHERE 1=1`
+	args := []interface{}{}
+
+	if title != "" {
+		query += ` AND ` + s.dialect.lower + `(title) LIKE ? ESCAPE '\'`
+		args = append(args, likeContains(title))
+	}
+
+	if author != "" {
+		query += ` AND ` + s.dialect.lower + `(author) LIKE ? ESCAPE '\'`
+		args = append(args, likeContains(author))
+	}
+
+	if genre != "" {
+		query += ` AND ` + s.dialect.lower + `(genre) LIKE ? ESCAPE '\'`
+		args = append(args, likeContains(genre))
+	}
+
+	if available != nil {
+		query += ` AND available = ?`
+		args = append(args, *available)
+	}
+
+	query += ` ORDER BY title`
+
+	return s.db.Rebind(query), args
+}
+
+// likeContains - Patrón LIKE que busca el texto en cualquier posición,
+// tratando % y _ como caracteres literales
+func likeContains(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(text))
+	return "%" + escaped + "%"
+}
+
+// ==============================================
+// CAMBIOS INCREMENTALES (OAI-PMH)
+// ==============================================
+
+// GetBookChanges - Altas, modificaciones y bajas ordenadas por (fecha, id)
+func (s *sqlStore) GetBookChanges(ctx context.Context, q models.BookChangeQuery) ([]models.BookChange, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, datestamp, deleted FROM (
+            SELECT id, updated_at AS datestamp, 0 AS deleted FROM books
+            UNION ALL
+            SELECT id, deleted_at AS datestamp, 1 AS deleted FROM deleted_books
+        ) AS changes WHERE 1=1`
+	args := []interface{}{}
+
+	if q.From != nil {
+		query += ` AND datestamp >= ?`
+		args = append(args, s.dialect.timeArg(*q.From))
+	}
+	if q.Before != nil {
+		query += ` AND datestamp < ?`
+		args = append(args, s.dialect.timeArg(*q.Before))
+	}
+	if q.After != nil {
+		query += ` AND (datestamp > ? OR (datestamp = ? AND id > ?))`
+		args = append(args, s.dialect.timeArg(*q.After), s.dialect.timeArg(*q.After), q.AfterID)
+	}
+
+	query += ` ORDER BY datestamp, id`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	var rows []struct {
+		ID        string    `db:"id"`
+		Datestamp time.Time `db:"datestamp"`
+		Deleted   bool      `db:"deleted"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("error getting book changes: %w", err)
+	}
+
+	var liveIDs []string
+	changes := make([]models.BookChange, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, models.BookChange{ID: row.ID, Datestamp: row.Datestamp, Deleted: row.Deleted})
+		if !row.Deleted {
+			liveIDs = append(liveIDs, row.ID)
+		}
+	}
+
+	books, err := s.getBooksByIDs(ctx, liveIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		if book, ok := books[changes[i].ID]; ok {
+			changes[i].Book = &book
+		}
+	}
+
+	return changes, nil
+}
+
+// GetBookChange - Último cambio de un libro (vigente o eliminado)
+func (s *sqlStore) GetBookChange(ctx context.Context, id string) (*models.BookChange, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	book, err := s.GetBookByID(ctx, id)
+	if err == nil {
+		return &models.BookChange{ID: book.ID, Datestamp: book.UpdatedAt, Book: book}, nil
+	}
+	if err != ErrBookNotFound {
+		return nil, err
+	}
+
+	var deleted models.DeletedBook
+	err = s.db.GetContext(ctx, &deleted, s.db.Rebind(`SELECT * FROM deleted_books WHERE id = ?`), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookNotFound
+		}
+		return nil, fmt.Errorf("error getting deleted book: %w", err)
+	}
+
+	return &models.BookChange{ID: deleted.ID, Datestamp: deleted.DeletedAt, Deleted: true}, nil
+}
+
+// getBooksByIDs - Libros por ID, con autores y materias
+func (s *sqlStore) getBooksByIDs(ctx context.Context, ids []string) (map[string]models.Book, error) {
+	result := make(map[string]models.Book, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT * FROM books WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+
+	var books []models.Book
+	if err := s.db.SelectContext(ctx, &books, s.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("error getting books: %w", err)
+	}
+	if err := s.loadContributors(ctx, books); err != nil {
+		return nil, err
+	}
+
+	for _, book := range books {
+		result[book.ID] = book
+	}
+	return result, nil
+}
+
+// GetBookByISBN - Obtener libro por ISBN (usa el índice UNIQUE de isbn)
+func (s *sqlStore) GetBookByISBN(ctx context.Context, code string) (*models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	normalized, err := isbn.Normalize(code)
+	if err != nil {
+		return nil, InvalidValue(ErrInvalidISBN, "isbn", code, err.Error())
+	}
+
+	var book models.Book
+	err = s.db.GetContext(ctx, &book, s.db.Rebind(`SELECT * FROM books WHERE isbn = ?`), normalized)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookNotFound
+		}
+		return nil, fmt.Errorf("error getting book by isbn: %w", err)
+	}
+
+	books := []models.Book{book}
+	if err := s.loadContributors(ctx, books); err != nil {
+		return nil, err
+	}
+
+	return &books[0], nil
+}
+
+// GetBooksByISBNs - Obtener los libros existentes para una lista de ISBN,
+// indexados por ISBN normalizado
+func (s *sqlStore) GetBooksByISBNs(ctx context.Context, codes []string) (map[string]models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const chunkSize = 500
+
+	normalized := normalizeISBNList(codes)
+	result := make(map[string]models.Book, len(normalized))
+
+	for start := 0; start < len(normalized); start += chunkSize {
+		end := start + chunkSize
+		if end > len(normalized) {
+			end = len(normalized)
+		}
+
+		query, args, err := sqlx.In(`SELECT * FROM books WHERE isbn IN (?)`, normalized[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("error building isbn query: %w", err)
+		}
+
+		var books []models.Book
+		if err := s.db.SelectContext(ctx, &books, s.db.Rebind(query), args...); err != nil {
+			return nil, fmt.Errorf("error getting books by isbn: %w", err)
+		}
+
+		if err := s.loadContributors(ctx, books); err != nil {
+			return nil, err
+		}
+
+		for _, book := range books {
+			result[book.ISBN] = book
+		}
+	}
+
+	return result, nil
+}
+
+// MigrateISBNs - Normaliza a ISBN-13 los ISBN guardados antes de la validación
+func (s *sqlStore) MigrateISBNs(ctx context.Context) (*ISBNMigrationReport, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var books []models.Book
+	if err := s.db.SelectContext(ctx, &books, `SELECT * FROM books`); err != nil {
+		return nil, fmt.Errorf("error getting books: %w", err)
+	}
+
+	updates, report := planISBNMigration(books)
+	if len(updates) == 0 {
+		return report, nil
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := dbNow()
+	for id, normalized := range updates {
+		query := `UPDATE books SET isbn = ?, updated_at = ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), normalized, now, id); err != nil {
+			return nil, fmt.Errorf("error updating isbn of book %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	report.Normalized = len(updates)
+	return report, nil
+}
+
+// ==============================================
+// MÉTODOS PARA AUTORES Y MATERIAS
+// ==============================================
+
+// GetAuthors - Obtener autores que tienen al menos un libro
+func (s *sqlStore) GetAuthors(ctx context.Context) ([]models.Author, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	authors := []models.Author{}
+	query := `SELECT a.id, a.name, COUNT(ba.book_id) AS book_count
+        FROM authors a
+        JOIN book_authors ba ON ba.author_id = a.id
+        GROUP BY a.id, a.name
+        ORDER BY a.name`
+
+	if err := s.db.SelectContext(ctx, &authors, query); err != nil {
+		return nil, fmt.Errorf("error getting authors: %w", err)
+	}
+
+	return authors, nil
+}
+
+// GetAuthorByID - Obtener autor por ID
+func (s *sqlStore) GetAuthorByID(ctx context.Context, id string) (*models.Author, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var author models.Author
+	query := `SELECT a.id, a.name, COUNT(ba.book_id) AS book_count
+        FROM authors a
+        LEFT JOIN book_authors ba ON ba.author_id = a.id
+        WHERE a.id = ?
+        GROUP BY a.id, a.name`
+
+	err := s.db.GetContext(ctx, &author, s.db.Rebind(query), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAuthorNotFound
+		}
+		return nil, fmt.Errorf("error getting author: %w", err)
+	}
+
+	return &author, nil
+}
+
+// GetBooksByAuthor - Obtener todos los libros de un autor
+func (s *sqlStore) GetBooksByAuthor(ctx context.Context, authorID string) ([]models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.GetAuthorByID(ctx, authorID); err != nil {
+		return nil, err
+	}
+
+	books := []models.Book{}
+	query := `SELECT b.* FROM books b
+        JOIN book_authors ba ON ba.book_id = b.id
+        WHERE ba.author_id = ?
+        ORDER BY b.published, b.title`
+
+	if err := s.db.SelectContext(ctx, &books, s.db.Rebind(query), authorID); err != nil {
+		return nil, fmt.Errorf("error getting books by author: %w", err)
+	}
+
+	if err := s.loadContributors(ctx, books); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// GetSubjects - Obtener materias que tienen al menos un libro
+func (s *sqlStore) GetSubjects(ctx context.Context) ([]models.Subject, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	subjects := []models.Subject{}
+	query := `SELECT sb.id, sb.name, COUNT(bs.book_id) AS book_count
+        FROM subjects sb
+        JOIN book_subjects bs ON bs.subject_id = sb.id
+        GROUP BY sb.id, sb.name
+        ORDER BY sb.name`
+
+	if err := s.db.SelectContext(ctx, &subjects, query); err != nil {
+		return nil, fmt.Errorf("error getting subjects: %w", err)
+	}
+
+	return subjects, nil
+}
+
+// GetSubjectByID - Obtener materia por ID
+func (s *sqlStore) GetSubjectByID(ctx context.Context, id string) (*models.Subject, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var subject models.Subject
+	query := `SELECT sb.id, sb.name, COUNT(bs.book_id) AS book_count
+        FROM subjects sb
+        LEFT JOIN book_subjects bs ON bs.subject_id = sb.id
+        WHERE sb.id = ?
+        GROUP BY sb.id, sb.name`
+
+	err := s.db.GetContext(ctx, &subject, s.db.Rebind(query), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSubjectNotFound
+		}
+		return nil, fmt.Errorf("error getting subject: %w", err)
+	}
+
+	return &subject, nil
+}
+
+// GetBooksBySubject - Obtener todos los libros de una materia
+func (s *sqlStore) GetBooksBySubject(ctx context.Context, subjectID string) ([]models.Book, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.GetSubjectByID(ctx, subjectID); err != nil {
+		return nil, err
+	}
+
+	books := []models.Book{}
+	query := `SELECT b.* FROM books b
+        JOIN book_subjects bs ON bs.book_id = b.id
+        WHERE bs.subject_id = ?
+        ORDER BY b.title`
+
+	if err := s.db.SelectContext(ctx, &books, s.db.Rebind(query), subjectID); err != nil {
+		return nil, fmt.Errorf("error getting books by subject: %w", err)
+	}
+
+	if err := s.loadContributors(ctx, books); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// saveBookContributors - Reemplaza los autores y materias vinculados a un libro
+func (s *sqlStore) saveBookContributors(ctx context.Context, tx sqlConn, book models.Book) error {
+	if err := s.replaceBookLinks(ctx, tx, "authors", "book_authors", "author_id", book.ID, book.Authors); err != nil {
+		return err
+	}
+	return s.replaceBookLinks(ctx, tx, "subjects", "book_subjects", "subject_id", book.ID, book.Subjects)
+}
+
+// replaceBookLinks - Sincroniza una tabla de relación libro ↔ entidad por nombre
+func (s *sqlStore) replaceBookLinks(ctx context.Context, tx sqlConn, table, linkTable, linkColumn, bookID string, names []string) error {
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM `+linkTable+` WHERE book_id = ?`), bookID); err != nil {
+		return fmt.Errorf("error clearing %s: %w", linkTable, err)
+	}
+
+	for position, name := range names {
+		insertQuery := `INSERT INTO ` + table + ` (id, name) VALUES (?, ?) ON CONFLICT ` + s.dialect.nameConflict + ` DO NOTHING`
+		if _, err := tx.ExecContext(ctx, tx.Rebind(insertQuery), uuid.New().String(), name); err != nil {
+			return fmt.Errorf("error saving %s: %w", table, err)
+		}
+
+		var entityID string
+		if err := tx.GetContext(ctx, &entityID, tx.Rebind(`SELECT id FROM `+table+` WHERE `+s.dialect.nameMatch), name); err != nil {
+			return fmt.Errorf("error getting %s id: %w", table, err)
+		}
+
+		linkQuery := `INSERT INTO ` + linkTable + ` (book_id, ` + linkColumn + `, position) VALUES (?, ?, ?)
+            ON CONFLICT DO NOTHING`
+		if _, err := tx.ExecContext(ctx, tx.Rebind(linkQuery), bookID, entityID, position); err != nil {
+			return fmt.Errorf("error linking %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// bookLink - Fila auxiliar para cargar nombres vinculados a libros
+type bookLink struct {
+	BookID string `db:"book_id"`
+	Name   string `db:"name"`
+}
+
+// loadContributors - Completa los autores y materias de una lista de libros
+func (s *sqlStore) loadContributors(ctx context.Context, books []models.Book) error {
+	const chunkSize = 500
+
+	index := make(map[string]int, len(books))
+	for i := range books {
+		index[books[i].ID] = i
+		books[i].Authors = []string{}
+		books[i].Subjects = []string{}
+	}
+
+	for start := 0; start < len(books); start += chunkSize {
+		end := start + chunkSize
+		if end > len(books) {
+			end = len(books)
+		}
+
+		ids := make([]string, 0, end-start)
+		for _, book := range books[start:end] {
+			ids = append(ids, book.ID)
+		}
+
+		authors, err := s.selectBookLinks(ctx, `SELECT ba.book_id, a.name FROM book_authors ba
+            JOIN authors a ON a.id = ba.author_id
+            WHERE ba.book_id IN (?) ORDER BY ba.position`, ids)
+		if err != nil {
+			return fmt.Errorf("error loading authors: %w", err)
+		}
+		for _, link := range authors {
+			i := index[link.BookID]
+			books[i].Authors = append(books[i].Authors, link.Name)
+		}
+
+		subjects, err := s.selectBookLinks(ctx, `SELECT bs.book_id, sb.name FROM book_subjects bs
+            JOIN subjects sb ON sb.id = bs.subject_id
+            WHERE bs.book_id IN (?) ORDER BY bs.position`, ids)
+		if err != nil {
+			return fmt.Errorf("error loading subjects: %w", err)
+		}
+		for _, link := range subjects {
+			i := index[link.BookID]
+			books[i].Subjects = append(books[i].Subjects, link.Name)
+		}
+	}
+
+	// Libros sin vínculos: derivar listas de los campos planos
+	for i := range books {
+		books[i].NormalizeContributors()
+	}
+
+	return nil
+}
+
+func (s *sqlStore) selectBookLinks(ctx context.Context, query string, ids []string) ([]bookLink, error) {
+	query, args, err := sqlx.In(query, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []bookLink
+	if err := s.db.SelectContext(ctx, &links, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// UpdateBookAvailability - Actualizar disponibilidad de libro
+func (s *sqlStore) UpdateBookAvailability(ctx context.Context, bookID string, available bool) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE books SET available = ?, updated_at = ? WHERE id = ?`
+
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(query), available, dbNow(), bookID)
+	if err != nil {
+		return fmt.Errorf("error updating book availability: %w", err)
+	}
+
+	return nil
+}
+
+// ==============================================
+// MÉTODOS PARA PRÉSTAMOS (CORREGIDOS PARA DEVOLVER PUNTEROS)
+// ==============================================
+
+// CreateLoan implementación (DEVUELVE PUNTERO). El libro se marca como
+// prestado con un UPDATE condicional dentro de la misma transacción que crea
+// el préstamo, así dos préstamos simultáneos no pueden prosperar a la vez.
+func (s *sqlStore) CreateLoan(ctx context.Context, loan models.Loan) (*models.Loan, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Verificar que el libro existe y está disponible (bloqueando la fila
+	// hasta el fin de la transacción en los motores que lo permiten)
+	var available bool
+	checkQuery := `SELECT available FROM books WHERE id = ?` + s.dialect.forUpdate
+	err = tx.GetContext(ctx, &available, tx.Rebind(checkQuery), loan.BookID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookNotFound
+		}
+		return nil, fmt.Errorf("error checking book availability: %w", err)
+	}
+
+	if !available {
+		return nil, ErrBookNotAvailable
+	}
+
+	// Marcar el libro como no disponible solo si sigue disponible
+	updateBookQuery := `UPDATE books SET available = FALSE, updated_at = ? WHERE id = ? AND available = TRUE`
+	result, err := tx.ExecContext(ctx, tx.Rebind(updateBookQuery), dbNow(), loan.BookID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating book status: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return nil, ErrBookNotAvailable
+	}
+
+	// Crear el préstamo
+	loan.ID = uuid.New().String()
+	loan.LoanDate = dbNow()
+	loan.Returned = false
+
+	// Insertar préstamo - ESPECIFICAR COLUMNAS EXPLÍCITAMENTE
+	loanQuery := `INSERT INTO loans (id, book_id, "user", loan_date, returned) 
+                  VALUES (:id, :book_id, :user, :loan_date, :returned)`
+
+	// Usar un mapa para asegurar el mapeo correcto
+	loanMap := map[string]interface{}{
+		"id":        loan.ID,
+		"book_id":   loan.BookID, // Asegurar que se mapea a book_id
+		"user":      loan.User,
+		"loan_date": loan.LoanDate,
+		"returned":  loan.Returned,
+	}
+
+	_, err = tx.NamedExecContext(ctx, loanQuery, loanMap)
+	if err != nil {
+		return nil, fmt.Errorf("error creating loan: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return &loan, nil // ← CORREGIDO: devolver puntero
+}
+
+// ReturnBook implementación. El préstamo se cierra con un UPDATE condicional:
+// si otra devolución simultánea ya lo cerró, esta no hace nada.
+func (s *sqlStore) ReturnBook(ctx context.Context, loanID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Verificar que el préstamo existe
+	var loan models.Loan
+	getLoanQuery := `SELECT * FROM loans WHERE id = ?` + s.dialect.forUpdate
+	err = tx.GetContext(ctx, &loan, tx.Rebind(getLoanQuery), loanID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrLoanNotFound
+		}
+		return fmt.Errorf("error getting loan: %w", err)
+	}
+
+	if loan.Returned {
+		return nil // Ya está devuelto
+	}
+
+	// Actualizar préstamo como devuelto
+	now := dbNow()
+	returnLoanQuery := `UPDATE loans SET returned = TRUE, return_date = ? WHERE id = ? AND returned = FALSE`
+	result, err := tx.ExecContext(ctx, tx.Rebind(returnLoanQuery), now, loanID)
+	if err != nil {
+		return fmt.Errorf("error updating loan: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return nil // Devuelto por otra petición
+	}
+
+	// Actualizar libro como disponible
+	updateBookQuery := `UPDATE books SET available = TRUE, updated_at = ? WHERE id = ?`
+	_, err = tx.ExecContext(ctx, tx.Rebind(updateBookQuery), now, loan.BookID)
+	if err != nil {
+		return fmt.Errorf("error updating book: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetLoans implementación
+func (s *sqlStore) GetLoans(ctx context.Context) ([]models.Loan, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var loans []models.Loan
+	query := `SELECT * FROM loans ORDER BY loan_date DESC`
+
+	err := s.db.SelectContext(ctx, &loans, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting loans: %w", err)
+	}
+
+	return loans, nil
+}
+
+// GetActiveLoans implementación
+func (s *sqlStore) GetActiveLoans(ctx context.Context) ([]models.Loan, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var loans []models.Loan
+	query := `SELECT * FROM loans WHERE returned = FALSE ORDER BY loan_date DESC`
+
+	err := s.db.SelectContext(ctx, &loans, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting active loans: %w", err)
+	}
+
+	return loans, nil
+}
+
+// GetLoanByID implementación (DEVUELVE PUNTERO)
+func (s *sqlStore) GetLoanByID(ctx context.Context, id string) (*models.Loan, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var loan models.Loan
+	query := `SELECT * FROM loans WHERE id = ?` + s.lockRows()
+
+	err := s.db.GetContext(ctx, &loan, s.db.Rebind(query), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrLoanNotFound
+		}
+		return nil, fmt.Errorf("error getting loan: %w", err)
+	}
+
+	return &loan, nil // ← CORREGIDO: devolver puntero
+}
+
+// GetLoansWithBooks - Obtener préstamos con información de libros
+func (s *sqlStore) GetLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	loansWithBooks, err := s.selectLoansWithBooks(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("error getting loans with books: %w", err)
+	}
+
+	return loansWithBooks, nil
+}
+
+// GetActiveLoansWithBooks - Obtener préstamos activos con información de libros
+func (s *sqlStore) GetActiveLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	loansWithBooks, err := s.selectLoansWithBooks(ctx, "WHERE l.returned = FALSE")
+	if err != nil {
+		return nil, fmt.Errorf("error getting active loans with books: %w", err)
+	}
+
+	return loansWithBooks, nil
+}
+
+// selectLoansWithBooks - Préstamos unidos a su libro; los libros eliminados
+// se muestran como DeletedLoanBook. Las fechas del libro se leen sin
+// COALESCE porque SQLite no conserva el tipo de una expresión y las
+// devolvería como texto.
+func (s *sqlStore) selectLoansWithBooks(ctx context.Context, where string) ([]models.LoanWithBook, error) {
+	query := `
+    SELECT 
+        l.*,
+        COALESCE(b.id, ?) as "book.id",
+        COALESCE(b.title, ?) as "book.title",
+        COALESCE(b.author, ?) as "book.author",
+        COALESCE(b.isbn, ?) as "book.isbn",
+        COALESCE(b.published, 0) as "book.published",
+        COALESCE(b.genre, ?) as "book.genre",
+        COALESCE(b.description, ?) as "book.description",
+        COALESCE(b.available, FALSE) as "book.available",
+        COALESCE(b.publisher, '') as "book.publisher",
+        COALESCE(b.language, '') as "book.language",
+        COALESCE(b.page_count, 0) as "book.page_count",
+        COALESCE(b.edition, '') as "book.edition",
+        COALESCE(b.cover_url, '') as "book.cover_url",
+        COALESCE(b.translations, '{}') as "book.translations",
+        b.created_at as book_created_at,
+        b.updated_at as book_updated_at
+    FROM loans l
+    LEFT JOIN books b ON l.book_id = b.id
+    ` + where + `
+    ORDER BY l.loan_date DESC
+    `
+
+	deleted := DeletedLoanBook(models.Loan{})
+	args := []interface{}{deleted.ID, deleted.Title, deleted.Author, deleted.ISBN, deleted.Genre, deleted.Description}
+
+	var rows []struct {
+		models.LoanWithBook
+		BookCreatedAt *time.Time `db:"book_created_at"`
+		BookUpdatedAt *time.Time `db:"book_updated_at"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	loansWithBooks := make([]models.LoanWithBook, 0, len(rows))
+	for _, row := range rows {
+		row.Book.CreatedAt = row.LoanDate
+		if row.BookCreatedAt != nil {
+			row.Book.CreatedAt = *row.BookCreatedAt
+		}
+		row.Book.UpdatedAt = row.LoanDate
+		if row.BookUpdatedAt != nil {
+			row.Book.UpdatedAt = *row.BookUpdatedAt
+		}
+		row.Book.NormalizeContributors()
+		loansWithBooks = append(loansWithBooks, row.LoanWithBook)
+	}
+
+	return loansWithBooks, nil
+}
+
+// ==============================================
+// MÉTODOS PARA TRABAJOS EN SEGUNDO PLANO
+// ==============================================
+
+// jobColumns - Columnas de jobs salvo items, que puede ocupar megas y solo
+// se lee con GetJobItems
+const jobColumns = `id, type, status, params, total, processed, created_count, updated_count,
+        skipped_count, failed_count, item_errors, error, cancel_requested, created_by,
+        created_at, updated_at, started_at, finished_at`
+
+// CreateJob - Registrar un nuevo trabajo
+func (s *sqlStore) CreateJob(ctx context.Context, job models.Job) (*models.Job, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+	if job.Status == "" {
+		job.Status = models.JobPending
+	}
+	job.CreatedAt = dbNow()
+	job.UpdatedAt = job.CreatedAt
+
+	query := `INSERT INTO jobs (id, type, status, params, total, processed, created_count, updated_count,
+              skipped_count, failed_count, item_errors, error, cancel_requested, created_by,
+              created_at, updated_at, started_at, finished_at)
+              VALUES (:id, :type, :status, :params, :total, :processed, :created_count, :updated_count,
+              :skipped_count, :failed_count, :item_errors, :error, :cancel_requested, :created_by,
+              :created_at, :updated_at, :started_at, :finished_at)`
+
+	if _, err := s.db.NamedExecContext(ctx, query, job); err != nil {
+		return nil, fmt.Errorf("error creating job: %w", err)
+	}
+
+	job.Items = nil
+	return &job, nil
+}
+
+// GetJobByID - Obtener trabajo por ID
+func (s *sqlStore) GetJobByID(ctx context.Context, id string) (*models.Job, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var job models.Job
+	err := s.db.GetContext(ctx, &job, s.db.Rebind(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`+s.lockRows()), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("error getting job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// UpdateJob - Guardar el estado y progreso de un trabajo (sin los
+// elementos, ver SaveJobItems). Una cancelación pedida no se pierde aunque
+// el trabajo que se guarda no la tenga (ver CancelJob)
+func (s *sqlStore) UpdateJob(ctx context.Context, job models.Job) (*models.Job, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	job.UpdatedAt = dbNow()
+
+	query := `UPDATE jobs SET
+        status = :status,
+        params = :params,
+        total = :total,
+        processed = :processed,
+        created_count = :created_count,
+        updated_count = :updated_count,
+        skipped_count = :skipped_count,
+        failed_count = :failed_count,
+        item_errors = :item_errors,
+        error = :error,
+        cancel_requested = cancel_requested OR :cancel_requested,
+        updated_at = :updated_at,
+        started_at = :started_at,
+        finished_at = :finished_at
+        WHERE id = :id`
+
+	result, err := s.db.NamedExecContext(ctx, query, job)
+	if err != nil {
+		return nil, fmt.Errorf("error updating job: %w", err)
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return nil, ErrJobNotFound
+	}
+
+	job.Items = nil
+	return &job, nil
+}
+
+// SaveJobItems - Guardar los elementos resueltos de un trabajo
+func (s *sqlStore) SaveJobItems(ctx context.Context, id string, items models.JobItems) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE jobs SET items = ? WHERE id = ?`), items, id)
+	if err != nil {
+		return fmt.Errorf("error saving job items: %w", err)
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrJobNotFound
+	}
+
+	return nil
+}
+
+// GetJobItems - Obtener los elementos de un trabajo (nil si aún no se
+// resolvieron)
+func (s *sqlStore) GetJobItems(ctx context.Context, id string) (models.JobItems, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var items models.JobItems
+	err := s.db.GetContext(ctx, &items, s.db.Rebind(`SELECT items FROM jobs WHERE id = ?`), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("error getting job items: %w", err)
+	}
+
+	return items, nil
+}
+
+// CancelJob - Pide la cancelación de un trabajo en una sola sentencia, sin
+// pisar el progreso que guarda el worker: marca cancel_requested y, si el
+// trabajo sigue pendiente, lo da por cancelado. Un trabajo ya terminado no
+// cambia (devuelve ErrJobFinished junto con el trabajo).
+func (s *sqlStore) CancelJob(ctx context.Context, id string) (*models.Job, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	now := dbNow()
+	query := `UPDATE jobs SET
+        cancel_requested = TRUE,
+        status = CASE WHEN status = ? THEN ? ELSE status END,
+        finished_at = CASE WHEN status = ? THEN ? ELSE finished_at END,
+        updated_at = ?
+        WHERE id = ? AND status IN (?, ?)`
+
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(query),
+		models.JobPending, models.JobCancelled, models.JobPending, now, now,
+		id, models.JobPending, models.JobRunning)
+	if err != nil {
+		return nil, fmt.Errorf("error cancelling job: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+
+	job, err := s.GetJobByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return job, ErrJobFinished
+	}
+	return job, nil
+}
+
+// GetJobs - Listar trabajos, opcionalmente filtrados por estado
+func (s *sqlStore) GetJobs(ctx context.Context, statuses ...string) ([]models.Job, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	jobs := []models.Job{}
+	query := `SELECT ` + jobColumns + ` FROM jobs`
+	args := []interface{}{}
+
+	if len(statuses) > 0 {
+		inQuery, inArgs, err := sqlx.In(` WHERE status IN (?)`, statuses)
+		if err != nil {
+			return nil, fmt.Errorf("error building jobs query: %w", err)
+		}
+		query += inQuery
+		args = inArgs
+	}
+
+	query += ` ORDER BY created_at`
+
+	if err := s.db.SelectContext(ctx, &jobs, s.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("error getting jobs: %w", err)
+	}
+
+	return jobs, nil
+}
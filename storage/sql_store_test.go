@@ -0,0 +1,120 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+// TestSQLiteStoreQueryTimeout - Una operación que supera el plazo falla con
+// un error que storage.IsTimeout reconoce
+func TestSQLiteStoreQueryTimeout(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+
+	store.SetQueryTimeout(time.Nanosecond)
+	_, err = store.GetBooks(context.Background())
+	if !storage.IsTimeout(err) {
+		t.Errorf("GetBooks with expired timeout: got %v, want a timeout", err)
+	}
+
+	store.SetQueryTimeout(0)
+	if _, err := store.GetBooks(context.Background()); err != nil {
+		t.Errorf("GetBooks without timeout: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.GetBooks(ctx); err == nil || storage.IsTimeout(err) {
+		t.Errorf("GetBooks with canceled context: got %v, want a cancellation", err)
+	}
+}
+
+// TestSQLiteStoreClose - Close libera el archivo y las operaciones
+// posteriores fallan en lugar de bloquearse
+func TestSQLiteStoreClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.db")
+	store, err := storage.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := store.GetBooks(context.Background()); err == nil {
+		t.Error("GetBooks after Close: expected an error")
+	}
+
+	reopened, err := storage.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore after Close: %v", err)
+	}
+	reopened.Close()
+}
+
+// TestSQLiteStoreOuterWriteInsideTx - Escribir con el store de fuera dentro
+// de WithTx falla enseguida con ErrTxReentry en lugar de esperar a la propia
+// transacción, también a través de un store instrumentado
+func TestSQLiteStoreOuterWriteInsideTx(t *testing.T) {
+	sqlite, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	sqlite.SetQueryTimeout(0)
+	store := storage.Instrument(sqlite, func(context.Context, string, time.Duration, error) {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	job, err := store.CreateJob(ctx, models.Job{Type: models.JobTypeBulkImport})
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+
+	writes := map[string]func(ctx context.Context) error{
+		"CreateBook (transaction)": func(ctx context.Context) error {
+			_, err := store.CreateBook(ctx, models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"})
+			return err
+		},
+		"UpdateJob (single statement)": func(ctx context.Context) error {
+			_, err := store.UpdateJob(ctx, *job)
+			return err
+		},
+		"WithTx": func(ctx context.Context) error {
+			return store.WithTx(ctx, func(tx storage.Store) error { return nil })
+		},
+	}
+
+	for name, write := range writes {
+		started := time.Now()
+		err := store.WithTx(ctx, func(tx storage.Store) error {
+			if err := write(ctx); !errors.Is(err, storage.ErrTxReentry) {
+				t.Errorf("%s with the outer store: got %v, want ErrTxReentry", name, err)
+			}
+			// Lo mismo con un contexto derivado del de la transacción
+			if err := write(context.WithValue(ctx, struct{}{}, name)); !errors.Is(err, storage.ErrTxReentry) {
+				t.Errorf("%s with a derived context: got %v, want ErrTxReentry", name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: WithTx: %v", name, err)
+		}
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Errorf("%s: took %v, want an immediate error", name, elapsed)
+		}
+
+		// Fuera de la transacción el mismo contexto escribe sin problema
+		if err := write(ctx); err != nil && !errors.Is(err, storage.ErrDuplicateISBN) {
+			t.Errorf("%s after WithTx: %v", name, err)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"library-api/models"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Errores comunes
@@ -13,28 +18,188 @@ var (
 	ErrUserNotFound       = fmt.Errorf("user not found")
 	ErrUserAlreadyExists  = fmt.Errorf("user already exists")
 	ErrInvalidCredentials = fmt.Errorf("invalid credentials")
+	ErrAuthorNotFound     = fmt.Errorf("author not found")
+	ErrSubjectNotFound    = fmt.Errorf("subject not found")
+	ErrInvalidISBN        = fmt.Errorf("invalid isbn")
+	ErrDuplicateISBN      = fmt.Errorf("a book with this isbn already exists")
+	ErrJobNotFound        = fmt.Errorf("job not found")
+	ErrJobFinished        = fmt.Errorf("job already finished")
+	// ErrTxReentry - Escritura con el store de fuera dentro de WithTx, que
+	// debe usar el que recibe fn
+	ErrTxReentry = fmt.Errorf("write through the outer store inside WithTx")
 )
 
+// InvalidValueError - Valor rechazado por la validación. Envuelve el error
+// del dominio (ErrInvalidISBN...) y guarda el campo y el valor aparte, para
+// que la API los responda sin el texto del error
+type InvalidValueError struct {
+	Err    error
+	Field  string
+	Value  any
+	Reason string
+}
+
+// InvalidValue - Crea un InvalidValueError
+func InvalidValue(err error, field string, value any, reason string) error {
+	return &InvalidValueError{Err: err, Field: field, Value: value, Reason: reason}
+}
+
+func (e *InvalidValueError) Error() string {
+	return e.Err.Error() + ": " + e.Reason
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// Store - Almacenamiento de la API. Todos los métodos reciben el contexto de
+// la petición: si se cancela o vence su plazo la consulta se interrumpe y el
+// método devuelve ctx.Err() (envuelto).
 type Store interface {
 	// ========== MÉTODOS PARA USUARIOS ==========
-	CreateUser(user models.User) (*models.User, error)
-	GetUserByUsername(username string) (*models.User, error)
-	GetUserByID(id string) (*models.User, error)
-	UpdateUser(id string, user models.User) (*models.User, error)
-	DeleteUser(id string) error
+	CreateUser(ctx context.Context, user models.User) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	UpdateUser(ctx context.Context, id string, user models.User) (*models.User, error)
+	DeleteUser(ctx context.Context, id string) error
 
 	// ========== MÉTODOS PARA LIBROS ==========
-	CreateBook(book models.Book) (*models.Book, error)
-	GetBooks() ([]models.Book, error)
-	GetBookByID(id string) (*models.Book, error)
-	UpdateBook(id string, book models.Book) (*models.Book, error)
-	DeleteBook(id string) error
-	SearchBooks(title, author, genre string, available *bool) ([]models.Book, error)
+	CreateBook(ctx context.Context, book models.Book) (*models.Book, error)
+	GetBooks(ctx context.Context) ([]models.Book, error)
+	GetBookByID(ctx context.Context, id string) (*models.Book, error)
+	UpdateBook(ctx context.Context, id string, book models.Book) (*models.Book, error)
+	DeleteBook(ctx context.Context, id string) error
+	SearchBooks(ctx context.Context, title, author, genre string, available *bool) ([]models.Book, error)
+	ForEachBook(ctx context.Context, title, author, genre string, available *bool, fn func(models.Book) error) error
+	GetBookByISBN(ctx context.Context, isbn string) (*models.Book, error)
+	GetBooksByISBNs(ctx context.Context, isbns []string) (map[string]models.Book, error)
+
+	// ========== CAMBIOS INCREMENTALES (OAI-PMH) ==========
+	GetBookChanges(ctx context.Context, query models.BookChangeQuery) ([]models.BookChange, error)
+	GetBookChange(ctx context.Context, id string) (*models.BookChange, error)
+
+	// ========== MÉTODOS PARA AUTORES Y MATERIAS ==========
+	GetAuthors(ctx context.Context) ([]models.Author, error)
+	GetAuthorByID(ctx context.Context, id string) (*models.Author, error)
+	GetBooksByAuthor(ctx context.Context, authorID string) ([]models.Book, error)
+	GetSubjects(ctx context.Context) ([]models.Subject, error)
+	GetSubjectByID(ctx context.Context, id string) (*models.Subject, error)
+	GetBooksBySubject(ctx context.Context, subjectID string) ([]models.Book, error)
 
 	// ========== MÉTODOS PARA PRÉSTAMOS ==========
-	CreateLoan(loan models.Loan) (*models.Loan, error)
-	ReturnBook(loanID string) error
-	GetLoans() ([]models.Loan, error)
-	GetActiveLoans() ([]models.Loan, error)
-	GetLoanByID(id string) (*models.Loan, error)
+	CreateLoan(ctx context.Context, loan models.Loan) (*models.Loan, error)
+	ReturnBook(ctx context.Context, loanID string) error
+	GetLoans(ctx context.Context) ([]models.Loan, error)
+	GetActiveLoans(ctx context.Context) ([]models.Loan, error)
+	GetLoanByID(ctx context.Context, id string) (*models.Loan, error)
+
+	// ========== MÉTODOS PARA TRABAJOS EN SEGUNDO PLANO ==========
+	CreateJob(ctx context.Context, job models.Job) (*models.Job, error)
+	GetJobByID(ctx context.Context, id string) (*models.Job, error)
+	UpdateJob(ctx context.Context, job models.Job) (*models.Job, error)
+	// SaveJobItems y GetJobItems - Elementos resueltos de un trabajo, que se
+	// guardan una sola vez y no viajan con el resto del trabajo
+	SaveJobItems(ctx context.Context, id string, items models.JobItems) error
+	GetJobItems(ctx context.Context, id string) (models.JobItems, error)
+	CancelJob(ctx context.Context, id string) (*models.Job, error)
+	GetJobs(ctx context.Context, statuses ...string) ([]models.Job, error)
+
+	// ========== TRANSACCIONES ==========
+	// WithTx - Ejecuta varias operaciones como una unidad: fn recibe un Store
+	// que trabaja dentro de la transacción; si fn devuelve error no se aplica
+	// ninguna de sus escrituras. Las llamadas anidadas se deshacen por separado.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
+
+	// ========== ESTADO ==========
+	// Ping - Comprueba que el backend responde (sonda /readyz)
+	Ping(ctx context.Context) error
+	// CheckSchema - Comprueba que están todas las tablas y columnas que usan
+	// las consultas: una restauración o un cambio manual pueden dejar un
+	// esquema a medio migrar
+	CheckSchema(ctx context.Context) error
+	// GetStats - Cifras del catálogo y de los préstamos; los préstamos activos
+	// desde antes de overdueBefore cuentan como vencidos
+	GetStats(ctx context.Context, overdueBefore time.Time) (*Stats, error)
+
+	// ========== CIERRE ==========
+	// Close - Libera la conexión o guarda los datos pendientes al apagar la
+	// API; después el Store no se puede usar
+	Close() error
+}
+
+// ==============================================
+// VALORES COMPARTIDOS POR TODOS LOS BACKENDS
+// ==============================================
+
+// Stats - Cifras actuales del almacenamiento (gauges de /metrics)
+type Stats struct {
+	Books          int
+	AvailableBooks int
+	ActiveLoans    int
+	OverdueLoans   int
+}
+
+// AdminCredentials - Usuario admin que se crea en un almacenamiento vacío
+type AdminCredentials struct {
+	Username string
+	Password string
+}
+
+// DefaultAdmin - Credenciales del admin si la configuración no indica otras
+var DefaultAdmin = AdminCredentials{Username: "admin", Password: "admin123"}
+
+// adminCredentials - Las de DefaultAdmin o las de Config.Admin del último Open
+var adminCredentials = DefaultAdmin
+
+// defaultAdminUser - Usuario admin inicial, con la contraseña ya hasheada
+func defaultAdminUser() (models.User, error) {
+	hashed, err := hashPassword(adminCredentials.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return models.User{
+		ID:       "1",
+		Username: adminCredentials.Username,
+		Password: hashed,
+		Role:     "admin",
+	}, nil
+}
+
+// hashPassword - Hash bcrypt de una contraseña
+func hashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password: %w", err)
+	}
+	return string(hashed), nil
+}
+
+// isPasswordHash - Indica si la contraseña guardada ya es un hash bcrypt
+func isPasswordHash(password string) bool {
+	_, err := bcrypt.Cost([]byte(password))
+	return err == nil
+}
+
+// IsTimeout - Indica si la operación falló por vencer el plazo de la
+// consulta o de la petición
+func IsTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || isPostgresQueryCanceled(err)
+}
+
+// DeletedLoanBook - Libro que acompaña en los listados a un préstamo cuyo
+// libro fue eliminado (los préstamos se conservan en el historial)
+func DeletedLoanBook(loan models.Loan) models.Book {
+	book := models.Book{
+		ID:          "DELETED",
+		Title:       "Libro eliminado",
+		Author:      "N/A",
+		ISBN:        "N/A",
+		Genre:       "N/A",
+		Description: "Este libro ha sido eliminado",
+		CreatedAt:   loan.LoanDate,
+		UpdatedAt:   loan.LoanDate,
+	}
+	book.NormalizeContributors()
+	return book
 }
@@ -0,0 +1,223 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+func testBookCRUD(t *testing.T, store storage.Store) {
+	created := mustCreateBook(t, store, models.Book{
+		Title:  "Rayuela",
+		Author: "Julio Cortázar",
+		ISBN:   "978-84-376-0457-2",
+		Genre:  "Novela",
+	})
+	if created.ID == "" || !created.Available {
+		t.Fatalf("created book: got id %q available %v", created.ID, created.Available)
+	}
+	if created.ISBN != "9788437604572" {
+		t.Errorf("isbn not normalized: %q", created.ISBN)
+	}
+
+	got, err := store.GetBookByID(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("GetBookByID: %v", err)
+	}
+	if got.Title != "Rayuela" || got.Author != "Julio Cortázar" {
+		t.Errorf("GetBookByID: got %q by %q", got.Title, got.Author)
+	}
+
+	byISBN, err := store.GetBookByISBN(t.Context(), "8437604575")
+	if err != nil || byISBN.ID != created.ID {
+		t.Errorf("GetBookByISBN (ISBN-10): got %v, %v", byISBN, err)
+	}
+
+	got.Title = "Rayuela (edición crítica)"
+	got.Translations = models.BookTranslations{"en": {Title: "Hopscotch", Description: "A counter-novel"}}
+	updated, err := store.UpdateBook(t.Context(), created.ID, *got)
+	if err != nil {
+		t.Fatalf("UpdateBook: %v", err)
+	}
+	if updated.Title != "Rayuela (edición crítica)" || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("UpdateBook: got %q created %v, want created %v", updated.Title, updated.CreatedAt, created.CreatedAt)
+	}
+	if reread, err := store.GetBookByID(t.Context(), created.ID); err != nil || reread.Translations["en"].Title != "Hopscotch" {
+		t.Errorf("translations after UpdateBook: got %v, %v", reread, err)
+	}
+
+	if err := store.DeleteBook(t.Context(), created.ID); err != nil {
+		t.Fatalf("DeleteBook: %v", err)
+	}
+	if _, err := store.GetBookByID(t.Context(), created.ID); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("GetBookByID after delete: got %v, want ErrBookNotFound", err)
+	}
+	if err := store.DeleteBook(t.Context(), created.ID); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("DeleteBook twice: got %v, want ErrBookNotFound", err)
+	}
+	if _, err := store.UpdateBook(t.Context(), created.ID, *got); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("UpdateBook after delete: got %v, want ErrBookNotFound", err)
+	}
+}
+
+func testDuplicateISBN(t *testing.T, store storage.Store) {
+	first := mustCreateBook(t, store, models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"})
+
+	_, err := store.CreateBook(t.Context(), models.Book{Title: "Otro", Author: "Otro", ISBN: "84-206-3311-9"})
+	if !errors.Is(err, storage.ErrDuplicateISBN) {
+		t.Errorf("CreateBook duplicate: got %v, want ErrDuplicateISBN", err)
+	}
+
+	second := mustCreateBook(t, store, models.Book{Title: "El Aleph", Author: "Jorge Luis Borges", ISBN: "9788420633121"})
+	second.ISBN = first.ISBN
+	if _, err := store.UpdateBook(t.Context(), second.ID, *second); !errors.Is(err, storage.ErrDuplicateISBN) {
+		t.Errorf("UpdateBook duplicate: got %v, want ErrDuplicateISBN", err)
+	}
+
+	if _, err := store.CreateBook(t.Context(), models.Book{Title: "Malo", Author: "X", ISBN: "123"}); !errors.Is(err, storage.ErrInvalidISBN) {
+		t.Errorf("CreateBook invalid isbn: got %v, want ErrInvalidISBN", err)
+	}
+}
+
+func testSearchBooks(t *testing.T, store storage.Store) {
+	mustCreateBook(t, store, models.Book{Title: "Cien años de soledad", Author: "Gabriel García Márquez", ISBN: "9780307474728", Genre: "Novela"})
+	mustCreateBook(t, store, models.Book{Title: "Crónica de una muerte anunciada", Author: "Gabriel García Márquez", ISBN: "9781400034710", Genre: "Novela corta"})
+	poems := mustCreateBook(t, store, models.Book{Title: "Veinte poemas de amor", Author: "Pablo Neruda", ISBN: "9780143039969", Genre: "Poesía"})
+
+	assertTitles(t, "title", search(t, store, "SOLEDAD", "", "", nil), "Cien años de soledad")
+	assertTitles(t, "author", search(t, store, "", "garcía", "", nil), "Cien años de soledad", "Crónica de una muerte anunciada")
+	assertTitles(t, "genre", search(t, store, "", "", "novela", nil), "Cien años de soledad", "Crónica de una muerte anunciada")
+	assertTitles(t, "no match", search(t, store, "inexistente", "", "", nil))
+
+	if _, err := store.CreateLoan(t.Context(), models.Loan{BookID: poems.ID, User: "ana"}); err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+	available := true
+	assertTitles(t, "available", search(t, store, "", "", "", &available), "Cien años de soledad", "Crónica de una muerte anunciada")
+
+	var streamed []models.Book
+	err := store.ForEachBook(t.Context(), "", "", "", nil, func(book models.Book) error {
+		streamed = append(streamed, book)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachBook: %v", err)
+	}
+	assertTitles(t, "ForEachBook", streamed, "Cien años de soledad", "Crónica de una muerte anunciada", "Veinte poemas de amor")
+}
+
+func testContributors(t *testing.T, store storage.Store) {
+	mustCreateBook(t, store, models.Book{Title: "Good Omens", Authors: []string{"Terry Pratchett", "Neil Gaiman"}, ISBN: "9780060853983", Subjects: []string{"Fantasy"}})
+	mustCreateBook(t, store, models.Book{Title: "Mort", Authors: []string{"terry pratchett"}, ISBN: "9780062225719", Subjects: []string{"fantasy"}})
+
+	authors, err := store.GetAuthors(t.Context())
+	if err != nil {
+		t.Fatalf("GetAuthors: %v", err)
+	}
+	if len(authors) != 2 {
+		t.Fatalf("GetAuthors: got %d authors, want 2 (names are case-insensitive): %+v", len(authors), authors)
+	}
+
+	var pratchett models.Author
+	for _, author := range authors {
+		if author.BookCount == 2 {
+			pratchett = author
+		}
+	}
+	if pratchett.ID == "" {
+		t.Fatalf("GetAuthors: no author with 2 books: %+v", authors)
+	}
+
+	books, err := store.GetBooksByAuthor(t.Context(), pratchett.ID)
+	if err != nil || len(books) != 2 {
+		t.Errorf("GetBooksByAuthor: got %d books, %v", len(books), err)
+	}
+	if _, err := store.GetBooksByAuthor(t.Context(), "missing"); !errors.Is(err, storage.ErrAuthorNotFound) {
+		t.Errorf("GetBooksByAuthor missing: got %v, want ErrAuthorNotFound", err)
+	}
+
+	subjects, err := store.GetSubjects(t.Context())
+	if err != nil || len(subjects) != 1 || subjects[0].BookCount != 2 {
+		t.Fatalf("GetSubjects: got %+v, %v", subjects, err)
+	}
+	if books, err := store.GetBooksBySubject(t.Context(), subjects[0].ID); err != nil || len(books) != 2 {
+		t.Errorf("GetBooksBySubject: got %d books, %v", len(books), err)
+	}
+	if _, err := store.GetBooksBySubject(t.Context(), "missing"); !errors.Is(err, storage.ErrSubjectNotFound) {
+		t.Errorf("GetBooksBySubject missing: got %v, want ErrSubjectNotFound", err)
+	}
+
+	// Al eliminar un libro sus autores dejan de contarlo
+	if err := store.DeleteBook(t.Context(), books[0].ID); err != nil {
+		t.Fatalf("DeleteBook: %v", err)
+	}
+	author, err := store.GetAuthorByID(t.Context(), pratchett.ID)
+	if err != nil || author.BookCount != 1 {
+		t.Errorf("GetAuthorByID after delete: got %+v, %v", author, err)
+	}
+}
+
+func testISBNLookup(t *testing.T, store storage.Store) {
+	rayuela := mustCreateBook(t, store, models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"})
+	ficciones := mustCreateBook(t, store, models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"})
+
+	if _, err := store.GetBookByISBN(t.Context(), "not-an-isbn"); !errors.Is(err, storage.ErrInvalidISBN) {
+		t.Errorf("GetBookByISBN invalid: got %v, want ErrInvalidISBN", err)
+	}
+	if _, err := store.GetBookByISBN(t.Context(), "9780307474728"); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("GetBookByISBN missing: got %v, want ErrBookNotFound", err)
+	}
+
+	// Claves normalizadas a ISBN-13; los inválidos y ausentes se omiten
+	found, err := store.GetBooksByISBNs(t.Context(), []string{"84-376-0457-5", "978-84-206-3311-4", "9780307474728", "basura"})
+	if err != nil {
+		t.Fatalf("GetBooksByISBNs: %v", err)
+	}
+	if len(found) != 2 || found[rayuela.ISBN].ID != rayuela.ID || found[ficciones.ISBN].ID != ficciones.ID {
+		t.Errorf("GetBooksByISBNs: got %+v", found)
+	}
+}
+
+// testSearchLiteralText - La búsqueda es "contiene" sin distinguir
+// mayúsculas (también en letras acentuadas) y los comodines de LIKE se
+// buscan como texto literal
+func testSearchLiteralText(t *testing.T, store storage.Store) {
+	mustCreateBook(t, store, models.Book{Title: "100% Cocina", Author: "Ana", ISBN: "9780307474728"})
+	mustCreateBook(t, store, models.Book{Title: "El_Guion", Author: "Ana", ISBN: "9781400034710"})
+	mustCreateBook(t, store, models.Book{Title: "Ética para Amador", Author: "Fernando Savater", ISBN: "9788434403147"})
+	mustCreateBook(t, store, models.Book{Title: "El Gato", Author: "Luis", ISBN: "9780143039969"})
+
+	assertTitles(t, "percent", search(t, store, "%", "", "", nil), "100% Cocina")
+	assertTitles(t, "underscore", search(t, store, "l_g", "", "", nil), "El_Guion")
+	assertTitles(t, "accented upper case", search(t, store, "ÉTICA", "", "", nil), "Ética para Amador")
+	assertTitles(t, "accented lower case", search(t, store, "ética", "", "", nil), "Ética para Amador")
+
+	books, err := store.GetBooks(t.Context())
+	if err != nil {
+		t.Fatalf("GetBooks: %v", err)
+	}
+	assertTitles(t, "GetBooks", books, "100% Cocina", "El Gato", "El_Guion", "Ética para Amador")
+}
+
+// testForEachBookCanceled - Cancelar el contexto corta el recorrido
+func testForEachBookCanceled(t *testing.T, store storage.Store) {
+	mustCreateBook(t, store, models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"})
+	mustCreateBook(t, store, models.Book{Title: "El Aleph", Author: "Jorge Luis Borges", ISBN: "9788420633121"})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	visited := 0
+	err := store.ForEachBook(ctx, "", "", "", nil, func(models.Book) error {
+		visited++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ForEachBook after cancel: got %v, want context.Canceled", err)
+	}
+	if visited != 1 {
+		t.Errorf("ForEachBook visited %d books after cancel, want 1", visited)
+	}
+}
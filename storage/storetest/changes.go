@@ -0,0 +1,158 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+func testBookChanges(t *testing.T, store storage.Store) {
+	kept := mustCreateBook(t, store, models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"})
+	removed := mustCreateBook(t, store, models.Book{Title: "El Aleph", Author: "Jorge Luis Borges", ISBN: "9788420633121"})
+	if err := store.DeleteBook(t.Context(), removed.ID); err != nil {
+		t.Fatalf("DeleteBook: %v", err)
+	}
+
+	changes, err := store.GetBookChanges(t.Context(), models.BookChangeQuery{})
+	if err != nil {
+		t.Fatalf("GetBookChanges: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("GetBookChanges: got %d changes, want 2", len(changes))
+	}
+	for _, change := range changes {
+		switch change.ID {
+		case kept.ID:
+			if change.Deleted || change.Book == nil || change.Book.Title != "Ficciones" {
+				t.Errorf("GetBookChanges live book: got %+v", change)
+			}
+		case removed.ID:
+			if !change.Deleted || change.Book != nil {
+				t.Errorf("GetBookChanges deleted book: got %+v", change)
+			}
+		default:
+			t.Errorf("GetBookChanges: unexpected id %q", change.ID)
+		}
+	}
+
+	page, err := store.GetBookChanges(t.Context(), models.BookChangeQuery{Limit: 1})
+	if err != nil || len(page) != 1 {
+		t.Fatalf("GetBookChanges limit: got %d, %v", len(page), err)
+	}
+	next, err := store.GetBookChanges(t.Context(), models.BookChangeQuery{After: &page[0].Datestamp, AfterID: page[0].ID})
+	if err != nil || len(next) != 1 || next[0].ID == page[0].ID {
+		t.Errorf("GetBookChanges after: got %+v, %v", next, err)
+	}
+
+	change, err := store.GetBookChange(t.Context(), removed.ID)
+	if err != nil || !change.Deleted {
+		t.Errorf("GetBookChange deleted: got %+v, %v", change, err)
+	}
+	if _, err := store.GetBookChange(t.Context(), "missing"); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("GetBookChange missing: got %v, want ErrBookNotFound", err)
+	}
+}
+
+func testJobs(t *testing.T, store storage.Store) {
+	job, err := store.CreateJob(t.Context(), models.Job{Type: "import", Params: models.JobParams{Source: "openlibrary", Query: "borges"}})
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if job.ID == "" || job.Status != models.JobPending {
+		t.Errorf("CreateJob: got %+v", job)
+	}
+
+	items, err := store.GetJobItems(t.Context(), job.ID)
+	if err != nil || items != nil {
+		t.Errorf("GetJobItems before resolving items: got %+v, %v", items, err)
+	}
+
+	items = models.JobItems{{ISBN: "9780060853983"}, {Book: &models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633121"}}}
+	if err := store.SaveJobItems(t.Context(), job.ID, items); err != nil {
+		t.Fatalf("SaveJobItems: %v", err)
+	}
+
+	// UpdateJob guarda solo el progreso: no pisa los elementos guardados
+	job.Status = models.JobRunning
+	job.Processed = 3
+	job.Items = models.JobItems{{ISBN: "9780000000002"}}
+	if _, err := store.UpdateJob(t.Context(), *job); err != nil {
+		t.Fatalf("UpdateJob: %v", err)
+	}
+
+	got, err := store.GetJobByID(t.Context(), job.ID)
+	if err != nil || got.Status != models.JobRunning || got.Processed != 3 || got.Items != nil {
+		t.Errorf("GetJobByID: got %+v, %v", got, err)
+	}
+	items, err = store.GetJobItems(t.Context(), job.ID)
+	if err != nil || len(items) != 2 || items[0].ISBN != "9780060853983" || items[1].Book == nil || items[1].Book.Title != "Ficciones" {
+		t.Errorf("GetJobItems: got %+v, %v", items, err)
+	}
+	listed, err := store.GetJobs(t.Context())
+	if err != nil || len(listed) != 1 || listed[0].Items != nil {
+		t.Errorf("GetJobs: got %+v, %v", listed, err)
+	}
+
+	running, err := store.GetJobs(t.Context(), models.JobRunning)
+	if err != nil || len(running) != 1 {
+		t.Errorf("GetJobs running: got %d, %v", len(running), err)
+	}
+	pending, err := store.GetJobs(t.Context(), models.JobPending)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("GetJobs pending: got %d, %v", len(pending), err)
+	}
+
+	if _, err := store.GetJobByID(t.Context(), "missing"); !errors.Is(err, storage.ErrJobNotFound) {
+		t.Errorf("GetJobByID missing: got %v, want ErrJobNotFound", err)
+	}
+	if _, err := store.UpdateJob(t.Context(), models.Job{ID: "missing"}); !errors.Is(err, storage.ErrJobNotFound) {
+		t.Errorf("UpdateJob missing: got %v, want ErrJobNotFound", err)
+	}
+	if err := store.SaveJobItems(t.Context(), "missing", models.JobItems{}); !errors.Is(err, storage.ErrJobNotFound) {
+		t.Errorf("SaveJobItems missing: got %v, want ErrJobNotFound", err)
+	}
+	if _, err := store.GetJobItems(t.Context(), "missing"); !errors.Is(err, storage.ErrJobNotFound) {
+		t.Errorf("GetJobItems missing: got %v, want ErrJobNotFound", err)
+	}
+
+	testCancelJob(t, store, job)
+}
+
+// testCancelJob - running es un trabajo en ejecución
+func testCancelJob(t *testing.T, store storage.Store, running *models.Job) {
+	// En ejecución: solo se marca; lo detiene el worker
+	got, err := store.CancelJob(t.Context(), running.ID)
+	if err != nil || !got.CancelRequested || got.Status != models.JobRunning || got.Processed != 3 {
+		t.Errorf("CancelJob running: got %+v, %v", got, err)
+	}
+
+	// El worker guarda su progreso sin saber de la cancelación: no se pierde
+	running.Processed = 4
+	running.CancelRequested = false
+	if _, err := store.UpdateJob(t.Context(), *running); err != nil {
+		t.Fatalf("UpdateJob: %v", err)
+	}
+	if got, err := store.GetJobByID(t.Context(), running.ID); err != nil || !got.CancelRequested || got.Processed != 4 {
+		t.Errorf("UpdateJob after CancelJob: got %+v, %v", got, err)
+	}
+
+	// Pendiente: queda cancelado
+	pending, err := store.CreateJob(t.Context(), models.Job{Type: "import", Params: models.JobParams{Source: "openlibrary", Query: "cortázar"}})
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	got, err = store.CancelJob(t.Context(), pending.ID)
+	if err != nil || !got.CancelRequested || got.Status != models.JobCancelled || got.FinishedAt == nil {
+		t.Errorf("CancelJob pending: got %+v, %v", got, err)
+	}
+
+	// Terminado: no cambia
+	if got, err := store.CancelJob(t.Context(), pending.ID); !errors.Is(err, storage.ErrJobFinished) || got == nil || got.Status != models.JobCancelled {
+		t.Errorf("CancelJob finished: got %+v, %v, want ErrJobFinished", got, err)
+	}
+	if _, err := store.CancelJob(t.Context(), "missing"); !errors.Is(err, storage.ErrJobNotFound) {
+		t.Errorf("CancelJob missing: got %v, want ErrJobNotFound", err)
+	}
+}
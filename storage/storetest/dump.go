@@ -0,0 +1,125 @@
+package storetest
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+// testDumpLoad - Un volcado guardado como JSON y cargado después deja los
+// datos exactamente como estaban, con los mismos IDs
+func testDumpLoad(t *testing.T, store storage.Store) {
+	dumper, ok := store.(storage.Dumper)
+	if !ok {
+		t.Skip("store does not implement storage.Dumper")
+	}
+
+	SeedDumpData(t, store)
+
+	before, err := dumper.Dump(t.Context())
+	if err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	if len(before.Books) != 2 || len(before.Loans) != 2 || len(before.DeletedBooks) != 1 || len(before.Jobs) != 1 || len(before.JobItems) != 1 || len(before.Users) != 2 {
+		t.Fatalf("Dump counts: %v", before.Counts())
+	}
+
+	data, err := json.Marshal(before)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	var saved storage.Dump
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	// Cambios posteriores al volcado que Load debe descartar
+	mustCreateBook(t, store, models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"})
+	ana, err := store.GetUserByUsername(t.Context(), "ana")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+	if err := store.DeleteUser(t.Context(), ana.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	if err := dumper.Load(t.Context(), &saved); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	after, err := dumper.Dump(t.Context())
+	if err != nil {
+		t.Fatalf("Dump after Load: %v", err)
+	}
+	after.CreatedAt = before.CreatedAt
+	if got, want := mustJSON(t, after), mustJSON(t, before); got != want {
+		t.Errorf("Dump after Load differs:\n got %s\nwant %s", got, want)
+	}
+
+	if _, err := store.GetBookByISBN(t.Context(), "9788420633114"); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("GetBookByISBN after Load: got %v, want ErrBookNotFound", err)
+	}
+	if _, err := store.GetUserByUsername(t.Context(), "ana"); err != nil {
+		t.Errorf("GetUserByUsername after Load: %v", err)
+	}
+	authors, err := store.GetAuthors(t.Context())
+	if err != nil || len(authors) != 2 {
+		t.Errorf("GetAuthors after Load: got %+v, %v", authors, err)
+	}
+
+	saved.Version = storage.DumpVersion + 1
+	if err := dumper.Load(t.Context(), &saved); !errors.Is(err, storage.ErrUnsupportedDump) {
+		t.Errorf("Load future version: got %v, want ErrUnsupportedDump", err)
+	}
+}
+
+// SeedDumpData - Datos de todos los tipos para probar volcados: libros con
+// autores y materias, un usuario, un préstamo activo y uno devuelto, un
+// libro eliminado y un trabajo con sus elementos
+func SeedDumpData(t *testing.T, store storage.Store) {
+	t.Helper()
+
+	omens := mustCreateBook(t, store, models.Book{Title: "Good Omens", Authors: []string{"Terry Pratchett", "Neil Gaiman"}, ISBN: "9780060853983", Subjects: []string{"Fantasy"}})
+	mort := mustCreateBook(t, store, models.Book{Title: "Mort", Authors: []string{"Terry Pratchett"}, ISBN: "9780062225719"})
+	gone := mustCreateBook(t, store, models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"})
+
+	if _, err := store.CreateUser(t.Context(), models.User{Username: "ana", Password: "hash", Role: "user"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	returned, err := store.CreateLoan(t.Context(), models.Loan{BookID: mort.ID, User: "ana"})
+	if err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+	if err := store.ReturnBook(t.Context(), returned.ID); err != nil {
+		t.Fatalf("ReturnBook: %v", err)
+	}
+	if _, err := store.CreateLoan(t.Context(), models.Loan{BookID: omens.ID, User: "ana"}); err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+
+	if err := store.DeleteBook(t.Context(), gone.ID); err != nil {
+		t.Fatalf("DeleteBook: %v", err)
+	}
+
+	job := models.Job{Type: models.JobTypeBulkImport, Params: models.JobParams{Source: "openlibrary", ISBNs: []string{"9780060853983"}}, CreatedBy: "admin"}
+	created, err := store.CreateJob(t.Context(), job)
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if err := store.SaveJobItems(t.Context(), created.ID, models.JobItems{{ISBN: "9780060853983"}}); err != nil {
+		t.Fatalf("SaveJobItems: %v", err)
+	}
+}
+
+func mustJSON(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	return string(data)
+}
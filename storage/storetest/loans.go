@@ -0,0 +1,288 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+func testLoanLifecycle(t *testing.T, store storage.Store) {
+	book := mustCreateBook(t, store, models.Book{Title: "Pedro Páramo", Author: "Juan Rulfo", ISBN: "9788437604183"})
+
+	loan, err := store.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: "ana"})
+	if err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+	if loan.ID == "" || loan.Returned {
+		t.Errorf("CreateLoan: got %+v", loan)
+	}
+	assertAvailable(t, store, book.ID, false)
+
+	if _, err := store.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: "luis"}); !errors.Is(err, storage.ErrBookNotAvailable) {
+		t.Errorf("CreateLoan on lent book: got %v, want ErrBookNotAvailable", err)
+	}
+	if _, err := store.CreateLoan(t.Context(), models.Loan{BookID: "missing", User: "luis"}); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("CreateLoan on missing book: got %v, want ErrBookNotFound", err)
+	}
+
+	active, err := store.GetActiveLoans(t.Context())
+	if err != nil || len(active) != 1 {
+		t.Errorf("GetActiveLoans: got %d, %v", len(active), err)
+	}
+
+	if err := store.ReturnBook(t.Context(), loan.ID); err != nil {
+		t.Fatalf("ReturnBook: %v", err)
+	}
+	assertAvailable(t, store, book.ID, true)
+
+	returned, err := store.GetLoanByID(t.Context(), loan.ID)
+	if err != nil {
+		t.Fatalf("GetLoanByID: %v", err)
+	}
+	if !returned.Returned || returned.ReturnDate == nil || returned.User != "ana" {
+		t.Errorf("GetLoanByID after return: got %+v", returned)
+	}
+
+	if err := store.ReturnBook(t.Context(), loan.ID); err != nil {
+		t.Errorf("ReturnBook twice: got %v, want nil", err)
+	}
+	if err := store.ReturnBook(t.Context(), "missing"); !errors.Is(err, storage.ErrLoanNotFound) {
+		t.Errorf("ReturnBook missing: got %v, want ErrLoanNotFound", err)
+	}
+
+	active, err = store.GetActiveLoans(t.Context())
+	if err != nil || len(active) != 0 {
+		t.Errorf("GetActiveLoans after return: got %d, %v", len(active), err)
+	}
+}
+
+// loansWithBooksStore - Extensión opcional de Store que usa el listado de
+// préstamos (ver BookHandler.GetLoans); los casos la comprueban si existe
+type loansWithBooksStore interface {
+	GetLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error)
+	GetActiveLoansWithBooks(ctx context.Context) ([]models.LoanWithBook, error)
+}
+
+// testLoansSurviveBookDeletion - Eliminar un libro no elimina sus préstamos:
+// siguen en el historial, ordenados del más reciente al más antiguo, con el
+// libro sustituido por storage.DeletedLoanBook
+func testLoansSurviveBookDeletion(t *testing.T, s storage.Store) {
+	kept := mustCreateBook(t, s, models.Book{Title: "El túnel", Author: "Ernesto Sabato", ISBN: "9788432248221"})
+	removed := mustCreateBook(t, s, models.Book{Title: "Sobre héroes y tumbas", Author: "Ernesto Sabato", ISBN: "9788432217722"})
+
+	removedLoan, err := s.CreateLoan(t.Context(), models.Loan{BookID: removed.ID, User: "luis"})
+	if err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+	if err := s.ReturnBook(t.Context(), removedLoan.ID); err != nil {
+		t.Fatalf("ReturnBook: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	keptLoan, err := s.CreateLoan(t.Context(), models.Loan{BookID: kept.ID, User: "ana"})
+	if err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+
+	if err := s.DeleteBook(t.Context(), removed.ID); err != nil {
+		t.Fatalf("DeleteBook: %v", err)
+	}
+
+	loans, err := s.GetLoans(t.Context())
+	if err != nil {
+		t.Fatalf("GetLoans: %v", err)
+	}
+	if len(loans) != 2 || loans[0].ID != keptLoan.ID || loans[1].ID != removedLoan.ID {
+		t.Fatalf("GetLoans: got %+v, want newest first", loans)
+	}
+	if orphan, err := s.GetLoanByID(t.Context(), removedLoan.ID); err != nil || orphan.BookID != removed.ID {
+		t.Errorf("GetLoanByID orphan: got %+v, %v", orphan, err)
+	}
+
+	store, ok := s.(loansWithBooksStore)
+	if !ok {
+		return
+	}
+
+	all, err := store.GetLoansWithBooks(t.Context())
+	if err != nil {
+		t.Fatalf("GetLoansWithBooks: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != keptLoan.ID || all[1].ID != removedLoan.ID {
+		t.Fatalf("GetLoansWithBooks: got %+v, want newest first", all)
+	}
+	if all[0].Book.ID != kept.ID || all[0].Book.Title != "El túnel" || all[0].User != "ana" {
+		t.Errorf("GetLoansWithBooks live book: got %+v", all[0])
+	}
+	want := storage.DeletedLoanBook(all[1].Loan)
+	got := all[1].Book
+	if got.ID != want.ID || got.Title != want.Title || got.ISBN != want.ISBN || got.Available || !got.CreatedAt.Equal(all[1].LoanDate) {
+		t.Errorf("GetLoansWithBooks deleted book: got %+v, want %+v", got, want)
+	}
+
+	active, err := store.GetActiveLoansWithBooks(t.Context())
+	if err != nil {
+		t.Fatalf("GetActiveLoansWithBooks: %v", err)
+	}
+	if len(active) != 1 || active[0].ID != keptLoan.ID || active[0].Book.ISBN != kept.ISBN {
+		t.Errorf("GetActiveLoansWithBooks: got %+v", active)
+	}
+}
+
+// testConcurrentBorrow - De varios préstamos simultáneos del mismo libro
+// solo uno puede prosperar; el resto falla con ErrBookNotAvailable
+func testConcurrentBorrow(t *testing.T, store storage.Store) {
+	const borrowers = 16
+	book := mustCreateBook(t, store, models.Book{Title: "Pedro Páramo", Author: "Juan Rulfo", ISBN: "9788437604183"})
+
+	errs := make([]error, borrowers)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < borrowers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = store.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: fmt.Sprintf("user-%d", i)})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, storage.ErrBookNotAvailable):
+			t.Errorf("CreateLoan: got %v, want nil or ErrBookNotAvailable", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d concurrent borrows succeeded, want exactly 1", succeeded)
+	}
+
+	active, err := store.GetActiveLoans(t.Context())
+	if err != nil || len(active) != 1 {
+		t.Errorf("GetActiveLoans: got %d, %v", len(active), err)
+	}
+	assertAvailable(t, store, book.ID, false)
+}
+
+// testConcurrentReturn - Devolver el mismo préstamo a la vez es idempotente
+// y deja el libro disponible para un único préstamo nuevo
+func testConcurrentReturn(t *testing.T, store storage.Store) {
+	const returners = 8
+	book := mustCreateBook(t, store, models.Book{Title: "El llano en llamas", Author: "Juan Rulfo", ISBN: "9788437604190"})
+	loan, err := store.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: "ana"})
+	if err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+
+	errs := make([]error, returners)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < returners; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = store.ReturnBook(t.Context(), loan.ID)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("ReturnBook: got %v, want nil", err)
+		}
+	}
+	assertAvailable(t, store, book.ID, true)
+
+	if _, err := store.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: "luis"}); err != nil {
+		t.Errorf("CreateLoan after return: %v", err)
+	}
+	if _, err := store.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: "eva"}); !errors.Is(err, storage.ErrBookNotAvailable) {
+		t.Errorf("second CreateLoan after return: got %v, want ErrBookNotAvailable", err)
+	}
+}
+
+// testCirculationStress - Muchos préstamos y devoluciones simultáneos sobre
+// pocos libros: un libro nunca puede estar prestado dos veces a la vez
+func testCirculationStress(t *testing.T, store storage.Store) {
+	const (
+		bookCount = 4
+		workers   = 16
+	)
+	iterations := 100
+	if testing.Short() {
+		iterations = 20
+	}
+
+	isbns := []string{"9788437604572", "9788420633114", "9788420633121", "9788437604183"}
+	books := make([]*models.Book, bookCount)
+	for i := range books {
+		books[i] = mustCreateBook(t, store, models.Book{Title: fmt.Sprintf("Libro %d", i), Author: "Autor", ISBN: isbns[i]})
+	}
+
+	// holders - Préstamos abiertos por libro según los propios workers
+	holders := make([]int32, bookCount)
+	var borrowed int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				b := (w + i) % bookCount
+				loan, err := store.CreateLoan(t.Context(), models.Loan{BookID: books[b].ID, User: fmt.Sprintf("user-%d", w)})
+				if errors.Is(err, storage.ErrBookNotAvailable) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("CreateLoan: %v", err)
+					return
+				}
+				atomic.AddInt64(&borrowed, 1)
+				if n := atomic.AddInt32(&holders[b], 1); n != 1 {
+					t.Errorf("book %d lent %d times at once", b, n)
+				}
+
+				// Se libera antes de devolver: en cuanto ReturnBook confirma,
+				// otro worker puede tomar el libro
+				atomic.AddInt32(&holders[b], -1)
+				if err := store.ReturnBook(t.Context(), loan.ID); err != nil {
+					t.Errorf("ReturnBook: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	loans, err := store.GetLoans(t.Context())
+	if err != nil {
+		t.Fatalf("GetLoans: %v", err)
+	}
+	if int64(len(loans)) != borrowed {
+		t.Errorf("GetLoans: got %d loans, want %d successful borrows", len(loans), borrowed)
+	}
+	if borrowed == 0 {
+		t.Error("no borrow succeeded")
+	}
+	for _, loan := range loans {
+		if !loan.Returned {
+			t.Errorf("loan %s still open", loan.ID)
+		}
+	}
+	for _, book := range books {
+		assertAvailable(t, store, book.ID, true)
+	}
+}
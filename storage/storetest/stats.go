@@ -0,0 +1,53 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+func testHealthAndStats(t *testing.T, store storage.Store) {
+	if err := store.Ping(t.Context()); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+	if err := store.CheckSchema(t.Context()); err != nil {
+		t.Errorf("CheckSchema: %v", err)
+	}
+
+	stats, err := store.GetStats(t.Context(), time.Now())
+	if err != nil || *stats != (storage.Stats{}) {
+		t.Fatalf("GetStats on an empty store: got %+v, %v", stats, err)
+	}
+
+	books := []*models.Book{
+		mustCreateBook(t, store, models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"}),
+		mustCreateBook(t, store, models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"}),
+		mustCreateBook(t, store, models.Book{Title: "Aura", Author: "Carlos Fuentes", ISBN: "9789684110113"}),
+	}
+	var loanIDs []string
+	for _, book := range books[:2] {
+		loan, err := store.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: "ana"})
+		if err != nil {
+			t.Fatalf("CreateLoan: %v", err)
+		}
+		loanIDs = append(loanIDs, loan.ID)
+	}
+	if err := store.ReturnBook(t.Context(), loanIDs[0]); err != nil {
+		t.Fatalf("ReturnBook: %v", err)
+	}
+
+	// Con el límite en el pasado ningún préstamo está vencido; en el futuro,
+	// todos los activos
+	stats, err = store.GetStats(t.Context(), time.Now().Add(-time.Hour))
+	want := storage.Stats{Books: 3, AvailableBooks: 2, ActiveLoans: 1}
+	if err != nil || *stats != want {
+		t.Errorf("GetStats: got %+v, %v; want %+v", stats, err, want)
+	}
+	stats, err = store.GetStats(t.Context(), time.Now().Add(time.Hour))
+	want.OverdueLoans = 1
+	if err != nil || *stats != want {
+		t.Errorf("GetStats with overdue loans: got %+v, %v; want %+v", stats, err, want)
+	}
+}
@@ -0,0 +1,109 @@
+// Package storetest - Casos de conformidad que toda implementación de
+// storage.Store debe cumplir. Cada backend los ejecuta desde sus pruebas con
+// Run, pasando una función que crea un Store vacío.
+package storetest
+
+import (
+	"testing"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+// Factory - Crea un Store vacío (solo con el usuario admin) para un caso
+type Factory func(t *testing.T) storage.Store
+
+// Case - Caso de conformidad
+type Case struct {
+	Name string
+	Fn   func(t *testing.T, store storage.Store)
+}
+
+// Cases - Todos los casos, agrupados por área
+var Cases = []Case{
+	{"DefaultAdmin", testDefaultAdmin},
+	{"UserCRUD", testUserCRUD},
+	{"BookCRUD", testBookCRUD},
+	{"DuplicateISBN", testDuplicateISBN},
+	{"ISBNLookup", testISBNLookup},
+	{"SearchBooks", testSearchBooks},
+	{"SearchLiteralText", testSearchLiteralText},
+	{"ForEachBookCanceled", testForEachBookCanceled},
+	{"Contributors", testContributors},
+	{"LoanLifecycle", testLoanLifecycle},
+	{"LoansSurviveBookDeletion", testLoansSurviveBookDeletion},
+	{"ConcurrentBorrow", testConcurrentBorrow},
+	{"ConcurrentReturn", testConcurrentReturn},
+	{"CirculationStress", testCirculationStress},
+	{"TxCommit", testTxCommit},
+	{"TxRollback", testTxRollback},
+	{"TxRollbackUpdates", testTxRollbackUpdates},
+	{"TxNested", testTxNested},
+	{"TxCanceledContext", testTxCanceledContext},
+	{"TxReadModifyWrite", testTxReadModifyWrite},
+	{"BookChanges", testBookChanges},
+	{"Jobs", testJobs},
+	{"DumpLoad", testDumpLoad},
+	{"HealthAndStats", testHealthAndStats},
+}
+
+// Run - Ejecuta todos los casos de conformidad contra el backend; cada caso
+// recibe un Store nuevo. skip (nombre del caso → motivo) permite omitir
+// casos que el backend todavía no cumple.
+func Run(t *testing.T, newStore Factory, skip map[string]string) {
+	for _, tc := range Cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if reason, ok := skip[tc.Name]; ok {
+				t.Skip(reason)
+			}
+			tc.Fn(t, newStore(t))
+		})
+	}
+}
+
+// ==============================================
+// FUNCIONES AUXILIARES
+// ==============================================
+
+func mustCreateBook(t *testing.T, store storage.Store, book models.Book) *models.Book {
+	t.Helper()
+	created, err := store.CreateBook(t.Context(), book)
+	if err != nil {
+		t.Fatalf("CreateBook %q: %v", book.Title, err)
+	}
+	return created
+}
+
+func search(t *testing.T, store storage.Store, title, author, genre string, available *bool) []models.Book {
+	t.Helper()
+	books, err := store.SearchBooks(t.Context(), title, author, genre, available)
+	if err != nil {
+		t.Fatalf("SearchBooks: %v", err)
+	}
+	return books
+}
+
+// assertTitles - Compara los títulos devueltos, en orden alfabético
+func assertTitles(t *testing.T, name string, books []models.Book, want ...string) {
+	t.Helper()
+	if len(books) != len(want) {
+		t.Errorf("%s: got %d books, want %d (%v)", name, len(books), len(want), want)
+		return
+	}
+	for i, book := range books {
+		if book.Title != want[i] {
+			t.Errorf("%s: book %d is %q, want %q", name, i, book.Title, want[i])
+		}
+	}
+}
+
+func assertAvailable(t *testing.T, store storage.Store, bookID string, want bool) {
+	t.Helper()
+	book, err := store.GetBookByID(t.Context(), bookID)
+	if err != nil {
+		t.Fatalf("GetBookByID: %v", err)
+	}
+	if book.Available != want {
+		t.Errorf("book available = %v, want %v", book.Available, want)
+	}
+}
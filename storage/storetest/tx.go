@@ -0,0 +1,225 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+var errAbort = errors.New("abort transaction")
+
+// testTxCommit - Lo escrito dentro de WithTx se ve dentro de la transacción
+// y queda guardado al terminar sin error
+func testTxCommit(t *testing.T, store storage.Store) {
+	var book *models.Book
+	err := store.WithTx(t.Context(), func(tx storage.Store) error {
+		var err error
+		book, err = tx.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"})
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.GetBookByID(t.Context(), book.ID); err != nil {
+			return err
+		}
+		_, err = tx.CreateLoan(t.Context(), models.Loan{BookID: book.ID, User: "ana"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	assertAvailable(t, store, book.ID, false)
+	active, err := store.GetActiveLoans(t.Context())
+	if err != nil || len(active) != 1 {
+		t.Errorf("GetActiveLoans: got %d, %v", len(active), err)
+	}
+}
+
+// testTxRollback - Si fn devuelve error no queda ninguna de sus escrituras
+func testTxRollback(t *testing.T, store storage.Store) {
+	kept := mustCreateBook(t, store, models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"})
+
+	err := store.WithTx(t.Context(), func(tx storage.Store) error {
+		if _, err := tx.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"}); err != nil {
+			return err
+		}
+		if _, err := tx.CreateUser(t.Context(), models.User{Username: "ana", Password: "hash", Role: "user"}); err != nil {
+			return err
+		}
+		if _, err := tx.CreateLoan(t.Context(), models.Loan{BookID: kept.ID, User: "ana"}); err != nil {
+			return err
+		}
+		if err := tx.DeleteBook(t.Context(), kept.ID); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithTx: got %v, want the error returned by fn", err)
+	}
+
+	if _, err := store.GetBookByISBN(t.Context(), "9788437604572"); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("GetBookByISBN after rollback: got %v, want ErrBookNotFound", err)
+	}
+	if _, err := store.GetUserByUsername(t.Context(), "ana"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("GetUserByUsername after rollback: got %v, want ErrUserNotFound", err)
+	}
+	assertAvailable(t, store, kept.ID, true)
+	if loans, err := store.GetLoans(t.Context()); err != nil || len(loans) != 0 {
+		t.Errorf("GetLoans after rollback: got %d, %v", len(loans), err)
+	}
+}
+
+// testTxRollbackUpdates - Al deshacer una transacción los registros
+// modificados vuelven a su valor anterior (con su ISBN), también lo que
+// confirmó una transacción anidada y lo escrito antes de un pánico
+func testTxRollbackUpdates(t *testing.T, store storage.Store) {
+	book := mustCreateBook(t, store, models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"})
+
+	err := store.WithTx(t.Context(), func(tx storage.Store) error {
+		changed := *book
+		changed.Title = "El Aleph"
+		changed.ISBN = "9788420633121"
+		if _, err := tx.UpdateBook(t.Context(), book.ID, changed); err != nil {
+			return err
+		}
+
+		inner := tx.WithTx(t.Context(), func(tx storage.Store) error {
+			_, err := tx.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"})
+			return err
+		})
+		if inner != nil {
+			return inner
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithTx: got %v, want the error returned by fn", err)
+	}
+
+	assertRolledBack := func(when string) {
+		t.Helper()
+		got, err := store.GetBookByISBN(t.Context(), "9788420633114")
+		if err != nil || got.ID != book.ID || got.Title != "Ficciones" {
+			t.Errorf("GetBookByISBN original %s: got %+v, %v", when, got, err)
+		}
+		for _, code := range []string{"9788420633121", "9788437604572"} {
+			if _, err := store.GetBookByISBN(t.Context(), code); !errors.Is(err, storage.ErrBookNotFound) {
+				t.Errorf("GetBookByISBN %s %s: got %v, want ErrBookNotFound", code, when, err)
+			}
+		}
+	}
+	assertRolledBack("after rollback")
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("WithTx: the panic in fn was not propagated")
+			}
+		}()
+		store.WithTx(t.Context(), func(tx storage.Store) error {
+			if err := tx.DeleteBook(t.Context(), book.ID); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"}); err != nil {
+				return err
+			}
+			panic("abort transaction")
+		})
+	}()
+	assertRolledBack("after panic")
+}
+
+// testTxNested - Una transacción anidada que falla solo deshace lo suyo, y
+// una operación que falla dentro de la transacción no la invalida
+func testTxNested(t *testing.T, store storage.Store) {
+	err := store.WithTx(t.Context(), func(tx storage.Store) error {
+		if _, err := tx.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"}); err != nil {
+			return err
+		}
+
+		inner := tx.WithTx(t.Context(), func(tx storage.Store) error {
+			if _, err := tx.CreateBook(t.Context(), models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", ISBN: "9788420633114"}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(inner, errAbort) {
+			t.Errorf("nested WithTx: got %v, want the error returned by fn", inner)
+		}
+
+		if _, err := tx.CreateBook(t.Context(), models.Book{Title: "Rayuela (bis)", Author: "Julio Cortázar", ISBN: "9788437604572"}); !errors.Is(err, storage.ErrDuplicateISBN) {
+			t.Errorf("CreateBook duplicate in tx: got %v, want ErrDuplicateISBN", err)
+		}
+
+		_, err := tx.CreateBook(t.Context(), models.Book{Title: "El Aleph", Author: "Jorge Luis Borges", ISBN: "9788420633121"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	books, err := store.GetBooks(t.Context())
+	if err != nil {
+		t.Fatalf("GetBooks: %v", err)
+	}
+	assertTitles(t, "GetBooks", books, "El Aleph", "Rayuela")
+}
+
+// testTxCanceledContext - Con el contexto cancelado no se aplica nada
+func testTxCanceledContext(t *testing.T, store storage.Store) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	err := store.WithTx(ctx, func(tx storage.Store) error {
+		_, err := tx.CreateBook(t.Context(), models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"})
+		return err
+	})
+	if err == nil {
+		t.Fatal("WithTx with canceled context: got nil error")
+	}
+	if _, err := store.GetBookByISBN(t.Context(), "9788437604572"); !errors.Is(err, storage.ErrBookNotFound) {
+		t.Errorf("GetBookByISBN: got %v, want ErrBookNotFound", err)
+	}
+}
+
+// testTxReadModifyWrite - Leer y actualizar dentro de WithTx no pierde
+// actualizaciones concurrentes del mismo libro
+func testTxReadModifyWrite(t *testing.T, store storage.Store) {
+	const writers = 8
+	book := mustCreateBook(t, store, models.Book{Title: "Rayuela", Author: "Julio Cortázar", ISBN: "9788437604572"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.WithTx(t.Context(), func(tx storage.Store) error {
+				current, err := tx.GetBookByID(t.Context(), book.ID)
+				if err != nil {
+					return err
+				}
+				current.PageCount++
+				_, err = tx.UpdateBook(t.Context(), book.ID, *current)
+				return err
+			})
+			if err != nil {
+				t.Errorf("WithTx: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	updated, err := store.GetBookByID(t.Context(), book.ID)
+	if err != nil {
+		t.Fatalf("GetBookByID: %v", err)
+	}
+	if updated.PageCount != writers {
+		t.Errorf("page count = %d, want %d", updated.PageCount, writers)
+	}
+}
@@ -0,0 +1,79 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"library-api/models"
+	"library-api/storage"
+)
+
+// testDefaultAdmin - Todo Store nuevo trae el usuario admin con la
+// contraseña por defecto guardada como hash bcrypt
+func testDefaultAdmin(t *testing.T, store storage.Store) {
+	admin, err := store.GetUserByUsername(t.Context(), "admin")
+	if err != nil {
+		t.Fatalf("GetUserByUsername(admin): %v", err)
+	}
+	if admin.Role != "admin" {
+		t.Errorf("admin role = %q, want admin", admin.Role)
+	}
+	if admin.Password == "admin123" {
+		t.Fatal("admin password stored in plain text")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("admin123")); err != nil {
+		t.Errorf("admin password is not the bcrypt hash of the default: %v", err)
+	}
+
+	byID, err := store.GetUserByID(t.Context(), admin.ID)
+	if err != nil || byID.Username != "admin" {
+		t.Errorf("GetUserByID(%q): got %+v, %v", admin.ID, byID, err)
+	}
+}
+
+func testUserCRUD(t *testing.T, store storage.Store) {
+	created, err := store.CreateUser(t.Context(), models.User{Username: "ana", Password: "hash", Role: "user"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if created.ID == "" {
+		t.Error("CreateUser: empty id")
+	}
+
+	if _, err := store.CreateUser(t.Context(), models.User{Username: "ana", Password: "other", Role: "user"}); !errors.Is(err, storage.ErrUserAlreadyExists) {
+		t.Errorf("CreateUser duplicate: got %v, want ErrUserAlreadyExists", err)
+	}
+
+	created.Role = "admin"
+	updated, err := store.UpdateUser(t.Context(), created.ID, *created)
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if updated.Role != "admin" || updated.Username != "ana" {
+		t.Errorf("UpdateUser: got %+v", updated)
+	}
+
+	// Renombrar a un usuario existente no puede duplicar el nombre
+	created.Username = "admin"
+	if _, err := store.UpdateUser(t.Context(), created.ID, *created); !errors.Is(err, storage.ErrUserAlreadyExists) {
+		t.Errorf("UpdateUser to taken username: got %v, want ErrUserAlreadyExists", err)
+	}
+	if _, err := store.UpdateUser(t.Context(), "missing", *created); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("UpdateUser missing: got %v, want ErrUserNotFound", err)
+	}
+
+	if err := store.DeleteUser(t.Context(), created.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := store.GetUserByUsername(t.Context(), "ana"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("GetUserByUsername after delete: got %v, want ErrUserNotFound", err)
+	}
+	if _, err := store.GetUserByID(t.Context(), created.ID); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("GetUserByID after delete: got %v, want ErrUserNotFound", err)
+	}
+	if err := store.DeleteUser(t.Context(), created.ID); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("DeleteUser twice: got %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"library-api/isbn"
+	"library-api/models"
+)
+
+// UpsertBook - Crea el libro o completa el existente con el mismo ISBN.
+// Si existing es nil se busca por ISBN; los datos locales nunca se
+// sobrescriben, solo se completan los campos vacíos.
+func UpsertBook(ctx context.Context, store Store, incoming models.Book, existing *models.Book) models.ImportItemResult {
+	result := models.ImportItemResult{
+		ISBN:  incoming.ISBN,
+		Title: incoming.Title,
+	}
+
+	normalized, err := isbn.Normalize(incoming.ISBN)
+	if err != nil {
+		result.Status = models.ImportFailed
+		result.Error = ErrInvalidISBN.Error() + ": " + err.Error()
+		return result
+	}
+	result.ISBN = normalized
+	incoming.ISBN = normalized
+
+	if existing == nil {
+		existing, err = store.GetBookByISBN(ctx, normalized)
+		if err != nil && !errors.Is(err, ErrBookNotFound) {
+			result.Status = models.ImportFailed
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	if existing == nil {
+		created, err := store.CreateBook(ctx, incoming)
+		if err != nil {
+			result.Status = models.ImportFailed
+			result.Error = err.Error()
+			return result
+		}
+		result.Status = models.ImportCreated
+		result.Book = created
+		return result
+	}
+
+	merged := *existing
+	if !merged.FillMissing(incoming) {
+		result.Status = models.ImportSkipped
+		result.Book = existing
+		return result
+	}
+
+	updated, err := store.UpdateBook(ctx, existing.ID, merged)
+	if err != nil {
+		result.Status = models.ImportFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = models.ImportUpdated
+	result.Book = updated
+	return result
+}
+
+// UpsertBooks - Importa una lista de libros consultando los existentes en
+// una sola búsqueda por ISBN
+func UpsertBooks(ctx context.Context, store Store, books []models.Book) ([]models.ImportItemResult, error) {
+	isbns := make([]string, 0, len(books))
+	for _, book := range books {
+		isbns = append(isbns, book.ISBN)
+	}
+
+	existing, err := store.GetBooksByISBNs(ctx, isbns)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.ImportItemResult, 0, len(books))
+	for _, book := range books {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var current *models.Book
+		if normalized, err := isbn.Normalize(book.ISBN); err == nil {
+			if found, ok := existing[normalized]; ok {
+				current = &found
+			}
+		}
+
+		result := UpsertBook(ctx, store, book, current)
+
+		// Libros repetidos dentro del mismo lote
+		if result.Book != nil && (result.Status == models.ImportCreated || result.Status == models.ImportUpdated) {
+			existing[result.ISBN] = *result.Book
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// PreviewUpsertBooks - Igual que UpsertBooks pero sin escribir: indica qué
+// pasaría con cada libro (simulación de una importación)
+func PreviewUpsertBooks(ctx context.Context, store Store, books []models.Book) ([]models.ImportItemResult, error) {
+	isbns := make([]string, 0, len(books))
+	for _, book := range books {
+		isbns = append(isbns, book.ISBN)
+	}
+
+	existing, err := store.GetBooksByISBNs(ctx, isbns)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.ImportItemResult, 0, len(books))
+	for _, book := range books {
+		result := models.ImportItemResult{ISBN: book.ISBN, Title: book.Title}
+
+		normalized, err := isbn.Normalize(book.ISBN)
+		if err != nil {
+			result.Status = models.ImportFailed
+			result.Error = ErrInvalidISBN.Error() + ": " + err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.ISBN = normalized
+		book.ISBN = normalized
+
+		current, found := existing[normalized]
+		switch {
+		case !found:
+			result.Status = models.ImportCreated
+			existing[normalized] = book
+		case current.FillMissing(book):
+			result.Status = models.ImportUpdated
+			existing[normalized] = current
+		default:
+			result.Status = models.ImportSkipped
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+
+	"library-api/i18n"
+	"library-api/models"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// messages - Plantillas en inglés por regla; el catálogo de i18n las traduce.
+// Las reglas min/max llevan el sufijo del tipo de campo (.string, .number,
+// .list). %[1]s es el campo, %[2]s el parámetro de la regla, %[3]s el valor
+// recibido y, en pubyear, %[4]d y %[5]d los años límite
+var messages = map[string]string{
+	"required":           "%[1]s is required",
+	"required_without":   "%[1]s is required when %[2]s is missing",
+	"min.string":         "%[1]s must be at least %[2]s characters long",
+	"min.number":         "%[1]s must be at least %[2]s",
+	"min.list":           "%[1]s must have at least %[2]s items",
+	"max.string":         "%[1]s must be at most %[2]s characters long",
+	"max.number":         "%[1]s must be at most %[2]s",
+	"max.list":           "%[1]s must have at most %[2]s items",
+	"oneof":              "%[1]s must be one of: %[2]s",
+	"url":                "%[1]s must be a valid URL",
+	"bcp47_language_tag": "%[1]s must be a BCP 47 language tag (es, en, pt-BR...)",
+	"isbn":               "%[1]s is not a valid ISBN-10 or ISBN-13 (check digit included)",
+	"pubyear":            "%[1]s must be a year between %[4]d and %[5]d",
+	"genre":              "%[1]s contains a genre outside the catalogue vocabulary: %[3]s",
+	"default":            "%[1]s failed the %[2]s rule",
+}
+
+// Detail - Resumen de un error de validación en el idioma indicado
+func Detail(lang string) string {
+	return i18n.T(lang, "The request has invalid fields")
+}
+
+// Message - Texto de una regla incumplida en el idioma indicado
+func Message(fe validator.FieldError, lang string) string {
+	field := fe.Field()
+
+	key := fe.Tag()
+	switch key {
+	case "min", "max":
+		key += "." + kindName(fe.Kind())
+	case "required_without":
+		return i18n.Sprintf(lang, messages[key], field, jsonName(fe.Param()))
+	case "oneof":
+		return i18n.Sprintf(lang, messages[key], field, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "genre":
+		return i18n.Sprintf(lang, messages[key], field, fe.Param(), unknownGenres(fe.Value()))
+	case "pubyear":
+		return i18n.Sprintf(lang, messages[key], field, fe.Param(), fe.Value(), MinPublishedYear, MaxPublishedYear())
+	}
+
+	template, ok := messages[key]
+	if !ok {
+		return i18n.Sprintf(lang, messages["default"], field, fe.Tag())
+	}
+	return i18n.Sprintf(lang, template, field, fe.Param())
+}
+
+// kindName - Tipo de campo para elegir el texto de min/max
+func kindName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "list"
+	}
+	return "number"
+}
+
+// unknownGenres - Géneros del valor que no están en el vocabulario
+func unknownGenres(value any) string {
+	list, _ := value.(string)
+	var unknown []string
+	for _, genre := range models.SplitList(list) {
+		if !IsGenre(genre) {
+			unknown = append(unknown, genre)
+		}
+	}
+	return strings.Join(unknown, ", ")
+}
+
+// jsonName - Nombre JSON aproximado de un campo de Go citado en una regla
+// (required_without=Authors → authors)
+func jsonName(goName string) string {
+	return strings.ToLower(goName)
+}
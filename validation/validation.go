@@ -0,0 +1,140 @@
+// Package validation - Reglas de validación propias de las peticiones (ISBN,
+// año de publicación, vocabulario de géneros) y sus mensajes, que se traducen
+// con el catálogo de i18n
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"library-api/isbn"
+	"library-api/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MinPublishedYear - Primer año de publicación aceptado (imprenta de Gutenberg)
+const MinPublishedYear = 1450
+
+// Genres - Vocabulario controlado de géneros y materias (español e inglés).
+// La comparación ignora mayúsculas y tildes
+var Genres = []string{
+	// Español
+	"Novela", "Novela corta", "Novela gráfica", "Cuento", "Poesía", "Teatro",
+	"Ensayo", "Crónica", "Biografía", "Autobiografía", "Memorias", "Historia",
+	"Ciencia ficción", "Ciencia ficción política", "Fantasía", "Terror",
+	"Misterio", "Policiaca", "Suspenso", "Romance", "Aventura", "Drama",
+	"Humor", "Sátira", "Distopía", "Realismo mágico", "Clásicos", "Filosofía",
+	"Ciencia", "Divulgación científica", "Tecnología", "Informática",
+	"Programación", "Matemáticas", "Economía", "Política", "Sociología",
+	"Psicología", "Religión", "Arte", "Música", "Cocina", "Viajes",
+	"Autoayuda", "Educación", "Derecho", "Medicina", "Salud", "Deportes",
+	"Infantil", "Juvenil", "Cómic", "Referencia",
+	// Inglés
+	"Fiction", "Novel", "Short stories", "Graphic novel", "Poetry", "Drama",
+	"Essays", "Biography", "Autobiography", "Memoir", "History",
+	"Science fiction", "Fantasy", "Horror", "Mystery", "Crime", "Thriller",
+	"Romance", "Adventure", "Humor", "Satire", "Dystopia", "Magical realism",
+	"Classics", "Philosophy", "Science", "Popular science", "Technology",
+	"Computers", "Programming", "Mathematics", "Economics", "Politics",
+	"Sociology", "Psychology", "Religion", "Art", "Music", "Cooking", "Travel",
+	"Self-help", "Education", "Law", "Medicine", "Health", "Sports",
+	"Children", "Young adult", "Comics", "Reference",
+}
+
+// genreIndex - Géneros normalizados para buscarlos en O(1)
+var genreIndex = make(map[string]bool)
+
+func init() {
+	for _, genre := range Genres {
+		genreIndex[genreKey(genre)] = true
+	}
+
+	// Las reglas se registran en el validador de Gin: ShouldBindJSON las aplica
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		if err := Register(validate); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register - Registra las reglas propias y el nombre JSON de los campos
+func Register(validate *validator.Validate) error {
+	// Los errores de validación usan el nombre JSON del campo, no el de Go
+	validate.RegisterTagNameFunc(jsonFieldName)
+
+	rules := map[string]validator.Func{
+		"isbn":    validISBN,
+		"pubyear": validPublishedYear,
+		"genre":   validGenre,
+	}
+	for tag, fn := range rules {
+		if err := validate.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsGenre - Indica si el nombre pertenece al vocabulario de géneros
+func IsGenre(name string) bool {
+	return genreIndex[genreKey(name)]
+}
+
+// MaxPublishedYear - Último año aceptado: el siguiente al actual (preventas)
+func MaxPublishedYear() int {
+	return time.Now().Year() + 1
+}
+
+// ==============================================
+// REGLAS
+// ==============================================
+
+// validISBN - ISBN-10 o ISBN-13 con dígito de control correcto
+func validISBN(fl validator.FieldLevel) bool {
+	return isbn.IsValid(fl.Field().String())
+}
+
+// validPublishedYear - Año entre MinPublishedYear y el año siguiente al actual
+func validPublishedYear(fl validator.FieldLevel) bool {
+	year := fl.Field().Int()
+	return year >= MinPublishedYear && year <= int64(MaxPublishedYear())
+}
+
+// validGenre - Cada género de la lista (separada por comas) es del vocabulario
+func validGenre(fl validator.FieldLevel) bool {
+	for _, genre := range models.SplitList(fl.Field().String()) {
+		if !IsGenre(genre) {
+			return false
+		}
+	}
+	return true
+}
+
+// genreKey - Minúsculas y sin tildes: "Poesía" y "poesia" son el mismo género
+func genreKey(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(strings.TrimSpace(name))) {
+		if unicode.Is(unicode.Mn, r) { // tildes y diéresis tras NFD
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// jsonFieldName - Nombre del campo en la etiqueta json
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
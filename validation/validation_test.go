@@ -0,0 +1,60 @@
+package validation_test
+
+import (
+	"errors"
+	"testing"
+
+	"library-api/i18n"
+	"library-api/models"
+	"library-api/validation"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestIsGenreIgnoresCaseAndAccents(t *testing.T) {
+	for _, genre := range []string{"Poesía", "poesia", "CIENCIA FICCIÓN", "science fiction"} {
+		if !validation.IsGenre(genre) {
+			t.Errorf("IsGenre(%q): got false", genre)
+		}
+	}
+	if validation.IsGenre("Recetas de la abuela") {
+		t.Error("IsGenre: accepted a genre outside the vocabulary")
+	}
+}
+
+func TestCreateBookRequestRules(t *testing.T) {
+	req := models.CreateBookRequest{
+		Title:     "Rayuela",
+		Author:    "Julio Cortázar",
+		ISBN:      "978-84-376-0494-8",
+		Published: 3000,
+		Genre:     "Novela, Cosas",
+		PageCount: -1,
+		CoverURL:  "not a url",
+	}
+	err := binding.Validator.ValidateStruct(req)
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("ValidateStruct: got %v", err)
+	}
+
+	messages := make(map[string]string)
+	for _, fe := range validationErrors {
+		messages[fe.Field()] = validation.Message(fe, i18n.Spanish)
+	}
+	want := map[string]string{
+		"isbn":       "isbn no es un ISBN-10 o ISBN-13 válido (revisa el dígito de control)",
+		"genre":      "genre contiene un género fuera del vocabulario del catálogo: Cosas",
+		"page_count": "page_count debe ser como mínimo 1",
+		"cover_url":  "cover_url debe ser una URL válida",
+	}
+	for field, message := range want {
+		if messages[field] != message {
+			t.Errorf("%s: got %q, want %q", field, messages[field], message)
+		}
+	}
+	if _, ok := messages["published"]; !ok || len(messages) != len(want)+1 {
+		t.Errorf("got errors %v", messages)
+	}
+}